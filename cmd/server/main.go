@@ -1,24 +1,51 @@
 package main
 
 import (
+	"context"
 	"log"
+	"net/http"
 	"os"
+	"os/exec"
+	"strings"
+	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/go-redis/redis/v8"
 	"github.com/joho/godotenv"
 
+	"socialnetwork/internal/account"
+	"socialnetwork/internal/analytics"
+	"socialnetwork/internal/audit"
+	"socialnetwork/internal/auth"
+	"socialnetwork/internal/auth/oidc"
+	"socialnetwork/internal/auth/oidc/mock"
 	"socialnetwork/internal/comment"
+	"socialnetwork/internal/contentfilter"
+	"socialnetwork/internal/export"
+	"socialnetwork/internal/feed"
 	"socialnetwork/internal/follow"
+	"socialnetwork/internal/groups"
+	"socialnetwork/internal/messaging"
+	"socialnetwork/internal/moderation"
 	"socialnetwork/internal/notification"
 	"socialnetwork/internal/otp"
+	"socialnetwork/internal/passwordless"
 	"socialnetwork/internal/post"
+	"socialnetwork/internal/processing"
+	"socialnetwork/internal/revision"
 	"socialnetwork/internal/short"
+	"socialnetwork/internal/trash"
+	"socialnetwork/internal/twofactor"
 	"socialnetwork/internal/user"
 	"socialnetwork/internal/video"
+	"socialnetwork/internal/views"
+	pkgauth "socialnetwork/pkg/auth"
 	"socialnetwork/pkg/config"
 	"socialnetwork/pkg/email"
+	"socialnetwork/pkg/media"
+	"socialnetwork/pkg/middleware"
+	"socialnetwork/pkg/ratelimit"
 	"socialnetwork/pkg/sms"
 	"socialnetwork/routes"
 )
@@ -45,6 +72,22 @@ func main() {
 		log.Fatalf("❌ Redis connection failed: %v", err)
 	}
 
+	// Rate limit dùng chung Redis để giới hạn đúng khi chạy nhiều instance
+	limiter := ratelimit.New(redisClient)
+
+	// Thu hồi phiên (vd: user báo "không phải tôi"): JWT cấp trước thời điểm thu hồi bị từ chối
+	sessionStore := pkgauth.NewSessionStore(redisClient)
+	middleware.UseSessionStore(sessionStore)
+
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = "8080"
+	}
+	appBaseURL := os.Getenv("APP_BASE_URL")
+	if appBaseURL == "" {
+		appBaseURL = "http://localhost:" + port
+	}
+
 	// Email & SMS Sender
 	smtpHost := os.Getenv("SMTP_HOST")
 	smtpPort := os.Getenv("SMTP_PORT")
@@ -63,23 +106,83 @@ func main() {
 	smsSender := sms.NewMockSMSSender()
 	log.Println("✅ Using MockSMS sender")
 
+	// Khoá HMAC để băm OTP; mặc định dùng chung JWT_SECRET
+	otpSecret := os.Getenv("OTP_SECRET")
+	if otpSecret == "" {
+		otpSecret = os.Getenv("JWT_SECRET")
+	}
+
+	// Băm mật khẩu: argon2id mặc định, hash bcrypt cũ được băm lại khi user đăng nhập
+	hashConfig, err := pkgauth.HashConfigFromEnv()
+	if err != nil {
+		log.Fatalf("❌ Invalid password hash config: %v", err)
+	}
+	pkgauth.SetHashConfig(hashConfig)
+
 	// Services & Handlers
 	userRepo := user.NewRepository(db)
-	otpService := otp.NewService(redisClient, emailSender, smsSender, userRepo)
-	userService := user.NewService(userRepo, otpService, emailSender)
+	if err := userRepo.EnsureIndexes(context.Background()); err != nil {
+		log.Println("⚠️ Không tạo được unique index cho users (kiểm tra dữ liệu trùng username/email/phone):", err)
+	}
+	// Nhật ký bảo mật (chỉ thêm): đăng nhập, đổi mật khẩu/email, 2FA, OTP, thao tác admin
+	auditRepo := audit.NewRepository(db)
+	if err := auditRepo.EnsureIndexes(context.Background()); err != nil {
+		log.Println("⚠️ Không tạo được index cho audit_events:", err)
+	}
+	auditLog := audit.NewLogger(auditRepo)
+	auditHandler := audit.NewHandler(audit.NewService(auditRepo))
+
+	otpService := otp.NewService(redisClient, emailSender, smsSender, userRepo, auditLog, otp.Config{
+		Secret: []byte(otpSecret),
+	})
+	// Xác thực hai lớp: Login trả challenge thay cho JWT khi user đã bật TOTP
+	twoFactorService := twofactor.NewService(twofactor.NewRepository(db), userRepo, otpService, redisClient, auditLog, twofactor.Config{
+		Issuer: os.Getenv("TOTP_ISSUER"),
+	})
+	// Thông báo trong ứng dụng (cảnh báo đăng nhập lạ, bình luận, nội dung mới...)
+	notifRepo := notification.NewNotificationRepository(db)
+
+	// Cảnh báo đăng nhập từ thiết bị/vị trí mới, kèm link "Không phải tôi"
+	loginAlertURL := os.Getenv("LOGIN_ALERT_URL")
+	if loginAlertURL == "" {
+		loginAlertURL = appBaseURL + "/auth/login-alerts/not-me"
+	}
+	userService := user.NewService(userRepo, otpService, emailSender, twoFactorService, notifRepo, redisClient, auditLog, user.Config{
+		BreachListDir: os.Getenv("BREACHED_PASSWORDS_DIR"),
+		LoginAlertURL: loginAlertURL,
+		// Link huỷ yêu cầu đổi email/số điện thoại gửi tới địa chỉ hiện tại
+		ContactChangeCancelURL: appBaseURL + "/users/contact-change/cancel",
+	})
 
 	userHandler := user.NewHandler(userService)
 	otpHandler := otp.NewOTPHandler(otpService)
+	twoFactorHandler := twofactor.NewHandler(twoFactorService, userService)
+
+	revisionRepo := revision.NewRepository(db)
+
+	// Bộ lọc nội dung tự động: luật lưu trong DB, nạp lại mỗi phút; nội dung bị giữ lại vào hàng đợi kiểm duyệt
+	moderationRepo := moderation.NewRepository(db)
+	filterService := contentfilter.NewService(contentfilter.NewRepository(db), moderationRepo)
+	filterHandler := contentfilter.NewHandler(filterService, auditLog)
+	go filterService.RunReloader(context.Background(), time.Minute)
 
 	postRepo := post.NewPostRepository(db)
-	postService := post.NewPostService(&postRepo)
+	postService := post.NewPostService(&postRepo, revisionRepo, filterService)
 	postHandler := post.NewPostHandler(postService)
 
+	// Nhóm/cộng đồng: thành viên, vai trò, bài viết trong nhóm và kiểm duyệt theo nhóm
+	groupsRepo := groups.NewRepository(db)
+	if err := groupsRepo.EnsureIndexes(context.Background()); err != nil {
+		log.Println("⚠️ Không tạo được index cho groups/group_members:", err)
+	}
+	groupsService := groups.NewService(groupsRepo, &postRepo, postService, userRepo, notifRepo)
+	groupsHandler := groups.NewHandler(groupsService)
+
+	analyticsRepo := analytics.NewRepository(db)
 	commentRepo := comment.NewCommentRepository(db)
-	commentService := comment.NewCommentService(commentRepo)
+	commentService := comment.NewCommentService(commentRepo, &postRepo, groupsService, revisionRepo, analyticsRepo, filterService)
 	commentHandler := comment.NewCommentHandler(commentService)
 
-	notifRepo := notification.NewNotificationRepository(db)
 	notifService := notification.NewNotificationService(notifRepo)
 	notifHandler := notification.NewNotificationHandler(notifService)
 
@@ -87,23 +190,155 @@ func main() {
 	followService := follow.NewFollowService(followRepo, userRepo, notifRepo)
 	followHandler := follow.NewFollowHandler(followService)
 
+	// Media processing (probe, transcode HLS, thumbnail)
+	mediaDir := os.Getenv("MEDIA_OUTPUT_DIR")
+	if mediaDir == "" {
+		mediaDir = "./media"
+	}
+	mediaBaseURL := os.Getenv("MEDIA_BASE_URL")
+	if mediaBaseURL == "" {
+		mediaBaseURL = "/media"
+	}
+
+	var mediaProcessor media.Processor
+	ffmpegPath, ffmpegErr := exec.LookPath("ffmpeg")
+	ffprobePath, ffprobeErr := exec.LookPath("ffprobe")
+	if ffmpegErr == nil && ffprobeErr == nil {
+		mediaProcessor = media.NewFFmpegProcessor(ffmpegPath, ffprobePath)
+		log.Println("✅ Using ffmpeg media processor")
+	} else {
+		mediaProcessor = media.NewMockProcessor()
+		log.Println("⚠️ ffmpeg/ffprobe not found. Using mock media processor")
+	}
+	mediaQueue := media.NewRedisJobQueue(redisClient)
+
 	videoRepo := video.NewVideoRepository(db)
-	videoService := video.NewVideoService(videoRepo, followRepo, notifRepo)
+	videoService := video.NewVideoService(videoRepo, followRepo, notifRepo, mediaQueue, revisionRepo, filterService)
 
 	shortRepo := short.NewShortRepository(db)
-	shortService := short.NewShortService(shortRepo, followRepo, notifRepo)
+	shortService := short.NewShortService(shortRepo, followRepo, notifRepo, mediaQueue, revisionRepo, filterService)
+
+	// URL gốc của kho upload, cách nhau bởi dấu phẩy; file gốc ngoài các URL này bị từ chối
+	var mediaSources []string
+	for _, src := range strings.Split(os.Getenv("MEDIA_UPLOAD_URLS"), ",") {
+		if src = strings.TrimSpace(src); src != "" {
+			mediaSources = append(mediaSources, src)
+		}
+	}
+	if len(mediaSources) == 0 {
+		log.Println("⚠️ MEDIA_UPLOAD_URLS chưa cấu hình, mọi video/short sẽ bị từ chối khi xử lý")
+	}
+	mediaWorker := processing.NewWorker(mediaQueue, mediaProcessor, videoRepo, videoService, shortRepo, shortService, processing.Config{
+		OutputDir:      mediaDir,
+		BaseURL:        mediaBaseURL,
+		AllowedSources: mediaSources,
+	})
+	go mediaWorker.Run(context.Background())
+
+	// View tracking (chống trùng trên Redis, flush bộ đếm xuống MongoDB theo lô)
+	viewRepo := views.NewRepository(db)
+	viewService := views.NewService(viewRepo, videoRepo, shortRepo, redisClient, views.Config{})
+	viewHandler := views.NewHandler(viewService)
+	go viewService.RunFlusher(context.Background(), 10*time.Second)
+
+	feedService := feed.NewService(shortRepo, followRepo, userRepo, viewService, redisClient, feed.Config{})
+	feedHandler := feed.NewHandler(feedService)
+
+	analyticsService := analytics.NewService(analyticsRepo, redisClient, 10*time.Minute)
+	analyticsHandler := analytics.NewHandler(analyticsService)
+
+	// Thùng rác: khôi phục trong 30 ngày, sau đó xoá vĩnh viễn kèm dữ liệu phụ thuộc
+	trashService := trash.NewService(&postRepo, commentRepo, videoRepo, shortRepo, notifRepo, revisionRepo, viewRepo, analyticsRepo, trash.Config{
+		MediaDir: mediaDir,
+	})
+	trashHandler := trash.NewHandler(trashService)
+	go trashService.RunPurger(context.Background(), time.Hour)
+
+	// Vô hiệu hoá / xoá tài khoản (xoá vĩnh viễn sau thời gian chờ 30 ngày)
+	accountService := account.NewService(userRepo, userService, sessionStore, &postRepo, commentRepo, followRepo, notifRepo, trashService, emailSender, account.Config{})
+	accountHandler := account.NewHandler(accountService)
+	go accountService.RunCleanup(context.Background(), time.Hour)
+
+	// Xuất dữ liệu cá nhân: build ZIP bất đồng bộ, gửi link tải có chữ ký qua email
+	exportSecret := os.Getenv("EXPORT_SIGNING_SECRET")
+	if exportSecret == "" {
+		exportSecret = os.Getenv("JWT_SECRET")
+	}
+	exportRepo := export.NewRepository(db)
+	exportService := export.NewService(exportRepo, userRepo, emailSender, redisClient, export.Config{
+		Dir:          os.Getenv("EXPORT_DIR"),
+		BaseURL:      appBaseURL,
+		Secret:       []byte(exportSecret),
+		MediaDir:     mediaDir,
+		MediaBaseURL: mediaBaseURL,
+	})
+	exportHandler := export.NewHandler(exportService)
+	go exportService.RunWorker(context.Background())
+
+	// Đăng nhập không mật khẩu: link qua email hoặc mã qua SMS
+	passwordlessLinkURL := os.Getenv("PASSWORDLESS_LINK_URL")
+	if passwordlessLinkURL == "" {
+		passwordlessLinkURL = appBaseURL + "/auth/passwordless/complete"
+	}
+	passwordlessService := passwordless.NewService(userRepo, otpService, emailSender, passwordless.Config{
+		LinkURL: passwordlessLinkURL,
+	})
+	passwordlessHandler := passwordless.NewHandler(passwordlessService, userService)
+
+	// Đăng nhập bằng Google/Facebook/GitHub (OIDC + PKCE), liên kết nhiều tài khoản vào một user
+	identityRepo := auth.NewRepository(db)
+	if err := identityRepo.EnsureIndexes(context.Background()); err != nil {
+		log.Println("⚠️ Không tạo được index cho user_identities:", err)
+	}
+	var oauthProviders []*oidc.Provider
+	for _, cfg := range auth.ProvidersFromEnv(appBaseURL) {
+		oauthProviders = append(oauthProviders, oidc.NewProvider(cfg, nil))
+	}
+	// OAUTH_MOCK=true: provider giả lập tại /mock-oidc để chạy thử khi phát triển
+	var mockProvider *mock.Provider
+	if os.Getenv("OAUTH_MOCK") == "true" {
+		mockProvider, err = mock.New(appBaseURL + "/mock-oidc")
+		if err != nil {
+			log.Fatalf("❌ Failed to create mock OIDC provider: %v", err)
+		}
+		oauthProviders = append(oauthProviders, oidc.NewProvider(oidc.ProviderConfig{
+			Name:        "mock",
+			DisplayName: "Mock",
+			ClientID:    "mock",
+			RedirectURL: auth.CallbackURL(appBaseURL, "mock"),
+			Scopes:      []string{"openid", "email", "profile"},
+			Issuer:      mockProvider.Issuer,
+		}, nil))
+		log.Println("⚠️ OAUTH_MOCK bật: đang dùng OIDC provider giả lập")
+	}
+	oauthService := auth.NewService(identityRepo, userRepo, redisClient, oauthProviders, auth.Config{})
+	oauthHandler := auth.NewHandler(oauthService, userService)
+
+	// Báo cáo vi phạm & hàng đợi kiểm duyệt
+	moderationService := moderation.NewService(moderationRepo, userRepo, notifRepo, sessionStore, auditLog, moderation.Config{})
+	moderationHandler := moderation.NewHandler(moderationService)
+
+	// Tin nhắn trực tiếp/nhóm; sự kiện realtime qua WebSocket, phát giữa các instance bằng Redis pub/sub
+	messagingRepo := messaging.NewRepository(db)
+	if err := messagingRepo.EnsureIndexes(context.Background()); err != nil {
+		log.Println("⚠️ Không tạo được index cho conversations/messages:", err)
+	}
+	messagingHub := messaging.NewHub(redisClient)
+	go messagingHub.Run(context.Background())
+	messagingService := messaging.NewService(messagingRepo, userRepo, followRepo, userService, messagingHub, messaging.Config{})
+	messagingHandler := messaging.NewHandler(messagingService, messagingHub)
 
 	// Gin Setup
 	gin.SetMode(gin.ReleaseMode)
 	r := gin.New()
-	r.Use(gin.Logger(), gin.Recovery())
+	r.Use(gin.Logger(), gin.Recovery(), middleware.RequestInfo())
 
 	// ✅ CORS Middleware cho phép React kết nối
 	r.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"http://localhost:5173"}, // FE Vite default
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
 		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
+		ExposeHeaders:    []string{"Content-Length", "Retry-After"},
 		AllowCredentials: true,
 	}))
 
@@ -111,25 +346,40 @@ func main() {
 		log.Fatalf("❌ Failed to set trusted proxies: %v", err)
 	}
 
+	// REQUIRE_VERIFIED_EMAIL=true: phải xác minh email mới được đăng bài/bình luận/tải video
+	verifiedGuard := middleware.RequireVerifiedEmail(db, os.Getenv("REQUIRE_VERIFIED_EMAIL") == "true")
+	// Tài khoản bị tạm khoá không được ghi: route ghi nào không qua verifiedGuard thì qua activeGuard
+	activeGuard := middleware.RequireActiveAccount(db)
+
 	// Routes
-	r.POST("/register", userHandler.Register)
-	r.POST("/login", userHandler.Login)
+	r.Static("/media", mediaDir)
+	routes.AuthRoutes(r, userHandler, limiter)
+	routes.TwoFactorRoutes(r, twoFactorHandler, limiter)
+	routes.PasswordlessRoutes(r, passwordlessHandler, limiter)
+	routes.OAuthRoutes(r, oauthHandler, limiter)
+	if mockProvider != nil {
+		r.Any("/mock-oidc/*path", gin.WrapH(http.StripPrefix("/mock-oidc", mockProvider.Handler())))
+	}
 
-	routes.UserRoutes(r, db, userHandler)
-	routes.OTProutes(r, otpHandler)
-	routes.PostRoutes(r, postHandler)
-	routes.CommentRoutes(r, db, commentHandler)
+	routes.UserRoutes(r, db, userHandler, limiter, activeGuard)
+	routes.OTProutes(r, otpHandler, limiter)
+	routes.PostRoutes(r, postHandler, limiter, verifiedGuard)
+	routes.CommentRoutes(r, db, commentHandler, limiter, verifiedGuard, activeGuard)
 
 	api := r.Group("/api")
-	routes.FollowRoutes(api, db, followHandler)
-	routes.Video_ShortRoutes(r, videoService, shortService)
+	routes.FollowRoutes(api, db, followHandler, activeGuard)
+	routes.Video_ShortRoutes(r, videoService, shortService, limiter, verifiedGuard)
+	routes.ViewRoutes(r, viewHandler, limiter)
+	routes.FeedRoutes(r, feedHandler)
+	routes.MeRoutes(r, analyticsHandler, trashHandler, accountHandler, exportHandler, activeGuard)
+	routes.ExportRoutes(r, exportHandler)
+	routes.ModerationRoutes(r, db, moderationHandler, filterHandler, activeGuard)
+	routes.AuditRoutes(r, db, auditHandler)
+	routes.MessagingRoutes(r, messagingHandler, limiter, activeGuard)
+	routes.GroupRoutes(r, groupsHandler, limiter, verifiedGuard, activeGuard)
 	routes.NotificationRoutes(api, notifHandler)
 
 	// Run server
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
-	}
 	log.Println("🚀 Server is running at port:", port)
 	if err := r.Run(":" + port); err != nil {
 		log.Fatalf("❌ Failed to start server: %v", err)
@@ -0,0 +1,8 @@
+package request
+
+// ConfirmPasswordRequest - xác nhận lại trước thao tác nhạy cảm (vô hiệu hoá, xoá tài khoản):
+// mật khẩu hoặc mã 2FA; tài khoản chỉ đăng nhập OIDC bỏ trống cả hai và đăng nhập lại ngay trước đó
+type ConfirmPasswordRequest struct {
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
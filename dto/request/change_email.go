@@ -1,10 +1,28 @@
 package request
 
+// Đổi email/số điện thoại phải xác thực lại bằng mật khẩu hoặc mã 2FA (Code: TOTP hoặc mã khôi phục)
 type ChangeEmailRequest struct {
-	OldEmail string `json:"oldEmail" binding:"required,email"`
+	OldEmail string `json:"oldEmail" binding:"omitempty,email"` // trống khi tài khoản chưa có email
 	NewEmail string `json:"newEmail" binding:"required,email"`
+	Password string `json:"password,omitempty"`
+	Code     string `json:"code,omitempty"`
 }
 
 type VerifyEmailRequest struct {
 	OTP      string `json:"otp" binding:"required"`
 }
+
+type ChangePhoneRequest struct {
+	NewPhone string `json:"newPhone" binding:"required"`
+	Password string `json:"password,omitempty"`
+	Code     string `json:"code,omitempty"`
+}
+
+type VerifyPhoneRequest struct {
+	OTP string `json:"otp" binding:"required"`
+}
+
+// CancelContactChangeRequest - token trong link huỷ gửi tới email/số điện thoại hiện tại
+type CancelContactChangeRequest struct {
+	Token string `json:"token" form:"token" binding:"required"`
+}
@@ -0,0 +1,54 @@
+package request
+
+type GroupRuleRequest struct {
+	Title       string `json:"title" binding:"required,max=100"`
+	Description string `json:"description" binding:"omitempty,max=1000"`
+}
+
+type CreateGroupRequest struct {
+	Name         string             `json:"name" binding:"required,min=3,max=100"`
+	Description  string             `json:"description" binding:"omitempty,max=2000"`
+	Privacy      string             `json:"privacy" binding:"required,oneof=public private secret"`
+	PostApproval bool               `json:"postApproval"`
+	Rules        []GroupRuleRequest `json:"rules" binding:"omitempty,max=20,dive"`
+}
+
+// UpdateGroupRequest - chỉ cập nhật các field được gửi lên
+type UpdateGroupRequest struct {
+	Name         *string `json:"name" binding:"omitempty,min=3,max=100"`
+	Description  *string `json:"description" binding:"omitempty,max=2000"`
+	Privacy      *string `json:"privacy" binding:"omitempty,oneof=public private secret"`
+	PostApproval *bool   `json:"postApproval"`
+}
+
+type UpdateGroupRulesRequest struct {
+	Rules []GroupRuleRequest `json:"rules" binding:"max=20,dive"`
+}
+
+type GroupInviteRequest struct {
+	UserID string `json:"userId" binding:"required"`
+}
+
+type GroupRoleRequest struct {
+	Role string `json:"role" binding:"required,oneof=owner admin moderator member"`
+}
+
+// GroupModerationRequest - lý do xử lý, ghi vào nhật ký kiểm duyệt của nhóm và gửi cho người bị xử lý
+type GroupModerationRequest struct {
+	Reason string `json:"reason" binding:"omitempty,max=500"`
+}
+
+type GroupMuteRequest struct {
+	Hours  int    `json:"hours" binding:"required,min=1,max=720"`
+	Reason string `json:"reason" binding:"omitempty,max=500"`
+}
+
+type GroupNotificationsRequest struct {
+	Level string `json:"level" binding:"required,oneof=all off"`
+}
+
+type GroupPostRequest struct {
+	Content  string   `json:"content" binding:"required,max=10000"`
+	ImageURL string   `json:"image_url" binding:"omitempty,url"`
+	Media    []string `json:"media" binding:"omitempty,max=10,dive,url"`
+}
@@ -0,0 +1,5 @@
+package request
+
+type LoginAlertRequest struct {
+	Token string `json:"token" form:"token" binding:"required"`
+}
@@ -0,0 +1,36 @@
+package request
+
+type StartDirectConversationRequest struct {
+	UserID string `json:"userId" binding:"required"`
+}
+
+type CreateGroupConversationRequest struct {
+	Title     string   `json:"title" binding:"required,max=100"`
+	MemberIDs []string `json:"memberIds" binding:"required,min=1"`
+}
+
+type AddConversationMembersRequest struct {
+	MemberIDs []string `json:"memberIds" binding:"required,min=1"`
+}
+
+type MessageAttachmentRequest struct {
+	Type     string `json:"type" binding:"required,oneof=image video audio file"`
+	URL      string `json:"url" binding:"required,url"`
+	Name     string `json:"name" binding:"omitempty,max=255"`
+	MimeType string `json:"mimeType" binding:"omitempty,max=100"`
+	Size     int64  `json:"size" binding:"omitempty,min=0"`
+}
+
+// SendMessageRequest - cần ít nhất nội dung hoặc một tệp đính kèm
+type SendMessageRequest struct {
+	Text        string                     `json:"text" binding:"max=5000"`
+	Attachments []MessageAttachmentRequest `json:"attachments" binding:"max=10,dive"`
+}
+
+type EditMessageRequest struct {
+	Text string `json:"text" binding:"required,max=5000"`
+}
+
+type MarkReadRequest struct {
+	MessageID string `json:"messageId"` // trống thì đánh dấu đã đọc tới tin mới nhất
+}
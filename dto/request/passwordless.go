@@ -0,0 +1,13 @@
+package request
+
+type PasswordlessStartRequest struct {
+	Channel    string `json:"channel" binding:"required,oneof=email phone"`
+	Identifier string `json:"identifier" binding:"required"` // email hoặc số điện thoại
+}
+
+// PasswordlessCompleteRequest - email: Identifier là uid trong link, Code là token; phone: số điện thoại và mã SMS
+type PasswordlessCompleteRequest struct {
+	Channel    string `json:"channel" binding:"required,oneof=email phone"`
+	Identifier string `json:"identifier" binding:"required"`
+	Code       string `json:"code" binding:"required"`
+}
@@ -0,0 +1,29 @@
+package request
+
+// CreateReportRequest - báo cáo post/comment/video/short/user vi phạm
+type CreateReportRequest struct {
+	TargetType string `json:"targetType" binding:"required,oneof=post comment video short user"`
+	TargetID   string `json:"targetId" binding:"required"`
+	Reason     string `json:"reason" binding:"required"`
+	Details    string `json:"details" binding:"omitempty,max=1000"`
+}
+
+// ModerationActionRequest - kiểm duyệt viên xử lý các báo cáo của một nội dung/user
+type ModerationActionRequest struct {
+	Action      string `json:"action" binding:"required,oneof=dismiss remove warn suspend"`
+	Note        string `json:"note" binding:"omitempty,max=1000"`
+	SuspendDays int    `json:"suspendDays" binding:"omitempty,min=1,max=3650"`
+}
+
+// FilterRuleRequest - tạo/cập nhật luật lọc nội dung tự động
+type FilterRuleRequest struct {
+	Name      string   `json:"name" binding:"required,max=100"`
+	Type      string   `json:"type" binding:"required,oneof=words links spam"`
+	Language  string   `json:"language" binding:"omitempty,max=10"`
+	Words     []string `json:"words" binding:"omitempty,dive,min=1,max=100"`
+	Domains   []string `json:"domains" binding:"omitempty,dive,min=1,max=253"`
+	MaxLinks  int      `json:"maxLinks" binding:"omitempty,min=0"`
+	MaxRepeat int      `json:"maxRepeat" binding:"omitempty,min=0"`
+	Action    string   `json:"action" binding:"required,oneof=mask hold reject"`
+	Enabled   *bool    `json:"enabled"`
+}
@@ -0,0 +1,21 @@
+package request
+
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type TwoFactorDisableRequest struct {
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code" binding:"required"` // mã TOTP hoặc mã khôi phục
+}
+
+type TwoFactorVerifyRequest struct {
+	ChallengeToken string `json:"challengeToken" binding:"required"`
+	Method         string `json:"method" binding:"required,oneof=totp recovery email phone"`
+	Code           string `json:"code" binding:"required"`
+}
+
+type TwoFactorSendRequest struct {
+	ChallengeToken string `json:"challengeToken" binding:"required"`
+	Channel        string `json:"channel" binding:"required,oneof=email phone"`
+}
@@ -2,6 +2,7 @@ package request
 
 type RegisterRequest struct {
 	Name       string `json:"name" binding:"required,min=2,max=100"`
+	Username   string `json:"username" binding:"omitempty,alphanum,min=3,max=30"`
 	Identifier string `json:"identifier" binding:"required"`
 	Password   string `json:"password" binding:"required,min=8,max=32"`
 }
\ No newline at end of file
@@ -13,6 +13,8 @@ type UpdateProfileRequest struct {
 	Location    string     `json:"location,omitempty"`
 	Website     string     `json:"website,omitempty"`
 	Phone       string     `json:"phone,omitempty"`
+
+	MessagePrivacy string `json:"messagePrivacy,omitempty"` // everyone | friends
 }
 
 type UpdateSecurityRequest struct {
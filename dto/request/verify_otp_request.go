@@ -3,6 +3,6 @@ package request
 type VerifyOTPRequest struct {
 	Identifier string `json:"identifier" binding:"required"`
 	Purpose    string `json:"purpose" binding:"required"`
-	OTP        string `json:"otp" binding:"required,len=6"`
+	OTP        string `json:"otp" binding:"required,numeric,min=4,max=10"`
 	Channel    string `json:"channel" binding:"required"`
 }
@@ -0,0 +1,14 @@
+package request
+
+type UpdateVideoRequest struct {
+	Title       *string `json:"title" binding:"omitempty,min=1,max=150"`
+	Description *string `json:"description" binding:"omitempty,max=5000"`
+	Thumbnail   *string `json:"thumbnail" binding:"omitempty,url"`
+	Visibility  *string `json:"visibility" binding:"omitempty,oneof=public private"`
+}
+
+type UpdateShortRequest struct {
+	Title      *string `json:"title" binding:"omitempty,min=1,max=150"`
+	Thumbnail  *string `json:"thumbnail" binding:"omitempty,url"`
+	Visibility *string `json:"visibility" binding:"omitempty,oneof=public private"`
+}
@@ -0,0 +1,90 @@
+package account
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"socialnetwork/dto/request"
+	"socialnetwork/internal/user"
+	"socialnetwork/pkg/ratelimit"
+)
+
+type Handler struct {
+	service Service
+}
+
+func NewHandler(service Service) *Handler {
+	return &Handler{service: service}
+}
+
+// POST /me/deactivate
+func (h *Handler) Deactivate(c *gin.Context) {
+	userID, err := primitive.ObjectIDFromHex(c.GetString("userID"))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	// Tài khoản chỉ đăng nhập OIDC có thể không gửi body (xác thực bằng phiên vừa đăng nhập)
+	var req request.ConfirmPasswordRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	if err := h.service.Deactivate(c.Request.Context(), userID, confirmation(c, req)); err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Tài khoản đã được vô hiệu hoá. Đăng nhập lại để kích hoạt."})
+}
+
+// DELETE /me
+func (h *Handler) Delete(c *gin.Context) {
+	userID, err := primitive.ObjectIDFromHex(c.GetString("userID"))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	// Tài khoản chỉ đăng nhập OIDC có thể không gửi body (xác thực bằng phiên vừa đăng nhập)
+	var req request.ConfirmPasswordRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	deleteAt, err := h.service.RequestDeletion(c.Request.Context(), userID, confirmation(c, req))
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{
+		"message":             "Tài khoản sẽ bị xoá vĩnh viễn sau thời gian chờ. Đăng nhập lại để huỷ.",
+		"deletionScheduledAt": deleteAt,
+	})
+}
+
+func confirmation(c *gin.Context, req request.ConfirmPasswordRequest) Confirmation {
+	return Confirmation{Password: req.Password, Code: req.Code, IssuedAt: c.GetTime("tokenIssuedAt")}
+}
+
+func writeError(c *gin.Context, err error) {
+	if ratelimit.WriteError(c, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, user.ErrReauthRequired), errors.Is(err, user.ErrReauthFailed):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error(), "code": "reauth_required"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
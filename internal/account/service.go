@@ -0,0 +1,212 @@
+package account
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"socialnetwork/internal/comment"
+	"socialnetwork/internal/follow"
+	"socialnetwork/internal/notification"
+	"socialnetwork/internal/post"
+	"socialnetwork/internal/trash"
+	"socialnetwork/internal/user"
+	"socialnetwork/models"
+	"socialnetwork/pkg/auth"
+	"socialnetwork/pkg/email"
+)
+
+var ErrUserNotFound = errors.New("người dùng không tồn tại")
+
+// Reauthenticator - xác thực lại bằng mật khẩu, mã 2FA hoặc phiên vừa đăng nhập (user.Service)
+type Reauthenticator interface {
+	Reauthenticate(ctx context.Context, user *models.User, password, code string, issuedAt time.Time) error
+}
+
+// Confirmation - thông tin xác thực lại gửi kèm yêu cầu vô hiệu hoá/xoá tài khoản
+type Confirmation struct {
+	Password string
+	Code     string
+	IssuedAt time.Time // thời điểm cấp JWT của request
+}
+
+type Config struct {
+	GracePeriod time.Duration // thời gian chờ trước khi xoá vĩnh viễn, đăng nhập lại để huỷ
+	BatchSize   int64
+}
+
+type Service interface {
+	Deactivate(ctx context.Context, userID primitive.ObjectID, confirm Confirmation) error
+	RequestDeletion(ctx context.Context, userID primitive.ObjectID, confirm Confirmation) (time.Time, error)
+	Cleanup(ctx context.Context) (int, error)
+	RunCleanup(ctx context.Context, interval time.Duration)
+}
+
+type service struct {
+	userRepo         user.Repository
+	reauth           Reauthenticator
+	sessions         *auth.SessionStore
+	postRepo         *post.PostRepository
+	commentRepo      comment.Repository
+	followRepo       follow.FollowRepository
+	notificationRepo notification.NotificationRepository
+	trashService     trash.Service
+	emailSender      email.EmailSender
+	config           Config
+}
+
+func NewService(
+	userRepo user.Repository,
+	reauth Reauthenticator,
+	sessions *auth.SessionStore,
+	postRepo *post.PostRepository,
+	commentRepo comment.Repository,
+	followRepo follow.FollowRepository,
+	notificationRepo notification.NotificationRepository,
+	trashService trash.Service,
+	emailSender email.EmailSender,
+	config Config,
+) Service {
+	if config.GracePeriod <= 0 {
+		config.GracePeriod = 30 * 24 * time.Hour
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = 20
+	}
+	return &service{
+		userRepo:         userRepo,
+		reauth:           reauth,
+		sessions:         sessions,
+		postRepo:         postRepo,
+		commentRepo:      commentRepo,
+		followRepo:       followRepo,
+		notificationRepo: notificationRepo,
+		trashService:     trashService,
+		emailSender:      emailSender,
+		config:           config,
+	}
+}
+
+func (s *service) confirm(ctx context.Context, userID primitive.ObjectID, confirm Confirmation) (*models.User, error) {
+	u, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil || u.DeletedAt != nil {
+		return nil, ErrUserNotFound
+	}
+	if err := s.reauth.Reauthenticate(ctx, u, confirm.Password, confirm.Code, confirm.IssuedAt); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// Deactivate ẩn tài khoản và toàn bộ nội dung, thu hồi mọi phiên; đăng nhập lại để khôi phục
+func (s *service) Deactivate(ctx context.Context, userID primitive.ObjectID, confirm Confirmation) error {
+	if _, err := s.confirm(ctx, userID, confirm); err != nil {
+		return err
+	}
+	if err := s.userRepo.Deactivate(ctx, userID, nil); err != nil {
+		return err
+	}
+	return s.sessions.RevokeAll(ctx, userID.Hex())
+}
+
+// RequestDeletion vô hiệu hoá tài khoản ngay và lên lịch xoá vĩnh viễn sau GracePeriod
+func (s *service) RequestDeletion(ctx context.Context, userID primitive.ObjectID, confirm Confirmation) (time.Time, error) {
+	u, err := s.confirm(ctx, userID, confirm)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if u.DeletionScheduledAt != nil {
+		return *u.DeletionScheduledAt, s.sessions.RevokeAll(ctx, userID.Hex())
+	}
+
+	deleteAt := time.Now().Add(s.config.GracePeriod)
+	if err := s.userRepo.Deactivate(ctx, userID, &deleteAt); err != nil {
+		return time.Time{}, err
+	}
+	if err := s.sessions.RevokeAll(ctx, userID.Hex()); err != nil {
+		return time.Time{}, err
+	}
+
+	if u.Email != "" {
+		body := fmt.Sprintf(
+			"Tài khoản của bạn sẽ bị xoá vĩnh viễn vào %s. Nếu đổi ý, chỉ cần đăng nhập lại trước thời điểm này để huỷ yêu cầu xoá.",
+			deleteAt.Format("02/01/2006 15:04"),
+		)
+		if err := s.emailSender.Send(u.Email, "Yêu cầu xoá tài khoản", body); err != nil {
+			log.Printf("⚠️ không gửi được email xác nhận xoá tài khoản %s: %v", userID.Hex(), err)
+		}
+	}
+	return deleteAt, nil
+}
+
+// RunCleanup định kỳ xoá các tài khoản đã hết thời gian chờ
+func (s *service) RunCleanup(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := s.Cleanup(ctx)
+			if err != nil {
+				log.Printf("⚠️ xoá tài khoản thất bại: %v", err)
+			} else if n > 0 {
+				log.Printf("🗑️ đã xoá vĩnh viễn %d tài khoản", n)
+			}
+		}
+	}
+}
+
+func (s *service) Cleanup(ctx context.Context) (int, error) {
+	users, err := s.userRepo.FindDueDeletions(ctx, time.Now(), s.config.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+	for i := range users {
+		if err := s.deleteAccount(ctx, users[i].ID); err != nil {
+			return i, fmt.Errorf("user %s: %w", users[i].ID.Hex(), err)
+		}
+	}
+	return len(users), nil
+}
+
+// deleteAccount xoá nội dung, quan hệ xã hội, thông báo và ẩn danh hoá hồ sơ.
+// Các bước đều chạy lại được nếu lần trước bị lỗi giữa chừng.
+func (s *service) deleteAccount(ctx context.Context, userID primitive.ObjectID) error {
+	// Post (kèm comment, thông báo), comment, video, short (kèm file media)
+	if _, err := s.trashService.PurgeOwner(ctx, userID); err != nil {
+		return err
+	}
+	if err := s.postRepo.PullUserReactions(ctx, userID); err != nil {
+		return err
+	}
+	if err := s.commentRepo.PullUserLikes(ctx, userID); err != nil {
+		return err
+	}
+
+	// Xoá quan hệ follow và trừ lại bộ đếm của người liên quan
+	follows, err := s.followRepo.DeleteAllByUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, f := range follows {
+		if f.Follower == userID {
+			_ = s.userRepo.DecrementFollowerCount(ctx, f.Following)
+		} else {
+			_ = s.userRepo.DecrementFollowingCount(ctx, f.Follower)
+		}
+	}
+
+	if err := s.userRepo.RemoveFromSocialGraph(ctx, userID); err != nil {
+		return err
+	}
+	if err := s.notificationRepo.DeleteByUser(ctx, userID); err != nil {
+		return err
+	}
+	return s.userRepo.Anonymize(ctx, userID)
+}
@@ -0,0 +1,275 @@
+package analytics
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ReactionComment - loại nội dung của lượt thích trong reaction_events
+const ReactionComment = "comment"
+
+// dayCount - kết quả group theo ngày (YYYY-MM-DD)
+type dayCount struct {
+	Day   string `bson:"_id"`
+	Count int    `bson:"count"`
+}
+
+// Bucket - một nhóm trong phân tích khán giả
+type Bucket struct {
+	Key   string `bson:"_id" json:"key"`
+	Count int    `bson:"count" json:"count"`
+}
+
+// TopItem - nội dung nổi bật
+type TopItem struct {
+	ID        primitive.ObjectID `bson:"_id" json:"id"`
+	Title     string             `bson:"title,omitempty" json:"title,omitempty"`
+	Views     int                `bson:"views" json:"views"`
+	Likes     int                `bson:"likes" json:"likes"`
+	Comments  int                `bson:"comments" json:"comments"`
+	CreatedAt time.Time          `bson:"createdAt" json:"createdAt"`
+}
+
+type Repository interface {
+	NewFollowersByDay(ctx context.Context, userID primitive.ObjectID, since time.Time) (map[string]int, error)
+	CountFollowers(ctx context.Context, userID primitive.ObjectID) (int, error)
+	ViewsByDay(ctx context.Context, ownerID primitive.ObjectID, contentType string, since time.Time) (map[string]int, error)
+	ReactionsByDay(ctx context.Context, userID primitive.ObjectID, since time.Time) (map[string]int, error)
+	RecordReaction(ctx context.Context, contentType string, contentID, ownerID, userID primitive.ObjectID, liked bool) error
+	DeleteReactions(ctx context.Context, contentType string, contentIDs []primitive.ObjectID) error
+	CommentsByDay(ctx context.Context, userID primitive.ObjectID, since time.Time) (map[string]int, error)
+	TopVideos(ctx context.Context, ownerID primitive.ObjectID, limit int64) ([]TopItem, error)
+	TopShorts(ctx context.Context, ownerID primitive.ObjectID, limit int64) ([]TopItem, error)
+	TopPosts(ctx context.Context, ownerID primitive.ObjectID, limit int64) ([]TopItem, error)
+	AudienceBy(ctx context.Context, userID primitive.ObjectID, field string, limit int64) ([]Bucket, error)
+	AudienceAgeGroups(ctx context.Context, userID primitive.ObjectID, now time.Time) ([]Bucket, error)
+}
+
+type repository struct {
+	db *mongo.Database
+}
+
+func NewRepository(db *mongo.Database) Repository {
+	return &repository{db: db}
+}
+
+func dayExpr(field string) bson.M {
+	return bson.M{"$dateToString": bson.M{"format": "%Y-%m-%d", "date": field}}
+}
+
+func (r *repository) countByDay(ctx context.Context, collection string, match bson.M, dateField string) (map[string]int, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$group", Value: bson.M{"_id": dayExpr("$" + dateField), "count": bson.M{"$sum": 1}}}},
+	}
+	cursor, err := r.db.Collection(collection).Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var rows []dayCount
+	if err := cursor.All(ctx, &rows); err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int, len(rows))
+	for _, row := range rows {
+		counts[row.Day] = row.Count
+	}
+	return counts, nil
+}
+
+// NewFollowersByDay - số follow mới mỗi ngày (follows.createdAt)
+func (r *repository) NewFollowersByDay(ctx context.Context, userID primitive.ObjectID, since time.Time) (map[string]int, error) {
+	return r.countByDay(ctx, "follows", bson.M{
+		"following": userID,
+		"createdAt": bson.M{"$gte": since},
+	}, "createdAt")
+}
+
+func (r *repository) CountFollowers(ctx context.Context, userID primitive.ObjectID) (int, error) {
+	count, err := r.db.Collection("follows").CountDocuments(ctx, bson.M{"following": userID})
+	return int(count), err
+}
+
+// ViewsByDay - lượt xem (đã chống trùng) mỗi ngày từ view_events
+func (r *repository) ViewsByDay(ctx context.Context, ownerID primitive.ObjectID, contentType string, since time.Time) (map[string]int, error) {
+	return r.countByDay(ctx, "view_events", bson.M{
+		"ownerId":     ownerID,
+		"contentType": contentType,
+		"counted":     true,
+		"createdAt":   bson.M{"$gte": since},
+	}, "createdAt")
+}
+
+// ReactionsByDay - lượt thích nhận được mỗi ngày từ reaction_events
+func (r *repository) ReactionsByDay(ctx context.Context, userID primitive.ObjectID, since time.Time) (map[string]int, error) {
+	return r.countByDay(ctx, "reaction_events", bson.M{
+		"ownerId":   userID,
+		"createdAt": bson.M{"$gte": since},
+	}, "createdAt")
+}
+
+// RecordReaction ghi lượt thích khi thích, xoá khi bỏ thích. Mảng likes trên bài viết/bình luận
+// không lưu thời điểm nên không group theo ngày được, phải ghi riêng vào reaction_events
+func (r *repository) RecordReaction(ctx context.Context, contentType string, contentID, ownerID, userID primitive.ObjectID, liked bool) error {
+	events := r.db.Collection("reaction_events")
+	key := bson.M{"contentType": contentType, "contentId": contentID, "userId": userID}
+	if !liked {
+		_, err := events.DeleteMany(ctx, key)
+		return err
+	}
+	_, err := events.UpdateOne(ctx, key, bson.M{
+		"$setOnInsert": bson.M{"ownerId": ownerID, "createdAt": time.Now()},
+	}, options.Update().SetUpsert(true))
+	return err
+}
+
+// DeleteReactions xoá lượt thích của nội dung đã bị xoá vĩnh viễn
+func (r *repository) DeleteReactions(ctx context.Context, contentType string, contentIDs []primitive.ObjectID) error {
+	if len(contentIDs) == 0 {
+		return nil
+	}
+	_, err := r.db.Collection("reaction_events").DeleteMany(ctx, bson.M{"contentType": contentType, "contentId": bson.M{"$in": contentIDs}})
+	return err
+}
+
+// CommentsByDay - bình luận của người khác trên bài viết của user
+func (r *repository) CommentsByDay(ctx context.Context, userID primitive.ObjectID, since time.Time) (map[string]int, error) {
+	postIDs, err := r.db.Collection("posts").Distinct(ctx, "_id", bson.M{"user_id": userID})
+	if err != nil {
+		return nil, err
+	}
+	if len(postIDs) == 0 {
+		return map[string]int{}, nil
+	}
+	return r.countByDay(ctx, "comments", bson.M{
+		"post_id":    bson.M{"$in": postIDs},
+		"user_id":    bson.M{"$ne": userID},
+		"created_at": bson.M{"$gte": since},
+	}, "created_at")
+}
+
+func (r *repository) topMedia(ctx context.Context, collection string, ownerID primitive.ObjectID, limit int64) ([]TopItem, error) {
+	opts := options.Find().
+		SetSort(bson.D{{Key: "views", Value: -1}, {Key: "likes", Value: -1}}).
+		SetLimit(limit).
+		SetProjection(bson.M{"title": 1, "views": 1, "likes": 1, "createdAt": 1})
+	cursor, err := r.db.Collection(collection).Find(ctx, bson.M{"ownerId": ownerID, "deletedAt": nil}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	items := []TopItem{}
+	err = cursor.All(ctx, &items)
+	return items, err
+}
+
+func (r *repository) TopVideos(ctx context.Context, ownerID primitive.ObjectID, limit int64) ([]TopItem, error) {
+	return r.topMedia(ctx, "videos", ownerID, limit)
+}
+
+func (r *repository) TopShorts(ctx context.Context, ownerID primitive.ObjectID, limit int64) ([]TopItem, error) {
+	return r.topMedia(ctx, "shorts", ownerID, limit)
+}
+
+// TopPosts - xếp bài viết theo số like + số bình luận
+func (r *repository) TopPosts(ctx context.Context, ownerID primitive.ObjectID, limit int64) ([]TopItem, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"user_id": ownerID, "deleted_at": nil}}},
+		{{Key: "$lookup", Value: bson.M{
+			"from":         "comments",
+			"localField":   "_id",
+			"foreignField": "post_id",
+			"as":           "commentDocs",
+		}}},
+		{{Key: "$project", Value: bson.M{
+			"title": bson.M{"$substrCP": bson.A{"$content", 0, 80}},
+			"likes": bson.M{"$size": bson.M{"$ifNull": bson.A{"$likes", bson.A{}}}},
+			"comments": bson.M{"$size": bson.M{"$filter": bson.M{
+				"input": "$commentDocs",
+				"cond":  bson.M{"$not": bson.A{"$$this.deleted_at"}},
+			}}},
+			"createdAt": "$created_at",
+		}}},
+		{{Key: "$addFields", Value: bson.M{"engagement": bson.M{"$add": bson.A{"$likes", "$comments"}}}}},
+		{{Key: "$sort", Value: bson.D{{Key: "engagement", Value: -1}, {Key: "createdAt", Value: -1}}}},
+		{{Key: "$limit", Value: limit}},
+	}
+	cursor, err := r.db.Collection("posts").Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	items := []TopItem{}
+	err = cursor.All(ctx, &items)
+	return items, err
+}
+
+// followerProfiles - pipeline ghép follows với users để lấy hồ sơ follower
+func followerProfiles(userID primitive.ObjectID) mongo.Pipeline {
+	return mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"following": userID}}},
+		{{Key: "$lookup", Value: bson.M{
+			"from":         "users",
+			"localField":   "follower",
+			"foreignField": "_id",
+			"as":           "profile",
+		}}},
+		{{Key: "$unwind", Value: "$profile"}},
+	}
+}
+
+// AudienceBy - phân bố follower theo một trường hồ sơ (gender, location...)
+func (r *repository) AudienceBy(ctx context.Context, userID primitive.ObjectID, field string, limit int64) ([]Bucket, error) {
+	pipeline := append(followerProfiles(userID),
+		bson.D{{Key: "$group", Value: bson.M{
+			"_id":   bson.M{"$ifNull": bson.A{"$profile." + field, "unknown"}},
+			"count": bson.M{"$sum": 1},
+		}}},
+		bson.D{{Key: "$sort", Value: bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}}},
+		bson.D{{Key: "$limit", Value: limit}},
+	)
+	return r.buckets(ctx, pipeline)
+}
+
+// AudienceAgeGroups - phân bố follower theo nhóm tuổi (từ birthDate)
+func (r *repository) AudienceAgeGroups(ctx context.Context, userID primitive.ObjectID, now time.Time) ([]Bucket, error) {
+	yearsAgo := func(n int) time.Time { return now.AddDate(-n, 0, 0) }
+	pipeline := append(followerProfiles(userID),
+		bson.D{{Key: "$group", Value: bson.M{
+			"_id": bson.M{"$switch": bson.M{
+				"branches": bson.A{
+					bson.M{"case": bson.M{"$not": bson.A{"$profile.birthDate"}}, "then": "unknown"},
+					bson.M{"case": bson.M{"$gt": bson.A{"$profile.birthDate", yearsAgo(18)}}, "then": "<18"},
+					bson.M{"case": bson.M{"$gt": bson.A{"$profile.birthDate", yearsAgo(25)}}, "then": "18-24"},
+					bson.M{"case": bson.M{"$gt": bson.A{"$profile.birthDate", yearsAgo(35)}}, "then": "25-34"},
+					bson.M{"case": bson.M{"$gt": bson.A{"$profile.birthDate", yearsAgo(45)}}, "then": "35-44"},
+				},
+				"default": "45+",
+			}},
+			"count": bson.M{"$sum": 1},
+		}}},
+		bson.D{{Key: "$sort", Value: bson.M{"_id": 1}}},
+	)
+	return r.buckets(ctx, pipeline)
+}
+
+func (r *repository) buckets(ctx context.Context, pipeline mongo.Pipeline) ([]Bucket, error) {
+	cursor, err := r.db.Collection("follows").Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	buckets := []Bucket{}
+	err = cursor.All(ctx, &buckets)
+	return buckets, err
+}
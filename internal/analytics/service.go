@@ -0,0 +1,219 @@
+package analytics
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"socialnetwork/internal/views"
+)
+
+const (
+	defaultDays = 30
+	maxDays     = 365
+	topLimit    = 5
+)
+
+// DailyPoint - một điểm trong chuỗi thời gian
+type DailyPoint struct {
+	Date  string `json:"date"` // YYYY-MM-DD (UTC)
+	Value int    `json:"value"`
+}
+
+type FollowerGrowth struct {
+	Total        int          `json:"total"`
+	NewFollowers []DailyPoint `json:"newFollowers"`
+	Cumulative   []DailyPoint `json:"cumulative"`
+}
+
+type ViewSeries struct {
+	Total  int          `json:"total"`
+	Videos []DailyPoint `json:"videos"`
+	Shorts []DailyPoint `json:"shorts"`
+}
+
+type TopContent struct {
+	Videos []TopItem `json:"videos"`
+	Shorts []TopItem `json:"shorts"`
+	Posts  []TopItem `json:"posts"`
+}
+
+type Audience struct {
+	Gender    []Bucket `json:"gender"`
+	Locations []Bucket `json:"locations"`
+	AgeGroups []Bucket `json:"ageGroups"`
+}
+
+// Dashboard - toàn bộ số liệu trả về cho GET /me/analytics
+type Dashboard struct {
+	From           string         `json:"from"`
+	To             string         `json:"to"`
+	Days           int            `json:"days"`
+	FollowerGrowth FollowerGrowth `json:"followerGrowth"`
+	Views          ViewSeries     `json:"views"`
+	Reactions      []DailyPoint   `json:"reactions"`
+	Comments       []DailyPoint   `json:"comments"`
+	TopContent     TopContent     `json:"topContent"`
+	Audience       Audience       `json:"audience"`
+	GeneratedAt    time.Time      `json:"generatedAt"`
+}
+
+type Service interface {
+	GetDashboard(ctx context.Context, userID primitive.ObjectID, days int) (*Dashboard, error)
+}
+
+type service struct {
+	repo        Repository
+	redisClient *redis.Client
+	cacheTTL    time.Duration
+}
+
+func NewService(repo Repository, redisClient *redis.Client, cacheTTL time.Duration) Service {
+	if cacheTTL <= 0 {
+		cacheTTL = 10 * time.Minute
+	}
+	return &service{repo: repo, redisClient: redisClient, cacheTTL: cacheTTL}
+}
+
+func cacheKey(userID primitive.ObjectID, days int) string {
+	return fmt.Sprintf("analytics:me:%s:%d", userID.Hex(), days)
+}
+
+func (s *service) GetDashboard(ctx context.Context, userID primitive.ObjectID, days int) (*Dashboard, error) {
+	if days <= 0 || days > maxDays {
+		days = defaultDays
+	}
+
+	key := cacheKey(userID, days)
+	if cached, err := s.redisClient.Get(ctx, key).Bytes(); err == nil {
+		var d Dashboard
+		if json.Unmarshal(cached, &d) == nil {
+			return &d, nil
+		}
+	}
+
+	d, err := s.build(ctx, userID, days)
+	if err != nil {
+		return nil, err
+	}
+
+	// Lỗi cache không ảnh hưởng kết quả trả về
+	if data, err := json.Marshal(d); err == nil {
+		_ = s.redisClient.Set(ctx, key, data, s.cacheTTL).Err()
+	}
+	return d, nil
+}
+
+func (s *service) build(ctx context.Context, userID primitive.ObjectID, days int) (*Dashboard, error) {
+	now := time.Now().UTC()
+	since := now.Truncate(24*time.Hour).AddDate(0, 0, -days+1)
+	dates := dateRange(since, days)
+
+	d := &Dashboard{
+		From:        dates[0],
+		To:          dates[len(dates)-1],
+		Days:        days,
+		GeneratedAt: now,
+	}
+
+	newFollowers, err := s.repo.NewFollowersByDay(ctx, userID, since)
+	if err != nil {
+		return nil, err
+	}
+	total, err := s.repo.CountFollowers(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	d.FollowerGrowth = FollowerGrowth{
+		Total:        total,
+		NewFollowers: series(dates, newFollowers),
+		Cumulative:   cumulative(dates, newFollowers, total),
+	}
+
+	videoViews, err := s.repo.ViewsByDay(ctx, userID, views.ContentVideo, since)
+	if err != nil {
+		return nil, err
+	}
+	shortViews, err := s.repo.ViewsByDay(ctx, userID, views.ContentShort, since)
+	if err != nil {
+		return nil, err
+	}
+	d.Views = ViewSeries{
+		Total:  sum(videoViews) + sum(shortViews),
+		Videos: series(dates, videoViews),
+		Shorts: series(dates, shortViews),
+	}
+
+	reactions, err := s.repo.ReactionsByDay(ctx, userID, since)
+	if err != nil {
+		return nil, err
+	}
+	d.Reactions = series(dates, reactions)
+
+	comments, err := s.repo.CommentsByDay(ctx, userID, since)
+	if err != nil {
+		return nil, err
+	}
+	d.Comments = series(dates, comments)
+
+	if d.TopContent.Videos, err = s.repo.TopVideos(ctx, userID, topLimit); err != nil {
+		return nil, err
+	}
+	if d.TopContent.Shorts, err = s.repo.TopShorts(ctx, userID, topLimit); err != nil {
+		return nil, err
+	}
+	if d.TopContent.Posts, err = s.repo.TopPosts(ctx, userID, topLimit); err != nil {
+		return nil, err
+	}
+
+	if d.Audience.Gender, err = s.repo.AudienceBy(ctx, userID, "gender", 10); err != nil {
+		return nil, err
+	}
+	if d.Audience.Locations, err = s.repo.AudienceBy(ctx, userID, "location", 10); err != nil {
+		return nil, err
+	}
+	if d.Audience.AgeGroups, err = s.repo.AudienceAgeGroups(ctx, userID, now); err != nil {
+		return nil, err
+	}
+
+	return d, nil
+}
+
+func dateRange(since time.Time, days int) []string {
+	dates := make([]string, days)
+	for i := 0; i < days; i++ {
+		dates[i] = since.AddDate(0, 0, i).Format("2006-01-02")
+	}
+	return dates
+}
+
+// series điền 0 cho những ngày không có dữ liệu
+func series(dates []string, counts map[string]int) []DailyPoint {
+	points := make([]DailyPoint, len(dates))
+	for i, date := range dates {
+		points[i] = DailyPoint{Date: date, Value: counts[date]}
+	}
+	return points
+}
+
+// cumulative dựng tổng follower cuối mỗi ngày, lùi dần từ tổng hiện tại
+func cumulative(dates []string, newByDay map[string]int, total int) []DailyPoint {
+	points := make([]DailyPoint, len(dates))
+	running := total
+	for i := len(dates) - 1; i >= 0; i-- {
+		points[i] = DailyPoint{Date: dates[i], Value: running}
+		running -= newByDay[dates[i]]
+	}
+	return points
+}
+
+func sum(counts map[string]int) int {
+	total := 0
+	for _, c := range counts {
+		total += c
+	}
+	return total
+}
@@ -0,0 +1,117 @@
+package audit
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"socialnetwork/models"
+)
+
+type Handler struct {
+	service Service
+}
+
+func NewHandler(service Service) *Handler {
+	return &Handler{service: service}
+}
+
+// respondPage trả danh sách kèm nextCursor (truyền lại qua ?before=) khi còn trang sau
+func respondPage(c *gin.Context, events []models.AuditEvent, limit int64) {
+	resp := gin.H{"events": events}
+	if n := len(events); n > 0 && int64(n) == clampLimit(limit) {
+		resp["nextCursor"] = events[n-1].ID.Hex()
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+func parseOptionalID(value string) (*primitive.ObjectID, bool) {
+	if value == "" {
+		return nil, true
+	}
+	id, err := primitive.ObjectIDFromHex(value)
+	if err != nil {
+		return nil, false
+	}
+	return &id, true
+}
+
+func parseOptionalTime(value string) (*time.Time, bool) {
+	if value == "" {
+		return nil, true
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, false
+	}
+	return &t, true
+}
+
+// GET /me/security-events?limit=&before=
+func (h *Handler) MyEvents(c *gin.Context) {
+	userID, err := primitive.ObjectIDFromHex(c.GetString("userID"))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+	before, ok := parseOptionalID(c.Query("before"))
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "before không hợp lệ"})
+		return
+	}
+	limit, _ := strconv.ParseInt(c.Query("limit"), 10, 64)
+
+	events, err := h.service.UserEvents(c.Request.Context(), userID, before, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	respondPage(c, events, limit)
+}
+
+// GET /admin/audit-events?userId=&actorId=&action=a,b&ip=&from=&to=&before=&limit=
+// from/to theo RFC3339
+func (h *Handler) Search(c *gin.Context) {
+	var filter Filter
+	var ok bool
+	if filter.UserID, ok = parseOptionalID(c.Query("userId")); !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "userId không hợp lệ"})
+		return
+	}
+	if filter.ActorID, ok = parseOptionalID(c.Query("actorId")); !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "actorId không hợp lệ"})
+		return
+	}
+	if filter.Before, ok = parseOptionalID(c.Query("before")); !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "before không hợp lệ"})
+		return
+	}
+	if filter.From, ok = parseOptionalTime(c.Query("from")); !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from phải theo định dạng RFC3339"})
+		return
+	}
+	if filter.To, ok = parseOptionalTime(c.Query("to")); !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "to phải theo định dạng RFC3339"})
+		return
+	}
+	if actions := c.Query("action"); actions != "" {
+		for _, a := range strings.Split(actions, ",") {
+			if a = strings.TrimSpace(a); a != "" {
+				filter.Actions = append(filter.Actions, models.AuditAction(a))
+			}
+		}
+	}
+	filter.IP = c.Query("ip")
+	filter.Limit, _ = strconv.ParseInt(c.Query("limit"), 10, 64)
+	limit := filter.Limit
+
+	events, err := h.service.Search(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	respondPage(c, events, limit)
+}
@@ -0,0 +1,57 @@
+// Package audit ghi nhật ký các hành động nhạy cảm về bảo mật (đăng nhập, đổi mật khẩu/email, 2FA,
+// chặn người dùng, thao tác của admin) vào collection chỉ-thêm audit_events.
+package audit
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"socialnetwork/models"
+	"socialnetwork/pkg/requestinfo"
+)
+
+// Logger - dùng được với giá trị nil (không ghi gì) để các service không bắt buộc phải có audit
+type Logger struct {
+	repo Repository
+}
+
+func NewLogger(repo Repository) *Logger {
+	return &Logger{repo: repo}
+}
+
+// Record bổ sung IP/User-Agent từ context và thời điểm rồi ghi lại sự kiện.
+// Lỗi ghi audit chỉ được log, không làm hỏng request chính.
+func (l *Logger) Record(ctx context.Context, event models.AuditEvent) {
+	if l == nil || l.repo == nil {
+		return
+	}
+	info := requestinfo.From(ctx)
+	if event.IP == "" {
+		event.IP = info.IP
+	}
+	if event.UserAgent == "" {
+		event.UserAgent = info.UserAgent
+	}
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now()
+	}
+	// Request có thể đã bị huỷ (client ngắt kết nối) nhưng vẫn phải lưu được dấu vết
+	writeCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+	if err := l.repo.Insert(writeCtx, &event); err != nil {
+		log.Printf("⚠️ Không ghi được audit event %s: %v", event.Action, err)
+	}
+}
+
+// MaskIdentifier che bớt email/số điện thoại trước khi đưa vào metadata (vd: n***@gmail.com, +8491****678)
+func MaskIdentifier(identifier string) string {
+	if at := strings.Index(identifier, "@"); at > 0 {
+		return identifier[:1] + "***" + identifier[at:]
+	}
+	if len(identifier) > 7 {
+		return identifier[:5] + strings.Repeat("*", len(identifier)-8) + identifier[len(identifier)-3:]
+	}
+	return "***"
+}
@@ -0,0 +1,98 @@
+package audit
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"socialnetwork/models"
+)
+
+// Filter - điều kiện truy vấn; trường rỗng thì bỏ qua. Before là ID của bản ghi cuối trang trước.
+type Filter struct {
+	UserID  *primitive.ObjectID
+	ActorID *primitive.ObjectID
+	Actions []models.AuditAction
+	IP      string
+	From    *time.Time
+	To      *time.Time
+	Before  *primitive.ObjectID
+	Limit   int64
+}
+
+// Repository chỉ có thêm và đọc: không sửa, không xoá bản ghi audit
+type Repository interface {
+	EnsureIndexes(ctx context.Context) error
+	Insert(ctx context.Context, event *models.AuditEvent) error
+	Find(ctx context.Context, filter Filter) ([]models.AuditEvent, error)
+}
+
+type repository struct {
+	collection *mongo.Collection
+}
+
+func NewRepository(db *mongo.Database) Repository {
+	return &repository{collection: db.Collection("audit_events")}
+}
+
+func (r *repository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "_id", Value: -1}}},
+		{Keys: bson.D{{Key: "actorId", Value: 1}, {Key: "_id", Value: -1}}},
+		{Keys: bson.D{{Key: "action", Value: 1}, {Key: "_id", Value: -1}}},
+		{Keys: bson.D{{Key: "ip", Value: 1}, {Key: "_id", Value: -1}}},
+	})
+	return err
+}
+
+func (r *repository) Insert(ctx context.Context, event *models.AuditEvent) error {
+	event.ID = primitive.NewObjectID()
+	_, err := r.collection.InsertOne(ctx, event)
+	return err
+}
+
+func (r *repository) Find(ctx context.Context, f Filter) ([]models.AuditEvent, error) {
+	query := bson.M{}
+	if f.UserID != nil {
+		query["userId"] = *f.UserID
+	}
+	if f.ActorID != nil {
+		query["actorId"] = *f.ActorID
+	}
+	if len(f.Actions) > 0 {
+		query["action"] = bson.M{"$in": f.Actions}
+	}
+	if f.IP != "" {
+		query["ip"] = f.IP
+	}
+	createdAt := bson.M{}
+	if f.From != nil {
+		createdAt["$gte"] = *f.From
+	}
+	if f.To != nil {
+		createdAt["$lt"] = *f.To
+	}
+	if len(createdAt) > 0 {
+		query["createdAt"] = createdAt
+	}
+	if f.Before != nil {
+		query["_id"] = bson.M{"$lt": *f.Before}
+	}
+
+	// _id tăng theo thời gian nên sắp xếp theo _id là mới nhất trước
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: -1}}).SetLimit(f.Limit)
+	cursor, err := r.collection.Find(ctx, query, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	events := []models.AuditEvent{}
+	if err := cursor.All(ctx, &events); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
@@ -0,0 +1,46 @@
+package audit
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"socialnetwork/models"
+)
+
+const (
+	defaultLimit = 50
+	maxLimit     = 200
+)
+
+type Service interface {
+	// UserEvents - sự kiện bảo mật của chính tài khoản (GET /me/security-events)
+	UserEvents(ctx context.Context, userID primitive.ObjectID, before *primitive.ObjectID, limit int64) ([]models.AuditEvent, error)
+	Search(ctx context.Context, filter Filter) ([]models.AuditEvent, error)
+}
+
+type service struct {
+	repo Repository
+}
+
+func NewService(repo Repository) Service {
+	return &service{repo: repo}
+}
+
+func clampLimit(limit int64) int64 {
+	if limit <= 0 {
+		return defaultLimit
+	}
+	if limit > maxLimit {
+		return maxLimit
+	}
+	return limit
+}
+
+func (s *service) UserEvents(ctx context.Context, userID primitive.ObjectID, before *primitive.ObjectID, limit int64) ([]models.AuditEvent, error) {
+	return s.repo.Find(ctx, Filter{UserID: &userID, Before: before, Limit: clampLimit(limit)})
+}
+
+func (s *service) Search(ctx context.Context, filter Filter) ([]models.AuditEvent, error) {
+	filter.Limit = clampLimit(filter.Limit)
+	return s.repo.Find(ctx, filter)
+}
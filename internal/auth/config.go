@@ -0,0 +1,78 @@
+package auth
+
+import (
+	"os"
+	"strings"
+
+	"socialnetwork/internal/auth/oidc"
+)
+
+// presets - endpoint sẵn cho các provider phổ biến; có thể ghi đè bằng biến môi trường
+var presets = map[string]oidc.ProviderConfig{
+	"google": {
+		DisplayName: "Google",
+		Issuer:      "https://accounts.google.com",
+		Scopes:      []string{"openid", "email", "profile"},
+	},
+	"facebook": {
+		DisplayName: "Facebook",
+		AuthURL:     "https://www.facebook.com/v19.0/dialog/oauth",
+		TokenURL:    "https://graph.facebook.com/v19.0/oauth/access_token",
+		UserInfoURL: "https://graph.facebook.com/me?fields=id,name,email",
+		Scopes:      []string{"email", "public_profile"},
+		TrustEmail:  true, // Graph API chỉ trả email đã xác minh
+	},
+	"github": {
+		DisplayName: "GitHub",
+		AuthURL:     "https://github.com/login/oauth/authorize",
+		TokenURL:    "https://github.com/login/oauth/access_token",
+		UserInfoURL: "https://api.github.com/user",
+		Scopes:      []string{"read:user", "user:email"},
+	},
+}
+
+// ProvidersFromEnv đọc OAUTH_PROVIDERS (vd: "google,github") và OAUTH_<NAME>_CLIENT_ID/_CLIENT_SECRET/
+// _ISSUER/_AUTH_URL/_TOKEN_URL/_USERINFO_URL/_SCOPES; provider thiếu client id sẽ bị bỏ qua
+func ProvidersFromEnv(baseURL string) []oidc.ProviderConfig {
+	var configs []oidc.ProviderConfig
+	for _, name := range strings.Split(os.Getenv("OAUTH_PROVIDERS"), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		prefix := "OAUTH_" + strings.ToUpper(name) + "_"
+
+		cfg := presets[name]
+		cfg.Name = name
+		cfg.ClientID = os.Getenv(prefix + "CLIENT_ID")
+		cfg.ClientSecret = os.Getenv(prefix + "CLIENT_SECRET")
+		if cfg.ClientID == "" {
+			continue
+		}
+		if v := os.Getenv(prefix + "ISSUER"); v != "" {
+			cfg.Issuer = v
+		}
+		if v := os.Getenv(prefix + "AUTH_URL"); v != "" {
+			cfg.AuthURL = v
+		}
+		if v := os.Getenv(prefix + "TOKEN_URL"); v != "" {
+			cfg.TokenURL = v
+		}
+		if v := os.Getenv(prefix + "USERINFO_URL"); v != "" {
+			cfg.UserInfoURL = v
+		}
+		if v := os.Getenv(prefix + "SCOPES"); v != "" {
+			cfg.Scopes = strings.Fields(strings.ReplaceAll(v, ",", " "))
+		}
+		if len(cfg.Scopes) == 0 {
+			cfg.Scopes = []string{"openid", "email", "profile"}
+		}
+		cfg.RedirectURL = CallbackURL(baseURL, name)
+		configs = append(configs, cfg)
+	}
+	return configs
+}
+
+func CallbackURL(baseURL, provider string) string {
+	return strings.TrimSuffix(baseURL, "/") + "/auth/oauth/" + provider + "/callback"
+}
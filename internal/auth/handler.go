@@ -0,0 +1,132 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"socialnetwork/internal/auth/oidc"
+	"socialnetwork/internal/user"
+	"socialnetwork/models"
+	"socialnetwork/pkg/ratelimit"
+)
+
+type Handler struct {
+	service     Service
+	userService user.Service
+}
+
+func NewHandler(service Service, userService user.Service) *Handler {
+	return &Handler{service: service, userService: userService}
+}
+
+// GET /auth/oauth/providers
+func (h *Handler) ListProviders(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"providers": h.service.Providers()})
+}
+
+// GET /auth/oauth/:provider - chuyển hướng sang trang đăng nhập của provider
+func (h *Handler) Start(c *gin.Context) {
+	url, err := h.service.LoginURL(c.Request.Context(), c.Param("provider"))
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.Redirect(http.StatusFound, url)
+}
+
+// GET /auth/oauth/:provider/callback?code=&state=
+func (h *Handler) Callback(c *gin.Context) {
+	// Người dùng từ chối cấp quyền hoặc provider báo lỗi
+	if providerErr := c.Query("error"); providerErr != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": providerErr, "description": c.Query("error_description")})
+		return
+	}
+
+	result, err := h.service.Callback(c.Request.Context(), c.Param("provider"), c.Query("state"), c.Query("code"))
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	if result.Linked {
+		c.JSON(http.StatusOK, gin.H{"message": "Liên kết tài khoản thành công", "identity": result.Identity})
+		return
+	}
+
+	login, err := h.userService.LoginVerified(user.WithLoginMethod(c.Request.Context(), models.LoginMethodOAuth), result.User)
+	if err != nil {
+		user.WriteLoginError(c, err)
+		return
+	}
+	user.RespondLogin(c, login)
+}
+
+// GET /me/identities
+func (h *Handler) ListIdentities(c *gin.Context) {
+	userID, err := primitive.ObjectIDFromHex(c.GetString("userID"))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	identities, err := h.service.Identities(c.Request.Context(), userID)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"identities": identities})
+}
+
+// POST /me/identities/:provider - trả về URL để client chuyển hướng liên kết thêm tài khoản
+func (h *Handler) Link(c *gin.Context) {
+	userID, err := primitive.ObjectIDFromHex(c.GetString("userID"))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	url, err := h.service.LinkURL(c.Request.Context(), c.Param("provider"), userID)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"url": url})
+}
+
+// DELETE /me/identities/:id
+func (h *Handler) Unlink(c *gin.Context) {
+	userID, err := primitive.ObjectIDFromHex(c.GetString("userID"))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+	identityID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID không hợp lệ"})
+		return
+	}
+
+	if err := h.service.Unlink(c.Request.Context(), userID, identityID); err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Đã gỡ liên kết"})
+}
+
+func writeError(c *gin.Context, err error) {
+	if ratelimit.WriteError(c, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, ErrUnknownProvider), errors.Is(err, ErrIdentityNotFound), errors.Is(err, ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrInvalidState), errors.Is(err, oidc.ErrExchange), errors.Is(err, oidc.ErrInvalidToken), errors.Is(err, oidc.ErrNoSubject):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrIdentityInUse), errors.Is(err, ErrEmailNotVerified), errors.Is(err, ErrAccountUnverified), errors.Is(err, ErrLastLoginMethod):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,115 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// keySet - cache public key của provider theo kid; gặp kid lạ thì tải lại (provider xoay vòng khoá)
+type keySet struct {
+	url        string
+	httpClient *http.Client
+
+	mu        sync.RWMutex
+	keys      map[string]*rsa.PublicKey
+	fetchedAt time.Time
+}
+
+type jwk struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// Không tải lại JWKS dồn dập khi nhận token có kid rác
+const minRefreshInterval = time.Minute
+
+func (k *keySet) get(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	k.mu.RLock()
+	key, ok := k.keys[kid]
+	stale := time.Since(k.fetchedAt) > minRefreshInterval
+	k.mu.RUnlock()
+	if ok {
+		return key, nil
+	}
+	if !stale && k.keys != nil {
+		return nil, fmt.Errorf("không tìm thấy khoá %q", kid)
+	}
+
+	if err := k.refresh(ctx); err != nil {
+		return nil, err
+	}
+
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	if key, ok := k.keys[kid]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("không tìm thấy khoá %q", kid)
+}
+
+func (k *keySet) refresh(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, k.url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := k.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("tải JWKS thất bại: %s", resp.Status)
+	}
+
+	var body struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return err
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(body.Keys))
+	for _, j := range body.Keys {
+		if j.Kty != "RSA" || (j.Use != "" && j.Use != "sig") {
+			continue
+		}
+		key, err := parseRSAKey(j)
+		if err != nil {
+			continue
+		}
+		keys[j.Kid] = key
+	}
+
+	k.mu.Lock()
+	k.keys = keys
+	k.fetchedAt = time.Now()
+	k.mu.Unlock()
+	return nil
+}
+
+func parseRSAKey(j jwk) (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(j.N)
+	if err != nil {
+		return nil, err
+	}
+	e, err := base64.RawURLEncoding.DecodeString(j.E)
+	if err != nil {
+		return nil, err
+	}
+	exponent := new(big.Int).SetBytes(e)
+	if !exponent.IsInt64() || exponent.Int64() < 3 {
+		return nil, errors.New("exponent không hợp lệ")
+	}
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+}
@@ -0,0 +1,252 @@
+// Package mock là OIDC provider giả lập (discovery, authorize, token, JWKS, userinfo) để chạy thử
+// luồng đăng nhập mạng xã hội khi phát triển và trong test mà không cần Google/Facebook/GitHub.
+package mock
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const keyID = "mock-key"
+
+// User - danh tính mà provider giả lập sẽ trả về
+type User struct {
+	Subject       string `json:"sub"`
+	Email         string `json:"email,omitempty"`
+	EmailVerified bool   `json:"email_verified"`
+	Name          string `json:"name,omitempty"`
+}
+
+type authCode struct {
+	clientID    string
+	redirectURI string
+	challenge   string
+	nonce       string
+	user        User
+	expiresAt   time.Time
+}
+
+// Provider - phục vụ các endpoint OIDC dưới Issuer; mọi client_id/secret đều được chấp nhận
+type Provider struct {
+	Issuer string
+	// Users tra theo login_hint (query của /authorize); không có hint thì dùng DefaultUser
+	Users       map[string]User
+	DefaultUser User
+
+	key *rsa.PrivateKey
+
+	mu     sync.Mutex
+	codes  map[string]authCode
+	tokens map[string]User
+}
+
+func New(issuer string) (*Provider, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+	return &Provider{
+		Issuer: strings.TrimSuffix(issuer, "/"),
+		Users:  map[string]User{},
+		DefaultUser: User{
+			Subject:       "mock-user-1",
+			Email:         "mock.user@example.com",
+			EmailVerified: true,
+			Name:          "Mock User",
+		},
+		key:    key,
+		codes:  map[string]authCode{},
+		tokens: map[string]User{},
+	}, nil
+}
+
+// Handler trả về http.Handler với các route tương đối so với Issuer
+// (dùng với httptest.NewServer hoặc gắn vào router dưới prefix của Issuer)
+func (p *Provider) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", p.discovery)
+	mux.HandleFunc("/authorize", p.authorize)
+	mux.HandleFunc("/token", p.token)
+	mux.HandleFunc("/jwks", p.jwks)
+	mux.HandleFunc("/userinfo", p.userinfo)
+	return mux
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func oauthError(w http.ResponseWriter, code, desc string) {
+	writeJSON(w, http.StatusBadRequest, map[string]string{"error": code, "error_description": desc})
+}
+
+func randomString() string {
+	buf := make([]byte, 24)
+	rand.Read(buf)
+	return base64.RawURLEncoding.EncodeToString(buf)
+}
+
+func (p *Provider) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                p.Issuer,
+		"authorization_endpoint":                p.Issuer + "/authorize",
+		"token_endpoint":                        p.Issuer + "/token",
+		"userinfo_endpoint":                     p.Issuer + "/userinfo",
+		"jwks_uri":                              p.Issuer + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+// authorize tự động đồng ý và chuyển hướng về redirect_uri kèm code + state
+func (p *Provider) authorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	redirectURI := q.Get("redirect_uri")
+	target, err := url.Parse(redirectURI)
+	if err != nil || redirectURI == "" {
+		oauthError(w, "invalid_request", "redirect_uri không hợp lệ")
+		return
+	}
+	if q.Get("response_type") != "code" {
+		oauthError(w, "unsupported_response_type", "chỉ hỗ trợ code")
+		return
+	}
+	if q.Get("code_challenge") == "" || q.Get("code_challenge_method") != "S256" {
+		oauthError(w, "invalid_request", "bắt buộc PKCE S256")
+		return
+	}
+
+	user := p.DefaultUser
+	if hint := q.Get("login_hint"); hint != "" {
+		if u, ok := p.Users[hint]; ok {
+			user = u
+		}
+	}
+
+	code := randomString()
+	p.mu.Lock()
+	p.codes[code] = authCode{
+		clientID:    q.Get("client_id"),
+		redirectURI: redirectURI,
+		challenge:   q.Get("code_challenge"),
+		nonce:       q.Get("nonce"),
+		user:        user,
+		expiresAt:   time.Now().Add(time.Minute),
+	}
+	p.mu.Unlock()
+
+	params := target.Query()
+	params.Set("code", code)
+	params.Set("state", q.Get("state"))
+	target.RawQuery = params.Encode()
+	http.Redirect(w, r, target.String(), http.StatusFound)
+}
+
+func (p *Provider) token(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		oauthError(w, "invalid_request", err.Error())
+		return
+	}
+	if r.PostForm.Get("grant_type") != "authorization_code" {
+		oauthError(w, "unsupported_grant_type", "")
+		return
+	}
+
+	code := r.PostForm.Get("code")
+	p.mu.Lock()
+	ac, ok := p.codes[code]
+	delete(p.codes, code) // code chỉ dùng một lần
+	p.mu.Unlock()
+	if !ok || time.Now().After(ac.expiresAt) {
+		oauthError(w, "invalid_grant", "code không hợp lệ hoặc đã dùng")
+		return
+	}
+	if r.PostForm.Get("redirect_uri") != ac.redirectURI || r.PostForm.Get("client_id") != ac.clientID {
+		oauthError(w, "invalid_grant", "redirect_uri hoặc client_id không khớp")
+		return
+	}
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if base64.RawURLEncoding.EncodeToString(sum[:]) != ac.challenge {
+		oauthError(w, "invalid_grant", "code_verifier không khớp")
+		return
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":            p.Issuer,
+		"sub":            ac.user.Subject,
+		"aud":            ac.clientID,
+		"iat":            now.Unix(),
+		"exp":            now.Add(10 * time.Minute).Unix(),
+		"email":          ac.user.Email,
+		"email_verified": ac.user.EmailVerified,
+		"name":           ac.user.Name,
+	}
+	if ac.nonce != "" {
+		claims["nonce"] = ac.nonce
+	}
+	idToken := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	idToken.Header["kid"] = keyID
+	signed, err := idToken.SignedString(p.key)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+
+	accessToken := randomString()
+	p.mu.Lock()
+	p.tokens[accessToken] = ac.user
+	p.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": accessToken,
+		"token_type":   "Bearer",
+		"expires_in":   600,
+		"id_token":     signed,
+	})
+}
+
+func (p *Provider) jwks(w http.ResponseWriter, r *http.Request) {
+	pub := p.key.PublicKey
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kid": keyID,
+			"kty": "RSA",
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}},
+	})
+}
+
+func (p *Provider) userinfo(w http.ResponseWriter, r *http.Request) {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	p.mu.Lock()
+	user, ok := p.tokens[token]
+	p.mu.Unlock()
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	writeJSON(w, http.StatusOK, user)
+}
@@ -0,0 +1,342 @@
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrExchange     = errors.New("không đổi được mã xác thực với nhà cung cấp")
+	ErrInvalidToken = errors.New("id_token không hợp lệ")
+	ErrNoSubject    = errors.New("nhà cung cấp không trả về định danh người dùng")
+)
+
+// ProviderConfig - có Issuer thì endpoint lấy qua discovery; provider chỉ hỗ trợ OAuth2 (vd: GitHub) thì khai báo endpoint trực tiếp
+type ProviderConfig struct {
+	Name         string
+	DisplayName  string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+
+	Issuer      string
+	AuthURL     string
+	TokenURL    string
+	UserInfoURL string
+	JWKSURL     string
+
+	// TrustEmail: provider chỉ trả email đã xác minh nhưng không có claim email_verified (vd: Facebook)
+	TrustEmail bool
+}
+
+// Claims - thông tin người dùng đã chuẩn hoá từ id_token hoặc userinfo
+type Claims struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	Picture       string
+}
+
+type Provider struct {
+	config     ProviderConfig
+	httpClient *http.Client
+
+	mu    sync.Mutex
+	ready bool
+	keys  *keySet
+}
+
+func NewProvider(config ProviderConfig, httpClient *http.Client) *Provider {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &Provider{config: config, httpClient: httpClient}
+}
+
+func (p *Provider) Name() string {
+	return p.config.Name
+}
+
+func (p *Provider) DisplayName() string {
+	if p.config.DisplayName != "" {
+		return p.config.DisplayName
+	}
+	return p.config.Name
+}
+
+// discover tải cấu hình /.well-known/openid-configuration; endpoint khai báo sẵn được ưu tiên.
+// Lỗi (vd: provider tạm thời không truy cập được) sẽ được thử lại ở request sau.
+func (p *Provider) discover(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.ready {
+		return nil
+	}
+
+	if p.config.Issuer != "" {
+		if err := p.fetchDiscovery(ctx); err != nil {
+			return err
+		}
+	}
+	if p.config.AuthURL == "" || p.config.TokenURL == "" {
+		return fmt.Errorf("provider %s thiếu authorization/token endpoint", p.config.Name)
+	}
+	if p.config.JWKSURL != "" {
+		p.keys = &keySet{url: p.config.JWKSURL, httpClient: p.httpClient}
+	}
+	p.ready = true
+	return nil
+}
+
+func (p *Provider) fetchDiscovery(ctx context.Context) error {
+	wellKnown := strings.TrimSuffix(p.config.Issuer, "/") + "/.well-known/openid-configuration"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, wellKnown, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("discovery %s thất bại: %s", p.config.Name, resp.Status)
+	}
+
+	var doc struct {
+		Issuer                string `json:"issuer"`
+		AuthorizationEndpoint string `json:"authorization_endpoint"`
+		TokenEndpoint         string `json:"token_endpoint"`
+		UserinfoEndpoint      string `json:"userinfo_endpoint"`
+		JWKSURI               string `json:"jwks_uri"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return err
+	}
+	if strings.TrimSuffix(doc.Issuer, "/") != strings.TrimSuffix(p.config.Issuer, "/") {
+		return fmt.Errorf("issuer của %s không khớp: %s", p.config.Name, doc.Issuer)
+	}
+
+	if p.config.AuthURL == "" {
+		p.config.AuthURL = doc.AuthorizationEndpoint
+	}
+	if p.config.TokenURL == "" {
+		p.config.TokenURL = doc.TokenEndpoint
+	}
+	if p.config.UserInfoURL == "" {
+		p.config.UserInfoURL = doc.UserinfoEndpoint
+	}
+	if p.config.JWKSURL == "" {
+		p.config.JWKSURL = doc.JWKSURI
+	}
+	return nil
+}
+
+// AuthCodeURL tạo URL chuyển hướng người dùng sang provider (authorization code + PKCE S256)
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce, codeVerifier string) (string, error) {
+	if err := p.discover(ctx); err != nil {
+		return "", err
+	}
+
+	q := url.Values{}
+	q.Set("response_type", "code")
+	q.Set("client_id", p.config.ClientID)
+	q.Set("redirect_uri", p.config.RedirectURL)
+	q.Set("scope", strings.Join(p.config.Scopes, " "))
+	q.Set("state", state)
+	q.Set("code_challenge", CodeChallenge(codeVerifier))
+	q.Set("code_challenge_method", "S256")
+	if nonce != "" {
+		q.Set("nonce", nonce)
+	}
+
+	sep := "?"
+	if strings.Contains(p.config.AuthURL, "?") {
+		sep = "&"
+	}
+	return p.config.AuthURL + sep + q.Encode(), nil
+}
+
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	IDToken     string `json:"id_token"`
+	Error       string `json:"error"`
+	ErrorDesc   string `json:"error_description"`
+}
+
+// Exchange đổi code lấy token và trả về Claims đã xác thực; nonce phải khớp với nonce gửi đi ở AuthCodeURL
+func (p *Provider) Exchange(ctx context.Context, code, codeVerifier, nonce string) (*Claims, error) {
+	if err := p.discover(ctx); err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.config.RedirectURL)
+	form.Set("client_id", p.config.ClientID)
+	form.Set("client_secret", p.config.ClientSecret)
+	form.Set("code_verifier", codeVerifier)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.config.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var token tokenResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&token); err != nil {
+		return nil, ErrExchange
+	}
+	if resp.StatusCode != http.StatusOK || token.Error != "" || token.AccessToken == "" {
+		return nil, fmt.Errorf("%w: %s %s", ErrExchange, token.Error, token.ErrorDesc)
+	}
+
+	claims := &Claims{}
+	if token.IDToken != "" {
+		if claims, err = p.verifyIDToken(ctx, token.IDToken, nonce); err != nil {
+			return nil, err
+		}
+	}
+
+	// id_token không có email (hoặc provider thuần OAuth2) thì bổ sung từ userinfo
+	if (claims.Subject == "" || claims.Email == "") && p.config.UserInfoURL != "" {
+		info, err := p.userInfo(ctx, token.AccessToken)
+		if err != nil {
+			return nil, err
+		}
+		if claims.Subject != "" && info.Subject != claims.Subject {
+			return nil, ErrInvalidToken
+		}
+		if claims.Subject == "" {
+			claims = info
+		} else if claims.Email == "" {
+			claims.Email, claims.EmailVerified = info.Email, info.EmailVerified
+		}
+	}
+
+	if claims.Subject == "" {
+		return nil, ErrNoSubject
+	}
+	if p.config.TrustEmail && claims.Email != "" {
+		claims.EmailVerified = true
+	}
+	claims.Email = strings.ToLower(strings.TrimSpace(claims.Email))
+	return claims, nil
+}
+
+func (p *Provider) verifyIDToken(ctx context.Context, raw, nonce string) (*Claims, error) {
+	if p.keys == nil {
+		return nil, fmt.Errorf("%w: provider %s không có jwks_uri", ErrInvalidToken, p.config.Name)
+	}
+
+	parsed, err := jwt.Parse(raw, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return p.keys.get(ctx, kid)
+	},
+		jwt.WithValidMethods([]string{"RS256"}),
+		jwt.WithIssuer(p.config.Issuer),
+		jwt.WithAudience(p.config.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil || !parsed.Valid {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	mc, ok := parsed.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+	if nonce != "" {
+		if got, _ := mc["nonce"].(string); got != nonce {
+			return nil, fmt.Errorf("%w: nonce không khớp", ErrInvalidToken)
+		}
+	}
+	return claimsFromMap(mc), nil
+}
+
+func (p *Provider) userInfo(ctx context.Context, accessToken string) (*Claims, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.config.UserInfoURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("userinfo %s thất bại: %s", p.config.Name, resp.Status)
+	}
+
+	var m map[string]interface{}
+	dec := json.NewDecoder(io.LimitReader(resp.Body, 1<<20))
+	dec.UseNumber()
+	if err := dec.Decode(&m); err != nil {
+		return nil, err
+	}
+	return claimsFromMap(m), nil
+}
+
+// claimsFromMap đọc claim chuẩn OIDC, kèm các tên trường thường gặp của OAuth2 (id, login, avatar_url)
+func claimsFromMap(m map[string]interface{}) *Claims {
+	c := &Claims{
+		Subject: stringClaim(m, "sub"),
+		Email:   stringClaim(m, "email"),
+		Name:    stringClaim(m, "name"),
+		Picture: stringClaim(m, "picture"),
+	}
+	if c.Subject == "" {
+		c.Subject = stringClaim(m, "id")
+	}
+	if c.Name == "" {
+		c.Name = stringClaim(m, "login")
+	}
+	if c.Picture == "" {
+		c.Picture = stringClaim(m, "avatar_url")
+	}
+	switch v := m["email_verified"].(type) {
+	case bool:
+		c.EmailVerified = v
+	case string:
+		c.EmailVerified, _ = strconv.ParseBool(v)
+	}
+	return c
+}
+
+func stringClaim(m map[string]interface{}, key string) string {
+	switch v := m[key].(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return ""
+}
@@ -0,0 +1,137 @@
+package oidc_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"socialnetwork/internal/auth/oidc"
+	"socialnetwork/internal/auth/oidc/mock"
+)
+
+const redirectURL = "http://app.test/auth/oidc/mock/callback"
+
+func newMockProvider(t *testing.T) (*mock.Provider, *oidc.Provider) {
+	t.Helper()
+	m, err := mock.New("http://placeholder")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(m.Handler())
+	t.Cleanup(srv.Close)
+	m.Issuer = srv.URL
+
+	p := oidc.NewProvider(oidc.ProviderConfig{
+		Name:         "mock",
+		ClientID:     "client-1",
+		ClientSecret: "secret",
+		RedirectURL:  redirectURL,
+		Scopes:       []string{"openid", "email", "profile"},
+		Issuer:       srv.URL,
+	}, srv.Client())
+	return m, p
+}
+
+// authorize mở URL đăng nhập và trả về code, state trong redirect về callback
+func authorize(t *testing.T, authURL string) (code, state string) {
+	t.Helper()
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("authorize: status %d", resp.StatusCode)
+	}
+	loc, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loc.Query().Get("code"), loc.Query().Get("state")
+}
+
+func TestExchangeWithPKCEAndNonce(t *testing.T) {
+	_, p := newMockProvider(t)
+	ctx := context.Background()
+
+	verifier, _ := oidc.NewCodeVerifier()
+	authURL, err := p.AuthCodeURL(ctx, "state-1", "nonce-1", verifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, state := authorize(t, authURL)
+	if state != "state-1" {
+		t.Fatalf("state = %q, muốn state-1", state)
+	}
+
+	claims, err := p.Exchange(ctx, code, verifier, "nonce-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Subject != "mock-user-1" || claims.Email != "mock.user@example.com" || !claims.EmailVerified {
+		t.Fatalf("claims không đúng: %+v", claims)
+	}
+
+	// code chỉ dùng một lần
+	if _, err := p.Exchange(ctx, code, verifier, "nonce-1"); !errors.Is(err, oidc.ErrExchange) {
+		t.Fatalf("dùng lại code: err = %v, muốn ErrExchange", err)
+	}
+}
+
+func TestExchangeRejectsWrongVerifier(t *testing.T) {
+	_, p := newMockProvider(t)
+	ctx := context.Background()
+
+	verifier, _ := oidc.NewCodeVerifier()
+	authURL, err := p.AuthCodeURL(ctx, "state-1", "nonce-1", verifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, _ := authorize(t, authURL)
+
+	other, _ := oidc.NewCodeVerifier()
+	if _, err := p.Exchange(ctx, code, other, "nonce-1"); !errors.Is(err, oidc.ErrExchange) {
+		t.Fatalf("err = %v, muốn ErrExchange", err)
+	}
+}
+
+func TestExchangeRejectsWrongNonce(t *testing.T) {
+	_, p := newMockProvider(t)
+	ctx := context.Background()
+
+	verifier, _ := oidc.NewCodeVerifier()
+	authURL, err := p.AuthCodeURL(ctx, "state-1", "nonce-1", verifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, _ := authorize(t, authURL)
+
+	if _, err := p.Exchange(ctx, code, verifier, "nonce-khac"); !errors.Is(err, oidc.ErrInvalidToken) {
+		t.Fatalf("err = %v, muốn ErrInvalidToken", err)
+	}
+}
+
+func TestExchangeUsesLoginHint(t *testing.T) {
+	m, p := newMockProvider(t)
+	m.Users["bob"] = mock.User{Subject: "bob-1", Email: "Bob@Example.com", EmailVerified: false, Name: "Bob"}
+	ctx := context.Background()
+
+	verifier, _ := oidc.NewCodeVerifier()
+	authURL, err := p.AuthCodeURL(ctx, "state-1", "nonce-1", verifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, _ := authorize(t, authURL+"&login_hint=bob")
+
+	claims, err := p.Exchange(ctx, code, verifier, "nonce-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Subject != "bob-1" || claims.Email != "bob@example.com" || claims.EmailVerified {
+		t.Fatalf("claims không đúng: %+v", claims)
+	}
+}
@@ -0,0 +1,93 @@
+package auth
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"socialnetwork/models"
+)
+
+type Repository interface {
+	EnsureIndexes(ctx context.Context) error
+	FindBySubject(ctx context.Context, provider, subject string) (*models.UserIdentity, error)
+	FindByUser(ctx context.Context, userID primitive.ObjectID) ([]models.UserIdentity, error)
+	Create(ctx context.Context, identity *models.UserIdentity) error
+	TouchLogin(ctx context.Context, id primitive.ObjectID) error
+	Delete(ctx context.Context, userID, id primitive.ObjectID) error
+	DeleteByUser(ctx context.Context, userID primitive.ObjectID) error
+}
+
+type repository struct {
+	collection *mongo.Collection
+}
+
+func NewRepository(db *mongo.Database) Repository {
+	return &repository{collection: db.Collection("user_identities")}
+}
+
+// EnsureIndexes - mỗi tài khoản provider chỉ liên kết với đúng một user
+func (r *repository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "provider", Value: 1}, {Key: "subject", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "userId", Value: 1}},
+		},
+	})
+	return err
+}
+
+func (r *repository) FindBySubject(ctx context.Context, provider, subject string) (*models.UserIdentity, error) {
+	var identity models.UserIdentity
+	err := r.collection.FindOne(ctx, bson.M{"provider": provider, "subject": subject}).Decode(&identity)
+	if err != nil {
+		return nil, err
+	}
+	return &identity, nil
+}
+
+func (r *repository) FindByUser(ctx context.Context, userID primitive.ObjectID) ([]models.UserIdentity, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{"userId": userID}, options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	identities := []models.UserIdentity{}
+	err = cursor.All(ctx, &identities)
+	return identities, err
+}
+
+func (r *repository) Create(ctx context.Context, identity *models.UserIdentity) error {
+	identity.ID = primitive.NewObjectID()
+	identity.CreatedAt = time.Now()
+	_, err := r.collection.InsertOne(ctx, identity)
+	return err
+}
+
+func (r *repository) TouchLogin(ctx context.Context, id primitive.ObjectID) error {
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"lastLoginAt": time.Now()}})
+	return err
+}
+
+func (r *repository) Delete(ctx context.Context, userID, id primitive.ObjectID) error {
+	res, err := r.collection.DeleteOne(ctx, bson.M{"_id": id, "userId": userID})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+func (r *repository) DeleteByUser(ctx context.Context, userID primitive.ObjectID) error {
+	_, err := r.collection.DeleteMany(ctx, bson.M{"userId": userID})
+	return err
+}
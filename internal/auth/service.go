@@ -0,0 +1,350 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/go-redis/redis/v8"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"socialnetwork/internal/auth/oidc"
+	"socialnetwork/internal/user"
+	"socialnetwork/models"
+)
+
+var (
+	ErrUnknownProvider   = errors.New("nhà cung cấp đăng nhập không được hỗ trợ")
+	ErrInvalidState      = errors.New("phiên đăng nhập không hợp lệ hoặc đã hết hạn, vui lòng thử lại")
+	ErrIdentityInUse     = errors.New("tài khoản này đã được liên kết với người dùng khác")
+	ErrEmailNotVerified  = errors.New("email đã được dùng cho một tài khoản khác; hãy đăng nhập bằng mật khẩu rồi liên kết trong phần cài đặt")
+	ErrAccountUnverified = errors.New("email đã được dùng cho một tài khoản chưa xác minh; hãy đăng nhập và xác minh email hoặc dùng chức năng quên mật khẩu")
+	ErrIdentityNotFound  = errors.New("không tìm thấy liên kết")
+	ErrLastLoginMethod   = errors.New("không thể gỡ phương thức đăng nhập duy nhất; hãy đặt mật khẩu trước")
+	ErrUserNotFound      = errors.New("người dùng không tồn tại")
+)
+
+type Config struct {
+	StateTTL time.Duration // thời gian tối đa từ lúc chuyển sang provider đến khi callback
+}
+
+type ProviderInfo struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+}
+
+// CallbackResult - User khi đăng nhập; Identity khi liên kết thêm vào tài khoản đang đăng nhập
+type CallbackResult struct {
+	User     *models.User
+	Identity *models.UserIdentity
+	Linked   bool
+}
+
+type Service interface {
+	Providers() []ProviderInfo
+	LoginURL(ctx context.Context, provider string) (string, error)
+	LinkURL(ctx context.Context, provider string, userID primitive.ObjectID) (string, error)
+	Callback(ctx context.Context, provider, state, code string) (*CallbackResult, error)
+	Identities(ctx context.Context, userID primitive.ObjectID) ([]models.UserIdentity, error)
+	Unlink(ctx context.Context, userID, identityID primitive.ObjectID) error
+}
+
+type service struct {
+	repo        Repository
+	userRepo    user.Repository
+	redisClient *redis.Client
+	providers   map[string]*oidc.Provider
+	order       []string
+	config      Config
+}
+
+func NewService(repo Repository, userRepo user.Repository, redisClient *redis.Client, providers []*oidc.Provider, config Config) Service {
+	if config.StateTTL <= 0 {
+		config.StateTTL = 10 * time.Minute
+	}
+	s := &service{
+		repo:        repo,
+		userRepo:    userRepo,
+		redisClient: redisClient,
+		providers:   map[string]*oidc.Provider{},
+		config:      config,
+	}
+	for _, p := range providers {
+		s.providers[p.Name()] = p
+		s.order = append(s.order, p.Name())
+	}
+	return s
+}
+
+func (s *service) Providers() []ProviderInfo {
+	infos := make([]ProviderInfo, 0, len(s.order))
+	for _, name := range s.order {
+		infos = append(infos, ProviderInfo{Name: name, DisplayName: s.providers[name].DisplayName()})
+	}
+	return infos
+}
+
+// authState - lưu ở Redis theo state, chỉ dùng một lần để chống CSRF/replay
+type authState struct {
+	Provider string `json:"provider"`
+	Verifier string `json:"verifier"`
+	Nonce    string `json:"nonce"`
+	UserID   string `json:"userId,omitempty"` // có giá trị khi đang liên kết vào tài khoản đã đăng nhập
+}
+
+func stateKey(state string) string {
+	return "oauth:state:" + state
+}
+
+func (s *service) LoginURL(ctx context.Context, provider string) (string, error) {
+	return s.authURL(ctx, provider, "")
+}
+
+func (s *service) LinkURL(ctx context.Context, provider string, userID primitive.ObjectID) (string, error) {
+	return s.authURL(ctx, provider, userID.Hex())
+}
+
+func (s *service) authURL(ctx context.Context, name, userID string) (string, error) {
+	p, ok := s.providers[name]
+	if !ok {
+		return "", ErrUnknownProvider
+	}
+
+	state, err := oidc.RandomString(24)
+	if err != nil {
+		return "", err
+	}
+	nonce, err := oidc.RandomString(24)
+	if err != nil {
+		return "", err
+	}
+	verifier, err := oidc.NewCodeVerifier()
+	if err != nil {
+		return "", err
+	}
+
+	url, err := p.AuthCodeURL(ctx, state, nonce, verifier)
+	if err != nil {
+		return "", err
+	}
+
+	data, _ := json.Marshal(authState{Provider: name, Verifier: verifier, Nonce: nonce, UserID: userID})
+	if err := s.redisClient.Set(ctx, stateKey(state), data, s.config.StateTTL).Err(); err != nil {
+		return "", err
+	}
+	return url, nil
+}
+
+func (s *service) takeState(ctx context.Context, state string) (*authState, error) {
+	if state == "" {
+		return nil, ErrInvalidState
+	}
+	pipe := s.redisClient.TxPipeline()
+	get := pipe.Get(ctx, stateKey(state))
+	pipe.Del(ctx, stateKey(state))
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, err
+	}
+	raw, err := get.Bytes()
+	if err != nil {
+		return nil, ErrInvalidState
+	}
+
+	var st authState
+	if err := json.Unmarshal(raw, &st); err != nil {
+		return nil, ErrInvalidState
+	}
+	return &st, nil
+}
+
+func (s *service) Callback(ctx context.Context, name, state, code string) (*CallbackResult, error) {
+	p, ok := s.providers[name]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+	st, err := s.takeState(ctx, state)
+	if err != nil {
+		return nil, err
+	}
+	if st.Provider != name {
+		return nil, ErrInvalidState
+	}
+
+	claims, err := p.Exchange(ctx, code, st.Verifier, st.Nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	identity, err := s.repo.FindBySubject(ctx, name, claims.Subject)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, err
+	}
+
+	if st.UserID != "" {
+		return s.link(ctx, st.UserID, name, claims, identity)
+	}
+
+	// Đã liên kết trước đó: đăng nhập vào user tương ứng
+	if identity != nil {
+		u, err := s.userRepo.GetByID(ctx, identity.UserID)
+		if err != nil || u == nil || u.DeletedAt != nil {
+			return nil, ErrUserNotFound
+		}
+		s.repo.TouchLogin(ctx, identity.ID)
+		return &CallbackResult{User: u, Identity: identity}, nil
+	}
+
+	u, err := s.matchOrCreateUser(ctx, claims)
+	if err != nil {
+		return nil, err
+	}
+	identity, err = s.createIdentity(ctx, u.ID, name, claims)
+	if err != nil {
+		return nil, err
+	}
+	return &CallbackResult{User: u, Identity: identity}, nil
+}
+
+func (s *service) link(ctx context.Context, userIDHex, name string, claims *oidc.Claims, existing *models.UserIdentity) (*CallbackResult, error) {
+	userID, err := primitive.ObjectIDFromHex(userIDHex)
+	if err != nil {
+		return nil, ErrInvalidState
+	}
+	if existing != nil {
+		if existing.UserID != userID {
+			return nil, ErrIdentityInUse
+		}
+		return &CallbackResult{Identity: existing, Linked: true}, nil
+	}
+
+	identity, err := s.createIdentity(ctx, userID, name, claims)
+	if err != nil {
+		return nil, err
+	}
+	return &CallbackResult{Identity: identity, Linked: true}, nil
+}
+
+// matchOrCreateUser gộp vào tài khoản sẵn có chỉ khi cả provider lẫn tài khoản đó đã xác minh email
+// (tránh người khác đăng ký trước bằng email của nạn nhân rồi giữ quyền truy cập); không trùng email thì tạo tài khoản mới
+func (s *service) matchOrCreateUser(ctx context.Context, claims *oidc.Claims) (*models.User, error) {
+	if claims.Email != "" {
+		existing, err := s.userRepo.FindByEmail(ctx, claims.Email)
+		if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, err
+		}
+		if err == nil && existing != nil && existing.DeletedAt == nil {
+			if !claims.EmailVerified {
+				return nil, ErrEmailNotVerified
+			}
+			if !existing.EmailVerified {
+				return nil, ErrAccountUnverified
+			}
+			return existing, nil
+		}
+	}
+
+	u := &models.User{
+		ID:            primitive.NewObjectID(),
+		Username:      generateUsername(claims),
+		Email:         claims.Email,
+		EmailVerified: claims.Email != "" && claims.EmailVerified,
+		DisplayName:   claims.Name,
+		AvatarURL:     claims.Picture,
+		IsActive:      true,
+		UpdatedAt:     time.Now(),
+	}
+	if err := s.userRepo.Create(ctx, u); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+func (s *service) createIdentity(ctx context.Context, userID primitive.ObjectID, name string, claims *oidc.Claims) (*models.UserIdentity, error) {
+	now := time.Now()
+	identity := &models.UserIdentity{
+		UserID:      userID,
+		Provider:    name,
+		Subject:     claims.Subject,
+		Email:       claims.Email,
+		Name:        claims.Name,
+		LastLoginAt: &now,
+	}
+	if err := s.repo.Create(ctx, identity); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, ErrIdentityInUse
+		}
+		return nil, err
+	}
+	return identity, nil
+}
+
+// generateUsername lấy phần trước @ của email (hoặc tên hiển thị) và thêm số ngẫu nhiên để tránh trùng
+func generateUsername(claims *oidc.Claims) string {
+	source := claims.Name
+	if claims.Email != "" {
+		source = strings.SplitN(claims.Email, "@", 2)[0]
+	}
+
+	var b strings.Builder
+	for _, r := range strings.ToLower(source) {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			b.WriteRune(r)
+		}
+		if b.Len() >= 20 {
+			break
+		}
+	}
+	base := b.String()
+	if len(base) < 3 {
+		base = "user"
+	}
+
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return base + fmt.Sprint(time.Now().UnixNano()%1000000)
+	}
+	return fmt.Sprintf("%s%06d", base, n.Int64())
+}
+
+func (s *service) Identities(ctx context.Context, userID primitive.ObjectID) ([]models.UserIdentity, error) {
+	return s.repo.FindByUser(ctx, userID)
+}
+
+func (s *service) Unlink(ctx context.Context, userID, identityID primitive.ObjectID) error {
+	identities, err := s.repo.FindByUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	found := false
+	for _, identity := range identities {
+		if identity.ID == identityID {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return ErrIdentityNotFound
+	}
+
+	u, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil || u == nil {
+		return ErrUserNotFound
+	}
+	if u.Password == "" && len(identities) == 1 {
+		return ErrLastLoginMethod
+	}
+
+	if err := s.repo.Delete(ctx, userID, identityID); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return ErrIdentityNotFound
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,239 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+
+	"github.com/go-redis/redis/v8"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"socialnetwork/internal/auth/oidc"
+	"socialnetwork/internal/auth/oidc/mock"
+	"socialnetwork/internal/user"
+	"socialnetwork/models"
+)
+
+type fakeIdentities struct {
+	items []models.UserIdentity
+}
+
+func (f *fakeIdentities) EnsureIndexes(ctx context.Context) error { return nil }
+
+func (f *fakeIdentities) FindBySubject(ctx context.Context, provider, subject string) (*models.UserIdentity, error) {
+	for i := range f.items {
+		if f.items[i].Provider == provider && f.items[i].Subject == subject {
+			return &f.items[i], nil
+		}
+	}
+	return nil, mongo.ErrNoDocuments
+}
+
+func (f *fakeIdentities) FindByUser(ctx context.Context, userID primitive.ObjectID) ([]models.UserIdentity, error) {
+	var out []models.UserIdentity
+	for _, it := range f.items {
+		if it.UserID == userID {
+			out = append(out, it)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeIdentities) Create(ctx context.Context, identity *models.UserIdentity) error {
+	identity.ID = primitive.NewObjectID()
+	f.items = append(f.items, *identity)
+	return nil
+}
+
+func (f *fakeIdentities) TouchLogin(ctx context.Context, id primitive.ObjectID) error { return nil }
+
+func (f *fakeIdentities) Delete(ctx context.Context, userID, id primitive.ObjectID) error { return nil }
+
+func (f *fakeIdentities) DeleteByUser(ctx context.Context, userID primitive.ObjectID) error { return nil }
+
+// fakeUsers chỉ cài các hàm service dùng tới; hàm khác gọi vào sẽ panic
+type fakeUsers struct {
+	user.Repository
+	users []*models.User
+}
+
+func (f *fakeUsers) FindByEmail(ctx context.Context, email string) (*models.User, error) {
+	for _, u := range f.users {
+		if u.Email == email {
+			return u, nil
+		}
+	}
+	return nil, mongo.ErrNoDocuments
+}
+
+func (f *fakeUsers) GetByID(ctx context.Context, id primitive.ObjectID) (*models.User, error) {
+	for _, u := range f.users {
+		if u.ID == id {
+			return u, nil
+		}
+	}
+	return nil, mongo.ErrNoDocuments
+}
+
+func (f *fakeUsers) Create(ctx context.Context, u *models.User) error {
+	f.users = append(f.users, u)
+	return nil
+}
+
+type testEnv struct {
+	svc   Service
+	mock  *mock.Provider
+	users *fakeUsers
+	ids   *fakeIdentities
+}
+
+// newTestEnv cần Redis thật (REDIS_ADDR, mặc định localhost:6379) để lưu state; không có thì bỏ qua
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+	addr := os.Getenv("REDIS_ADDR")
+	if addr == "" {
+		addr = "localhost:6379"
+	}
+	rdb := redis.NewClient(&redis.Options{Addr: addr})
+	if err := rdb.Ping(context.Background()).Err(); err != nil {
+		t.Skipf("không kết nối được Redis %s: %v", addr, err)
+	}
+	t.Cleanup(func() { rdb.Close() })
+
+	m, err := mock.New("http://placeholder")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(m.Handler())
+	t.Cleanup(srv.Close)
+	m.Issuer = srv.URL
+
+	p := oidc.NewProvider(oidc.ProviderConfig{
+		Name:        "mock",
+		ClientID:    "client-1",
+		RedirectURL: "http://app.test/auth/oidc/mock/callback",
+		Scopes:      []string{"openid", "email"},
+		Issuer:      srv.URL,
+	}, srv.Client())
+
+	env := &testEnv{mock: m, users: &fakeUsers{}, ids: &fakeIdentities{}}
+	env.svc = NewService(env.ids, env.users, rdb, []*oidc.Provider{p}, Config{})
+	return env
+}
+
+// login chạy LoginURL rồi đi qua trang authorize của mock, trả về code và state nhận ở callback
+func (e *testEnv) login(t *testing.T, hint string) (code, state string) {
+	t.Helper()
+	authURL, err := e.svc.LoginURL(context.Background(), "mock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hint != "" {
+		authURL += "&login_hint=" + url.QueryEscape(hint)
+	}
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	loc, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loc.Query().Get("code"), loc.Query().Get("state")
+}
+
+func TestCallbackCreatesUser(t *testing.T) {
+	env := newTestEnv(t)
+	code, state := env.login(t, "")
+
+	res, err := env.svc.Callback(context.Background(), "mock", state, code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.User == nil || res.User.Email != "mock.user@example.com" || !res.User.EmailVerified {
+		t.Fatalf("user không đúng: %+v", res.User)
+	}
+	if len(env.ids.items) != 1 || env.ids.items[0].UserID != res.User.ID {
+		t.Fatalf("chưa tạo liên kết cho user mới")
+	}
+
+	// đăng nhập lần sau vào đúng user đã liên kết
+	code, state = env.login(t, "")
+	again, err := env.svc.Callback(context.Background(), "mock", state, code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.User.ID != res.User.ID || len(env.users.users) != 1 {
+		t.Fatalf("đăng nhập lại tạo thêm user")
+	}
+}
+
+func TestCallbackStateSingleUse(t *testing.T) {
+	env := newTestEnv(t)
+	code, state := env.login(t, "")
+
+	if _, err := env.svc.Callback(context.Background(), "mock", state, code); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.svc.Callback(context.Background(), "mock", state, code); !errors.Is(err, ErrInvalidState) {
+		t.Fatalf("dùng lại state: err = %v, muốn ErrInvalidState", err)
+	}
+}
+
+func TestCallbackRejectsUnknownState(t *testing.T) {
+	env := newTestEnv(t)
+	code, _ := env.login(t, "")
+
+	for _, state := range []string{"", "khong-ton-tai"} {
+		if _, err := env.svc.Callback(context.Background(), "mock", state, code); !errors.Is(err, ErrInvalidState) {
+			t.Fatalf("state %q: err = %v, muốn ErrInvalidState", state, err)
+		}
+	}
+}
+
+func TestCallbackLinksVerifiedLocalAccount(t *testing.T) {
+	env := newTestEnv(t)
+	local := &models.User{ID: primitive.NewObjectID(), Email: "mock.user@example.com", EmailVerified: true}
+	env.users.users = append(env.users.users, local)
+
+	code, state := env.login(t, "")
+	res, err := env.svc.Callback(context.Background(), "mock", state, code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.User.ID != local.ID {
+		t.Fatalf("không gộp vào tài khoản đã xác minh")
+	}
+}
+
+func TestCallbackRefusesUnverifiedLocalAccount(t *testing.T) {
+	env := newTestEnv(t)
+	local := &models.User{ID: primitive.NewObjectID(), Email: "mock.user@example.com", EmailVerified: false}
+	env.users.users = append(env.users.users, local)
+
+	code, state := env.login(t, "")
+	if _, err := env.svc.Callback(context.Background(), "mock", state, code); !errors.Is(err, ErrAccountUnverified) {
+		t.Fatalf("err = %v, muốn ErrAccountUnverified", err)
+	}
+	if len(env.ids.items) != 0 {
+		t.Fatalf("không được tạo liên kết vào tài khoản chưa xác minh")
+	}
+}
+
+func TestCallbackRefusesUnverifiedProviderEmail(t *testing.T) {
+	env := newTestEnv(t)
+	env.mock.Users["eve"] = mock.User{Subject: "eve-1", Email: "victim@example.com", EmailVerified: false}
+	local := &models.User{ID: primitive.NewObjectID(), Email: "victim@example.com", EmailVerified: true}
+	env.users.users = append(env.users.users, local)
+
+	code, state := env.login(t, "eve")
+	if _, err := env.svc.Callback(context.Background(), "mock", state, code); !errors.Is(err, ErrEmailNotVerified) {
+		t.Fatalf("err = %v, muốn ErrEmailNotVerified", err)
+	}
+}
@@ -3,3 +3,6 @@ package comment
 import "errors"
 
 var ErrUnauthorized = errors.New("unauthorized access to comment")
+var ErrNoChanges = errors.New("comment content is unchanged")
+var ErrPostNotFound = errors.New("post not found")
+var ErrCommentNotFound = errors.New("comment not found")
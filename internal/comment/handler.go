@@ -1,8 +1,10 @@
 package comment
 
 import (
+    "errors"
     "net/http"
 
+    "socialnetwork/internal/contentfilter"
     "socialnetwork/models"
 
     "github.com/gin-gonic/gin"
@@ -54,6 +56,14 @@ func (h *CommentHandler) CreateComment(c *gin.Context) {
     }
 
     created, err := h.service.Create(c.Request.Context(), comment)
+    if errors.Is(err, ErrPostNotFound) {
+        c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+        return
+    }
+    if errors.Is(err, contentfilter.ErrRejected) {
+        c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+        return
+    }
     if err != nil {
         c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
         return
@@ -62,8 +72,9 @@ func (h *CommentHandler) CreateComment(c *gin.Context) {
     c.JSON(http.StatusCreated, created)
 }
 
-// GET /comments/:postID
+// GET /comments/:postID (đăng nhập không bắt buộc, cần để xem bình luận bài trong nhóm riêng tư)
 func (h *CommentHandler) GetCommentsByPost(c *gin.Context) {
+    viewerID, _ := primitive.ObjectIDFromHex(c.GetString("userID"))
     postIDStr := c.Param("postID")
     postID, err := primitive.ObjectIDFromHex(postIDStr)
     if err != nil {
@@ -71,7 +82,11 @@ func (h *CommentHandler) GetCommentsByPost(c *gin.Context) {
         return
     }
 
-    comments, err := h.service.ListByPost(c.Request.Context(), postID)
+    comments, err := h.service.ListByPost(c.Request.Context(), viewerID, postID)
+    if errors.Is(err, ErrPostNotFound) {
+        c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+        return
+    }
     if err != nil {
         c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
         return
@@ -109,6 +124,14 @@ func (h *CommentHandler) UpdateComment(c *gin.Context) {
     }
 
     err = h.service.Update(c.Request.Context(), commentID, userID, update)
+    if errors.Is(err, contentfilter.ErrRejected) {
+        c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+        return
+    }
+    if errors.Is(err, ErrNoChanges) {
+        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        return
+    }
     if err != nil {
         c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
         return
@@ -151,6 +174,10 @@ func (h *CommentHandler) ToggleLike(c *gin.Context) {
     userID, _ := primitive.ObjectIDFromHex(userIDStr.(string))
 
     err = h.service.ToggleLike(c.Request.Context(), commentID, userID)
+    if errors.Is(err, ErrPostNotFound) {
+        c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+        return
+    }
     if err != nil {
         c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
         return
@@ -158,3 +185,23 @@ func (h *CommentHandler) ToggleLike(c *gin.Context) {
 
     c.JSON(http.StatusOK, gin.H{"message": "Like status updated"})
 }
+
+// GET /comments/by-id/:id/revisions
+func (h *CommentHandler) GetCommentRevisions(c *gin.Context) {
+    commentID, err := primitive.ObjectIDFromHex(c.Param("id"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid comment ID"})
+        return
+    }
+
+    revisions, err := h.service.ListRevisions(c.Request.Context(), commentID)
+    if errors.Is(err, ErrCommentNotFound) {
+        c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+        return
+    }
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        return
+    }
+    c.JSON(http.StatusOK, revisions)
+}
@@ -9,6 +9,7 @@ import (
     "go.mongodb.org/mongo-driver/bson"
     "go.mongodb.org/mongo-driver/bson/primitive"
     "go.mongodb.org/mongo-driver/mongo"
+    "go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type Repository interface {
@@ -17,7 +18,15 @@ type Repository interface {
     Update(ctx context.Context, id, userID primitive.ObjectID, data map[string]interface{}) error
     Delete(ctx context.Context, id, userID primitive.ObjectID) error
     ListByPost(ctx context.Context, postID primitive.ObjectID) ([]*models.Comment, error)
-    ToggleLike(ctx context.Context, commentID, userID primitive.ObjectID) error
+    ToggleLike(ctx context.Context, commentID, userID primitive.ObjectID) (bool, error)
+    ListDeleted(ctx context.Context, userID primitive.ObjectID, since time.Time) ([]*models.Comment, error)
+    FindDeleted(ctx context.Context, id, userID primitive.ObjectID, since time.Time) (*models.Comment, error)
+    Restore(ctx context.Context, id, userID primitive.ObjectID, since time.Time) error
+    FindPurgeable(ctx context.Context, before time.Time, limit int64) ([]*models.Comment, error)
+    Purge(ctx context.Context, id primitive.ObjectID) error
+    PurgeByPost(ctx context.Context, postID primitive.ObjectID) ([]primitive.ObjectID, error)
+    FindAllByUser(ctx context.Context, userID primitive.ObjectID, limit int64) ([]*models.Comment, error)
+    PullUserLikes(ctx context.Context, userID primitive.ObjectID) error
 }
 
 type commentRepo struct {
@@ -39,9 +48,17 @@ func (r *commentRepo) Create(ctx context.Context, c *models.Comment) (*models.Co
     return c, err
 }
 
+// visible thêm điều kiện loại bỏ comment đã xoá mềm, bị ẩn do vi phạm hoặc của tài khoản đã vô hiệu hoá
+func visible(filter bson.M) bson.M {
+    filter["deleted_at"] = nil
+    filter["owner_inactive"] = bson.M{"$ne": true}
+    filter["moderation_hidden"] = bson.M{"$ne": true}
+    return filter
+}
+
 func (r *commentRepo) GetByID(ctx context.Context, id primitive.ObjectID) (*models.Comment, error) {
     var comment models.Comment
-    err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&comment)
+    err := r.collection.FindOne(ctx, visible(bson.M{"_id": id})).Decode(&comment)
     return &comment, err
 }
 
@@ -49,7 +66,7 @@ func (r *commentRepo) Update(ctx context.Context, id, userID primitive.ObjectID,
     data["updated_at"] = time.Now()
     res, err := r.collection.UpdateOne(
         ctx,
-        bson.M{"_id": id, "user_id": userID}, // Check đúng người
+        visible(bson.M{"_id": id, "user_id": userID}), // Check đúng người
         bson.M{"$set": data},
     )
     if err != nil {
@@ -62,12 +79,17 @@ func (r *commentRepo) Update(ctx context.Context, id, userID primitive.ObjectID,
 }
 
 
+// Delete xoá mềm comment (chuyển vào thùng rác)
 func (r *commentRepo) Delete(ctx context.Context, id, userID primitive.ObjectID) error {
-    res, err := r.collection.DeleteOne(ctx, bson.M{"_id": id, "user_id": userID})
+    res, err := r.collection.UpdateOne(
+        ctx,
+        visible(bson.M{"_id": id, "user_id": userID}),
+        bson.M{"$set": bson.M{"deleted_at": time.Now()}},
+    )
     if err != nil {
         return err
     }
-    if res.DeletedCount == 0 {
+    if res.MatchedCount == 0 {
         return errors.New("unauthorized or comment not found")
     }
     return nil
@@ -75,7 +97,7 @@ func (r *commentRepo) Delete(ctx context.Context, id, userID primitive.ObjectID)
 
 
 func (r *commentRepo) ListByPost(ctx context.Context, postID primitive.ObjectID) ([]*models.Comment, error) {
-    cursor, err := r.collection.Find(ctx, bson.M{"post_id": postID})
+    cursor, err := r.collection.Find(ctx, visible(bson.M{"post_id": postID}))
     if err != nil {
         return nil, err
     }
@@ -92,11 +114,12 @@ func (r *commentRepo) ListByPost(ctx context.Context, postID primitive.ObjectID)
     return comments, nil
 }
 
-func (r *commentRepo) ToggleLike(ctx context.Context, commentID, userID primitive.ObjectID) error {
+// ToggleLike trả về true nếu vừa thích, false nếu vừa bỏ thích
+func (r *commentRepo) ToggleLike(ctx context.Context, commentID, userID primitive.ObjectID) (bool, error) {
     var comment models.Comment
-    err := r.collection.FindOne(ctx, bson.M{"_id": commentID}).Decode(&comment)
+    err := r.collection.FindOne(ctx, visible(bson.M{"_id": commentID})).Decode(&comment)
     if err != nil {
-        return err
+        return false, err
     }
 
     // Kiểm tra user đã like chưa
@@ -116,7 +139,94 @@ func (r *commentRepo) ToggleLike(ctx context.Context, commentID, userID primitiv
     }
 
     _, err = r.collection.UpdateOne(ctx, bson.M{"_id": commentID}, update)
+    return !liked, err
+}
+
+// ListDeleted - comment trong thùng rác của user, xoá sau thời điểm since
+func (r *commentRepo) ListDeleted(ctx context.Context, userID primitive.ObjectID, since time.Time) ([]*models.Comment, error) {
+    opts := options.Find().SetSort(bson.M{"deleted_at": -1})
+    cursor, err := r.collection.Find(ctx, bson.M{"user_id": userID, "deleted_at": bson.M{"$gte": since}}, opts)
+    if err != nil {
+        return nil, err
+    }
+    var comments []*models.Comment
+    err = cursor.All(ctx, &comments)
+    return comments, err
+}
+
+// FindDeleted - comment trong thùng rác của user, còn hạn khôi phục
+func (r *commentRepo) FindDeleted(ctx context.Context, id, userID primitive.ObjectID, since time.Time) (*models.Comment, error) {
+    var comment models.Comment
+    err := r.collection.FindOne(ctx, bson.M{"_id": id, "user_id": userID, "deleted_at": bson.M{"$gte": since}}).Decode(&comment)
+    if err != nil {
+        return nil, err
+    }
+    return &comment, nil
+}
+
+func (r *commentRepo) Restore(ctx context.Context, id, userID primitive.ObjectID, since time.Time) error {
+    res, err := r.collection.UpdateOne(
+        ctx,
+        bson.M{"_id": id, "user_id": userID, "deleted_at": bson.M{"$gte": since}},
+        bson.M{"$unset": bson.M{"deleted_at": ""}},
+    )
+    if err != nil {
+        return err
+    }
+    if res.MatchedCount == 0 {
+        return mongo.ErrNoDocuments
+    }
+    return nil
+}
+
+// FindPurgeable - comment đã xoá trước thời điểm before (hết hạn lưu)
+func (r *commentRepo) FindPurgeable(ctx context.Context, before time.Time, limit int64) ([]*models.Comment, error) {
+    cursor, err := r.collection.Find(ctx, bson.M{"deleted_at": bson.M{"$lt": before}}, options.Find().SetLimit(limit))
+    if err != nil {
+        return nil, err
+    }
+    var comments []*models.Comment
+    err = cursor.All(ctx, &comments)
+    return comments, err
+}
+
+func (r *commentRepo) Purge(ctx context.Context, id primitive.ObjectID) error {
+    _, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
     return err
 }
 
+// PurgeByPost xoá vĩnh viễn mọi comment của post, trả về ID các comment đã xoá
+func (r *commentRepo) PurgeByPost(ctx context.Context, postID primitive.ObjectID) ([]primitive.ObjectID, error) {
+    raw, err := r.collection.Distinct(ctx, "_id", bson.M{"post_id": postID})
+    if err != nil {
+        return nil, err
+    }
+    ids := make([]primitive.ObjectID, 0, len(raw))
+    for _, v := range raw {
+        if id, ok := v.(primitive.ObjectID); ok {
+            ids = append(ids, id)
+        }
+    }
+    if len(ids) == 0 {
+        return ids, nil
+    }
+    _, err = r.collection.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": ids}})
+    return ids, err
+}
+
+// FindAllByUser - mọi comment của user, kể cả đã xoá mềm (dùng khi xoá tài khoản)
+func (r *commentRepo) FindAllByUser(ctx context.Context, userID primitive.ObjectID, limit int64) ([]*models.Comment, error) {
+    cursor, err := r.collection.Find(ctx, bson.M{"user_id": userID}, options.Find().SetLimit(limit))
+    if err != nil {
+        return nil, err
+    }
+    var comments []*models.Comment
+    err = cursor.All(ctx, &comments)
+    return comments, err
+}
 
+// PullUserLikes gỡ lượt thích của user khỏi mọi comment
+func (r *commentRepo) PullUserLikes(ctx context.Context, userID primitive.ObjectID) error {
+    _, err := r.collection.UpdateMany(ctx, bson.M{"likes": userID}, bson.M{"$pull": bson.M{"likes": userID}})
+    return err
+}
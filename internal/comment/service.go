@@ -2,7 +2,13 @@ package comment
 
 import (
     "context"
+    "log"
+    "time"
 
+    "socialnetwork/internal/analytics"
+    "socialnetwork/internal/contentfilter"
+    "socialnetwork/internal/post"
+    "socialnetwork/internal/revision"
     "socialnetwork/models"
     "go.mongodb.org/mongo-driver/bson/primitive"
 )
@@ -12,20 +18,78 @@ type Service interface {
     GetByID(ctx context.Context, id primitive.ObjectID) (*models.Comment, error)
     Update(ctx context.Context, id, userID primitive.ObjectID, data map[string]interface{}) error
     Delete(ctx context.Context, id, userID primitive.ObjectID) error
-    ListByPost(ctx context.Context, postID primitive.ObjectID) ([]*models.Comment, error)
+    ListByPost(ctx context.Context, viewerID, postID primitive.ObjectID) ([]*models.Comment, error)
 	ToggleLike(ctx context.Context, commentID, userID primitive.ObjectID) error
+    ListRevisions(ctx context.Context, id primitive.ObjectID) ([]models.Revision, error)
+}
+
+// GroupAccess - quyền xem/viết với bài trong nhóm (groups.Service)
+type GroupAccess interface {
+    CanView(ctx context.Context, userID, groupID primitive.ObjectID) error
+    CanPost(ctx context.Context, userID, groupID primitive.ObjectID) error
+}
+
+// ReactionRecorder - ghi lượt thích cho thống kê của người viết (analytics.Repository)
+type ReactionRecorder interface {
+    RecordReaction(ctx context.Context, contentType string, contentID, ownerID, userID primitive.ObjectID, liked bool) error
 }
 
 type commentService struct {
-    repo Repository
+    repo         Repository
+    postRepo     *post.PostRepository
+    groups       GroupAccess
+    revisionRepo revision.Repository
+    reactions    ReactionRecorder
+    filter       contentfilter.Filter
+}
+
+func NewCommentService(repo Repository, postRepo *post.PostRepository, groups GroupAccess, revisionRepo revision.Repository, reactions ReactionRecorder, filter contentfilter.Filter) Service {
+    return &commentService{repo: repo, postRepo: postRepo, groups: groups, revisionRepo: revisionRepo, reactions: reactions, filter: filter}
 }
 
-func NewCommentService(repo Repository) Service {
-    return &commentService{repo: repo}
+// checkPost - bài trong nhóm: đọc cần xem được nội dung nhóm, viết cần là thành viên không bị tạm cấm.
+// Không đủ quyền thì coi như bài không tồn tại.
+func (s *commentService) checkPost(ctx context.Context, userID, postID primitive.ObjectID, write bool) error {
+    p, err := s.postRepo.GetByID(ctx, postID)
+    if err != nil {
+        return ErrPostNotFound
+    }
+    if p.GroupID == nil {
+        return nil
+    }
+    if p.GroupPending {
+        return ErrPostNotFound
+    }
+    check := s.groups.CanView
+    if write {
+        check = s.groups.CanPost
+    }
+    if err := check(ctx, userID, *p.GroupID); err != nil {
+        return ErrPostNotFound
+    }
+    return nil
 }
 
 func (s *commentService) Create(ctx context.Context, c *models.Comment) (*models.Comment, error) {
-    return s.repo.Create(ctx, c)
+    if err := s.checkPost(ctx, c.UserID, c.PostID, true); err != nil {
+        return nil, err
+    }
+    verdict, err := contentfilter.Apply(s.filter, &c.Content)
+    if err != nil {
+        return nil, err
+    }
+    c.ModerationHidden = verdict.Held()
+
+    created, err := s.repo.Create(ctx, c)
+    if err != nil {
+        return nil, err
+    }
+    if verdict.Held() {
+        if err := s.filter.Hold(ctx, models.ReportTargetComment, created.ID, created.UserID, verdict.Rules); err != nil {
+            return nil, err
+        }
+    }
+    return created, nil
 }
 
 func (s *commentService) GetByID(ctx context.Context, id primitive.ObjectID) (*models.Comment, error) {
@@ -40,7 +104,35 @@ func (s *commentService) Update(ctx context.Context, id, userID primitive.Object
     if c.UserID != userID {
         return ErrUnauthorized
     }
-    return s.repo.Update(ctx, id, userID, data)
+
+    var verdict contentfilter.Verdict
+    if content, ok := data["content"].(string); ok {
+        if verdict, err = contentfilter.Apply(s.filter, &content); err != nil {
+            return err
+        }
+        data["content"] = content
+    }
+
+    // Lưu nội dung cũ vào revisions, chỉ cập nhật khi thực sự thay đổi
+    previous := map[string]interface{}{"content": c.Content}
+    changed, rev := revision.Diff(revision.ContentComment, id, userID, previous, data)
+    if len(changed) == 0 {
+        return ErrNoChanges
+    }
+
+    changed["edited"] = true
+    changed["edited_at"] = time.Now()
+    if verdict.Held() {
+        changed["moderation_hidden"] = true
+    }
+    if err := s.repo.Update(ctx, id, userID, changed); err != nil {
+        return err
+    }
+    revision.Save(ctx, s.revisionRepo, rev)
+    if verdict.Held() {
+        return s.filter.Hold(ctx, models.ReportTargetComment, id, userID, verdict.Rules)
+    }
+    return nil
 }
 
 func (s *commentService) Delete(ctx context.Context, id, userID primitive.ObjectID) error {
@@ -54,11 +146,44 @@ func (s *commentService) Delete(ctx context.Context, id, userID primitive.Object
     return s.repo.Delete(ctx, id, userID)
 }
 
-func (s *commentService) ListByPost(ctx context.Context, postID primitive.ObjectID) ([]*models.Comment, error) {
+func (s *commentService) ListByPost(ctx context.Context, viewerID, postID primitive.ObjectID) ([]*models.Comment, error) {
+    if err := s.checkPost(ctx, viewerID, postID, false); err != nil {
+        return nil, err
+    }
     return s.repo.ListByPost(ctx, postID)
 }
 
 
 func (s *commentService) ToggleLike(ctx context.Context, commentID, userID primitive.ObjectID) error {
-    return s.repo.ToggleLike(ctx, commentID, userID)
-}
\ No newline at end of file
+    c, err := s.repo.GetByID(ctx, commentID)
+    if err != nil {
+        return err
+    }
+    if err := s.checkPost(ctx, userID, c.PostID, true); err != nil {
+        return err
+    }
+    liked, err := s.repo.ToggleLike(ctx, commentID, userID)
+    if err != nil {
+        return err
+    }
+    if c.UserID == userID {
+        return nil // tự thích không tính vào thống kê
+    }
+    if err := s.reactions.RecordReaction(ctx, analytics.ReactionComment, commentID, c.UserID, userID, liked); err != nil {
+        log.Printf("⚠️ không ghi được lượt thích comment %s: %v", commentID.Hex(), err)
+    }
+    return nil
+}
+
+// ListRevisions - lịch sử chỉnh sửa công khai như bài viết; bình luận trên bài trong nhóm thì ẩn
+func (s *commentService) ListRevisions(ctx context.Context, id primitive.ObjectID) ([]models.Revision, error) {
+    c, err := s.repo.GetByID(ctx, id)
+    if err != nil {
+        return nil, ErrCommentNotFound
+    }
+    p, err := s.postRepo.GetByID(ctx, c.PostID)
+    if err != nil || p.GroupID != nil {
+        return nil, ErrCommentNotFound
+    }
+    return s.revisionRepo.ListByContent(ctx, revision.ContentComment, id)
+}
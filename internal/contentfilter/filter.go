@@ -0,0 +1,241 @@
+package contentfilter
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"sort"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"socialnetwork/models"
+)
+
+var ErrRejected = errors.New("nội dung vi phạm tiêu chuẩn cộng đồng")
+
+// Filter - bộ lọc nội dung dùng trong service post/comment/video/short
+type Filter interface {
+	// Check kiểm tra một đoạn văn bản theo bộ luật hiện tại
+	Check(text string) Result
+	// Hold đưa nội dung (đã lưu ở trạng thái ẩn) vào hàng đợi kiểm duyệt
+	Hold(ctx context.Context, targetType models.ReportTargetType, targetID, ownerID primitive.ObjectID, rules []string) error
+}
+
+// Verdict - hành động mạnh nhất trong các luật bị vi phạm
+type Verdict struct {
+	Action models.FilterAction
+	Rules  []string // tên các luật bị vi phạm
+}
+
+func (v Verdict) Held() bool { return v.Action == models.FilterHold }
+
+type Result struct {
+	Verdict
+	Text string // văn bản sau khi che các từ vi phạm
+}
+
+var severity = map[models.FilterAction]int{
+	models.FilterAllow:  0,
+	models.FilterMask:   1,
+	models.FilterHold:   2,
+	models.FilterReject: 3,
+}
+
+func (v *Verdict) add(rule models.FilterRule) {
+	v.Rules = append(v.Rules, rule.Name)
+	if severity[rule.Action] > severity[v.Action] {
+		v.Action = rule.Action
+	}
+}
+
+// Apply chạy bộ lọc trên các trường văn bản, che từ vi phạm ngay trên chuỗi và
+// trả về kết quả gộp. Trả về ErrRejected nếu có luật từ chối.
+func Apply(f Filter, fields ...*string) (Verdict, error) {
+	verdict := Verdict{Action: models.FilterAllow}
+	for _, field := range fields {
+		if field == nil || *field == "" {
+			continue
+		}
+		res := f.Check(*field)
+		*field = res.Text
+		verdict.Rules = append(verdict.Rules, res.Rules...)
+		if severity[res.Action] > severity[verdict.Action] {
+			verdict.Action = res.Action
+		}
+	}
+	if verdict.Action == models.FilterReject {
+		return verdict, ErrRejected
+	}
+	return verdict, nil
+}
+
+var linkPattern = regexp.MustCompile(`(?i)(?:https?://|www\.)[^\s<>"]+|\b[a-z0-9][a-z0-9-]*(?:\.[a-z0-9-]+)*\.(?:com|net|org|info|biz|xyz|top|vn|io|co|me|ru|cn|ly|link|click|site|online|shop)\b(?:/[^\s<>"]*)?`)
+
+type compiledRule struct {
+	rule    models.FilterRule
+	phrases [][]string // mỗi cụm từ cấm đã chuẩn hoá thành các từ
+	domains []string
+}
+
+// Ruleset - bộ luật đã biên dịch, dùng chung giữa các goroutine (chỉ đọc)
+type Ruleset struct {
+	rules []compiledRule
+}
+
+func Compile(rules []models.FilterRule) *Ruleset {
+	rs := &Ruleset{}
+	for _, rule := range rules {
+		if !rule.Enabled {
+			continue
+		}
+		c := compiledRule{rule: rule}
+		for _, w := range rule.Words {
+			var words []string
+			for _, t := range tokenize(w) {
+				words = append(words, t.norm)
+			}
+			if len(words) > 0 {
+				c.phrases = append(c.phrases, words)
+			}
+		}
+		for _, d := range rule.Domains {
+			d = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(d)), "www.")
+			if d != "" {
+				c.domains = append(c.domains, d)
+			}
+		}
+		rs.rules = append(rs.rules, c)
+	}
+	return rs
+}
+
+type span struct{ start, end int }
+
+func (rs *Ruleset) Check(text string) Result {
+	res := Result{Verdict: Verdict{Action: models.FilterAllow}, Text: text}
+	if len(rs.rules) == 0 || strings.TrimSpace(text) == "" {
+		return res
+	}
+
+	tokens := tokenize(text)
+	spelled := joinSpelled(tokens)
+	links := linkPattern.FindAllStringIndex(text, -1)
+
+	var masks []span
+	for _, c := range rs.rules {
+		var found []span
+		switch c.rule.Type {
+		case models.FilterRuleWords:
+			found = matchPhrases(c.phrases, tokens)
+			found = append(found, matchPhrases(c.phrases, spelled)...)
+		case models.FilterRuleLinks:
+			found = matchLinks(c, text, links)
+		case models.FilterRuleSpam:
+			if c.rule.MaxRepeat > 0 && maxRepeat(tokens) > c.rule.MaxRepeat {
+				found = []span{{0, 0}}
+			}
+		}
+		if len(found) == 0 {
+			continue
+		}
+		res.add(c.rule)
+		if c.rule.Action == models.FilterMask {
+			masks = append(masks, found...)
+		}
+	}
+	res.Text = mask(text, masks)
+	return res
+}
+
+func matchPhrases(phrases [][]string, tokens []token) []span {
+	var found []span
+	for _, phrase := range phrases {
+		for i := 0; i+len(phrase) <= len(tokens); i++ {
+			ok := true
+			for j, w := range phrase {
+				if tokens[i+j].norm != w {
+					ok = false
+					break
+				}
+			}
+			if ok {
+				found = append(found, span{tokens[i].start, tokens[i+len(phrase)-1].end})
+			}
+		}
+	}
+	return found
+}
+
+// matchLinks: link thuộc tên miền cấm, hoặc số link vượt MaxLinks (khi đó mọi link đều bị đánh dấu)
+func matchLinks(c compiledRule, text string, links [][]int) []span {
+	var found []span
+	for _, l := range links {
+		host := linkHost(text[l[0]:l[1]])
+		for _, d := range c.domains {
+			if host == d || strings.HasSuffix(host, "."+d) {
+				found = append(found, span{l[0], l[1]})
+				break
+			}
+		}
+	}
+	if c.rule.MaxLinks > 0 && len(links) > c.rule.MaxLinks {
+		found = found[:0]
+		for _, l := range links {
+			found = append(found, span{l[0], l[1]})
+		}
+	}
+	return found
+}
+
+func linkHost(link string) string {
+	link = strings.ToLower(link)
+	if i := strings.Index(link, "://"); i >= 0 {
+		link = link[i+3:]
+	}
+	if i := strings.IndexAny(link, "/?#:"); i >= 0 {
+		link = link[:i]
+	}
+	return strings.TrimPrefix(link, "www.")
+}
+
+// maxRepeat - số lần xuất hiện nhiều nhất của một từ (bỏ qua từ 1 ký tự)
+func maxRepeat(tokens []token) int {
+	counts := map[string]int{}
+	max := 0
+	for _, t := range tokens {
+		if len(t.norm) < 2 {
+			continue
+		}
+		counts[t.norm]++
+		if counts[t.norm] > max {
+			max = counts[t.norm]
+		}
+	}
+	return max
+}
+
+// mask thay từng ký tự trong các đoạn vi phạm bằng '*'
+func mask(text string, spans []span) string {
+	if len(spans) == 0 {
+		return text
+	}
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+
+	var b strings.Builder
+	pos := 0
+	for _, s := range spans {
+		if s.end <= pos {
+			continue
+		}
+		if s.start < pos {
+			s.start = pos
+		}
+		b.WriteString(text[pos:s.start])
+		for range text[s.start:s.end] {
+			b.WriteByte('*')
+		}
+		pos = s.end
+	}
+	b.WriteString(text[pos:])
+	return b.String()
+}
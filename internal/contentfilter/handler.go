@@ -0,0 +1,125 @@
+package contentfilter
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"socialnetwork/dto/request"
+	"socialnetwork/internal/audit"
+	"socialnetwork/models"
+)
+
+type Handler struct {
+	service Service
+	audit   *audit.Logger
+}
+
+func NewHandler(service Service, auditLog *audit.Logger) *Handler {
+	return &Handler{service: service, audit: auditLog}
+}
+
+// record ghi lại thao tác của moderator/admin trên luật lọc
+func (h *Handler) record(c *gin.Context, action models.AuditAction, ruleID primitive.ObjectID, metadata map[string]interface{}) {
+	event := models.AuditEvent{
+		Action:     action,
+		Success:    true,
+		TargetType: "filter_rule",
+		Metadata:   metadata,
+	}
+	if actorID, err := primitive.ObjectIDFromHex(c.GetString("userID")); err == nil {
+		event.ActorID = &actorID
+	}
+	if !ruleID.IsZero() {
+		event.TargetID = ruleID.Hex()
+	}
+	h.audit.Record(c.Request.Context(), event)
+}
+
+// GET /moderation/filter-rules
+func (h *Handler) ListRules(c *gin.Context) {
+	rules, err := h.service.ListRules(c.Request.Context())
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, rules)
+}
+
+// POST /moderation/filter-rules
+func (h *Handler) CreateRule(c *gin.Context) {
+	var req request.FilterRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rule, err := h.service.CreateRule(c.Request.Context(), req)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	h.record(c, models.AuditFilterRuleCreated, rule.ID, map[string]interface{}{"name": rule.Name, "type": string(rule.Type), "action": string(rule.Action)})
+	c.JSON(http.StatusCreated, rule)
+}
+
+// PUT /moderation/filter-rules/:id
+func (h *Handler) UpdateRule(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "id không hợp lệ"})
+		return
+	}
+
+	var req request.FilterRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rule, err := h.service.UpdateRule(c.Request.Context(), id, req)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	h.record(c, models.AuditFilterRuleUpdated, id, map[string]interface{}{"name": rule.Name, "action": string(rule.Action), "enabled": rule.Enabled})
+	c.JSON(http.StatusOK, rule)
+}
+
+// DELETE /moderation/filter-rules/:id
+func (h *Handler) DeleteRule(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "id không hợp lệ"})
+		return
+	}
+
+	if err := h.service.DeleteRule(c.Request.Context(), id); err != nil {
+		writeError(c, err)
+		return
+	}
+	h.record(c, models.AuditFilterRuleDeleted, id, nil)
+	c.JSON(http.StatusOK, gin.H{"message": "Đã xoá luật lọc"})
+}
+
+// POST /moderation/filter-rules/reload
+func (h *Handler) Reload(c *gin.Context) {
+	if err := h.service.Reload(c.Request.Context()); err != nil {
+		writeError(c, err)
+		return
+	}
+	h.record(c, models.AuditFilterRulesReload, primitive.NilObjectID, nil)
+	c.JSON(http.StatusOK, gin.H{"message": "Đã nạp lại luật lọc"})
+}
+
+func writeError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrInvalidRule):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrRuleNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
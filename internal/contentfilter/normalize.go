@@ -0,0 +1,149 @@
+package contentfilter
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Bỏ dấu tiếng Việt: "Đồ ngốc" -> "do ngoc"
+var vietnameseFold = map[rune]rune{}
+
+func init() {
+	groups := map[rune]string{
+		'a': "àáảãạăằắẳẵặâầấẩẫậ",
+		'e': "èéẻẽẹêềếểễệ",
+		'i': "ìíỉĩị",
+		'o': "òóỏõọôồốổỗộơờớởỡợ",
+		'u': "ùúủũụưừứửữự",
+		'y': "ỳýỷỹỵ",
+		'd': "đ",
+	}
+	for base, chars := range groups {
+		for _, r := range chars {
+			vietnameseFold[r] = base
+		}
+	}
+}
+
+// Ký tự leetspeak thường dùng để lách bộ lọc: "sh1t", "@ss", "$pam"
+var leet = map[rune]rune{
+	'0': 'o',
+	'1': 'i',
+	'3': 'e',
+	'4': 'a',
+	'5': 's',
+	'7': 't',
+	'8': 'b',
+	'@': 'a',
+	'$': 's',
+	'!': 'i',
+}
+
+// token - một từ trong văn bản gốc (vị trí byte) và dạng đã chuẩn hoá để so khớp
+type token struct {
+	start, end int
+	norm       string
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r)
+}
+
+func isLeetSymbol(r rune) bool {
+	return r == '@' || r == '$' || r == '!'
+}
+
+// tokenize tách văn bản thành các từ. Ký hiệu leet (@, $, !) chỉ được tính là
+// một phần của từ khi dính liền với chữ, để "a@b" hay "$hit" vẫn là một từ.
+func tokenize(text string) []token {
+	var tokens []token
+	start := -1
+	for i, r := range text {
+		word := isWordRune(r) || (isLeetSymbol(r) && (start >= 0 || nextIsWord(text[i+utf8.RuneLen(r):])))
+		if word && start < 0 {
+			start = i
+		}
+		if !word && start >= 0 {
+			tokens = appendToken(tokens, text, start, i)
+			start = -1
+		}
+	}
+	if start >= 0 {
+		tokens = appendToken(tokens, text, start, len(text))
+	}
+	return tokens
+}
+
+func nextIsWord(rest string) bool {
+	r, _ := utf8.DecodeRuneInString(rest)
+	return isWordRune(r)
+}
+
+func appendToken(tokens []token, text string, start, end int) []token {
+	// "!" ở cuối từ là dấu chấm than, không phải chữ i
+	for end > start && text[end-1] == '!' {
+		end--
+	}
+	norm := normalizeWord(text[start:end])
+	if norm == "" {
+		return tokens
+	}
+	return append(tokens, token{start: start, end: end, norm: norm})
+}
+
+// normalizeWord: chữ thường, bỏ dấu, đổi leetspeak (chỉ khi từ có chữ cái, để số
+// như "2024" giữ nguyên) rồi gộp ký tự lặp ("nguuuu" -> "ngu")
+func normalizeWord(word string) string {
+	hasLetter := strings.IndexFunc(word, unicode.IsLetter) >= 0
+
+	var b strings.Builder
+	for _, r := range strings.ToLower(word) {
+		if unicode.Is(unicode.Mn, r) {
+			continue // dấu tổ hợp (văn bản dạng NFD)
+		}
+		if f, ok := vietnameseFold[r]; ok {
+			r = f
+		}
+		if l, ok := leet[r]; ok && hasLetter {
+			r = l
+		}
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return collapse(b.String())
+}
+
+func collapse(s string) string {
+	var b strings.Builder
+	var prev rune = -1
+	for _, r := range s {
+		if r != prev {
+			b.WriteRune(r)
+		}
+		prev = r
+	}
+	return b.String()
+}
+
+// joinSpelled gộp các chữ cái bị tách rời ("n g u", "n.g.u") thành một từ
+func joinSpelled(tokens []token) []token {
+	var joined []token
+	for i := 0; i < len(tokens); {
+		j := i
+		var b strings.Builder
+		for j < len(tokens) && utf8.RuneCountInString(tokens[j].norm) == 1 {
+			b.WriteString(tokens[j].norm)
+			j++
+		}
+		if j-i >= 3 {
+			joined = append(joined, token{start: tokens[i].start, end: tokens[j-1].end, norm: collapse(b.String())})
+		}
+		if j == i {
+			j++
+		}
+		i = j
+	}
+	return joined
+}
@@ -0,0 +1,90 @@
+package contentfilter
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"socialnetwork/models"
+)
+
+type Repository interface {
+	FindAll(ctx context.Context) ([]models.FilterRule, error)
+	FindEnabled(ctx context.Context) ([]models.FilterRule, error)
+	Create(ctx context.Context, rule *models.FilterRule) error
+	Update(ctx context.Context, id primitive.ObjectID, rule *models.FilterRule) error
+	Delete(ctx context.Context, id primitive.ObjectID) error
+}
+
+type repository struct {
+	collection *mongo.Collection
+}
+
+func NewRepository(db *mongo.Database) Repository {
+	return &repository{collection: db.Collection("filter_rules")}
+}
+
+func (r *repository) find(ctx context.Context, filter bson.M) ([]models.FilterRule, error) {
+	cursor, err := r.collection.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	rules := []models.FilterRule{}
+	err = cursor.All(ctx, &rules)
+	return rules, err
+}
+
+func (r *repository) FindAll(ctx context.Context) ([]models.FilterRule, error) {
+	return r.find(ctx, bson.M{})
+}
+
+func (r *repository) FindEnabled(ctx context.Context) ([]models.FilterRule, error) {
+	return r.find(ctx, bson.M{"enabled": true})
+}
+
+func (r *repository) Create(ctx context.Context, rule *models.FilterRule) error {
+	rule.ID = primitive.NewObjectID()
+	rule.CreatedAt = time.Now()
+	rule.UpdatedAt = rule.CreatedAt
+	_, err := r.collection.InsertOne(ctx, rule)
+	return err
+}
+
+func (r *repository) Update(ctx context.Context, id primitive.ObjectID, rule *models.FilterRule) error {
+	rule.UpdatedAt = time.Now()
+	res, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{
+		"name":      rule.Name,
+		"type":      rule.Type,
+		"language":  rule.Language,
+		"words":     rule.Words,
+		"domains":   rule.Domains,
+		"maxLinks":  rule.MaxLinks,
+		"maxRepeat": rule.MaxRepeat,
+		"action":    rule.Action,
+		"enabled":   rule.Enabled,
+		"updatedAt": rule.UpdatedAt,
+	}})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+func (r *repository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	res, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
@@ -0,0 +1,176 @@
+package contentfilter
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"socialnetwork/dto/request"
+	"socialnetwork/models"
+)
+
+var (
+	ErrRuleNotFound = errors.New("không tìm thấy luật lọc")
+	ErrInvalidRule  = errors.New("luật lọc không hợp lệ")
+)
+
+// ReviewQueue - nơi nhận nội dung bị giữ lại chờ duyệt (moderation.Repository)
+type ReviewQueue interface {
+	Create(ctx context.Context, report *models.Report) error
+	HasOpenReport(ctx context.Context, reporterID primitive.ObjectID, targetType models.ReportTargetType, targetID primitive.ObjectID) (bool, error)
+}
+
+type Service interface {
+	Filter
+	ListRules(ctx context.Context) ([]models.FilterRule, error)
+	CreateRule(ctx context.Context, req request.FilterRuleRequest) (*models.FilterRule, error)
+	UpdateRule(ctx context.Context, id primitive.ObjectID, req request.FilterRuleRequest) (*models.FilterRule, error)
+	DeleteRule(ctx context.Context, id primitive.ObjectID) error
+	Reload(ctx context.Context) error
+	RunReloader(ctx context.Context, interval time.Duration)
+}
+
+type service struct {
+	repo   Repository
+	queue  ReviewQueue
+	mu     sync.RWMutex
+	active *Ruleset
+}
+
+func NewService(repo Repository, queue ReviewQueue) Service {
+	return &service{repo: repo, queue: queue, active: Compile(nil)}
+}
+
+func (s *service) Check(text string) Result {
+	s.mu.RLock()
+	rs := s.active
+	s.mu.RUnlock()
+	return rs.Check(text)
+}
+
+// Hold tạo báo cáo hệ thống (không có người báo cáo) để nội dung xuất hiện trong hàng đợi kiểm duyệt
+func (s *service) Hold(ctx context.Context, targetType models.ReportTargetType, targetID, ownerID primitive.ObjectID, rules []string) error {
+	exists, err := s.queue.HasOpenReport(ctx, primitive.NilObjectID, targetType, targetID)
+	if err != nil || exists {
+		return err
+	}
+	return s.queue.Create(ctx, &models.Report{
+		TargetType:    targetType,
+		TargetID:      targetID,
+		TargetOwnerID: ownerID,
+		Reason:        models.ReportReasonAutoFilter,
+		Details:       "Luật: " + strings.Join(rules, ", "),
+	})
+}
+
+// Reload nạp lại bộ luật từ DB; các request đang chạy vẫn dùng bộ luật cũ cho tới khi xong
+func (s *service) Reload(ctx context.Context) error {
+	rules, err := s.repo.FindEnabled(ctx)
+	if err != nil {
+		return err
+	}
+	rs := Compile(rules)
+	s.mu.Lock()
+	s.active = rs
+	s.mu.Unlock()
+	return nil
+}
+
+// RunReloader định kỳ nạp lại bộ luật để mọi instance nhận thay đổi từ instance khác
+func (s *service) RunReloader(ctx context.Context, interval time.Duration) {
+	if err := s.Reload(ctx); err != nil {
+		log.Printf("⚠️ nạp luật lọc nội dung thất bại: %v", err)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Reload(ctx); err != nil {
+				log.Printf("⚠️ nạp luật lọc nội dung thất bại: %v", err)
+			}
+		}
+	}
+}
+
+func (s *service) ListRules(ctx context.Context) ([]models.FilterRule, error) {
+	return s.repo.FindAll(ctx)
+}
+
+func (s *service) CreateRule(ctx context.Context, req request.FilterRuleRequest) (*models.FilterRule, error) {
+	rule, err := buildRule(req)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.Create(ctx, rule); err != nil {
+		return nil, err
+	}
+	return rule, s.Reload(ctx)
+}
+
+func (s *service) UpdateRule(ctx context.Context, id primitive.ObjectID, req request.FilterRuleRequest) (*models.FilterRule, error) {
+	rule, err := buildRule(req)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.Update(ctx, id, rule); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrRuleNotFound
+		}
+		return nil, err
+	}
+	rule.ID = id
+	return rule, s.Reload(ctx)
+}
+
+func (s *service) DeleteRule(ctx context.Context, id primitive.ObjectID) error {
+	if err := s.repo.Delete(ctx, id); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return ErrRuleNotFound
+		}
+		return err
+	}
+	return s.Reload(ctx)
+}
+
+func buildRule(req request.FilterRuleRequest) (*models.FilterRule, error) {
+	rule := &models.FilterRule{
+		Name:      strings.TrimSpace(req.Name),
+		Type:      models.FilterRuleType(req.Type),
+		Language:  req.Language,
+		Words:     req.Words,
+		Domains:   req.Domains,
+		MaxLinks:  req.MaxLinks,
+		MaxRepeat: req.MaxRepeat,
+		Action:    models.FilterAction(req.Action),
+		Enabled:   req.Enabled == nil || *req.Enabled,
+	}
+
+	switch rule.Type {
+	case models.FilterRuleWords:
+		if len(rule.Words) == 0 {
+			return nil, ErrInvalidRule
+		}
+	case models.FilterRuleLinks:
+		if len(rule.Domains) == 0 && rule.MaxLinks <= 0 {
+			return nil, ErrInvalidRule
+		}
+	case models.FilterRuleSpam:
+		// Spam không có đoạn cụ thể để che
+		if rule.MaxRepeat <= 0 || rule.Action == models.FilterMask {
+			return nil, ErrInvalidRule
+		}
+	default:
+		return nil, ErrInvalidRule
+	}
+	return rule, nil
+}
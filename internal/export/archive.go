@@ -0,0 +1,223 @@
+package export
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"socialnetwork/models"
+	"socialnetwork/pkg/media"
+)
+
+// Friends - quan hệ bạn bè lưu trong hồ sơ user
+type Friends struct {
+	Friends        interface{} `json:"friends"`
+	FriendRequests interface{} `json:"friendRequests"`
+	BlockedUsers   interface{} `json:"blockedUsers"`
+}
+
+// buildArchive gom dữ liệu của user vào file ZIP (JSON + media lưu trên server).
+// File được ghi ra tên tạm rồi đổi tên để không bao giờ phục vụ file dở dang.
+func (s *service) buildArchive(ctx context.Context, u *models.User, path string) (int64, error) {
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp)
+
+	zw := zip.NewWriter(f)
+	if err := s.writeArchive(ctx, zw, u); err != nil {
+		zw.Close()
+		f.Close()
+		return 0, err
+	}
+	if err := zw.Close(); err != nil {
+		f.Close()
+		return 0, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return 0, err
+	}
+	if err := f.Close(); err != nil {
+		return 0, err
+	}
+	return info.Size(), os.Rename(tmp, path)
+}
+
+func (s *service) writeArchive(ctx context.Context, zw *zip.Writer, u *models.User) error {
+	profile := *u
+	profile.Password = ""
+	if err := writeJSON(zw, "profile.json", profile); err != nil {
+		return err
+	}
+	friends := Friends{Friends: u.Friends, FriendRequests: u.FriendRequests, BlockedUsers: u.BlockedUsers}
+	if err := writeJSON(zw, "friends.json", friends); err != nil {
+		return err
+	}
+
+	posts, err := s.repo.Posts(ctx, u.ID)
+	if err != nil {
+		return err
+	}
+	comments, err := s.repo.Comments(ctx, u.ID)
+	if err != nil {
+		return err
+	}
+	reactions, err := s.repo.Reactions(ctx, u.ID)
+	if err != nil {
+		return err
+	}
+	followers, err := s.repo.Followers(ctx, u.ID)
+	if err != nil {
+		return err
+	}
+	following, err := s.repo.Following(ctx, u.ID)
+	if err != nil {
+		return err
+	}
+	notifications, err := s.repo.Notifications(ctx, u.ID)
+	if err != nil {
+		return err
+	}
+	videos, err := s.repo.Videos(ctx, u.ID)
+	if err != nil {
+		return err
+	}
+	shorts, err := s.repo.Shorts(ctx, u.ID)
+	if err != nil {
+		return err
+	}
+
+	files := []struct {
+		name string
+		data interface{}
+	}{
+		{"posts.json", posts},
+		{"comments.json", comments},
+		{"reactions.json", reactions},
+		{"followers.json", followers},
+		{"following.json", following},
+		{"notifications.json", notifications},
+		{"videos.json", videos},
+		{"shorts.json", shorts},
+	}
+	for _, file := range files {
+		if err := writeJSON(zw, file.name, file.data); err != nil {
+			return err
+		}
+	}
+
+	// Media: file lưu trên server được đóng gói, URL bên ngoài chỉ liệt kê lại
+	m := &mediaCollector{service: s, zw: zw, seen: map[string]bool{}, external: []string{}}
+	m.addURL(u.AvatarURL)
+	m.addURL(u.CoverURL)
+	for _, p := range posts {
+		m.addURL(p.ImageURL)
+		for _, url := range p.Media {
+			m.addURL(url)
+		}
+	}
+	for _, v := range videos {
+		m.addDir(filepath.Join(media.KindVideo+"s", v.ID.Hex()))
+		m.addURL(v.URL)
+		m.addURL(v.Thumbnail)
+	}
+	for _, sh := range shorts {
+		m.addDir(filepath.Join(media.KindShort+"s", sh.ID.Hex()))
+		m.addURL(sh.URL)
+		m.addURL(sh.Thumbnail)
+	}
+	if m.err != nil {
+		return m.err
+	}
+	return writeJSON(zw, "media_links.json", m.external)
+}
+
+func writeJSON(zw *zip.Writer, name string, v interface{}) error {
+	w, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// mediaCollector chép file media cục bộ vào thư mục media/ của ZIP
+type mediaCollector struct {
+	service  *service
+	zw       *zip.Writer
+	seen     map[string]bool
+	external []string
+	err      error
+}
+
+// addURL đóng gói file nếu URL trỏ vào MEDIA_BASE_URL, ngược lại ghi vào media_links.json
+func (m *mediaCollector) addURL(url string) {
+	if url == "" || m.err != nil {
+		return
+	}
+	cfg := m.service.config
+	prefix := cfg.MediaBaseURL + "/"
+	if cfg.MediaDir == "" || cfg.MediaBaseURL == "" || !strings.HasPrefix(url, prefix) {
+		if !m.seen[url] {
+			m.seen[url] = true
+			m.external = append(m.external, url)
+		}
+		return
+	}
+	m.addFile(filepath.FromSlash(strings.TrimPrefix(url, prefix)))
+}
+
+// addDir đóng gói cả thư mục HLS + thumbnail của video/short (nếu có)
+func (m *mediaCollector) addDir(rel string) {
+	if m.err != nil || m.service.config.MediaDir == "" {
+		return
+	}
+	root := filepath.Join(m.service.config.MediaDir, rel)
+	if _, err := os.Stat(root); err != nil {
+		return
+	}
+	m.err = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		r, err := filepath.Rel(m.service.config.MediaDir, path)
+		if err != nil {
+			return err
+		}
+		m.addFile(r)
+		return m.err
+	})
+}
+
+func (m *mediaCollector) addFile(rel string) {
+	rel = filepath.Clean(rel)
+	// Không cho URL chứa ".." thoát ra ngoài thư mục media
+	if m.err != nil || m.seen[rel] || rel == "." || strings.HasPrefix(rel, "..") || filepath.IsAbs(rel) {
+		return
+	}
+	m.seen[rel] = true
+
+	src, err := os.Open(filepath.Join(m.service.config.MediaDir, rel))
+	if err != nil {
+		return // file đã bị xoá: bỏ qua
+	}
+	defer src.Close()
+
+	w, err := m.zw.Create("media/" + filepath.ToSlash(rel))
+	if err != nil {
+		m.err = err
+		return
+	}
+	if _, err := io.Copy(w, src); err != nil {
+		m.err = err
+	}
+}
@@ -0,0 +1,81 @@
+package export
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type Handler struct {
+	service Service
+}
+
+func NewHandler(service Service) *Handler {
+	return &Handler{service: service}
+}
+
+// POST /me/export
+func (h *Handler) RequestExport(c *gin.Context) {
+	userID, err := primitive.ObjectIDFromHex(c.GetString("userID"))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	job, err := h.service.Request(c.Request.Context(), userID)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "Đang chuẩn bị dữ liệu. Link tải sẽ được gửi qua email khi hoàn tất.",
+		"job":     job,
+	})
+}
+
+// GET /me/export
+func (h *Handler) ListExports(c *gin.Context) {
+	userID, err := primitive.ObjectIDFromHex(c.GetString("userID"))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	jobs, err := h.service.List(c.Request.Context(), userID)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, jobs)
+}
+
+// GET /exports/:id/download?expires=...&sig=...
+func (h *Handler) Download(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidLink.Error()})
+		return
+	}
+
+	job, err := h.service.Open(c.Request.Context(), id, c.Query("expires"), c.Query("sig"))
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.FileAttachment(job.FilePath, "socialnetwork-export-"+job.ID.Hex()+".zip")
+}
+
+func writeError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrInvalidLink):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrLinkExpired):
+		c.JSON(http.StatusGone, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,189 @@
+package export
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"socialnetwork/models"
+)
+
+// Reaction - một lượt thích/không thích của user
+type Reaction struct {
+	ContentType string             `json:"contentType"` // post | comment
+	ContentID   primitive.ObjectID `json:"contentId"`
+	Type        string             `json:"type"` // like | dislike
+}
+
+type Repository interface {
+	CreateJob(ctx context.Context, job *models.ExportJob) error
+	GetJob(ctx context.Context, id primitive.ObjectID) (*models.ExportJob, error)
+	UpdateJob(ctx context.Context, id primitive.ObjectID, fields bson.M) error
+	FindActiveJob(ctx context.Context, userID primitive.ObjectID) (*models.ExportJob, error)
+	ListJobs(ctx context.Context, userID primitive.ObjectID, limit int64) ([]models.ExportJob, error)
+	FindExpiredJobs(ctx context.Context, now time.Time) ([]models.ExportJob, error)
+
+	Posts(ctx context.Context, userID primitive.ObjectID) ([]models.Post, error)
+	Comments(ctx context.Context, userID primitive.ObjectID) ([]models.Comment, error)
+	Reactions(ctx context.Context, userID primitive.ObjectID) ([]Reaction, error)
+	Followers(ctx context.Context, userID primitive.ObjectID) ([]models.Follow, error)
+	Following(ctx context.Context, userID primitive.ObjectID) ([]models.Follow, error)
+	Notifications(ctx context.Context, userID primitive.ObjectID) ([]models.Notification, error)
+	Videos(ctx context.Context, userID primitive.ObjectID) ([]models.Video, error)
+	Shorts(ctx context.Context, userID primitive.ObjectID) ([]models.Short, error)
+}
+
+type repository struct {
+	db   *mongo.Database
+	jobs *mongo.Collection
+}
+
+func NewRepository(db *mongo.Database) Repository {
+	return &repository{db: db, jobs: db.Collection("export_jobs")}
+}
+
+func (r *repository) CreateJob(ctx context.Context, job *models.ExportJob) error {
+	job.ID = primitive.NewObjectID()
+	job.CreatedAt = time.Now()
+	_, err := r.jobs.InsertOne(ctx, job)
+	return err
+}
+
+func (r *repository) GetJob(ctx context.Context, id primitive.ObjectID) (*models.ExportJob, error) {
+	var job models.ExportJob
+	if err := r.jobs.FindOne(ctx, bson.M{"_id": id}).Decode(&job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (r *repository) UpdateJob(ctx context.Context, id primitive.ObjectID, fields bson.M) error {
+	_, err := r.jobs.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": fields})
+	return err
+}
+
+// FindActiveJob - job đang chờ hoặc đang xử lý của user (nil nếu không có)
+func (r *repository) FindActiveJob(ctx context.Context, userID primitive.ObjectID) (*models.ExportJob, error) {
+	var job models.ExportJob
+	err := r.jobs.FindOne(ctx, bson.M{
+		"userId": userID,
+		"status": bson.M{"$in": bson.A{models.ExportPending, models.ExportProcessing}},
+	}).Decode(&job)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (r *repository) ListJobs(ctx context.Context, userID primitive.ObjectID, limit int64) ([]models.ExportJob, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}}).SetLimit(limit)
+	cursor, err := r.jobs.Find(ctx, bson.M{"userId": userID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	jobs := []models.ExportJob{}
+	err = cursor.All(ctx, &jobs)
+	return jobs, err
+}
+
+func (r *repository) FindExpiredJobs(ctx context.Context, now time.Time) ([]models.ExportJob, error) {
+	cursor, err := r.jobs.Find(ctx, bson.M{"status": models.ExportReady, "expiresAt": bson.M{"$lte": now}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var jobs []models.ExportJob
+	err = cursor.All(ctx, &jobs)
+	return jobs, err
+}
+
+// findAll decode toàn bộ document khớp filter vào out (con trỏ tới slice)
+func (r *repository) findAll(ctx context.Context, collection string, filter bson.M, out interface{}) error {
+	cursor, err := r.db.Collection(collection).Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+	return cursor.All(ctx, out)
+}
+
+// Posts - mọi post của user, kể cả đang trong thùng rác
+func (r *repository) Posts(ctx context.Context, userID primitive.ObjectID) ([]models.Post, error) {
+	posts := []models.Post{}
+	err := r.findAll(ctx, "posts", bson.M{"user_id": userID}, &posts)
+	return posts, err
+}
+
+func (r *repository) Comments(ctx context.Context, userID primitive.ObjectID) ([]models.Comment, error) {
+	comments := []models.Comment{}
+	err := r.findAll(ctx, "comments", bson.M{"user_id": userID}, &comments)
+	return comments, err
+}
+
+// Reactions - like/dislike của user trên post và like trên comment
+func (r *repository) Reactions(ctx context.Context, userID primitive.ObjectID) ([]Reaction, error) {
+	reactions := []Reaction{}
+
+	var posts []models.Post
+	if err := r.findAll(ctx, "posts", bson.M{"$or": bson.A{bson.M{"likes": userID}, bson.M{"dislikes": userID}}}, &posts); err != nil {
+		return nil, err
+	}
+	for _, p := range posts {
+		reaction := "like"
+		for _, id := range p.Dislikes {
+			if id == userID {
+				reaction = "dislike"
+				break
+			}
+		}
+		reactions = append(reactions, Reaction{ContentType: "post", ContentID: p.ID, Type: reaction})
+	}
+
+	var comments []models.Comment
+	if err := r.findAll(ctx, "comments", bson.M{"likes": userID}, &comments); err != nil {
+		return nil, err
+	}
+	for _, c := range comments {
+		reactions = append(reactions, Reaction{ContentType: "comment", ContentID: c.ID, Type: "like"})
+	}
+	return reactions, nil
+}
+
+func (r *repository) Followers(ctx context.Context, userID primitive.ObjectID) ([]models.Follow, error) {
+	follows := []models.Follow{}
+	err := r.findAll(ctx, "follows", bson.M{"following": userID}, &follows)
+	return follows, err
+}
+
+func (r *repository) Following(ctx context.Context, userID primitive.ObjectID) ([]models.Follow, error) {
+	follows := []models.Follow{}
+	err := r.findAll(ctx, "follows", bson.M{"follower": userID}, &follows)
+	return follows, err
+}
+
+func (r *repository) Notifications(ctx context.Context, userID primitive.ObjectID) ([]models.Notification, error) {
+	notifications := []models.Notification{}
+	err := r.findAll(ctx, "notifications", bson.M{"recipient": userID}, &notifications)
+	return notifications, err
+}
+
+func (r *repository) Videos(ctx context.Context, userID primitive.ObjectID) ([]models.Video, error) {
+	videos := []models.Video{}
+	err := r.findAll(ctx, "videos", bson.M{"ownerId": userID}, &videos)
+	return videos, err
+}
+
+func (r *repository) Shorts(ctx context.Context, userID primitive.ObjectID) ([]models.Short, error) {
+	shorts := []models.Short{}
+	err := r.findAll(ctx, "shorts", bson.M{"ownerId": userID}, &shorts)
+	return shorts, err
+}
@@ -0,0 +1,157 @@
+package export
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"socialnetwork/internal/user"
+	"socialnetwork/models"
+	"socialnetwork/pkg/email"
+)
+
+const jobQueueKey = "export:jobs"
+
+var (
+	ErrNotFound    = errors.New("không tìm thấy bản xuất dữ liệu")
+	ErrInvalidLink = errors.New("link tải không hợp lệ")
+	ErrLinkExpired = errors.New("link tải đã hết hạn")
+)
+
+type Config struct {
+	Dir          string        // thư mục lưu file ZIP
+	BaseURL      string        // URL gốc của API, dùng để tạo link tải
+	Secret       []byte        // khoá ký link tải
+	LinkTTL      time.Duration // thời hạn của link tải
+	StaleAfter   time.Duration // job xử lý quá lâu (server restart...) được coi là lỗi
+	MediaDir     string        // thư mục media do worker xử lý media tạo ra
+	MediaBaseURL string        // URL public tương ứng với MediaDir
+}
+
+// JobView - trạng thái job trả về cho client, kèm link tải khi đã sẵn sàng
+type JobView struct {
+	models.ExportJob
+	DownloadURL string `json:"downloadUrl,omitempty"`
+}
+
+type Service interface {
+	Request(ctx context.Context, userID primitive.ObjectID) (*models.ExportJob, error)
+	List(ctx context.Context, userID primitive.ObjectID) ([]JobView, error)
+	Open(ctx context.Context, id primitive.ObjectID, expires, signature string) (*models.ExportJob, error)
+	RunWorker(ctx context.Context)
+}
+
+type service struct {
+	repo        Repository
+	userRepo    user.Repository
+	emailSender email.EmailSender
+	redisClient *redis.Client
+	config      Config
+}
+
+func NewService(repo Repository, userRepo user.Repository, emailSender email.EmailSender, redisClient *redis.Client, config Config) Service {
+	if config.Dir == "" {
+		config.Dir = "./exports"
+	}
+	if config.LinkTTL <= 0 {
+		config.LinkTTL = 48 * time.Hour
+	}
+	if config.StaleAfter <= 0 {
+		config.StaleAfter = time.Hour
+	}
+	config.BaseURL = strings.TrimRight(config.BaseURL, "/")
+	config.MediaBaseURL = strings.TrimRight(config.MediaBaseURL, "/")
+	return &service{
+		repo:        repo,
+		userRepo:    userRepo,
+		emailSender: emailSender,
+		redisClient: redisClient,
+		config:      config,
+	}
+}
+
+// Request tạo job xuất dữ liệu; nếu đã có job đang chạy thì trả về job đó
+func (s *service) Request(ctx context.Context, userID primitive.ObjectID) (*models.ExportJob, error) {
+	active, err := s.repo.FindActiveJob(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if active != nil {
+		if time.Since(active.CreatedAt) < s.config.StaleAfter {
+			return active, nil
+		}
+		_ = s.repo.UpdateJob(ctx, active.ID, bson.M{"status": models.ExportFailed, "error": "quá thời gian xử lý"})
+	}
+
+	job := &models.ExportJob{UserID: userID, Status: models.ExportPending}
+	if err := s.repo.CreateJob(ctx, job); err != nil {
+		return nil, err
+	}
+	if err := s.redisClient.LPush(ctx, jobQueueKey, job.ID.Hex()).Err(); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+func (s *service) List(ctx context.Context, userID primitive.ObjectID) ([]JobView, error) {
+	jobs, err := s.repo.ListJobs(ctx, userID, 10)
+	if err != nil {
+		return nil, err
+	}
+	views := make([]JobView, len(jobs))
+	for i, job := range jobs {
+		views[i] = JobView{ExportJob: job}
+		if job.Status == models.ExportReady && job.ExpiresAt != nil && time.Now().Before(*job.ExpiresAt) {
+			views[i].DownloadURL = s.downloadURL(job.ID, *job.ExpiresAt)
+		}
+	}
+	return views, nil
+}
+
+// Open kiểm tra chữ ký và hạn của link tải, trả về job đã sẵn sàng
+func (s *service) Open(ctx context.Context, id primitive.ObjectID, expires, signature string) (*models.ExportJob, error) {
+	exp, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return nil, ErrInvalidLink
+	}
+	expected := s.sign(id, exp)
+	if !hmac.Equal([]byte(signature), []byte(expected)) {
+		return nil, ErrInvalidLink
+	}
+	if time.Now().Unix() > exp {
+		return nil, ErrLinkExpired
+	}
+
+	job, err := s.repo.GetJob(ctx, id)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+	if job.Status == models.ExportExpired {
+		return nil, ErrLinkExpired
+	}
+	if job.Status != models.ExportReady || job.FilePath == "" {
+		return nil, ErrNotFound
+	}
+	return job, nil
+}
+
+// sign = hex(HMAC-SHA256(secret, "<jobID>:<expires>"))
+func (s *service) sign(id primitive.ObjectID, expires int64) string {
+	mac := hmac.New(sha256.New, s.config.Secret)
+	mac.Write([]byte(fmt.Sprintf("%s:%d", id.Hex(), expires)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s *service) downloadURL(id primitive.ObjectID, expiresAt time.Time) string {
+	exp := expiresAt.Unix()
+	return fmt.Sprintf("%s/exports/%s/download?expires=%d&sig=%s", s.config.BaseURL, id.Hex(), exp, s.sign(id, exp))
+}
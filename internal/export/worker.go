@@ -0,0 +1,126 @@
+package export
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"socialnetwork/models"
+)
+
+// RunWorker xử lý job xuất dữ liệu trong hàng đợi và dọn file có link đã hết hạn
+func (s *service) RunWorker(ctx context.Context) {
+	lastCleanup := time.Time{}
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+
+		if time.Since(lastCleanup) >= time.Hour {
+			if err := s.cleanupExpired(ctx); err != nil {
+				log.Printf("⚠️ dọn file xuất dữ liệu thất bại: %v", err)
+			}
+			lastCleanup = time.Now()
+		}
+
+		res, err := s.redisClient.BRPop(ctx, 5*time.Second, jobQueueKey).Result()
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("⚠️ export queue: %v", err)
+				time.Sleep(time.Second)
+			}
+			continue
+		}
+
+		// res[0] là key, res[1] là ID job
+		id, err := primitive.ObjectIDFromHex(res[1])
+		if err != nil {
+			log.Printf("⚠️ export job có ID không hợp lệ: %s", res[1])
+			continue
+		}
+		if err := s.process(ctx, id); err != nil {
+			log.Printf("❌ xuất dữ liệu %s thất bại: %v", id.Hex(), err)
+			_ = s.repo.UpdateJob(ctx, id, bson.M{"status": models.ExportFailed, "error": err.Error()})
+		}
+	}
+}
+
+func (s *service) process(ctx context.Context, id primitive.ObjectID) error {
+	job, err := s.repo.GetJob(ctx, id)
+	if err != nil {
+		return err
+	}
+	if job.Status != models.ExportPending {
+		return nil
+	}
+	if err := s.repo.UpdateJob(ctx, id, bson.M{"status": models.ExportProcessing}); err != nil {
+		return err
+	}
+
+	u, err := s.userRepo.GetByID(ctx, job.UserID)
+	if err != nil {
+		return fmt.Errorf("không tìm thấy người dùng: %w", err)
+	}
+
+	if err := os.MkdirAll(s.config.Dir, 0o750); err != nil {
+		return err
+	}
+	path := filepath.Join(s.config.Dir, id.Hex()+".zip")
+	size, err := s.buildArchive(ctx, u, path)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(s.config.LinkTTL)
+	err = s.repo.UpdateJob(ctx, id, bson.M{
+		"status":      models.ExportReady,
+		"filePath":    path,
+		"size":        size,
+		"completedAt": now,
+		"expiresAt":   expiresAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	// Người dùng chỉ có số điện thoại vẫn lấy được link qua GET /me/export
+	if u.Email != "" {
+		body := fmt.Sprintf(
+			"Bản sao dữ liệu của bạn đã sẵn sàng. Tải xuống tại:\n%s\n\nLink có hiệu lực đến %s.",
+			s.downloadURL(id, expiresAt), expiresAt.Format("02/01/2006 15:04"),
+		)
+		if err := s.emailSender.Send(u.Email, "Dữ liệu của bạn đã sẵn sàng để tải xuống", body); err != nil {
+			log.Printf("⚠️ không gửi được email xuất dữ liệu %s: %v", id.Hex(), err)
+		}
+	}
+	return nil
+}
+
+// cleanupExpired xoá file ZIP của các link đã hết hạn
+func (s *service) cleanupExpired(ctx context.Context) error {
+	jobs, err := s.repo.FindExpiredJobs(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+	for _, job := range jobs {
+		if job.FilePath != "" {
+			if err := os.Remove(job.FilePath); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+		if err := s.repo.UpdateJob(ctx, job.ID, bson.M{"status": models.ExportExpired, "filePath": ""}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
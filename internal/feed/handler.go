@@ -0,0 +1,41 @@
+package feed
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type Handler struct {
+	service Service
+}
+
+func NewHandler(service Service) *Handler {
+	return &Handler{service: service}
+}
+
+// GET /video_short/shorts/feed?cursor=...&limit=10
+func (h *Handler) GetShortsFeed(c *gin.Context) {
+	userID, err := primitive.ObjectIDFromHex(c.GetString("userID"))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+
+	page, err := h.service.GetShortsFeed(c.Request.Context(), userID, c.Query("cursor"), limit)
+	if err != nil {
+		if errors.Is(err, ErrInvalidCursor) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get shorts feed"})
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
@@ -0,0 +1,86 @@
+package feed
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"socialnetwork/models"
+)
+
+// Weights - trọng số của hàm xếp hạng
+type Weights struct {
+	Recency    float64
+	Views      float64
+	Likes      float64
+	Dislikes   float64
+	Completion float64
+	Affinity   float64
+	// RecencyHalfLife - sau khoảng này điểm mới giảm còn một nửa
+	RecencyHalfLife time.Duration
+}
+
+var DefaultWeights = Weights{
+	Recency:         2.0,
+	Views:           0.3,
+	Likes:           0.6,
+	Dislikes:        0.4,
+	Completion:      1.5,
+	Affinity:        1.0,
+	RecencyHalfLife: 48 * time.Hour,
+}
+
+// defaultCompletion - short chưa có lượt xem dùng tỉ lệ xem hết trung bình giả định
+const defaultCompletion = 0.5
+
+type scoredShort struct {
+	short models.Short
+	score float64
+}
+
+// Score tính điểm một short tại thời điểm asOf; affinity là mức độ thân thiết
+// của người xem với creator (0 = không quen, 1 = follow, 2 = follow + bạn bè)
+func Score(sh *models.Short, asOf time.Time, affinity float64, w Weights) float64 {
+	age := asOf.Sub(sh.CreatedAt)
+	if age < 0 {
+		age = 0
+	}
+	recency := math.Exp2(-float64(age) / float64(w.RecencyHalfLife))
+
+	completion := defaultCompletion
+	if sh.Views > 0 {
+		completion = math.Min(sh.CompletionTotal/float64(sh.Views), 1)
+	}
+
+	return w.Recency*recency +
+		w.Views*math.Log1p(float64(sh.Views)) +
+		w.Likes*math.Log1p(float64(sh.Likes)) -
+		w.Dislikes*math.Log1p(float64(sh.Dislikes)) +
+		w.Completion*completion +
+		w.Affinity*affinity
+}
+
+// Rank sắp xếp theo điểm giảm dần, hoà điểm thì theo ID giảm dần để thứ tự ổn định
+func Rank(shorts []models.Short, asOf time.Time, affinity map[primitive.ObjectID]float64, w Weights) []models.Short {
+	scored := make([]scoredShort, len(shorts))
+	for i := range shorts {
+		scored[i] = scoredShort{
+			short: shorts[i],
+			score: Score(&shorts[i], asOf, affinity[shorts[i].OwnerID], w),
+		}
+	}
+
+	sort.SliceStable(scored, func(i, j int) bool {
+		if scored[i].score != scored[j].score {
+			return scored[i].score > scored[j].score
+		}
+		return scored[i].short.ID.Hex() > scored[j].short.ID.Hex()
+	})
+
+	ranked := make([]models.Short, len(scored))
+	for i := range scored {
+		ranked[i] = scored[i].short
+	}
+	return ranked
+}
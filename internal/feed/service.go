@@ -0,0 +1,292 @@
+package feed
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"socialnetwork/internal/follow"
+	"socialnetwork/internal/short"
+	"socialnetwork/internal/user"
+	"socialnetwork/internal/views"
+	"socialnetwork/models"
+)
+
+var ErrInvalidCursor = errors.New("cursor không hợp lệ")
+
+type Config struct {
+	Weights        Weights
+	CandidateLimit int64         // số short tối đa đem đi xếp hạng mỗi lần
+	CandidateAge   time.Duration // chỉ lấy short trong khoảng thời gian này
+	SessionTTL     time.Duration // thời gian giữ snapshot xếp hạng để phân trang
+	ServedTTL      time.Duration // short đã trả về sẽ không lặp lại trong khoảng này
+}
+
+// ShortsPage - một trang feed, NextCursor dùng để lấy trang tiếp theo
+type ShortsPage struct {
+	Items      []models.Short `json:"items"`
+	NextCursor string         `json:"nextCursor,omitempty"`
+}
+
+type Service interface {
+	GetShortsFeed(ctx context.Context, userID primitive.ObjectID, cursor string, limit int) (*ShortsPage, error)
+}
+
+type service struct {
+	shortRepo   short.ShortRepository
+	followRepo  follow.FollowRepository
+	userRepo    user.Repository
+	viewService views.Service
+	redisClient *redis.Client
+	config      Config
+}
+
+func NewService(
+	shortRepo short.ShortRepository,
+	followRepo follow.FollowRepository,
+	userRepo user.Repository,
+	viewService views.Service,
+	redisClient *redis.Client,
+	config Config,
+) Service {
+	if config.Weights == (Weights{}) {
+		config.Weights = DefaultWeights
+	}
+	if config.CandidateLimit <= 0 {
+		config.CandidateLimit = 500
+	}
+	if config.CandidateAge <= 0 {
+		config.CandidateAge = 30 * 24 * time.Hour
+	}
+	if config.SessionTTL <= 0 {
+		config.SessionTTL = time.Hour
+	}
+	if config.ServedTTL <= 0 {
+		config.ServedTTL = 24 * time.Hour
+	}
+	return &service{
+		shortRepo:   shortRepo,
+		followRepo:  followRepo,
+		userRepo:    userRepo,
+		viewService: viewService,
+		redisClient: redisClient,
+		config:      config,
+	}
+}
+
+func sessionKey(userID primitive.ObjectID, sessionID string) string {
+	return fmt.Sprintf("feed:shorts:session:%s:%s", userID.Hex(), sessionID)
+}
+
+func servedKey(userID primitive.ObjectID) string {
+	return "feed:shorts:served:" + userID.Hex()
+}
+
+// Cursor = base64(sessionID:offset). Thứ tự được chốt trong snapshot Redis nên
+// lượt xem/like thay đổi giữa các trang không làm lặp hoặc sót short
+func encodeCursor(sessionID string, offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(sessionID + ":" + strconv.Itoa(offset)))
+}
+
+func decodeCursor(cursor string) (string, int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return "", 0, ErrInvalidCursor
+	}
+	parts := strings.SplitN(string(raw), ":", 2)
+	if len(parts) != 2 {
+		return "", 0, ErrInvalidCursor
+	}
+	offset, err := strconv.Atoi(parts[1])
+	if err != nil || offset < 0 {
+		return "", 0, ErrInvalidCursor
+	}
+	return parts[0], offset, nil
+}
+
+func (s *service) GetShortsFeed(ctx context.Context, userID primitive.ObjectID, cursor string, limit int) (*ShortsPage, error) {
+	if limit <= 0 || limit > 50 {
+		limit = 10
+	}
+
+	sessionID, offset := "", 0
+	if cursor != "" {
+		var err error
+		sessionID, offset, err = decodeCursor(cursor)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var ids []string
+	if sessionID != "" {
+		var err error
+		ids, err = s.redisClient.LRange(ctx, sessionKey(userID, sessionID), int64(offset), int64(offset+limit-1)).Result()
+		if err != nil && err != redis.Nil {
+			return nil, err
+		}
+	}
+
+	// Snapshot hết hạn hoặc đã cuộn hết -> xếp hạng lại (loại short đã xem/đã trả về)
+	if len(ids) == 0 {
+		var err error
+		sessionID, err = s.buildSession(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		offset = 0
+		ids, err = s.redisClient.LRange(ctx, sessionKey(userID, sessionID), 0, int64(limit-1)).Result()
+		if err != nil && err != redis.Nil {
+			return nil, err
+		}
+	}
+
+	page := &ShortsPage{Items: []models.Short{}}
+	if len(ids) == 0 {
+		return page, nil
+	}
+
+	items, err := s.loadInOrder(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	page.Items = items
+	page.NextCursor = encodeCursor(sessionID, offset+len(ids))
+
+	pipe := s.redisClient.Pipeline()
+	for _, id := range ids {
+		pipe.SAdd(ctx, servedKey(userID), id)
+	}
+	pipe.Expire(ctx, servedKey(userID), s.config.ServedTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	return page, nil
+}
+
+// buildSession xếp hạng các short ứng viên và lưu thứ tự vào Redis
+func (s *service) buildSession(ctx context.Context, userID primitive.ObjectID) (string, error) {
+	viewer, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+
+	// Loại short của chính mình, của người đã bị chặn và của người đã chặn mình
+	blockers, err := s.userRepo.FindBlockerIDs(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	excludeOwners := append([]primitive.ObjectID{userID}, viewer.BlockedUsers...)
+	excludeOwners = append(excludeOwners, blockers...)
+
+	asOf := time.Now()
+	candidates, err := s.shortRepo.FindFeedCandidates(ctx, asOf.Add(-s.config.CandidateAge), asOf, excludeOwners, s.config.CandidateLimit)
+	if err != nil {
+		return "", err
+	}
+
+	seen, err := s.viewService.HasSeen(ctx, views.ContentShort, userID)
+	if err != nil {
+		return "", err
+	}
+	served, err := s.redisClient.SMembers(ctx, servedKey(userID)).Result()
+	if err != nil && err != redis.Nil {
+		return "", err
+	}
+	for _, id := range served {
+		seen[id] = true
+	}
+
+	fresh := candidates[:0]
+	for _, sh := range candidates {
+		if !seen[sh.ID.Hex()] {
+			fresh = append(fresh, sh)
+		}
+	}
+
+	affinity, err := s.creatorAffinity(ctx, viewer)
+	if err != nil {
+		return "", err
+	}
+
+	ranked := Rank(fresh, asOf, affinity, s.config.Weights)
+
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	sessionID := hex.EncodeToString(buf)
+
+	if len(ranked) == 0 {
+		return sessionID, nil
+	}
+
+	ids := make([]interface{}, len(ranked))
+	for i := range ranked {
+		ids[i] = ranked[i].ID.Hex()
+	}
+	key := sessionKey(userID, sessionID)
+	pipe := s.redisClient.TxPipeline()
+	pipe.RPush(ctx, key, ids...)
+	pipe.Expire(ctx, key, s.config.SessionTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return "", err
+	}
+	return sessionID, nil
+}
+
+// creatorAffinity: +1 nếu đang follow creator, +1 nếu là bạn bè
+func (s *service) creatorAffinity(ctx context.Context, viewer *models.User) (map[primitive.ObjectID]float64, error) {
+	affinity := make(map[primitive.ObjectID]float64)
+
+	following, err := s.followRepo.GetFollowing(ctx, viewer.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range following {
+		affinity[f.Following]++
+	}
+	for _, friendID := range viewer.Friends {
+		affinity[friendID]++
+	}
+	return affinity, nil
+}
+
+// loadInOrder lấy short theo đúng thứ tự trong snapshot, bỏ qua short đã bị
+// xoá hoặc chuyển private sau khi snapshot được tạo
+func (s *service) loadInOrder(ctx context.Context, ids []string) ([]models.Short, error) {
+	objIDs := make([]primitive.ObjectID, 0, len(ids))
+	for _, id := range ids {
+		if oid, err := primitive.ObjectIDFromHex(id); err == nil {
+			objIDs = append(objIDs, oid)
+		}
+	}
+
+	shorts, err := s.shortRepo.GetByIDs(ctx, objIDs)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[primitive.ObjectID]models.Short, len(shorts))
+	for _, sh := range shorts {
+		byID[sh.ID] = sh
+	}
+
+	items := make([]models.Short, 0, len(objIDs))
+	for _, id := range objIDs {
+		sh, ok := byID[id]
+		if !ok || sh.Visibility != "public" || !models.IsProcessed(sh.Status) {
+			continue
+		}
+		items = append(items, sh)
+	}
+	return items, nil
+}
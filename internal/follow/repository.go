@@ -18,6 +18,7 @@ type FollowRepository interface {
 	GetFollowing(ctx context.Context, userID primitive.ObjectID) ([]models.Follow, error)
 	CountFollowers(ctx context.Context, userID primitive.ObjectID) (int, error)
 	CountFollowing(ctx context.Context, userID primitive.ObjectID) (int, error)
+	DeleteAllByUser(ctx context.Context, userID primitive.ObjectID) ([]models.Follow, error)
 }
 
 type followRepository struct {
@@ -90,3 +91,25 @@ func (r *followRepository) CountFollowing(ctx context.Context, userID primitive.
 	count, err := r.collection.CountDocuments(ctx, bson.M{"follower": userID})
 	return int(count), err
 }
+
+// DeleteAllByUser xoá mọi quan hệ follow có user tham gia, trả về các quan hệ đã xoá
+// để cập nhật lại bộ đếm follower/following
+func (r *followRepository) DeleteAllByUser(ctx context.Context, userID primitive.ObjectID) ([]models.Follow, error) {
+	filter := bson.M{"$or": bson.A{bson.M{"follower": userID}, bson.M{"following": userID}}}
+	cursor, err := r.collection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var follows []models.Follow
+	if err := cursor.All(ctx, &follows); err != nil {
+		return nil, err
+	}
+	if len(follows) == 0 {
+		return follows, nil
+	}
+
+	_, err = r.collection.DeleteMany(ctx, filter)
+	return follows, err
+}
@@ -0,0 +1,545 @@
+package groups
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"socialnetwork/dto/request"
+	"socialnetwork/internal/contentfilter"
+	"socialnetwork/models"
+)
+
+type Handler struct {
+	service Service
+}
+
+func NewHandler(service Service) *Handler {
+	return &Handler{service: service}
+}
+
+func currentUser(c *gin.Context) (primitive.ObjectID, bool) {
+	userID, err := primitive.ObjectIDFromHex(c.GetString("userID"))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return primitive.NilObjectID, false
+	}
+	return userID, true
+}
+
+func paramID(c *gin.Context, name string) (primitive.ObjectID, bool) {
+	id, err := primitive.ObjectIDFromHex(c.Param(name))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "id không hợp lệ"})
+		return primitive.NilObjectID, false
+	}
+	return id, true
+}
+
+// userAndGroup đọc user hiện tại và :id của nhóm
+func userAndGroup(c *gin.Context) (primitive.ObjectID, primitive.ObjectID, bool) {
+	userID, ok := currentUser(c)
+	if !ok {
+		return userID, primitive.NilObjectID, false
+	}
+	groupID, ok := paramID(c, "id")
+	return userID, groupID, ok
+}
+
+// cursor đọc ?before=<id>&limit= cho các danh sách phân trang theo _id
+func cursor(c *gin.Context) (primitive.ObjectID, int, bool) {
+	var before primitive.ObjectID
+	if v := c.Query("before"); v != "" {
+		var err error
+		if before, err = primitive.ObjectIDFromHex(v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "before không hợp lệ"})
+			return before, 0, false
+		}
+	}
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	return before, limit, true
+}
+
+// page trả danh sách kèm nextCursor khi còn trang sau
+func page(c *gin.Context, key string, items interface{}, n, limit int, last primitive.ObjectID) {
+	resp := gin.H{key: items}
+	if n > 0 && int64(n) == clampLimit(limit) {
+		resp["nextCursor"] = last.Hex()
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// POST /groups
+func (h *Handler) Create(c *gin.Context) {
+	userID, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	var req request.CreateGroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	group, err := h.service.Create(c.Request.Context(), userID, req)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, group)
+}
+
+// GET /groups?q=&page=&limit=
+func (h *Handler) Discover(c *gin.Context) {
+	p, _ := strconv.Atoi(c.Query("page"))
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	groups, err := h.service.Discover(c.Request.Context(), c.Query("q"), p, limit)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"groups": groups})
+}
+
+// GET /groups/mine?status=active|invited|pending
+func (h *Handler) Mine(c *gin.Context) {
+	userID, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	groups, err := h.service.Mine(c.Request.Context(), userID, models.GroupMemberStatus(c.Query("status")))
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"groups": groups})
+}
+
+// GET /groups/:id
+func (h *Handler) Get(c *gin.Context) {
+	userID, groupID, ok := userAndGroup(c)
+	if !ok {
+		return
+	}
+	group, err := h.service.Get(c.Request.Context(), userID, groupID)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, group)
+}
+
+// PUT /groups/:id
+func (h *Handler) Update(c *gin.Context) {
+	userID, groupID, ok := userAndGroup(c)
+	if !ok {
+		return
+	}
+	var req request.UpdateGroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	group, err := h.service.Update(c.Request.Context(), userID, groupID, req)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, group)
+}
+
+// PUT /groups/:id/rules
+func (h *Handler) UpdateRules(c *gin.Context) {
+	userID, groupID, ok := userAndGroup(c)
+	if !ok {
+		return
+	}
+	var req request.UpdateGroupRulesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	group, err := h.service.UpdateRules(c.Request.Context(), userID, groupID, req)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, group)
+}
+
+// DELETE /groups/:id
+func (h *Handler) Delete(c *gin.Context) {
+	userID, groupID, ok := userAndGroup(c)
+	if !ok {
+		return
+	}
+	if err := h.service.Delete(c.Request.Context(), userID, groupID); err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Đã xoá nhóm"})
+}
+
+// POST /groups/:id/join
+func (h *Handler) Join(c *gin.Context) {
+	userID, groupID, ok := userAndGroup(c)
+	if !ok {
+		return
+	}
+	member, err := h.service.Join(c.Request.Context(), userID, groupID)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"membership": member})
+}
+
+// POST /groups/:id/leave
+func (h *Handler) Leave(c *gin.Context) {
+	userID, groupID, ok := userAndGroup(c)
+	if !ok {
+		return
+	}
+	if err := h.service.Leave(c.Request.Context(), userID, groupID); err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Đã rời nhóm"})
+}
+
+// POST /groups/:id/invitations
+func (h *Handler) Invite(c *gin.Context) {
+	userID, groupID, ok := userAndGroup(c)
+	if !ok {
+		return
+	}
+	var req request.GroupInviteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.service.Invite(c.Request.Context(), userID, groupID, req); err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Đã gửi lời mời"})
+}
+
+// POST /groups/:id/invitation/decline
+func (h *Handler) DeclineInvite(c *gin.Context) {
+	userID, groupID, ok := userAndGroup(c)
+	if !ok {
+		return
+	}
+	if err := h.service.DeclineInvite(c.Request.Context(), userID, groupID); err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Đã từ chối lời mời"})
+}
+
+// GET /groups/:id/requests?before=&limit=
+func (h *Handler) Requests(c *gin.Context) {
+	userID, groupID, ok := userAndGroup(c)
+	if !ok {
+		return
+	}
+	before, limit, ok := cursor(c)
+	if !ok {
+		return
+	}
+	members, err := h.service.Requests(c.Request.Context(), userID, groupID, before, limit)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	var last primitive.ObjectID
+	if n := len(members); n > 0 {
+		last = members[n-1].ID
+	}
+	page(c, "requests", members, len(members), limit, last)
+}
+
+// POST /groups/:id/requests/:userId/approve
+func (h *Handler) ApproveRequest(c *gin.Context) {
+	h.targetAction(c, h.service.ApproveRequest, "Đã duyệt yêu cầu tham gia")
+}
+
+// POST /groups/:id/requests/:userId/decline
+func (h *Handler) DeclineRequest(c *gin.Context) {
+	h.targetAction(c, h.service.DeclineRequest, "Đã từ chối yêu cầu tham gia")
+}
+
+// POST /groups/:id/members/:userId/unban
+func (h *Handler) Unban(c *gin.Context) {
+	h.targetAction(c, h.service.Unban, "Đã gỡ lệnh cấm")
+}
+
+// targetAction - các thao tác quản lý nhóm nhắm vào một người (:userId) không cần body
+func (h *Handler) targetAction(c *gin.Context, action func(ctx context.Context, userID, groupID, targetID primitive.ObjectID) error, message string) {
+	userID, groupID, ok := userAndGroup(c)
+	if !ok {
+		return
+	}
+	targetID, ok := paramID(c, "userId")
+	if !ok {
+		return
+	}
+	if err := action(c.Request.Context(), userID, groupID, targetID); err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": message})
+}
+
+// GET /groups/:id/members?role=&before=&limit=
+func (h *Handler) Members(c *gin.Context) {
+	userID, groupID, ok := userAndGroup(c)
+	if !ok {
+		return
+	}
+	before, limit, ok := cursor(c)
+	if !ok {
+		return
+	}
+	members, err := h.service.Members(c.Request.Context(), userID, groupID, c.Query("role"), before, limit)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	var last primitive.ObjectID
+	if n := len(members); n > 0 {
+		last = members[n-1].ID
+	}
+	page(c, "members", members, len(members), limit, last)
+}
+
+// PUT /groups/:id/members/:userId/role
+func (h *Handler) SetRole(c *gin.Context) {
+	userID, groupID, ok := userAndGroup(c)
+	if !ok {
+		return
+	}
+	targetID, ok := paramID(c, "userId")
+	if !ok {
+		return
+	}
+	var req request.GroupRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.service.SetRole(c.Request.Context(), userID, groupID, targetID, req); err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Đã cập nhật vai trò"})
+}
+
+// POST /groups/:id/members/:userId/remove
+func (h *Handler) RemoveMember(c *gin.Context) {
+	h.removeMember(c, false, "Đã mời thành viên ra khỏi nhóm")
+}
+
+// POST /groups/:id/members/:userId/ban
+func (h *Handler) Ban(c *gin.Context) {
+	h.removeMember(c, true, "Đã cấm thành viên")
+}
+
+func (h *Handler) removeMember(c *gin.Context, ban bool, message string) {
+	userID, groupID, ok := userAndGroup(c)
+	if !ok {
+		return
+	}
+	targetID, ok := paramID(c, "userId")
+	if !ok {
+		return
+	}
+	var req request.GroupModerationRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if err := h.service.RemoveMember(c.Request.Context(), userID, groupID, targetID, ban, req.Reason); err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": message})
+}
+
+// POST /groups/:id/members/:userId/mute
+func (h *Handler) Mute(c *gin.Context) {
+	userID, groupID, ok := userAndGroup(c)
+	if !ok {
+		return
+	}
+	targetID, ok := paramID(c, "userId")
+	if !ok {
+		return
+	}
+	var req request.GroupMuteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.service.Mute(c.Request.Context(), userID, groupID, targetID, req); err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Đã tạm cấm đăng bài"})
+}
+
+// PUT /groups/:id/notifications
+func (h *Handler) SetNotifications(c *gin.Context) {
+	userID, groupID, ok := userAndGroup(c)
+	if !ok {
+		return
+	}
+	var req request.GroupNotificationsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.service.SetNotifications(c.Request.Context(), userID, groupID, req); err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Đã cập nhật thông báo nhóm"})
+}
+
+// GET /groups/:id/moderation-log?before=&limit=
+func (h *Handler) ModerationLog(c *gin.Context) {
+	userID, groupID, ok := userAndGroup(c)
+	if !ok {
+		return
+	}
+	before, limit, ok := cursor(c)
+	if !ok {
+		return
+	}
+	entries, err := h.service.ModerationLog(c.Request.Context(), userID, groupID, before, limit)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	var last primitive.ObjectID
+	if n := len(entries); n > 0 {
+		last = entries[n-1].ID
+	}
+	page(c, "entries", entries, len(entries), limit, last)
+}
+
+// POST /groups/:id/posts
+func (h *Handler) CreatePost(c *gin.Context) {
+	userID, groupID, ok := userAndGroup(c)
+	if !ok {
+		return
+	}
+	var req request.GroupPostRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	post, err := h.service.CreatePost(c.Request.Context(), userID, groupID, req)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, post)
+}
+
+// GET /groups/:id/posts?before=&limit=
+func (h *Handler) Posts(c *gin.Context) {
+	h.listPosts(c, h.service.Posts)
+}
+
+// GET /groups/:id/posts/pending?before=&limit=
+func (h *Handler) PendingPosts(c *gin.Context) {
+	h.listPosts(c, h.service.PendingPosts)
+}
+
+func (h *Handler) listPosts(c *gin.Context, list func(ctx context.Context, userID, groupID, before primitive.ObjectID, limit int) ([]models.Post, error)) {
+	userID, groupID, ok := userAndGroup(c)
+	if !ok {
+		return
+	}
+	before, limit, ok := cursor(c)
+	if !ok {
+		return
+	}
+	posts, err := list(c.Request.Context(), userID, groupID, before, limit)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	var last primitive.ObjectID
+	if n := len(posts); n > 0 {
+		last = posts[n-1].ID
+	}
+	page(c, "posts", posts, len(posts), limit, last)
+}
+
+// POST /groups/:id/posts/:postId/approve
+func (h *Handler) ApprovePost(c *gin.Context) {
+	userID, groupID, ok := userAndGroup(c)
+	if !ok {
+		return
+	}
+	postID, ok := paramID(c, "postId")
+	if !ok {
+		return
+	}
+	if err := h.service.ApprovePost(c.Request.Context(), userID, groupID, postID); err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Đã duyệt bài viết"})
+}
+
+// POST /groups/:id/posts/:postId/decline và DELETE /groups/:id/posts/:postId
+func (h *Handler) RemovePost(c *gin.Context) {
+	userID, groupID, ok := userAndGroup(c)
+	if !ok {
+		return
+	}
+	postID, ok := paramID(c, "postId")
+	if !ok {
+		return
+	}
+	var req request.GroupModerationRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if err := h.service.RemovePost(c.Request.Context(), userID, groupID, postID, req.Reason); err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Đã gỡ bài viết"})
+}
+
+func writeError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, contentfilter.ErrRejected):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrOwnerCannotLeave), errors.Is(err, ErrNoPendingRequest), errors.Is(err, ErrNoInvitation),
+		errors.Is(err, ErrInvalidFilter):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrNotAllowed), errors.Is(err, ErrNotMember), errors.Is(err, ErrBanned),
+		errors.Is(err, ErrBlocked), errors.Is(err, ErrMuted):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrAlreadyMember), errors.Is(err, ErrAlreadyRequested):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrGroupNotFound), errors.Is(err, ErrUserNotFound), errors.Is(err, ErrMemberNotFound),
+		errors.Is(err, ErrPostNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,210 @@
+package groups
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"socialnetwork/dto/request"
+	"socialnetwork/models"
+)
+
+// record ghi nhật ký kiểm duyệt; lỗi chỉ log lại, không chặn thao tác đã thực hiện
+func (s *service) record(ctx context.Context, entry *models.GroupModerationEntry) {
+	if err := s.repo.InsertLog(ctx, entry); err != nil {
+		log.Printf("⚠️ không ghi được nhật ký kiểm duyệt nhóm %s: %v", entry.GroupID.Hex(), err)
+	}
+}
+
+// target lấy thành viên bị xử lý; người xử lý phải có bậc cao hơn người bị xử lý
+func (s *service) target(ctx context.Context, actor *models.GroupMember, groupID, targetID primitive.ObjectID) (*models.GroupMember, error) {
+	if actor.UserID == targetID {
+		return nil, ErrNotAllowed
+	}
+	target, err := s.repo.FindMember(ctx, groupID, targetID)
+	if err != nil {
+		return nil, err
+	}
+	if target == nil {
+		return nil, ErrMemberNotFound
+	}
+	if rank(actor.Role) <= rank(target.Role) {
+		return nil, ErrNotAllowed
+	}
+	return target, nil
+}
+
+// SetRole - chủ nhóm bổ nhiệm quản trị viên hoặc chuyển quyền sở hữu (chủ cũ thành quản trị viên);
+// quản trị viên chỉ bổ nhiệm/gỡ kiểm duyệt viên
+func (s *service) SetRole(ctx context.Context, userID, groupID, targetID primitive.ObjectID, req request.GroupRoleRequest) error {
+	group, actor, err := s.staff(ctx, userID, groupID, models.GroupRoleAdmin)
+	if err != nil {
+		return err
+	}
+	target, err := s.target(ctx, actor, groupID, targetID)
+	if err != nil {
+		return err
+	}
+	if target.Status != models.GroupMemberActive {
+		return ErrMemberNotFound
+	}
+
+	role := models.GroupRole(req.Role)
+	if role == target.Role {
+		return nil
+	}
+	if role == models.GroupRoleOwner {
+		if actor.Role != models.GroupRoleOwner {
+			return ErrNotAllowed
+		}
+		if err := s.repo.UpdateMember(ctx, groupID, targetID, bson.M{"role": models.GroupRoleOwner}); err != nil {
+			return err
+		}
+		if err := s.repo.UpdateMember(ctx, groupID, userID, bson.M{"role": models.GroupRoleAdmin}); err != nil {
+			return err
+		}
+		if err := s.repo.UpdateGroup(ctx, groupID, bson.M{"ownerId": targetID}); err != nil {
+			return err
+		}
+	} else {
+		if rank(actor.Role) <= rank(role) {
+			return ErrNotAllowed
+		}
+		if err := s.repo.UpdateMember(ctx, groupID, targetID, bson.M{"role": role}); err != nil {
+			return err
+		}
+	}
+
+	s.record(ctx, &models.GroupModerationEntry{
+		GroupID:      groupID,
+		ActorID:      userID,
+		Action:       models.GroupActionRoleChanged,
+		TargetUserID: &targetID,
+		Metadata:     map[string]string{"from": string(target.Role), "to": string(role)},
+	})
+	s.notify(ctx, targetID, userID, models.NotificationGroupModeration, group, nil, "Vai trò của bạn trong nhóm "+group.Name+" đã đổi thành "+string(role))
+	return nil
+}
+
+// RemoveMember - mời ra khỏi nhóm (có thể xin vào lại) hoặc cấm hẳn (ban = true).
+// Cấm được cả người chưa tham gia để chặn họ xin vào.
+func (s *service) RemoveMember(ctx context.Context, userID, groupID, targetID primitive.ObjectID, ban bool, reason string) error {
+	group, actor, err := s.staff(ctx, userID, groupID, models.GroupRoleModerator)
+	if err != nil {
+		return err
+	}
+	target, err := s.target(ctx, actor, groupID, targetID)
+	if err != nil && !(ban && errors.Is(err, ErrMemberNotFound)) {
+		return err
+	}
+
+	action := models.GroupActionMemberRemoved
+	switch {
+	case !ban:
+		if target.Status == models.GroupMemberBanned {
+			return ErrMemberNotFound // gỡ cấm dùng Unban
+		}
+		if err := s.repo.DeleteMember(ctx, groupID, targetID); err != nil {
+			return err
+		}
+	case target == nil:
+		action = models.GroupActionMemberBanned
+		if _, err := s.userRepo.GetByID(ctx, targetID); err != nil {
+			return ErrUserNotFound
+		}
+		if err := s.repo.CreateMember(ctx, &models.GroupMember{GroupID: groupID, UserID: targetID, Role: models.GroupRoleMember, Status: models.GroupMemberBanned}); err != nil {
+			return err
+		}
+	default:
+		action = models.GroupActionMemberBanned
+		if target.Status == models.GroupMemberBanned {
+			return nil
+		}
+		if err := s.repo.UpdateMember(ctx, groupID, targetID, bson.M{"status": models.GroupMemberBanned, "role": models.GroupRoleMember}); err != nil {
+			return err
+		}
+	}
+	if target != nil && target.Status == models.GroupMemberActive {
+		if err := s.repo.IncMemberCount(ctx, groupID, -1); err != nil {
+			return err
+		}
+	}
+
+	s.record(ctx, &models.GroupModerationEntry{GroupID: groupID, ActorID: userID, Action: action, TargetUserID: &targetID, Reason: reason})
+	if target != nil && target.Status == models.GroupMemberActive {
+		message := "Bạn đã bị mời ra khỏi nhóm " + group.Name
+		if ban {
+			message = "Bạn đã bị cấm khỏi nhóm " + group.Name
+		}
+		if reason != "" {
+			message += ": " + reason
+		}
+		s.notify(ctx, targetID, userID, models.NotificationGroupModeration, group, nil, message)
+	}
+	return nil
+}
+
+// Unban gỡ lệnh cấm; người dùng phải xin vào lại từ đầu
+func (s *service) Unban(ctx context.Context, userID, groupID, targetID primitive.ObjectID) error {
+	if _, _, err := s.staff(ctx, userID, groupID, models.GroupRoleModerator); err != nil {
+		return err
+	}
+	target, err := s.repo.FindMember(ctx, groupID, targetID)
+	if err != nil {
+		return err
+	}
+	if target == nil || target.Status != models.GroupMemberBanned {
+		return ErrMemberNotFound
+	}
+	if err := s.repo.DeleteMember(ctx, groupID, targetID); err != nil {
+		return err
+	}
+	s.record(ctx, &models.GroupModerationEntry{GroupID: groupID, ActorID: userID, Action: models.GroupActionMemberUnbanned, TargetUserID: &targetID})
+	return nil
+}
+
+// Mute tạm cấm thành viên đăng bài trong nhóm
+func (s *service) Mute(ctx context.Context, userID, groupID, targetID primitive.ObjectID, req request.GroupMuteRequest) error {
+	group, actor, err := s.staff(ctx, userID, groupID, models.GroupRoleModerator)
+	if err != nil {
+		return err
+	}
+	target, err := s.target(ctx, actor, groupID, targetID)
+	if err != nil {
+		return err
+	}
+	if target.Status != models.GroupMemberActive {
+		return ErrMemberNotFound
+	}
+
+	until := time.Now().Add(time.Duration(req.Hours) * time.Hour)
+	if err := s.repo.UpdateMember(ctx, groupID, targetID, bson.M{"mutedUntil": until}); err != nil {
+		return err
+	}
+	s.record(ctx, &models.GroupModerationEntry{
+		GroupID:      groupID,
+		ActorID:      userID,
+		Action:       models.GroupActionMemberMuted,
+		TargetUserID: &targetID,
+		Reason:       req.Reason,
+		Metadata:     map[string]string{"hours": strconv.Itoa(req.Hours)},
+	})
+
+	message := "Bạn bị tạm cấm đăng bài trong nhóm " + group.Name + " tới " + until.Format("02/01/2006 15:04")
+	if req.Reason != "" {
+		message += ": " + req.Reason
+	}
+	s.notify(ctx, targetID, userID, models.NotificationGroupModeration, group, nil, message)
+	return nil
+}
+
+func (s *service) ModerationLog(ctx context.Context, userID, groupID, before primitive.ObjectID, limit int) ([]models.GroupModerationEntry, error) {
+	if _, _, err := s.staff(ctx, userID, groupID, models.GroupRoleModerator); err != nil {
+		return nil, err
+	}
+	return s.repo.ListLog(ctx, groupID, before, clampLimit(limit))
+}
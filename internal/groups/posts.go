@@ -0,0 +1,172 @@
+package groups
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"socialnetwork/dto/request"
+	"socialnetwork/models"
+)
+
+// CreatePost đăng bài vào nhóm qua post service (áp dụng bộ lọc nội dung như bài thường).
+// Nhóm bật duyệt bài thì bài của thành viên thường chờ duyệt.
+func (s *service) CreatePost(ctx context.Context, userID, groupID primitive.ObjectID, req request.GroupPostRequest) (*models.Post, error) {
+	group, member, err := s.load(ctx, userID, groupID)
+	if err != nil {
+		return nil, err
+	}
+	if err := canPost(member); err != nil {
+		return nil, err
+	}
+
+	p := &models.Post{
+		UserID:       userID,
+		Content:      req.Content,
+		ImageURL:     req.ImageURL,
+		Media:        req.Media,
+		Visibility:   models.PostVisibilityGroup,
+		GroupID:      &groupID,
+		GroupPending: group.PostApproval && rank(member.Role) < rank(models.GroupRoleModerator),
+	}
+	created, err := s.postService.CreatePost(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case created.GroupPending:
+		s.notifyStaff(ctx, userID, models.NotificationGroupPostPending, group, &created.ID, "Có bài viết chờ duyệt trong nhóm "+group.Name)
+	case !created.ModerationHidden:
+		go s.fanOut(context.WithoutCancel(ctx), group, created)
+	}
+	return created, nil
+}
+
+// canPost - thành viên chính thức, không bị tạm cấm
+func canPost(member *models.GroupMember) error {
+	if !active(member) {
+		return ErrNotMember
+	}
+	if member.MutedUntil != nil && member.MutedUntil.After(time.Now()) {
+		return ErrMuted
+	}
+	return nil
+}
+
+func (s *service) CanView(ctx context.Context, userID, groupID primitive.ObjectID) error {
+	group, member, err := s.load(ctx, userID, groupID)
+	if err != nil {
+		return err
+	}
+	if !canSeeContent(group, member) {
+		return ErrNotMember
+	}
+	return nil
+}
+
+func (s *service) CanPost(ctx context.Context, userID, groupID primitive.ObjectID) error {
+	_, member, err := s.load(ctx, userID, groupID)
+	if err != nil {
+		return err
+	}
+	return canPost(member)
+}
+
+// fanOut báo bài viết mới cho các thành viên không tắt thông báo nhóm
+func (s *service) fanOut(ctx context.Context, group *models.Group, p *models.Post) {
+	ids, err := s.repo.SubscriberIDs(ctx, group.ID, p.UserID)
+	if err != nil {
+		log.Printf("⚠️ không lấy được thành viên nhóm %s: %v", group.ID.Hex(), err)
+		return
+	}
+	for _, id := range ids {
+		s.notify(ctx, id, p.UserID, models.NotificationGroupPost, group, &p.ID, "Có bài viết mới trong nhóm "+group.Name)
+	}
+}
+
+func (s *service) Posts(ctx context.Context, userID, groupID, before primitive.ObjectID, limit int) ([]models.Post, error) {
+	group, member, err := s.load(ctx, userID, groupID)
+	if err != nil {
+		return nil, err
+	}
+	if !canSeeContent(group, member) {
+		return nil, ErrNotMember
+	}
+	return s.postRepo.ListByGroup(ctx, groupID, false, before, clampLimit(limit))
+}
+
+func (s *service) PendingPosts(ctx context.Context, userID, groupID, before primitive.ObjectID, limit int) ([]models.Post, error) {
+	if _, _, err := s.staff(ctx, userID, groupID, models.GroupRoleModerator); err != nil {
+		return nil, err
+	}
+	return s.postRepo.ListByGroup(ctx, groupID, true, before, clampLimit(limit))
+}
+
+// groupPost lấy bài và kiểm tra bài thuộc đúng nhóm
+func (s *service) groupPost(ctx context.Context, groupID, postID primitive.ObjectID) (*models.Post, error) {
+	p, err := s.postRepo.GetByID(ctx, postID)
+	if err != nil || p.GroupID == nil || *p.GroupID != groupID {
+		return nil, ErrPostNotFound
+	}
+	return p, nil
+}
+
+func (s *service) ApprovePost(ctx context.Context, userID, groupID, postID primitive.ObjectID) error {
+	group, _, err := s.staff(ctx, userID, groupID, models.GroupRoleModerator)
+	if err != nil {
+		return err
+	}
+	p, err := s.groupPost(ctx, groupID, postID)
+	if err != nil {
+		return err
+	}
+	if !p.GroupPending {
+		return ErrPostNotFound
+	}
+	if err := s.postRepo.ApproveGroupPost(ctx, postID); err != nil {
+		return ErrPostNotFound
+	}
+
+	s.record(ctx, &models.GroupModerationEntry{GroupID: groupID, ActorID: userID, Action: models.GroupActionPostApproved, TargetUserID: &p.UserID, TargetPostID: &postID})
+	s.notify(ctx, p.UserID, userID, models.NotificationGroupPostApproved, group, &postID, "Bài viết của bạn trong nhóm "+group.Name+" đã được duyệt")
+	go s.fanOut(context.WithoutCancel(ctx), group, p)
+	return nil
+}
+
+// RemovePost - tác giả tự xoá bài, hoặc kiểm duyệt viên trở lên gỡ bài (từ chối nếu bài đang chờ duyệt)
+func (s *service) RemovePost(ctx context.Context, userID, groupID, postID primitive.ObjectID, reason string) error {
+	group, member, err := s.load(ctx, userID, groupID)
+	if err != nil {
+		return err
+	}
+	p, err := s.groupPost(ctx, groupID, postID)
+	if err != nil {
+		return err
+	}
+
+	author := p.UserID == userID
+	if !author && (!active(member) || rank(member.Role) < rank(models.GroupRoleModerator)) {
+		return ErrNotAllowed
+	}
+	if err := s.postRepo.Delete(ctx, postID); err != nil {
+		return ErrPostNotFound
+	}
+	if author {
+		return nil
+	}
+
+	action := models.GroupActionPostRemoved
+	message := "Bài viết của bạn đã bị gỡ khỏi nhóm " + group.Name
+	if p.GroupPending {
+		action = models.GroupActionPostDeclined
+		message = "Bài viết của bạn trong nhóm " + group.Name + " không được duyệt"
+	}
+	if reason != "" {
+		message += ": " + reason
+	}
+	s.record(ctx, &models.GroupModerationEntry{GroupID: groupID, ActorID: userID, Action: action, TargetUserID: &p.UserID, TargetPostID: &postID, Reason: reason})
+	s.notify(ctx, p.UserID, userID, models.NotificationGroupModeration, group, &postID, message)
+	return nil
+}
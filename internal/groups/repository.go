@@ -0,0 +1,278 @@
+package groups
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"socialnetwork/models"
+)
+
+// Vai trò quản lý nhóm (được duyệt bài, duyệt thành viên, xem nhật ký kiểm duyệt)
+var staffRoles = bson.A{models.GroupRoleOwner, models.GroupRoleAdmin, models.GroupRoleModerator}
+
+type Repository interface {
+	EnsureIndexes(ctx context.Context) error
+
+	CreateGroup(ctx context.Context, group *models.Group) error
+	FindGroup(ctx context.Context, id primitive.ObjectID) (*models.Group, error)
+	FindGroups(ctx context.Context, ids []primitive.ObjectID) ([]models.Group, error)
+	SearchGroups(ctx context.Context, query string, skip, limit int64) ([]models.Group, error)
+	UpdateGroup(ctx context.Context, id primitive.ObjectID, set bson.M) error
+	IncMemberCount(ctx context.Context, id primitive.ObjectID, delta int64) error
+
+	FindMember(ctx context.Context, groupID, userID primitive.ObjectID) (*models.GroupMember, error)
+	CreateMember(ctx context.Context, member *models.GroupMember) error
+	UpdateMember(ctx context.Context, groupID, userID primitive.ObjectID, set bson.M) error
+	DeleteMember(ctx context.Context, groupID, userID primitive.ObjectID) error
+	ListMembers(ctx context.Context, groupID primitive.ObjectID, status models.GroupMemberStatus, role models.GroupRole, before primitive.ObjectID, limit int64) ([]models.GroupMember, error)
+	ListMemberships(ctx context.Context, userID primitive.ObjectID, status models.GroupMemberStatus) ([]models.GroupMember, error)
+	StaffIDs(ctx context.Context, groupID primitive.ObjectID) ([]primitive.ObjectID, error)
+	SubscriberIDs(ctx context.Context, groupID, exclude primitive.ObjectID) ([]primitive.ObjectID, error)
+
+	InsertLog(ctx context.Context, entry *models.GroupModerationEntry) error
+	ListLog(ctx context.Context, groupID, before primitive.ObjectID, limit int64) ([]models.GroupModerationEntry, error)
+}
+
+type repository struct {
+	groups  *mongo.Collection
+	members *mongo.Collection
+	logs    *mongo.Collection
+}
+
+func NewRepository(db *mongo.Database) Repository {
+	return &repository{
+		groups:  db.Collection("groups"),
+		members: db.Collection("group_members"),
+		logs:    db.Collection("group_moderation_log"),
+	}
+}
+
+func (r *repository) EnsureIndexes(ctx context.Context) error {
+	if _, err := r.groups.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "privacy", Value: 1}, {Key: "memberCount", Value: -1}},
+	}); err != nil {
+		return err
+	}
+	if _, err := r.members.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "groupId", Value: 1}, {Key: "userId", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "groupId", Value: 1}, {Key: "status", Value: 1}, {Key: "_id", Value: -1}}},
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "status", Value: 1}}},
+	}); err != nil {
+		return err
+	}
+	_, err := r.logs.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "groupId", Value: 1}, {Key: "_id", Value: -1}},
+	})
+	return err
+}
+
+func (r *repository) CreateGroup(ctx context.Context, group *models.Group) error {
+	now := time.Now()
+	group.ID = primitive.NewObjectID()
+	group.CreatedAt = now
+	group.UpdatedAt = now
+	_, err := r.groups.InsertOne(ctx, group)
+	return err
+}
+
+// FindGroup bỏ qua nhóm đã xoá (trả về mongo.ErrNoDocuments)
+func (r *repository) FindGroup(ctx context.Context, id primitive.ObjectID) (*models.Group, error) {
+	var group models.Group
+	if err := r.groups.FindOne(ctx, bson.M{"_id": id, "deletedAt": nil}).Decode(&group); err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
+
+func (r *repository) FindGroups(ctx context.Context, ids []primitive.ObjectID) ([]models.Group, error) {
+	groups := []models.Group{}
+	if len(ids) == 0 {
+		return groups, nil
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "name", Value: 1}})
+	cursor, err := r.groups.Find(ctx, bson.M{"_id": bson.M{"$in": ids}, "deletedAt": nil}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+	err = cursor.All(ctx, &groups)
+	return groups, err
+}
+
+// SearchGroups - nhóm công khai/riêng tư theo tên, đông thành viên trước; nhóm bí mật không bao giờ hiện
+func (r *repository) SearchGroups(ctx context.Context, query string, skip, limit int64) ([]models.Group, error) {
+	filter := bson.M{
+		"privacy":   bson.M{"$in": bson.A{models.GroupPublic, models.GroupPrivate}},
+		"deletedAt": nil,
+	}
+	if query != "" {
+		filter["name"] = primitive.Regex{Pattern: regexp.QuoteMeta(query), Options: "i"}
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "memberCount", Value: -1}, {Key: "_id", Value: -1}}).
+		SetSkip(skip).
+		SetLimit(limit)
+	cursor, err := r.groups.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	groups := []models.Group{}
+	err = cursor.All(ctx, &groups)
+	return groups, err
+}
+
+func (r *repository) UpdateGroup(ctx context.Context, id primitive.ObjectID, set bson.M) error {
+	set["updatedAt"] = time.Now()
+	res, err := r.groups.UpdateOne(ctx, bson.M{"_id": id, "deletedAt": nil}, bson.M{"$set": set})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+func (r *repository) IncMemberCount(ctx context.Context, id primitive.ObjectID, delta int64) error {
+	_, err := r.groups.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$inc": bson.M{"memberCount": delta}})
+	return err
+}
+
+// FindMember trả về nil, nil nếu user chưa có quan hệ gì với nhóm
+func (r *repository) FindMember(ctx context.Context, groupID, userID primitive.ObjectID) (*models.GroupMember, error) {
+	var member models.GroupMember
+	err := r.members.FindOne(ctx, bson.M{"groupId": groupID, "userId": userID}).Decode(&member)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &member, nil
+}
+
+func (r *repository) CreateMember(ctx context.Context, member *models.GroupMember) error {
+	now := time.Now()
+	member.ID = primitive.NewObjectID()
+	member.CreatedAt = now
+	member.UpdatedAt = now
+	_, err := r.members.InsertOne(ctx, member)
+	return err
+}
+
+func (r *repository) UpdateMember(ctx context.Context, groupID, userID primitive.ObjectID, set bson.M) error {
+	set["updatedAt"] = time.Now()
+	res, err := r.members.UpdateOne(ctx, bson.M{"groupId": groupID, "userId": userID}, bson.M{"$set": set})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+func (r *repository) DeleteMember(ctx context.Context, groupID, userID primitive.ObjectID) error {
+	_, err := r.members.DeleteOne(ctx, bson.M{"groupId": groupID, "userId": userID})
+	return err
+}
+
+// ListMembers phân trang theo _id giảm dần (người mới vào trước); role rỗng là mọi vai trò
+func (r *repository) ListMembers(ctx context.Context, groupID primitive.ObjectID, status models.GroupMemberStatus, role models.GroupRole, before primitive.ObjectID, limit int64) ([]models.GroupMember, error) {
+	filter := bson.M{"groupId": groupID, "status": status}
+	if role != "" {
+		filter["role"] = role
+	}
+	if !before.IsZero() {
+		filter["_id"] = bson.M{"$lt": before}
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: -1}}).SetLimit(limit)
+	cursor, err := r.members.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	members := []models.GroupMember{}
+	err = cursor.All(ctx, &members)
+	return members, err
+}
+
+func (r *repository) ListMemberships(ctx context.Context, userID primitive.ObjectID, status models.GroupMemberStatus) ([]models.GroupMember, error) {
+	cursor, err := r.members.Find(ctx, bson.M{"userId": userID, "status": status})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	members := []models.GroupMember{}
+	err = cursor.All(ctx, &members)
+	return members, err
+}
+
+// StaffIDs - chủ nhóm, quản trị viên và kiểm duyệt viên (nhận thông báo yêu cầu tham gia/bài chờ duyệt)
+func (r *repository) StaffIDs(ctx context.Context, groupID primitive.ObjectID) ([]primitive.ObjectID, error) {
+	return r.userIDs(ctx, bson.M{"groupId": groupID, "status": models.GroupMemberActive, "role": bson.M{"$in": staffRoles}})
+}
+
+// SubscriberIDs - thành viên nhận thông báo bài viết mới
+func (r *repository) SubscriberIDs(ctx context.Context, groupID, exclude primitive.ObjectID) ([]primitive.ObjectID, error) {
+	return r.userIDs(ctx, bson.M{
+		"groupId":       groupID,
+		"status":        models.GroupMemberActive,
+		"notifications": bson.M{"$ne": models.GroupNotifyOff},
+		"userId":        bson.M{"$ne": exclude},
+	})
+}
+
+func (r *repository) userIDs(ctx context.Context, filter bson.M) ([]primitive.ObjectID, error) {
+	cursor, err := r.members.Find(ctx, filter, options.Find().SetProjection(bson.M{"userId": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var ids []primitive.ObjectID
+	for cursor.Next(ctx) {
+		var m struct {
+			UserID primitive.ObjectID `bson:"userId"`
+		}
+		if err := cursor.Decode(&m); err != nil {
+			return nil, err
+		}
+		ids = append(ids, m.UserID)
+	}
+	return ids, cursor.Err()
+}
+
+func (r *repository) InsertLog(ctx context.Context, entry *models.GroupModerationEntry) error {
+	entry.ID = primitive.NewObjectID()
+	entry.CreatedAt = time.Now()
+	_, err := r.logs.InsertOne(ctx, entry)
+	return err
+}
+
+func (r *repository) ListLog(ctx context.Context, groupID, before primitive.ObjectID, limit int64) ([]models.GroupModerationEntry, error) {
+	filter := bson.M{"groupId": groupID}
+	if !before.IsZero() {
+		filter["_id"] = bson.M{"$lt": before}
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: -1}}).SetLimit(limit)
+	cursor, err := r.logs.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	entries := []models.GroupModerationEntry{}
+	err = cursor.All(ctx, &entries)
+	return entries, err
+}
@@ -0,0 +1,555 @@
+package groups
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"socialnetwork/dto/request"
+	"socialnetwork/internal/notification"
+	"socialnetwork/internal/post"
+	"socialnetwork/internal/user"
+	"socialnetwork/models"
+)
+
+var (
+	ErrGroupNotFound    = errors.New("không tìm thấy nhóm")
+	ErrUserNotFound     = errors.New("người dùng không tồn tại")
+	ErrMemberNotFound   = errors.New("người dùng không phải thành viên nhóm")
+	ErrPostNotFound     = errors.New("không tìm thấy bài viết trong nhóm")
+	ErrNotAllowed       = errors.New("bạn không có quyền thực hiện thao tác này trong nhóm")
+	ErrNotMember        = errors.New("bạn cần tham gia nhóm để xem hoặc đăng bài")
+	ErrAlreadyMember    = errors.New("người dùng đã là thành viên hoặc đã được mời")
+	ErrAlreadyRequested = errors.New("bạn đã gửi yêu cầu tham gia nhóm này")
+	ErrBanned           = errors.New("bạn không thể tham gia nhóm này")
+	ErrBlocked          = errors.New("không thể mời người dùng này")
+	ErrOwnerCannotLeave = errors.New("chủ nhóm phải chuyển quyền sở hữu trước khi rời nhóm")
+	ErrNoPendingRequest = errors.New("không có yêu cầu tham gia nào của người dùng này")
+	ErrNoInvitation     = errors.New("bạn không có lời mời tham gia nhóm này")
+	ErrMuted            = errors.New("bạn đang bị tạm cấm đăng bài trong nhóm")
+	ErrInvalidFilter    = errors.New("bộ lọc không hợp lệ")
+)
+
+// GroupView - nhóm kèm quan hệ của người xem với nhóm (nil nếu chưa tham gia)
+type GroupView struct {
+	models.Group
+	Membership *models.GroupMember `json:"membership,omitempty"`
+}
+
+type Service interface {
+	Create(ctx context.Context, userID primitive.ObjectID, req request.CreateGroupRequest) (*GroupView, error)
+	Get(ctx context.Context, userID, groupID primitive.ObjectID) (*GroupView, error)
+	Discover(ctx context.Context, query string, page, limit int) ([]models.Group, error)
+	Mine(ctx context.Context, userID primitive.ObjectID, status models.GroupMemberStatus) ([]models.Group, error)
+	Update(ctx context.Context, userID, groupID primitive.ObjectID, req request.UpdateGroupRequest) (*GroupView, error)
+	UpdateRules(ctx context.Context, userID, groupID primitive.ObjectID, req request.UpdateGroupRulesRequest) (*GroupView, error)
+	Delete(ctx context.Context, userID, groupID primitive.ObjectID) error
+
+	Join(ctx context.Context, userID, groupID primitive.ObjectID) (*models.GroupMember, error)
+	Leave(ctx context.Context, userID, groupID primitive.ObjectID) error
+	Invite(ctx context.Context, userID, groupID primitive.ObjectID, req request.GroupInviteRequest) error
+	DeclineInvite(ctx context.Context, userID, groupID primitive.ObjectID) error
+	Requests(ctx context.Context, userID, groupID, before primitive.ObjectID, limit int) ([]models.GroupMember, error)
+	ApproveRequest(ctx context.Context, userID, groupID, targetID primitive.ObjectID) error
+	DeclineRequest(ctx context.Context, userID, groupID, targetID primitive.ObjectID) error
+	Members(ctx context.Context, userID, groupID primitive.ObjectID, role string, before primitive.ObjectID, limit int) ([]models.GroupMember, error)
+	SetNotifications(ctx context.Context, userID, groupID primitive.ObjectID, req request.GroupNotificationsRequest) error
+
+	SetRole(ctx context.Context, userID, groupID, targetID primitive.ObjectID, req request.GroupRoleRequest) error
+	RemoveMember(ctx context.Context, userID, groupID, targetID primitive.ObjectID, ban bool, reason string) error
+	Unban(ctx context.Context, userID, groupID, targetID primitive.ObjectID) error
+	Mute(ctx context.Context, userID, groupID, targetID primitive.ObjectID, req request.GroupMuteRequest) error
+	ModerationLog(ctx context.Context, userID, groupID, before primitive.ObjectID, limit int) ([]models.GroupModerationEntry, error)
+
+	CreatePost(ctx context.Context, userID, groupID primitive.ObjectID, req request.GroupPostRequest) (*models.Post, error)
+	Posts(ctx context.Context, userID, groupID, before primitive.ObjectID, limit int) ([]models.Post, error)
+	PendingPosts(ctx context.Context, userID, groupID, before primitive.ObjectID, limit int) ([]models.Post, error)
+	ApprovePost(ctx context.Context, userID, groupID, postID primitive.ObjectID) error
+	RemovePost(ctx context.Context, userID, groupID, postID primitive.ObjectID, reason string) error
+
+	// Kiểm tra quyền với nội dung gắn với bài trong nhóm (bình luận...)
+	CanView(ctx context.Context, userID, groupID primitive.ObjectID) error
+	CanPost(ctx context.Context, userID, groupID primitive.ObjectID) error
+}
+
+type service struct {
+	repo             Repository
+	postRepo         *post.PostRepository
+	postService      post.PostService
+	userRepo         user.Repository
+	notificationRepo notification.NotificationRepository
+}
+
+func NewService(repo Repository, postRepo *post.PostRepository, postService post.PostService, userRepo user.Repository, notificationRepo notification.NotificationRepository) Service {
+	return &service{
+		repo:             repo,
+		postRepo:         postRepo,
+		postService:      postService,
+		userRepo:         userRepo,
+		notificationRepo: notificationRepo,
+	}
+}
+
+// rank - thứ bậc vai trò: người có bậc cao hơn mới xử lý được người bậc thấp hơn
+func rank(role models.GroupRole) int {
+	switch role {
+	case models.GroupRoleOwner:
+		return 4
+	case models.GroupRoleAdmin:
+		return 3
+	case models.GroupRoleModerator:
+		return 2
+	case models.GroupRoleMember:
+		return 1
+	}
+	return 0
+}
+
+func active(m *models.GroupMember) bool {
+	return m != nil && m.Status == models.GroupMemberActive
+}
+
+// canSeeContent - bài viết và danh sách thành viên nhóm công khai ai cũng xem được, còn lại chỉ thành viên
+func canSeeContent(group *models.Group, m *models.GroupMember) bool {
+	return group.Privacy == models.GroupPublic || active(m)
+}
+
+func clampLimit(limit int) int64 {
+	if limit < 1 || limit > 100 {
+		return 20
+	}
+	return int64(limit)
+}
+
+// load trả về nhóm và quan hệ của user với nhóm; nhóm bí mật coi như không tồn tại với người ngoài
+func (s *service) load(ctx context.Context, userID, groupID primitive.ObjectID) (*models.Group, *models.GroupMember, error) {
+	group, err := s.repo.FindGroup(ctx, groupID)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil, ErrGroupNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	member, err := s.repo.FindMember(ctx, groupID, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if group.Privacy == models.GroupSecret && (member == nil || (member.Status != models.GroupMemberActive && member.Status != models.GroupMemberInvited)) {
+		return nil, nil, ErrGroupNotFound
+	}
+	return group, member, nil
+}
+
+// staff yêu cầu user là thành viên có vai trò từ minRole trở lên
+func (s *service) staff(ctx context.Context, userID, groupID primitive.ObjectID, minRole models.GroupRole) (*models.Group, *models.GroupMember, error) {
+	group, member, err := s.load(ctx, userID, groupID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !active(member) || rank(member.Role) < rank(minRole) {
+		return nil, nil, ErrNotAllowed
+	}
+	return group, member, nil
+}
+
+func (s *service) notify(ctx context.Context, recipient, sender primitive.ObjectID, kind models.NotificationType, group *models.Group, postID *primitive.ObjectID, message string) {
+	noti := &models.Notification{
+		Recipient: recipient,
+		Sender:    sender,
+		Type:      kind,
+		GroupID:   &group.ID,
+		PostID:    postID,
+		Message:   message,
+		CreatedAt: time.Now(),
+	}
+	if err := s.notificationRepo.Create(ctx, noti); err != nil {
+		log.Printf("⚠️ không tạo được thông báo nhóm cho %s: %v", recipient.Hex(), err)
+	}
+}
+
+// notifyStaff báo cho người quản lý nhóm (yêu cầu tham gia, bài chờ duyệt)
+func (s *service) notifyStaff(ctx context.Context, sender primitive.ObjectID, kind models.NotificationType, group *models.Group, postID *primitive.ObjectID, message string) {
+	ids, err := s.repo.StaffIDs(ctx, group.ID)
+	if err != nil {
+		log.Printf("⚠️ không lấy được quản trị viên nhóm %s: %v", group.ID.Hex(), err)
+		return
+	}
+	for _, id := range ids {
+		s.notify(ctx, id, sender, kind, group, postID, message)
+	}
+}
+
+func rules(reqs []request.GroupRuleRequest) []models.GroupRule {
+	out := make([]models.GroupRule, 0, len(reqs))
+	for _, r := range reqs {
+		out = append(out, models.GroupRule{Title: strings.TrimSpace(r.Title), Description: strings.TrimSpace(r.Description)})
+	}
+	return out
+}
+
+func (s *service) Create(ctx context.Context, userID primitive.ObjectID, req request.CreateGroupRequest) (*GroupView, error) {
+	group := &models.Group{
+		Name:         strings.TrimSpace(req.Name),
+		Description:  strings.TrimSpace(req.Description),
+		Privacy:      models.GroupPrivacy(req.Privacy),
+		OwnerID:      userID,
+		Rules:        rules(req.Rules),
+		PostApproval: req.PostApproval,
+		MemberCount:  1,
+	}
+	if err := s.repo.CreateGroup(ctx, group); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	owner := &models.GroupMember{
+		GroupID:       group.ID,
+		UserID:        userID,
+		Role:          models.GroupRoleOwner,
+		Status:        models.GroupMemberActive,
+		Notifications: models.GroupNotifyAll,
+		JoinedAt:      &now,
+	}
+	if err := s.repo.CreateMember(ctx, owner); err != nil {
+		return nil, err
+	}
+	return &GroupView{Group: *group, Membership: owner}, nil
+}
+
+func (s *service) Get(ctx context.Context, userID, groupID primitive.ObjectID) (*GroupView, error) {
+	group, member, err := s.load(ctx, userID, groupID)
+	if err != nil {
+		return nil, err
+	}
+	return &GroupView{Group: *group, Membership: member}, nil
+}
+
+func (s *service) Discover(ctx context.Context, query string, page, limit int) ([]models.Group, error) {
+	if page < 1 {
+		page = 1
+	}
+	n := clampLimit(limit)
+	return s.repo.SearchGroups(ctx, strings.TrimSpace(query), int64(page-1)*n, n)
+}
+
+// Mine - nhóm đã tham gia (status rỗng), hoặc nhóm đang được mời/đang chờ duyệt
+func (s *service) Mine(ctx context.Context, userID primitive.ObjectID, status models.GroupMemberStatus) ([]models.Group, error) {
+	switch status {
+	case "":
+		status = models.GroupMemberActive
+	case models.GroupMemberActive, models.GroupMemberInvited, models.GroupMemberPending:
+	default:
+		return nil, ErrInvalidFilter
+	}
+	memberships, err := s.repo.ListMemberships(ctx, userID, status)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]primitive.ObjectID, 0, len(memberships))
+	for _, m := range memberships {
+		ids = append(ids, m.GroupID)
+	}
+	return s.repo.FindGroups(ctx, ids)
+}
+
+// Update - quản trị viên đổi thông tin nhóm; chỉ chủ nhóm được đổi quyền riêng tư
+func (s *service) Update(ctx context.Context, userID, groupID primitive.ObjectID, req request.UpdateGroupRequest) (*GroupView, error) {
+	group, member, err := s.staff(ctx, userID, groupID, models.GroupRoleAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	set := bson.M{}
+	changed := map[string]string{}
+	if req.Name != nil {
+		group.Name = strings.TrimSpace(*req.Name)
+		set["name"] = group.Name
+		changed["name"] = group.Name
+	}
+	if req.Description != nil {
+		group.Description = strings.TrimSpace(*req.Description)
+		set["description"] = group.Description
+		changed["description"] = "updated"
+	}
+	if req.Privacy != nil && models.GroupPrivacy(*req.Privacy) != group.Privacy {
+		if member.Role != models.GroupRoleOwner {
+			return nil, ErrNotAllowed
+		}
+		group.Privacy = models.GroupPrivacy(*req.Privacy)
+		set["privacy"] = group.Privacy
+		changed["privacy"] = string(group.Privacy)
+	}
+	if req.PostApproval != nil {
+		group.PostApproval = *req.PostApproval
+		set["postApproval"] = group.PostApproval
+		if group.PostApproval {
+			changed["postApproval"] = "on"
+		} else {
+			changed["postApproval"] = "off"
+		}
+	}
+	if len(set) == 0 {
+		return &GroupView{Group: *group, Membership: member}, nil
+	}
+
+	if err := s.repo.UpdateGroup(ctx, groupID, set); err != nil {
+		return nil, err
+	}
+	s.record(ctx, &models.GroupModerationEntry{GroupID: groupID, ActorID: userID, Action: models.GroupActionSettingsChanged, Metadata: changed})
+	return &GroupView{Group: *group, Membership: member}, nil
+}
+
+func (s *service) UpdateRules(ctx context.Context, userID, groupID primitive.ObjectID, req request.UpdateGroupRulesRequest) (*GroupView, error) {
+	group, member, err := s.staff(ctx, userID, groupID, models.GroupRoleAdmin)
+	if err != nil {
+		return nil, err
+	}
+	group.Rules = rules(req.Rules)
+	if err := s.repo.UpdateGroup(ctx, groupID, bson.M{"rules": group.Rules}); err != nil {
+		return nil, err
+	}
+	s.record(ctx, &models.GroupModerationEntry{GroupID: groupID, ActorID: userID, Action: models.GroupActionSettingsChanged, Metadata: map[string]string{"rules": "updated"}})
+	return &GroupView{Group: *group, Membership: member}, nil
+}
+
+// Delete - chỉ chủ nhóm; xoá mềm, bài viết trong nhóm không còn truy cập được qua nhóm
+func (s *service) Delete(ctx context.Context, userID, groupID primitive.ObjectID) error {
+	if _, _, err := s.staff(ctx, userID, groupID, models.GroupRoleOwner); err != nil {
+		return err
+	}
+	return s.repo.UpdateGroup(ctx, groupID, bson.M{"deletedAt": time.Now()})
+}
+
+// activate chuyển quan hệ sang thành viên chính thức và tăng số thành viên
+func (s *service) activate(ctx context.Context, groupID, userID primitive.ObjectID) error {
+	now := time.Now()
+	if err := s.repo.UpdateMember(ctx, groupID, userID, bson.M{
+		"status":        models.GroupMemberActive,
+		"role":          models.GroupRoleMember,
+		"notifications": models.GroupNotifyAll,
+		"joinedAt":      now,
+	}); err != nil {
+		return err
+	}
+	return s.repo.IncMemberCount(ctx, groupID, 1)
+}
+
+// Join - nhóm công khai vào ngay; nhóm riêng tư gửi yêu cầu chờ duyệt; nhóm bí mật chỉ vào được khi có lời mời.
+// Người đã được mời thì vào ngay với mọi loại nhóm.
+func (s *service) Join(ctx context.Context, userID, groupID primitive.ObjectID) (*models.GroupMember, error) {
+	group, member, err := s.load(ctx, userID, groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	if member != nil {
+		switch member.Status {
+		case models.GroupMemberActive:
+			return member, nil
+		case models.GroupMemberPending:
+			return nil, ErrAlreadyRequested
+		case models.GroupMemberBanned:
+			return nil, ErrBanned
+		case models.GroupMemberInvited:
+			if err := s.activate(ctx, groupID, userID); err != nil {
+				return nil, err
+			}
+			return s.repo.FindMember(ctx, groupID, userID)
+		}
+	}
+
+	if group.Privacy == models.GroupSecret {
+		return nil, ErrGroupNotFound // load đã chặn, giữ lại cho chắc
+	}
+
+	now := time.Now()
+	member = &models.GroupMember{GroupID: groupID, UserID: userID, Role: models.GroupRoleMember}
+	if group.Privacy == models.GroupPublic {
+		member.Status = models.GroupMemberActive
+		member.Notifications = models.GroupNotifyAll
+		member.JoinedAt = &now
+	} else {
+		member.Status = models.GroupMemberPending
+	}
+	if err := s.repo.CreateMember(ctx, member); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, ErrAlreadyRequested
+		}
+		return nil, err
+	}
+
+	if member.Status == models.GroupMemberActive {
+		if err := s.repo.IncMemberCount(ctx, groupID, 1); err != nil {
+			return nil, err
+		}
+	} else {
+		s.notifyStaff(ctx, userID, models.NotificationGroupJoinRequest, group, nil, "Có yêu cầu tham gia nhóm "+group.Name)
+	}
+	return member, nil
+}
+
+// Leave rời nhóm hoặc huỷ yêu cầu tham gia đang chờ
+func (s *service) Leave(ctx context.Context, userID, groupID primitive.ObjectID) error {
+	_, member, err := s.load(ctx, userID, groupID)
+	if err != nil {
+		return err
+	}
+	if member == nil || member.Status == models.GroupMemberBanned || member.Status == models.GroupMemberInvited {
+		return ErrMemberNotFound
+	}
+	if member.Role == models.GroupRoleOwner {
+		return ErrOwnerCannotLeave
+	}
+
+	if err := s.repo.DeleteMember(ctx, groupID, userID); err != nil {
+		return err
+	}
+	if member.Status == models.GroupMemberActive {
+		return s.repo.IncMemberCount(ctx, groupID, -1)
+	}
+	return nil
+}
+
+// Invite - thành viên mời người khác; người đã xin vào thì được duyệt luôn nếu người mời là quản trị viên/kiểm duyệt viên
+func (s *service) Invite(ctx context.Context, userID, groupID primitive.ObjectID, req request.GroupInviteRequest) error {
+	targetID, err := primitive.ObjectIDFromHex(req.UserID)
+	if err != nil {
+		return ErrUserNotFound
+	}
+	group, member, err := s.load(ctx, userID, groupID)
+	if err != nil {
+		return err
+	}
+	if !active(member) {
+		return ErrNotMember
+	}
+
+	target, err := s.userRepo.GetByID(ctx, targetID)
+	if err != nil || target.DeletedAt != nil {
+		return ErrUserNotFound
+	}
+	for _, id := range target.BlockedUsers {
+		if id == userID {
+			return ErrBlocked
+		}
+	}
+
+	existing, err := s.repo.FindMember(ctx, groupID, targetID)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		if existing.Status == models.GroupMemberPending && rank(member.Role) >= rank(models.GroupRoleModerator) {
+			return s.ApproveRequest(ctx, userID, groupID, targetID)
+		}
+		if existing.Status == models.GroupMemberBanned {
+			return ErrBlocked
+		}
+		return ErrAlreadyMember
+	}
+
+	invited := &models.GroupMember{
+		GroupID:   groupID,
+		UserID:    targetID,
+		Role:      models.GroupRoleMember,
+		Status:    models.GroupMemberInvited,
+		InvitedBy: &userID,
+	}
+	if err := s.repo.CreateMember(ctx, invited); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return ErrAlreadyMember
+		}
+		return err
+	}
+	s.notify(ctx, targetID, userID, models.NotificationGroupInvite, group, nil, "Bạn được mời tham gia nhóm "+group.Name)
+	return nil
+}
+
+func (s *service) DeclineInvite(ctx context.Context, userID, groupID primitive.ObjectID) error {
+	member, err := s.repo.FindMember(ctx, groupID, userID)
+	if err != nil {
+		return err
+	}
+	if member == nil || member.Status != models.GroupMemberInvited {
+		return ErrNoInvitation
+	}
+	return s.repo.DeleteMember(ctx, groupID, userID)
+}
+
+func (s *service) Requests(ctx context.Context, userID, groupID, before primitive.ObjectID, limit int) ([]models.GroupMember, error) {
+	if _, _, err := s.staff(ctx, userID, groupID, models.GroupRoleModerator); err != nil {
+		return nil, err
+	}
+	return s.repo.ListMembers(ctx, groupID, models.GroupMemberPending, "", before, clampLimit(limit))
+}
+
+func (s *service) pendingRequest(ctx context.Context, userID, groupID, targetID primitive.ObjectID) (*models.Group, error) {
+	group, _, err := s.staff(ctx, userID, groupID, models.GroupRoleModerator)
+	if err != nil {
+		return nil, err
+	}
+	target, err := s.repo.FindMember(ctx, groupID, targetID)
+	if err != nil {
+		return nil, err
+	}
+	if target == nil || target.Status != models.GroupMemberPending {
+		return nil, ErrNoPendingRequest
+	}
+	return group, nil
+}
+
+func (s *service) ApproveRequest(ctx context.Context, userID, groupID, targetID primitive.ObjectID) error {
+	group, err := s.pendingRequest(ctx, userID, groupID, targetID)
+	if err != nil {
+		return err
+	}
+	if err := s.activate(ctx, groupID, targetID); err != nil {
+		return err
+	}
+	s.record(ctx, &models.GroupModerationEntry{GroupID: groupID, ActorID: userID, Action: models.GroupActionRequestApproved, TargetUserID: &targetID})
+	s.notify(ctx, targetID, userID, models.NotificationGroupJoined, group, nil, "Yêu cầu tham gia nhóm "+group.Name+" đã được duyệt")
+	return nil
+}
+
+// DeclineRequest xoá yêu cầu; người bị từ chối có thể gửi lại (muốn chặn hẳn thì dùng cấm)
+func (s *service) DeclineRequest(ctx context.Context, userID, groupID, targetID primitive.ObjectID) error {
+	if _, err := s.pendingRequest(ctx, userID, groupID, targetID); err != nil {
+		return err
+	}
+	if err := s.repo.DeleteMember(ctx, groupID, targetID); err != nil {
+		return err
+	}
+	s.record(ctx, &models.GroupModerationEntry{GroupID: groupID, ActorID: userID, Action: models.GroupActionRequestDeclined, TargetUserID: &targetID})
+	return nil
+}
+
+// Members - danh sách thành viên có phân trang; role lọc theo vai trò (vd: "admin" để xem ban quản trị)
+func (s *service) Members(ctx context.Context, userID, groupID primitive.ObjectID, role string, before primitive.ObjectID, limit int) ([]models.GroupMember, error) {
+	group, member, err := s.load(ctx, userID, groupID)
+	if err != nil {
+		return nil, err
+	}
+	if !canSeeContent(group, member) {
+		return nil, ErrNotMember
+	}
+	if role != "" && rank(models.GroupRole(role)) == 0 {
+		return nil, ErrInvalidFilter
+	}
+	return s.repo.ListMembers(ctx, groupID, models.GroupMemberActive, models.GroupRole(role), before, clampLimit(limit))
+}
+
+func (s *service) SetNotifications(ctx context.Context, userID, groupID primitive.ObjectID, req request.GroupNotificationsRequest) error {
+	_, member, err := s.load(ctx, userID, groupID)
+	if err != nil {
+		return err
+	}
+	if !active(member) {
+		return ErrNotMember
+	}
+	return s.repo.UpdateMember(ctx, groupID, userID, bson.M{"notifications": req.Level})
+}
@@ -0,0 +1,462 @@
+package messaging
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"golang.org/x/net/websocket"
+	"socialnetwork/dto/request"
+)
+
+const (
+	// Client phải gửi frame (ít nhất "ping") trong khoảng này, nếu không kết nối bị đóng
+	readTimeout  = 90 * time.Second
+	writeTimeout = 10 * time.Second
+)
+
+type Handler struct {
+	service Service
+	hub     *Hub
+}
+
+func NewHandler(service Service, hub *Hub) *Handler {
+	return &Handler{service: service, hub: hub}
+}
+
+func currentUser(c *gin.Context) (primitive.ObjectID, bool) {
+	userID, err := primitive.ObjectIDFromHex(c.GetString("userID"))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return primitive.NilObjectID, false
+	}
+	return userID, true
+}
+
+func paramID(c *gin.Context, name string) (primitive.ObjectID, bool) {
+	id, err := primitive.ObjectIDFromHex(c.Param(name))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "id không hợp lệ"})
+		return primitive.NilObjectID, false
+	}
+	return id, true
+}
+
+// GET /conversations?folder=inbox|requests|spam&before=<RFC3339>&limit=
+func (h *Handler) List(c *gin.Context) {
+	userID, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	var before *time.Time
+	if v := c.Query("before"); v != "" {
+		t, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "before không hợp lệ"})
+			return
+		}
+		before = &t
+	}
+	limit, _ := strconv.Atoi(c.Query("limit"))
+
+	convs, err := h.service.List(c.Request.Context(), userID, c.Query("folder"), before, limit)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	resp := gin.H{"conversations": convs}
+	if n := len(convs); n > 0 && n == clampLimit(limit, conversationPageSize) {
+		resp["nextCursor"] = convs[n-1].UpdatedAt.Format(time.RFC3339Nano)
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// GET /conversations/unread
+func (h *Handler) Unread(c *gin.Context) {
+	userID, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	summary, err := h.service.Unread(c.Request.Context(), userID)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, summary)
+}
+
+// POST /conversations/direct
+func (h *Handler) StartDirect(c *gin.Context) {
+	userID, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	var req request.StartDirectConversationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	otherID, err := primitive.ObjectIDFromHex(req.UserID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "userId không hợp lệ"})
+		return
+	}
+
+	conv, err := h.service.StartDirect(c.Request.Context(), userID, otherID)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, conv)
+}
+
+// POST /conversations/groups
+func (h *Handler) CreateGroup(c *gin.Context) {
+	userID, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	var req request.CreateGroupConversationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	conv, err := h.service.CreateGroup(c.Request.Context(), userID, req)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, conv)
+}
+
+// GET /conversations/:id
+func (h *Handler) Get(c *gin.Context) {
+	userID, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	conv, err := h.service.Get(c.Request.Context(), userID, id)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, conv)
+}
+
+// POST /conversations/:id/members
+func (h *Handler) AddMembers(c *gin.Context) {
+	userID, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	var req request.AddConversationMembersRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	conv, err := h.service.AddMembers(c.Request.Context(), userID, id, req)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, conv)
+}
+
+// POST /conversations/:id/leave
+func (h *Handler) Leave(c *gin.Context) {
+	userID, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.service.Leave(c.Request.Context(), userID, id); err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Đã rời nhóm"})
+}
+
+// POST /conversations/:id/accept
+func (h *Handler) AcceptRequest(c *gin.Context) {
+	userID, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	conv, err := h.service.AcceptRequest(c.Request.Context(), userID, id)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, conv)
+}
+
+// POST /conversations/:id/decline
+func (h *Handler) DeclineRequest(c *gin.Context) {
+	userID, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeclineRequest(c.Request.Context(), userID, id); err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Đã chuyển vào thư mục spam"})
+}
+
+// POST /conversations/:id/block
+func (h *Handler) BlockRequest(c *gin.Context) {
+	userID, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.service.BlockRequest(c.Request.Context(), userID, id); err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Đã chặn người gửi"})
+}
+
+// GET /conversations/:id/messages?before=<messageId>&limit=
+func (h *Handler) Messages(c *gin.Context) {
+	userID, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	var before primitive.ObjectID
+	if v := c.Query("before"); v != "" {
+		var err error
+		if before, err = primitive.ObjectIDFromHex(v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "before không hợp lệ"})
+			return
+		}
+	}
+	limit, _ := strconv.Atoi(c.Query("limit"))
+
+	msgs, err := h.service.Messages(c.Request.Context(), userID, id, before, limit)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	resp := gin.H{"messages": msgs}
+	if n := len(msgs); n > 0 && n == clampLimit(limit, messagePageSize) {
+		resp["nextCursor"] = msgs[n-1].ID.Hex()
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// POST /conversations/:id/messages
+func (h *Handler) Send(c *gin.Context) {
+	userID, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	var req request.SendMessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	msg, err := h.service.Send(c.Request.Context(), userID, id, req)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, msg)
+}
+
+// POST /conversations/:id/read
+func (h *Handler) MarkRead(c *gin.Context) {
+	userID, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	var req request.MarkReadRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	var messageID primitive.ObjectID
+	if req.MessageID != "" {
+		var err error
+		if messageID, err = primitive.ObjectIDFromHex(req.MessageID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "messageId không hợp lệ"})
+			return
+		}
+	}
+
+	if err := h.service.MarkRead(c.Request.Context(), userID, id, messageID); err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Đã đánh dấu đã đọc"})
+}
+
+// PUT /messages/:id
+func (h *Handler) Edit(c *gin.Context) {
+	userID, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+	var req request.EditMessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	msg, err := h.service.Edit(c.Request.Context(), userID, id, req)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, msg)
+}
+
+// DELETE /messages/:id
+func (h *Handler) Delete(c *gin.Context) {
+	userID, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	id, ok := paramID(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.service.Delete(c.Request.Context(), userID, id); err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Đã thu hồi tin nhắn"})
+}
+
+// inboundFrame - frame client gửi lên qua WebSocket:
+// {"type":"typing","conversationId":"..."}, {"type":"read","conversationId":"...","messageId":"..."}, {"type":"ping"}
+type inboundFrame struct {
+	Type           string `json:"type"`
+	ConversationID string `json:"conversationId"`
+	MessageID      string `json:"messageId"`
+}
+
+// GET /ws/messages?token=
+func (h *Handler) Connect(c *gin.Context) {
+	userID, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	server := websocket.Server{
+		// Đã xác thực bằng token nên không kiểm tra Origin
+		Handshake: func(*websocket.Config, *http.Request) error { return nil },
+		Handler:   func(ws *websocket.Conn) { h.serve(ws, userID) },
+	}
+	server.ServeHTTP(c.Writer, c.Request)
+}
+
+func (h *Handler) serve(ws *websocket.Conn, userID primitive.ObjectID) {
+	cl := h.hub.register(userID)
+	defer h.hub.unregister(cl)
+
+	// Ghi: chuyển sự kiện từ hub xuống client; channel đóng (client bị gỡ) thì đóng kết nối
+	go func() {
+		defer ws.Close()
+		for payload := range cl.send {
+			ws.SetWriteDeadline(time.Now().Add(writeTimeout))
+			if err := websocket.Message.Send(ws, string(payload)); err != nil {
+				return
+			}
+		}
+	}()
+
+	// Đọc: typing và read receipt; frame không hợp lệ bị bỏ qua
+	ctx := context.WithoutCancel(ws.Request().Context())
+	for {
+		ws.SetReadDeadline(time.Now().Add(readTimeout))
+		var raw string
+		if err := websocket.Message.Receive(ws, &raw); err != nil {
+			return
+		}
+		var frame inboundFrame
+		if json.Unmarshal([]byte(raw), &frame) != nil {
+			continue
+		}
+		conversationID, err := primitive.ObjectIDFromHex(frame.ConversationID)
+		if err != nil {
+			continue
+		}
+		switch frame.Type {
+		case "typing":
+			_ = h.service.Typing(ctx, userID, conversationID)
+		case "read":
+			messageID, _ := primitive.ObjectIDFromHex(frame.MessageID)
+			_ = h.service.MarkRead(ctx, userID, conversationID, messageID)
+		}
+	}
+}
+
+func writeError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrEmptyMessage), errors.Is(err, ErrSelfConversation), errors.Is(err, ErrDirectConversation),
+		errors.Is(err, ErrTooManyMembers), errors.Is(err, ErrEditWindowExpired), errors.Is(err, ErrMessageDeleted),
+		errors.Is(err, ErrInvalidFolder), errors.Is(err, ErrNotARequest):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrNotAllowed):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrBlocked), errors.Is(err, ErrFriendsOnly):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error(), "code": "messaging_not_allowed"})
+	case errors.Is(err, ErrConversationNotFound), errors.Is(err, ErrMessageNotFound), errors.Is(err, ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
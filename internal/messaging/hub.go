@@ -0,0 +1,151 @@
+package messaging
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Sự kiện realtime gửi qua WebSocket
+const (
+	EventMessageCreated      = "message.created"
+	EventMessageUpdated      = "message.updated"
+	EventMessageDeleted      = "message.deleted"
+	EventConversationRead    = "conversation.read" // read receipt
+	EventConversationUpdated = "conversation.updated"
+	EventTyping              = "typing"
+)
+
+// Kênh Redis pub/sub để phát sự kiện giữa nhiều instance server
+const hubChannel = "messaging:events"
+
+// Số sự kiện chờ gửi tối đa cho một kết nối; client chậm hơn sẽ bị ngắt
+const clientBuffer = 64
+
+type Event struct {
+	Type           string             `json:"type"`
+	ConversationID primitive.ObjectID `json:"conversationId"`
+	Data           interface{}        `json:"data,omitempty"`
+}
+
+type envelope struct {
+	Recipients []primitive.ObjectID `json:"recipients"`
+	Event      json.RawMessage      `json:"event"`
+}
+
+type client struct {
+	userID primitive.ObjectID
+	send   chan []byte
+	once   sync.Once
+}
+
+func (c *client) close() {
+	c.once.Do(func() { close(c.send) })
+}
+
+// Hub giữ các kết nối WebSocket trên instance này và chuyển sự kiện tới đúng user.
+// Có Redis thì sự kiện đi qua pub/sub để user kết nối ở instance khác cũng nhận được.
+type Hub struct {
+	redisClient *redis.Client
+
+	mu      sync.RWMutex
+	clients map[primitive.ObjectID]map[*client]struct{}
+}
+
+func NewHub(redisClient *redis.Client) *Hub {
+	return &Hub{
+		redisClient: redisClient,
+		clients:     map[primitive.ObjectID]map[*client]struct{}{},
+	}
+}
+
+// Run nhận sự kiện từ Redis và chuyển cho các kết nối trên instance này
+func (h *Hub) Run(ctx context.Context) {
+	if h.redisClient == nil {
+		return
+	}
+	for ctx.Err() == nil {
+		sub := h.redisClient.Subscribe(ctx, hubChannel)
+		for msg := range sub.Channel() {
+			var env envelope
+			if err := json.Unmarshal([]byte(msg.Payload), &env); err != nil {
+				log.Printf("⚠️ messaging event không hợp lệ: %v", err)
+				continue
+			}
+			h.deliver(env.Recipients, env.Event)
+		}
+		sub.Close()
+		if ctx.Err() == nil {
+			log.Println("⚠️ mất kết nối pub/sub messaging, thử lại")
+			time.Sleep(time.Second)
+		}
+	}
+}
+
+// Publish gửi sự kiện tới các user; lỗi chỉ được log vì tin nhắn đã được lưu
+func (h *Hub) Publish(ctx context.Context, recipients []primitive.ObjectID, event Event) {
+	if len(recipients) == 0 {
+		return
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("⚠️ messaging event: %v", err)
+		return
+	}
+	if h.redisClient != nil {
+		data, _ := json.Marshal(envelope{Recipients: recipients, Event: payload})
+		err := h.redisClient.Publish(ctx, hubChannel, data).Err()
+		if err == nil {
+			return
+		}
+		log.Printf("⚠️ không phát được messaging event qua Redis, chỉ gửi trên instance này: %v", err)
+	}
+	h.deliver(recipients, payload)
+}
+
+func (h *Hub) deliver(recipients []primitive.ObjectID, payload []byte) {
+	var slow []*client
+	h.mu.RLock()
+	for _, userID := range recipients {
+		for c := range h.clients[userID] {
+			select {
+			case c.send <- payload:
+			default:
+				slow = append(slow, c)
+			}
+		}
+	}
+	h.mu.RUnlock()
+
+	for _, c := range slow {
+		h.unregister(c)
+	}
+}
+
+func (h *Hub) register(userID primitive.ObjectID) *client {
+	c := &client{userID: userID, send: make(chan []byte, clientBuffer)}
+	h.mu.Lock()
+	if h.clients[userID] == nil {
+		h.clients[userID] = map[*client]struct{}{}
+	}
+	h.clients[userID][c] = struct{}{}
+	h.mu.Unlock()
+	return c
+}
+
+func (h *Hub) unregister(c *client) {
+	h.mu.Lock()
+	if conns, ok := h.clients[c.userID]; ok {
+		delete(conns, c)
+		if len(conns) == 0 {
+			delete(h.clients, c.userID)
+		}
+	}
+	h.mu.Unlock()
+	c.close()
+}
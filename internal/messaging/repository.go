@@ -0,0 +1,298 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"socialnetwork/models"
+)
+
+type Repository interface {
+	EnsureIndexes(ctx context.Context) error
+
+	CreateConversation(ctx context.Context, conv *models.Conversation) error
+	FindConversation(ctx context.Context, id primitive.ObjectID) (*models.Conversation, error)
+	FindDirect(ctx context.Context, directKey string) (*models.Conversation, error)
+	ListConversations(ctx context.Context, userID primitive.ObjectID, folder string, before *time.Time, limit int64) ([]models.Conversation, error)
+	AddMembers(ctx context.Context, id primitive.ObjectID, members []models.ConversationMember) error
+	RemoveMember(ctx context.Context, id, userID primitive.ObjectID) error
+	SetMemberRole(ctx context.Context, id, userID primitive.ObjectID, role string) error
+	SetMemberFolder(ctx context.Context, id, userID primitive.ObjectID, folder string) error
+	UnreadTotal(ctx context.Context, userID primitive.ObjectID, folder string) (messages int64, conversations int64, err error)
+
+	InsertMessage(ctx context.Context, msg *models.Message) error
+	FindMessage(ctx context.Context, id primitive.ObjectID) (*models.Message, error)
+	ListMessages(ctx context.Context, conversationID, before primitive.ObjectID, limit int64) ([]models.Message, error)
+	UpdateMessage(ctx context.Context, msg *models.Message) error
+	LatestMessageID(ctx context.Context, conversationID primitive.ObjectID) (primitive.ObjectID, error)
+	MarkRead(ctx context.Context, conversationID, userID, messageID primitive.ObjectID, at time.Time) (bool, error)
+}
+
+type repository struct {
+	conversations *mongo.Collection
+	messages      *mongo.Collection
+}
+
+func NewRepository(db *mongo.Database) Repository {
+	return &repository{
+		conversations: db.Collection("conversations"),
+		messages:      db.Collection("messages"),
+	}
+}
+
+func (r *repository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.conversations.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "members.userId", Value: 1}, {Key: "updatedAt", Value: -1}}},
+		{
+			// Mỗi cặp user chỉ có một hội thoại trực tiếp
+			Keys: bson.D{{Key: "directKey", Value: 1}},
+			Options: options.Index().SetUnique(true).
+				SetPartialFilterExpression(bson.M{"directKey": bson.M{"$type": "string"}}),
+		},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = r.messages.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "conversationId", Value: 1}, {Key: "_id", Value: -1}},
+	})
+	return err
+}
+
+func (r *repository) CreateConversation(ctx context.Context, conv *models.Conversation) error {
+	now := time.Now()
+	conv.ID = primitive.NewObjectID()
+	conv.CreatedAt = now
+	conv.UpdatedAt = now
+	_, err := r.conversations.InsertOne(ctx, conv)
+	return err
+}
+
+func (r *repository) FindConversation(ctx context.Context, id primitive.ObjectID) (*models.Conversation, error) {
+	var conv models.Conversation
+	if err := r.conversations.FindOne(ctx, bson.M{"_id": id}).Decode(&conv); err != nil {
+		return nil, err
+	}
+	return &conv, nil
+}
+
+// FindDirect trả về nil, nil nếu cặp user chưa có hội thoại
+func (r *repository) FindDirect(ctx context.Context, directKey string) (*models.Conversation, error) {
+	var conv models.Conversation
+	err := r.conversations.FindOne(ctx, bson.M{"directKey": directKey}).Decode(&conv)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &conv, nil
+}
+
+// memberInFolder - điều kiện $elemMatch cho thành viên nằm trong thư mục; thiếu folder coi như inbox
+func memberInFolder(userID primitive.ObjectID, folder string) bson.M {
+	if folder == models.FolderInbox {
+		return bson.M{"userId": userID, "folder": bson.M{"$in": bson.A{nil, models.FolderInbox}}}
+	}
+	return bson.M{"userId": userID, "folder": folder}
+}
+
+// ListConversations - hộp thư của user theo thư mục, hội thoại có tin mới nhất lên đầu.
+// Tin nhắn chờ/spam chỉ hiện khi đã có tin nhắn.
+func (r *repository) ListConversations(ctx context.Context, userID primitive.ObjectID, folder string, before *time.Time, limit int64) ([]models.Conversation, error) {
+	filter := bson.M{"members": bson.M{"$elemMatch": memberInFolder(userID, folder)}}
+	if folder != models.FolderInbox {
+		filter["lastMessage"] = bson.M{"$exists": true}
+	}
+	if before != nil {
+		filter["updatedAt"] = bson.M{"$lt": *before}
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "updatedAt", Value: -1}}).SetLimit(limit)
+	cursor, err := r.conversations.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	convs := []models.Conversation{}
+	err = cursor.All(ctx, &convs)
+	return convs, err
+}
+
+func (r *repository) AddMembers(ctx context.Context, id primitive.ObjectID, members []models.ConversationMember) error {
+	_, err := r.conversations.UpdateOne(ctx, bson.M{"_id": id}, bson.M{
+		"$push": bson.M{"members": bson.M{"$each": members}},
+		"$set":  bson.M{"updatedAt": time.Now()},
+	})
+	return err
+}
+
+func (r *repository) RemoveMember(ctx context.Context, id, userID primitive.ObjectID) error {
+	_, err := r.conversations.UpdateOne(ctx, bson.M{"_id": id}, bson.M{
+		"$pull": bson.M{"members": bson.M{"userId": userID}},
+	})
+	return err
+}
+
+func (r *repository) SetMemberRole(ctx context.Context, id, userID primitive.ObjectID, role string) error {
+	_, err := r.conversations.UpdateOne(ctx,
+		bson.M{"_id": id, "members.userId": userID},
+		bson.M{"$set": bson.M{"members.$.role": role}},
+	)
+	return err
+}
+
+func (r *repository) SetMemberFolder(ctx context.Context, id, userID primitive.ObjectID, folder string) error {
+	_, err := r.conversations.UpdateOne(ctx,
+		bson.M{"_id": id, "members.userId": userID},
+		bson.M{"$set": bson.M{"members.$.folder": folder}},
+	)
+	return err
+}
+
+// UnreadTotal - tổng số tin chưa đọc và số hội thoại có tin chưa đọc trong một thư mục
+func (r *repository) UnreadTotal(ctx context.Context, userID primitive.ObjectID, folder string) (int64, int64, error) {
+	member := memberInFolder(userID, folder)
+	member["unreadCount"] = bson.M{"$gt": 0}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"members": bson.M{"$elemMatch": member}}}},
+		{{Key: "$unwind", Value: "$members"}},
+		{{Key: "$match", Value: bson.M{"members.userId": userID}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":           nil,
+			"messages":      bson.M{"$sum": "$members.unreadCount"},
+			"conversations": bson.M{"$sum": 1},
+		}}},
+	}
+	cursor, err := r.conversations.Aggregate(ctx, pipeline)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var result []struct {
+		Messages      int64 `bson:"messages"`
+		Conversations int64 `bson:"conversations"`
+	}
+	if err := cursor.All(ctx, &result); err != nil || len(result) == 0 {
+		return 0, 0, err
+	}
+	return result[0].Messages, result[0].Conversations, nil
+}
+
+// InsertMessage lưu tin nhắn, cập nhật tin cuối của hội thoại và tăng số chưa đọc của các thành viên khác.
+// Người gửi coi như đã đọc tới tin của chính mình.
+func (r *repository) InsertMessage(ctx context.Context, msg *models.Message) error {
+	msg.ID = primitive.NewObjectID()
+	msg.CreatedAt = time.Now()
+	if _, err := r.messages.InsertOne(ctx, msg); err != nil {
+		return err
+	}
+
+	preview := models.MessagePreview{ID: msg.ID, SenderID: msg.SenderID, Text: msg.Text, CreatedAt: msg.CreatedAt}
+	opts := options.Update().SetArrayFilters(options.ArrayFilters{Filters: []interface{}{
+		bson.M{"other.userId": bson.M{"$ne": msg.SenderID}},
+		bson.M{"sender.userId": msg.SenderID},
+	}})
+	_, err := r.conversations.UpdateOne(ctx, bson.M{"_id": msg.ConversationID}, bson.M{
+		"$set": bson.M{
+			"lastMessage":                         preview,
+			"updatedAt":                           msg.CreatedAt,
+			"members.$[sender].lastReadMessageId": msg.ID,
+			"members.$[sender].lastReadAt":        msg.CreatedAt,
+			"members.$[sender].unreadCount":       0,
+		},
+		"$inc": bson.M{"members.$[other].unreadCount": 1},
+	}, opts)
+	return err
+}
+
+func (r *repository) FindMessage(ctx context.Context, id primitive.ObjectID) (*models.Message, error) {
+	var msg models.Message
+	if err := r.messages.FindOne(ctx, bson.M{"_id": id}).Decode(&msg); err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
+
+// ListMessages - phân trang ngược theo _id: before rỗng thì lấy các tin mới nhất
+func (r *repository) ListMessages(ctx context.Context, conversationID, before primitive.ObjectID, limit int64) ([]models.Message, error) {
+	filter := bson.M{"conversationId": conversationID}
+	if !before.IsZero() {
+		filter["_id"] = bson.M{"$lt": before}
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: -1}}).SetLimit(limit)
+	cursor, err := r.messages.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	msgs := []models.Message{}
+	err = cursor.All(ctx, &msgs)
+	return msgs, err
+}
+
+// UpdateMessage lưu nội dung đã sửa/thu hồi; nếu là tin cuối của hội thoại thì cập nhật luôn bản xem trước
+func (r *repository) UpdateMessage(ctx context.Context, msg *models.Message) error {
+	set := bson.M{"text": msg.Text, "attachments": msg.Attachments, "editedAt": msg.EditedAt, "deletedAt": msg.DeletedAt}
+	if _, err := r.messages.UpdateOne(ctx, bson.M{"_id": msg.ID}, bson.M{"$set": set}); err != nil {
+		return err
+	}
+
+	_, err := r.conversations.UpdateOne(ctx,
+		bson.M{"_id": msg.ConversationID, "lastMessage.id": msg.ID},
+		bson.M{"$set": bson.M{"lastMessage.text": msg.Text, "lastMessage.deleted": msg.DeletedAt != nil}},
+	)
+	return err
+}
+
+func (r *repository) LatestMessageID(ctx context.Context, conversationID primitive.ObjectID) (primitive.ObjectID, error) {
+	var msg models.Message
+	opts := options.FindOne().SetSort(bson.D{{Key: "_id", Value: -1}}).SetProjection(bson.M{"_id": 1})
+	err := r.messages.FindOne(ctx, bson.M{"conversationId": conversationID}, opts).Decode(&msg)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return primitive.NilObjectID, nil
+	}
+	return msg.ID, err
+}
+
+// MarkRead đánh dấu đã đọc tới messageID và tính lại số chưa đọc. Không lùi lại nếu đã đọc tới tin mới hơn;
+// trả về false khi không có gì thay đổi.
+func (r *repository) MarkRead(ctx context.Context, conversationID, userID, messageID primitive.ObjectID, at time.Time) (bool, error) {
+	unread, err := r.messages.CountDocuments(ctx, bson.M{
+		"conversationId": conversationID,
+		"_id":            bson.M{"$gt": messageID},
+		"senderId":       bson.M{"$ne": userID},
+	})
+	if err != nil {
+		return false, err
+	}
+
+	res, err := r.conversations.UpdateOne(ctx,
+		bson.M{
+			"_id": conversationID,
+			"members": bson.M{"$elemMatch": bson.M{
+				"userId": userID,
+				"$or": bson.A{
+					bson.M{"lastReadMessageId": bson.M{"$exists": false}},
+					bson.M{"lastReadMessageId": bson.M{"$lt": messageID}},
+				},
+			}},
+		},
+		bson.M{"$set": bson.M{
+			"members.$.lastReadMessageId": messageID,
+			"members.$.lastReadAt":        at,
+			"members.$.unreadCount":       unread,
+		}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount > 0, nil
+}
@@ -0,0 +1,73 @@
+package messaging
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"socialnetwork/models"
+)
+
+// Tin nhắn chờ: hội thoại do người lạ (không phải bạn bè, mình không theo dõi) bắt đầu.
+// Người nhận chấp nhận để chuyển vào hộp thư, từ chối để chuyển vào spam, hoặc chặn luôn người gửi.
+
+// request trả về hội thoại đang nằm trong tin nhắn chờ/spam của user
+func (s *service) request(ctx context.Context, userID, conversationID primitive.ObjectID) (*models.Conversation, *models.ConversationMember, error) {
+	conv, err := s.conversation(ctx, userID, conversationID)
+	if err != nil {
+		return nil, nil, err
+	}
+	member := conv.Member(userID)
+	if member.InFolder() == models.FolderInbox {
+		return nil, nil, ErrNotARequest
+	}
+	return conv, member, nil
+}
+
+// AcceptRequest chuyển hội thoại vào hộp thư; từ giờ người gửi thấy trạng thái đã xem/đang soạn
+func (s *service) AcceptRequest(ctx context.Context, userID, conversationID primitive.ObjectID) (*ConversationView, error) {
+	conv, member, err := s.request(ctx, userID, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.SetMemberFolder(ctx, conversationID, userID, models.FolderInbox); err != nil {
+		return nil, err
+	}
+	member.Folder = models.FolderInbox
+	return view(conv, userID), nil
+}
+
+// DeclineRequest chuyển hội thoại vào spam; người gửi không được báo và tin nhắn sau đó cũng vào spam
+func (s *service) DeclineRequest(ctx context.Context, userID, conversationID primitive.ObjectID) error {
+	_, member, err := s.request(ctx, userID, conversationID)
+	if err != nil {
+		return err
+	}
+	if member.InFolder() == models.FolderSpam {
+		return nil
+	}
+	return s.repo.SetMemberFolder(ctx, conversationID, userID, models.FolderSpam)
+}
+
+// BlockRequest chặn người đã nhắn (hoặc người đã thêm mình vào nhóm) rồi chuyển hội thoại vào spam;
+// với nhóm thì rời luôn nhóm
+func (s *service) BlockRequest(ctx context.Context, userID, conversationID primitive.ObjectID) error {
+	conv, member, err := s.request(ctx, userID, conversationID)
+	if err != nil {
+		return err
+	}
+
+	target := conv.CreatedBy
+	if member.InvitedBy != nil {
+		target = *member.InvitedBy
+	}
+	if target != userID {
+		if err := s.blocker.BlockUser(ctx, userID, target); err != nil {
+			return err
+		}
+	}
+
+	if conv.Type == models.ConversationGroup {
+		return s.Leave(ctx, userID, conversationID)
+	}
+	return s.repo.SetMemberFolder(ctx, conversationID, userID, models.FolderSpam)
+}
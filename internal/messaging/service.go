@@ -0,0 +1,687 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"socialnetwork/dto/request"
+	"socialnetwork/internal/follow"
+	"socialnetwork/internal/user"
+	"socialnetwork/models"
+)
+
+var (
+	ErrConversationNotFound = errors.New("không tìm thấy cuộc trò chuyện")
+	ErrMessageNotFound      = errors.New("không tìm thấy tin nhắn")
+	ErrUserNotFound         = errors.New("người dùng không tồn tại")
+	ErrNotAllowed           = errors.New("bạn không có quyền thực hiện thao tác này")
+	ErrBlocked              = errors.New("không thể nhắn tin với người dùng này")
+	ErrFriendsOnly          = errors.New("người dùng này chỉ nhận tin nhắn từ bạn bè")
+	ErrSelfConversation     = errors.New("không thể trò chuyện với chính mình")
+	ErrEmptyMessage         = errors.New("tin nhắn phải có nội dung hoặc tệp đính kèm")
+	ErrEditWindowExpired    = errors.New("đã quá thời gian cho phép sửa tin nhắn")
+	ErrMessageDeleted       = errors.New("tin nhắn đã bị thu hồi")
+	ErrTooManyMembers       = errors.New("nhóm đã đạt số thành viên tối đa")
+	ErrDirectConversation   = errors.New("không thể thêm hoặc rời khỏi cuộc trò chuyện trực tiếp")
+	ErrInvalidFolder        = errors.New("thư mục không hợp lệ")
+	ErrNotARequest          = errors.New("cuộc trò chuyện không nằm trong tin nhắn chờ")
+)
+
+// Blocker chặn user (user.Service) để ghi nhận vào nhật ký bảo mật như chặn từ trang cá nhân
+type Blocker interface {
+	BlockUser(ctx context.Context, userID, targetID primitive.ObjectID) error
+}
+
+type Config struct {
+	EditWindow      time.Duration // thời gian được sửa tin nhắn sau khi gửi
+	MaxGroupMembers int
+}
+
+// ConversationView - hội thoại kèm số tin chưa đọc của người xem
+type ConversationView struct {
+	models.Conversation
+	Folder      string `json:"folder"`
+	UnreadCount int64  `json:"unreadCount"`
+}
+
+// UnreadSummary - số chưa đọc trong hộp thư chính; Requests là số tin nhắn chờ chưa đọc
+type UnreadSummary struct {
+	Messages      int64 `json:"messages"`
+	Conversations int64 `json:"conversations"`
+	Requests      int64 `json:"requests"`
+}
+
+type Service interface {
+	StartDirect(ctx context.Context, userID, otherID primitive.ObjectID) (*ConversationView, error)
+	CreateGroup(ctx context.Context, userID primitive.ObjectID, req request.CreateGroupConversationRequest) (*ConversationView, error)
+	AddMembers(ctx context.Context, userID, conversationID primitive.ObjectID, req request.AddConversationMembersRequest) (*ConversationView, error)
+	Leave(ctx context.Context, userID, conversationID primitive.ObjectID) error
+	List(ctx context.Context, userID primitive.ObjectID, folder string, before *time.Time, limit int) ([]ConversationView, error)
+	Get(ctx context.Context, userID, conversationID primitive.ObjectID) (*ConversationView, error)
+	Unread(ctx context.Context, userID primitive.ObjectID) (*UnreadSummary, error)
+	AcceptRequest(ctx context.Context, userID, conversationID primitive.ObjectID) (*ConversationView, error)
+	DeclineRequest(ctx context.Context, userID, conversationID primitive.ObjectID) error
+	BlockRequest(ctx context.Context, userID, conversationID primitive.ObjectID) error
+
+	Messages(ctx context.Context, userID, conversationID, before primitive.ObjectID, limit int) ([]models.Message, error)
+	Send(ctx context.Context, userID, conversationID primitive.ObjectID, req request.SendMessageRequest) (*models.Message, error)
+	Edit(ctx context.Context, userID, messageID primitive.ObjectID, req request.EditMessageRequest) (*models.Message, error)
+	Delete(ctx context.Context, userID, messageID primitive.ObjectID) error
+	MarkRead(ctx context.Context, userID, conversationID, messageID primitive.ObjectID) error
+	Typing(ctx context.Context, userID, conversationID primitive.ObjectID) error
+}
+
+type service struct {
+	repo       Repository
+	userRepo   user.Repository
+	followRepo follow.FollowRepository
+	blocker    Blocker
+	hub        *Hub
+	config     Config
+}
+
+func NewService(repo Repository, userRepo user.Repository, followRepo follow.FollowRepository, blocker Blocker, hub *Hub, config Config) Service {
+	if config.EditWindow <= 0 {
+		config.EditWindow = 15 * time.Minute
+	}
+	if config.MaxGroupMembers <= 0 {
+		config.MaxGroupMembers = 250
+	}
+	return &service{
+		repo:       repo,
+		userRepo:   userRepo,
+		followRepo: followRepo,
+		blocker:    blocker,
+		hub:        hub,
+		config:     config,
+	}
+}
+
+// Kích thước trang mặc định; tối đa gấp đôi
+const (
+	conversationPageSize = 20
+	messagePageSize      = 30
+)
+
+func clampLimit(limit, def int) int {
+	if limit <= 0 || limit > 2*def {
+		return def
+	}
+	return limit
+}
+
+func view(conv *models.Conversation, userID primitive.ObjectID) *ConversationView {
+	v := &ConversationView{Conversation: *conv}
+	if m := conv.Member(userID); m != nil {
+		v.Folder = m.InFolder()
+		v.UnreadCount = m.UnreadCount
+	}
+	return v
+}
+
+func directKey(a, b primitive.ObjectID) string {
+	x, y := a.Hex(), b.Hex()
+	if x > y {
+		x, y = y, x
+	}
+	return x + ":" + y
+}
+
+func containsID(ids []primitive.ObjectID, id primitive.ObjectID) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
+
+func memberIDs(conv *models.Conversation) []primitive.ObjectID {
+	ids := make([]primitive.ObjectID, 0, len(conv.Members))
+	for _, m := range conv.Members {
+		ids = append(ids, m.UserID)
+	}
+	return ids
+}
+
+// recipients - thành viên nhận sự kiện realtime; người đã từ chối (spam) không được báo
+func recipients(conv *models.Conversation) []primitive.ObjectID {
+	ids := make([]primitive.ObjectID, 0, len(conv.Members))
+	for _, m := range conv.Members {
+		if m.InFolder() != models.FolderSpam {
+			ids = append(ids, m.UserID)
+		}
+	}
+	return ids
+}
+
+func parseIDs(hexIDs []string) ([]primitive.ObjectID, error) {
+	ids := make([]primitive.ObjectID, 0, len(hexIDs))
+	for _, h := range hexIDs {
+		id, err := primitive.ObjectIDFromHex(h)
+		if err != nil {
+			return nil, ErrUserNotFound
+		}
+		if !containsID(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+func (s *service) findUser(ctx context.Context, id primitive.ObjectID) (*models.User, error) {
+	u, err := s.userRepo.GetByID(ctx, id)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if u.DeletedAt != nil || u.DeactivatedAt != nil {
+		return nil, ErrUserNotFound
+	}
+	return u, nil
+}
+
+// canMessage - chặn theo cả hai chiều, và người nhận đặt "chỉ bạn bè" thì người gửi phải là bạn
+func canMessage(sender, recipient *models.User) error {
+	if containsID(sender.BlockedUsers, recipient.ID) || containsID(recipient.BlockedUsers, sender.ID) {
+		return ErrBlocked
+	}
+	if recipient.MessagePrivacy == models.MessagePrivacyFriends && !containsID(recipient.Friends, sender.ID) {
+		return ErrFriendsOnly
+	}
+	return nil
+}
+
+func (s *service) checkPair(ctx context.Context, senderID, recipientID primitive.ObjectID) (*models.User, error) {
+	sender, err := s.findUser(ctx, senderID)
+	if err != nil {
+		return nil, err
+	}
+	recipient, err := s.findUser(ctx, recipientID)
+	if err != nil {
+		return nil, err
+	}
+	return recipient, canMessage(sender, recipient)
+}
+
+// folderFor - người nhận đã kết bạn hoặc đang theo dõi người gửi thì hội thoại vào thẳng hộp thư,
+// ngược lại vào tin nhắn chờ
+func (s *service) folderFor(ctx context.Context, senderID primitive.ObjectID, recipient *models.User) (string, error) {
+	if containsID(recipient.Friends, senderID) {
+		return models.FolderInbox, nil
+	}
+	following, err := s.followRepo.IsFollowing(ctx, recipient.ID, senderID)
+	if err != nil {
+		return "", err
+	}
+	if following {
+		return models.FolderInbox, nil
+	}
+	return models.FolderRequests, nil
+}
+
+// memberUsers tải hồ sơ thành viên hiện có; tài khoản đã xoá/vô hiệu hoá thì bỏ qua
+func (s *service) memberUsers(ctx context.Context, ids []primitive.ObjectID) ([]*models.User, error) {
+	users := make([]*models.User, 0, len(ids))
+	for _, id := range ids {
+		u, err := s.findUser(ctx, id)
+		if errors.Is(err, ErrUserNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+	return users, nil
+}
+
+// newMember kiểm tra người được mời với mọi thành viên trong hội thoại (members, gồm cả người mời):
+// chặn theo cả hai chiều và cài đặt "chỉ bạn bè" của người được mời. Thư mục xếp theo người mời.
+func (s *service) newMember(ctx context.Context, inviterID, userID primitive.ObjectID, role string, now time.Time, members []*models.User) (models.ConversationMember, *models.User, error) {
+	recipient, err := s.findUser(ctx, userID)
+	if err != nil {
+		return models.ConversationMember{}, nil, err
+	}
+	for _, m := range members {
+		if err := canMessage(m, recipient); err != nil {
+			return models.ConversationMember{}, nil, err
+		}
+	}
+	folder, err := s.folderFor(ctx, inviterID, recipient)
+	if err != nil {
+		return models.ConversationMember{}, nil, err
+	}
+	return models.ConversationMember{UserID: userID, Role: role, Folder: folder, InvitedBy: &inviterID, JoinedAt: now}, recipient, nil
+}
+
+// conversation trả về hội thoại mà user là thành viên; không phải thành viên thì coi như không tồn tại
+func (s *service) conversation(ctx context.Context, userID, conversationID primitive.ObjectID) (*models.Conversation, error) {
+	conv, err := s.repo.FindConversation(ctx, conversationID)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrConversationNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if conv.Member(userID) == nil {
+		return nil, ErrConversationNotFound
+	}
+	return conv, nil
+}
+
+func (s *service) StartDirect(ctx context.Context, userID, otherID primitive.ObjectID) (*ConversationView, error) {
+	if userID == otherID {
+		return nil, ErrSelfConversation
+	}
+	key := directKey(userID, otherID)
+	conv, err := s.repo.FindDirect(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if conv != nil {
+		if _, err := s.checkPair(ctx, userID, otherID); err != nil {
+			return nil, err
+		}
+		// Chủ động mở lại hội thoại đang ở tin nhắn chờ/spam coi như chấp nhận
+		if m := conv.Member(userID); m.InFolder() != models.FolderInbox {
+			if err := s.repo.SetMemberFolder(ctx, conv.ID, userID, models.FolderInbox); err != nil {
+				return nil, err
+			}
+			m.Folder = models.FolderInbox
+		}
+		return view(conv, userID), nil
+	}
+
+	sender, err := s.findUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	other, _, err := s.newMember(ctx, userID, otherID, "", now, []*models.User{sender})
+	if err != nil {
+		return nil, err
+	}
+	conv = &models.Conversation{
+		Type:      models.ConversationDirect,
+		DirectKey: key,
+		CreatedBy: userID,
+		Members: []models.ConversationMember{
+			{UserID: userID, Folder: models.FolderInbox, JoinedAt: now},
+			other,
+		},
+	}
+	if err := s.repo.CreateConversation(ctx, conv); err != nil {
+		// Hai bên mở hội thoại cùng lúc: dùng hội thoại bên kia vừa tạo
+		if mongo.IsDuplicateKeyError(err) {
+			if existing, err := s.repo.FindDirect(ctx, key); err == nil && existing != nil {
+				return view(existing, userID), nil
+			}
+		}
+		return nil, err
+	}
+	return view(conv, userID), nil
+}
+
+func (s *service) CreateGroup(ctx context.Context, userID primitive.ObjectID, req request.CreateGroupConversationRequest) (*ConversationView, error) {
+	ids, err := parseIDs(req.MemberIDs)
+	if err != nil {
+		return nil, err
+	}
+	if len(ids)+1 > s.config.MaxGroupMembers {
+		return nil, ErrTooManyMembers
+	}
+
+	creator, err := s.findUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	members := []models.ConversationMember{{UserID: userID, Role: models.ConversationRoleAdmin, Folder: models.FolderInbox, JoinedAt: now}}
+	users := []*models.User{creator}
+	for _, id := range ids {
+		if id == userID {
+			continue
+		}
+		member, u, err := s.newMember(ctx, userID, id, models.ConversationRoleMember, now, users)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, member)
+		users = append(users, u)
+	}
+
+	conv := &models.Conversation{
+		Type:      models.ConversationGroup,
+		Title:     strings.TrimSpace(req.Title),
+		CreatedBy: userID,
+		Members:   members,
+	}
+	if err := s.repo.CreateConversation(ctx, conv); err != nil {
+		return nil, err
+	}
+	s.hub.Publish(ctx, recipients(conv), Event{Type: EventConversationUpdated, ConversationID: conv.ID, Data: conv})
+	return view(conv, userID), nil
+}
+
+// AddMembers - chỉ admin của nhóm được thêm người; người được thêm phải nhắn tin được với mọi thành viên
+func (s *service) AddMembers(ctx context.Context, userID, conversationID primitive.ObjectID, req request.AddConversationMembersRequest) (*ConversationView, error) {
+	conv, err := s.conversation(ctx, userID, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	if conv.Type != models.ConversationGroup {
+		return nil, ErrDirectConversation
+	}
+	if conv.Member(userID).Role != models.ConversationRoleAdmin {
+		return nil, ErrNotAllowed
+	}
+
+	ids, err := parseIDs(req.MemberIDs)
+	if err != nil {
+		return nil, err
+	}
+	users, err := s.memberUsers(ctx, memberIDs(conv))
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	var added []models.ConversationMember
+	for _, id := range ids {
+		if conv.Member(id) != nil {
+			continue
+		}
+		member, u, err := s.newMember(ctx, userID, id, models.ConversationRoleMember, now, users)
+		if err != nil {
+			return nil, err
+		}
+		added = append(added, member)
+		users = append(users, u)
+	}
+	if len(added) == 0 {
+		return view(conv, userID), nil
+	}
+	if len(conv.Members)+len(added) > s.config.MaxGroupMembers {
+		return nil, ErrTooManyMembers
+	}
+
+	if err := s.repo.AddMembers(ctx, conversationID, added); err != nil {
+		return nil, err
+	}
+	conv.Members = append(conv.Members, added...)
+	conv.UpdatedAt = now
+	s.hub.Publish(ctx, recipients(conv), Event{Type: EventConversationUpdated, ConversationID: conv.ID, Data: conv})
+	return view(conv, userID), nil
+}
+
+// Leave rời nhóm; admin cuối cùng rời thì thành viên vào nhóm sớm nhất được làm admin
+func (s *service) Leave(ctx context.Context, userID, conversationID primitive.ObjectID) error {
+	conv, err := s.conversation(ctx, userID, conversationID)
+	if err != nil {
+		return err
+	}
+	if conv.Type != models.ConversationGroup {
+		return ErrDirectConversation
+	}
+	if err := s.repo.RemoveMember(ctx, conversationID, userID); err != nil {
+		return err
+	}
+
+	remaining := make([]models.ConversationMember, 0, len(conv.Members))
+	hasAdmin := false
+	for _, m := range conv.Members {
+		if m.UserID == userID {
+			continue
+		}
+		hasAdmin = hasAdmin || m.Role == models.ConversationRoleAdmin
+		remaining = append(remaining, m)
+	}
+	if !hasAdmin && len(remaining) > 0 {
+		heir := &remaining[0]
+		for i := range remaining {
+			if remaining[i].JoinedAt.Before(heir.JoinedAt) {
+				heir = &remaining[i]
+			}
+		}
+		if err := s.repo.SetMemberRole(ctx, conversationID, heir.UserID, models.ConversationRoleAdmin); err != nil {
+			return err
+		}
+		heir.Role = models.ConversationRoleAdmin
+	}
+
+	conv.Members = remaining
+	s.hub.Publish(ctx, append(recipients(conv), userID), Event{Type: EventConversationUpdated, ConversationID: conv.ID, Data: conv})
+	return nil
+}
+
+func (s *service) List(ctx context.Context, userID primitive.ObjectID, folder string, before *time.Time, limit int) ([]ConversationView, error) {
+	switch folder {
+	case "":
+		folder = models.FolderInbox
+	case models.FolderInbox, models.FolderRequests, models.FolderSpam:
+	default:
+		return nil, ErrInvalidFolder
+	}
+	convs, err := s.repo.ListConversations(ctx, userID, folder, before, int64(clampLimit(limit, conversationPageSize)))
+	if err != nil {
+		return nil, err
+	}
+	views := make([]ConversationView, 0, len(convs))
+	for i := range convs {
+		views = append(views, *view(&convs[i], userID))
+	}
+	return views, nil
+}
+
+func (s *service) Get(ctx context.Context, userID, conversationID primitive.ObjectID) (*ConversationView, error) {
+	conv, err := s.conversation(ctx, userID, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	return view(conv, userID), nil
+}
+
+func (s *service) Unread(ctx context.Context, userID primitive.ObjectID) (*UnreadSummary, error) {
+	messages, conversations, err := s.repo.UnreadTotal(ctx, userID, models.FolderInbox)
+	if err != nil {
+		return nil, err
+	}
+	_, requests, err := s.repo.UnreadTotal(ctx, userID, models.FolderRequests)
+	if err != nil {
+		return nil, err
+	}
+	return &UnreadSummary{Messages: messages, Conversations: conversations, Requests: requests}, nil
+}
+
+func (s *service) Messages(ctx context.Context, userID, conversationID, before primitive.ObjectID, limit int) ([]models.Message, error) {
+	if _, err := s.conversation(ctx, userID, conversationID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListMessages(ctx, conversationID, before, int64(clampLimit(limit, messagePageSize)))
+}
+
+func (s *service) Send(ctx context.Context, userID, conversationID primitive.ObjectID, req request.SendMessageRequest) (*models.Message, error) {
+	text := strings.TrimSpace(req.Text)
+	if text == "" && len(req.Attachments) == 0 {
+		return nil, ErrEmptyMessage
+	}
+
+	conv, err := s.conversation(ctx, userID, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	// Hội thoại trực tiếp: kiểm tra lại mỗi lần gửi vì có thể đã bị chặn/đổi quyền riêng tư sau khi bắt đầu
+	if conv.Type == models.ConversationDirect {
+		for _, m := range conv.Members {
+			if m.UserID != userID {
+				if _, err := s.checkPair(ctx, userID, m.UserID); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	msg := &models.Message{
+		ConversationID: conversationID,
+		SenderID:       userID,
+		Text:           text,
+	}
+	for _, a := range req.Attachments {
+		msg.Attachments = append(msg.Attachments, models.MessageAttachment{
+			Type:     a.Type,
+			URL:      a.URL,
+			Name:     a.Name,
+			MimeType: a.MimeType,
+			Size:     a.Size,
+		})
+	}
+	// Trả lời tin nhắn chờ coi như chấp nhận
+	if m := conv.Member(userID); m.InFolder() != models.FolderInbox {
+		if err := s.repo.SetMemberFolder(ctx, conversationID, userID, models.FolderInbox); err != nil {
+			return nil, err
+		}
+		m.Folder = models.FolderInbox
+	}
+	if err := s.repo.InsertMessage(ctx, msg); err != nil {
+		return nil, err
+	}
+
+	s.hub.Publish(ctx, recipients(conv), Event{Type: EventMessageCreated, ConversationID: conversationID, Data: msg})
+	return msg, nil
+}
+
+// ownMessage trả về tin nhắn của chính user, còn user vẫn phải là thành viên hội thoại
+func (s *service) ownMessage(ctx context.Context, userID, messageID primitive.ObjectID) (*models.Message, *models.Conversation, error) {
+	msg, err := s.repo.FindMessage(ctx, messageID)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil, ErrMessageNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	conv, err := s.conversation(ctx, userID, msg.ConversationID)
+	if errors.Is(err, ErrConversationNotFound) {
+		return nil, nil, ErrMessageNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if msg.SenderID != userID {
+		return nil, nil, ErrNotAllowed
+	}
+	if msg.DeletedAt != nil {
+		return nil, nil, ErrMessageDeleted
+	}
+	return msg, conv, nil
+}
+
+func (s *service) Edit(ctx context.Context, userID, messageID primitive.ObjectID, req request.EditMessageRequest) (*models.Message, error) {
+	text := strings.TrimSpace(req.Text)
+	if text == "" {
+		return nil, ErrEmptyMessage
+	}
+	msg, conv, err := s.ownMessage(ctx, userID, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if time.Since(msg.CreatedAt) > s.config.EditWindow {
+		return nil, ErrEditWindowExpired
+	}
+
+	now := time.Now()
+	msg.Text = text
+	msg.EditedAt = &now
+	if err := s.repo.UpdateMessage(ctx, msg); err != nil {
+		return nil, err
+	}
+	s.hub.Publish(ctx, recipients(conv), Event{Type: EventMessageUpdated, ConversationID: conv.ID, Data: msg})
+	return msg, nil
+}
+
+// Delete thu hồi tin nhắn với mọi người: xoá nội dung và tệp đính kèm, giữ lại vị trí trong hội thoại
+func (s *service) Delete(ctx context.Context, userID, messageID primitive.ObjectID) error {
+	msg, conv, err := s.ownMessage(ctx, userID, messageID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	msg.Text = ""
+	msg.Attachments = nil
+	msg.DeletedAt = &now
+	if err := s.repo.UpdateMessage(ctx, msg); err != nil {
+		return err
+	}
+	s.hub.Publish(ctx, recipients(conv), Event{Type: EventMessageDeleted, ConversationID: conv.ID, Data: msg})
+	return nil
+}
+
+// MarkRead đánh dấu đã đọc tới messageID (rỗng = tin mới nhất) và gửi read receipt cho các thành viên
+func (s *service) MarkRead(ctx context.Context, userID, conversationID, messageID primitive.ObjectID) error {
+	conv, err := s.conversation(ctx, userID, conversationID)
+	if err != nil {
+		return err
+	}
+
+	if messageID.IsZero() {
+		if messageID, err = s.repo.LatestMessageID(ctx, conversationID); err != nil {
+			return err
+		}
+		if messageID.IsZero() {
+			return nil // chưa có tin nào
+		}
+	} else {
+		msg, err := s.repo.FindMessage(ctx, messageID)
+		if errors.Is(err, mongo.ErrNoDocuments) || (err == nil && msg.ConversationID != conversationID) {
+			return ErrMessageNotFound
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	now := time.Now()
+	changed, err := s.repo.MarkRead(ctx, conversationID, userID, messageID, now)
+	if err != nil || !changed {
+		return err
+	}
+	// Xem tin nhắn chờ không báo "đã xem" cho người gửi cho tới khi chấp nhận
+	if conv.Member(userID).InFolder() != models.FolderInbox {
+		return nil
+	}
+	s.hub.Publish(ctx, recipients(conv), Event{
+		Type:           EventConversationRead,
+		ConversationID: conversationID,
+		Data:           map[string]interface{}{"userId": userID, "messageId": messageID, "readAt": now},
+	})
+	return nil
+}
+
+// Typing báo "đang soạn tin" cho các thành viên khác; không lưu lại
+func (s *service) Typing(ctx context.Context, userID, conversationID primitive.ObjectID) error {
+	conv, err := s.conversation(ctx, userID, conversationID)
+	if err != nil {
+		return err
+	}
+	if conv.Member(userID).InFolder() != models.FolderInbox {
+		return nil
+	}
+	others := make([]primitive.ObjectID, 0, len(conv.Members))
+	for _, id := range recipients(conv) {
+		if id != userID {
+			others = append(others, id)
+		}
+	}
+	s.hub.Publish(ctx, others, Event{
+		Type:           EventTyping,
+		ConversationID: conversationID,
+		Data:           map[string]interface{}{"userId": userID},
+	})
+	return nil
+}
@@ -0,0 +1,200 @@
+package moderation
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"socialnetwork/models"
+)
+
+// QueueItem - các báo cáo của cùng một nội dung/user, gom lại cho hàng đợi kiểm duyệt
+type QueueItem struct {
+	TargetType      models.ReportTargetType `bson:"targetType" json:"targetType"`
+	TargetID        primitive.ObjectID      `bson:"targetId" json:"targetId"`
+	TargetOwnerID   primitive.ObjectID      `bson:"targetOwnerId" json:"targetOwnerId"`
+	ReportCount     int                     `bson:"reportCount" json:"reportCount"`
+	Reasons         []models.ReportReason   `bson:"reasons" json:"reasons"`
+	FirstReportedAt time.Time               `bson:"firstReportedAt" json:"firstReportedAt"`
+	LastReportedAt  time.Time               `bson:"lastReportedAt" json:"lastReportedAt"`
+	Hidden          bool                    `bson:"-" json:"hidden"`
+}
+
+// target - nơi lưu nội dung bị báo cáo (tên field khác nhau giữa các collection)
+type target struct {
+	collection string
+	ownerField string
+	deletedAt  string
+	hiddenFlag string
+}
+
+var targets = map[models.ReportTargetType]target{
+	models.ReportTargetPost:    {"posts", "user_id", "deleted_at", "moderation_hidden"},
+	models.ReportTargetComment: {"comments", "user_id", "deleted_at", "moderation_hidden"},
+	models.ReportTargetVideo:   {"videos", "ownerId", "deletedAt", "moderationHidden"},
+	models.ReportTargetShort:   {"shorts", "ownerId", "deletedAt", "moderationHidden"},
+	models.ReportTargetUser:    {"users", "_id", "deletedAt", ""},
+}
+
+type Repository interface {
+	Create(ctx context.Context, report *models.Report) error
+	HasOpenReport(ctx context.Context, reporterID primitive.ObjectID, targetType models.ReportTargetType, targetID primitive.ObjectID) (bool, error)
+	CountOpen(ctx context.Context, targetType models.ReportTargetType, targetID primitive.ObjectID) (int64, error)
+	FindByTarget(ctx context.Context, targetType models.ReportTargetType, targetID primitive.ObjectID) ([]models.Report, error)
+	ListQueue(ctx context.Context, status models.ReportStatus, skip, limit int64) ([]QueueItem, error)
+	Resolve(ctx context.Context, targetType models.ReportTargetType, targetID primitive.ObjectID, status models.ReportStatus, action models.ModerationAction, note string, moderatorID primitive.ObjectID) ([]models.Report, error)
+
+	FindTargetOwner(ctx context.Context, targetType models.ReportTargetType, targetID primitive.ObjectID) (primitive.ObjectID, error)
+	IsHidden(ctx context.Context, targetType models.ReportTargetType, targetID primitive.ObjectID) (bool, error)
+	SetHidden(ctx context.Context, targetType models.ReportTargetType, targetID primitive.ObjectID, hidden bool) error
+}
+
+type repository struct {
+	db         *mongo.Database
+	collection *mongo.Collection
+}
+
+func NewRepository(db *mongo.Database) Repository {
+	return &repository{db: db, collection: db.Collection("reports")}
+}
+
+func (r *repository) Create(ctx context.Context, report *models.Report) error {
+	report.ID = primitive.NewObjectID()
+	report.Status = models.ReportOpen
+	report.CreatedAt = time.Now()
+	_, err := r.collection.InsertOne(ctx, report)
+	return err
+}
+
+// HasOpenReport - user đã báo cáo nội dung này và báo cáo chưa được xử lý
+func (r *repository) HasOpenReport(ctx context.Context, reporterID primitive.ObjectID, targetType models.ReportTargetType, targetID primitive.ObjectID) (bool, error) {
+	n, err := r.collection.CountDocuments(ctx, bson.M{
+		"reporterId": reporterID,
+		"targetType": targetType,
+		"targetId":   targetID,
+		"status":     models.ReportOpen,
+	})
+	return n > 0, err
+}
+
+// CountOpen - số báo cáo chưa xử lý (mỗi người tối đa một báo cáo mở cho một nội dung)
+func (r *repository) CountOpen(ctx context.Context, targetType models.ReportTargetType, targetID primitive.ObjectID) (int64, error) {
+	return r.collection.CountDocuments(ctx, bson.M{
+		"targetType": targetType,
+		"targetId":   targetID,
+		"status":     models.ReportOpen,
+	})
+}
+
+func (r *repository) FindByTarget(ctx context.Context, targetType models.ReportTargetType, targetID primitive.ObjectID) ([]models.Report, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}})
+	cursor, err := r.collection.Find(ctx, bson.M{"targetType": targetType, "targetId": targetID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	reports := []models.Report{}
+	err = cursor.All(ctx, &reports)
+	return reports, err
+}
+
+// ListQueue gom báo cáo theo nội dung, nhiều báo cáo nhất lên đầu
+func (r *repository) ListQueue(ctx context.Context, status models.ReportStatus, skip, limit int64) ([]QueueItem, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"status": status}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":             bson.M{"targetType": "$targetType", "targetId": "$targetId"},
+			"targetOwnerId":   bson.M{"$first": "$targetOwnerId"},
+			"reportCount":     bson.M{"$sum": 1},
+			"reasons":         bson.M{"$addToSet": "$reason"},
+			"firstReportedAt": bson.M{"$min": "$createdAt"},
+			"lastReportedAt":  bson.M{"$max": "$createdAt"},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "reportCount", Value: -1}, {Key: "firstReportedAt", Value: 1}}}},
+		{{Key: "$skip", Value: skip}},
+		{{Key: "$limit", Value: limit}},
+		{{Key: "$addFields", Value: bson.M{"targetType": "$_id.targetType", "targetId": "$_id.targetId"}}},
+	}
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	items := []QueueItem{}
+	err = cursor.All(ctx, &items)
+	return items, err
+}
+
+// Resolve đóng toàn bộ báo cáo mở của nội dung, trả về các báo cáo vừa được đóng
+func (r *repository) Resolve(ctx context.Context, targetType models.ReportTargetType, targetID primitive.ObjectID, status models.ReportStatus, action models.ModerationAction, note string, moderatorID primitive.ObjectID) ([]models.Report, error) {
+	filter := bson.M{"targetType": targetType, "targetId": targetID, "status": models.ReportOpen}
+	cursor, err := r.collection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	var reports []models.Report
+	if err := cursor.All(ctx, &reports); err != nil {
+		return nil, err
+	}
+	if len(reports) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]primitive.ObjectID, len(reports))
+	for i, rep := range reports {
+		ids[i] = rep.ID
+	}
+	set := bson.M{
+		"status":     status,
+		"action":     action,
+		"resolvedBy": moderatorID,
+		"resolvedAt": time.Now(),
+	}
+	if note != "" {
+		set["note"] = note
+	}
+	// Chỉ cập nhật các báo cáo đã đọc ở trên để báo cáo mới đến trong lúc xử lý vẫn ở trạng thái mở
+	_, err = r.collection.UpdateMany(ctx, bson.M{"_id": bson.M{"$in": ids}, "status": models.ReportOpen}, bson.M{"$set": set})
+	return reports, err
+}
+
+// FindTargetOwner trả về chủ của nội dung (hoặc chính user), ErrNoDocuments nếu đã bị xoá
+func (r *repository) FindTargetOwner(ctx context.Context, targetType models.ReportTargetType, targetID primitive.ObjectID) (primitive.ObjectID, error) {
+	t := targets[targetType]
+	var doc bson.M
+	opts := options.FindOne().SetProjection(bson.M{t.ownerField: 1})
+	err := r.db.Collection(t.collection).FindOne(ctx, bson.M{"_id": targetID, t.deletedAt: nil}, opts).Decode(&doc)
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+	owner, _ := doc[t.ownerField].(primitive.ObjectID)
+	return owner, nil
+}
+
+func (r *repository) IsHidden(ctx context.Context, targetType models.ReportTargetType, targetID primitive.ObjectID) (bool, error) {
+	t := targets[targetType]
+	if t.hiddenFlag == "" {
+		return false, nil
+	}
+	n, err := r.db.Collection(t.collection).CountDocuments(ctx, bson.M{"_id": targetID, t.hiddenFlag: true})
+	return n > 0, err
+}
+
+// SetHidden ẩn/hiện nội dung; không áp dụng cho user
+func (r *repository) SetHidden(ctx context.Context, targetType models.ReportTargetType, targetID primitive.ObjectID, hidden bool) error {
+	t := targets[targetType]
+	if t.hiddenFlag == "" {
+		return nil
+	}
+	update := bson.M{"$unset": bson.M{t.hiddenFlag: ""}}
+	if hidden {
+		update = bson.M{"$set": bson.M{t.hiddenFlag: true}}
+	}
+	_, err := r.db.Collection(t.collection).UpdateOne(ctx, bson.M{"_id": targetID}, update)
+	return err
+}
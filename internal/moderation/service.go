@@ -0,0 +1,311 @@
+package moderation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"socialnetwork/dto/request"
+	"socialnetwork/internal/audit"
+	"socialnetwork/internal/notification"
+	"socialnetwork/internal/user"
+	"socialnetwork/models"
+	"socialnetwork/pkg/auth"
+)
+
+var (
+	ErrInvalidTarget   = errors.New("loại nội dung không hợp lệ")
+	ErrInvalidReason   = errors.New("lý do báo cáo không hợp lệ")
+	ErrInvalidAction   = errors.New("hành động không áp dụng được cho loại nội dung này")
+	ErrTargetNotFound  = errors.New("nội dung không tồn tại")
+	ErrSelfReport      = errors.New("không thể báo cáo chính mình")
+	ErrAlreadyReported = errors.New("bạn đã báo cáo nội dung này")
+	ErrNoOpenReports   = errors.New("không có báo cáo nào đang chờ xử lý")
+)
+
+var reasons = map[models.ReportReason]bool{
+	models.ReportReasonSpam:           true,
+	models.ReportReasonHarassment:     true,
+	models.ReportReasonHateSpeech:     true,
+	models.ReportReasonViolence:       true,
+	models.ReportReasonNudity:         true,
+	models.ReportReasonMisinformation: true,
+	models.ReportReasonImpersonation:  true,
+	models.ReportReasonOther:          true,
+}
+
+type Config struct {
+	AutoHideThreshold int64         // số người báo cáo để tự động ẩn nội dung chờ kiểm duyệt
+	SuspendDuration   time.Duration // thời gian khoá mặc định khi không chỉ định số ngày
+}
+
+type Service interface {
+	Report(ctx context.Context, reporterID primitive.ObjectID, req request.CreateReportRequest) (*models.Report, error)
+	Queue(ctx context.Context, status models.ReportStatus, page, limit int) ([]QueueItem, error)
+	Reports(ctx context.Context, targetType string, targetID primitive.ObjectID) ([]models.Report, error)
+	Act(ctx context.Context, moderatorID primitive.ObjectID, targetType string, targetID primitive.ObjectID, req request.ModerationActionRequest) error
+}
+
+type service struct {
+	repo             Repository
+	userRepo         user.Repository
+	notificationRepo notification.NotificationRepository
+	sessions         *auth.SessionStore
+	audit            *audit.Logger
+	config           Config
+}
+
+func NewService(repo Repository, userRepo user.Repository, notificationRepo notification.NotificationRepository, sessions *auth.SessionStore, auditLog *audit.Logger, config Config) Service {
+	if config.AutoHideThreshold <= 0 {
+		config.AutoHideThreshold = 5
+	}
+	if config.SuspendDuration <= 0 {
+		config.SuspendDuration = 7 * 24 * time.Hour
+	}
+	return &service{
+		repo:             repo,
+		userRepo:         userRepo,
+		notificationRepo: notificationRepo,
+		sessions:         sessions,
+		audit:            auditLog,
+		config:           config,
+	}
+}
+
+func parseTarget(targetType string) (models.ReportTargetType, error) {
+	t := models.ReportTargetType(targetType)
+	if _, ok := targets[t]; !ok {
+		return "", ErrInvalidTarget
+	}
+	return t, nil
+}
+
+// Report ghi nhận báo cáo; nội dung đủ ngưỡng người báo cáo sẽ bị ẩn ngay chờ kiểm duyệt
+func (s *service) Report(ctx context.Context, reporterID primitive.ObjectID, req request.CreateReportRequest) (*models.Report, error) {
+	targetType, err := parseTarget(req.TargetType)
+	if err != nil {
+		return nil, err
+	}
+	targetID, err := primitive.ObjectIDFromHex(req.TargetID)
+	if err != nil {
+		return nil, ErrTargetNotFound
+	}
+	reason := models.ReportReason(req.Reason)
+	if !reasons[reason] {
+		return nil, ErrInvalidReason
+	}
+
+	ownerID, err := s.repo.FindTargetOwner(ctx, targetType, targetID)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrTargetNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if ownerID == reporterID {
+		return nil, ErrSelfReport
+	}
+
+	exists, err := s.repo.HasOpenReport(ctx, reporterID, targetType, targetID)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, ErrAlreadyReported
+	}
+
+	report := &models.Report{
+		ReporterID:    reporterID,
+		TargetType:    targetType,
+		TargetID:      targetID,
+		TargetOwnerID: ownerID,
+		Reason:        reason,
+		Details:       req.Details,
+	}
+	if err := s.repo.Create(ctx, report); err != nil {
+		return nil, err
+	}
+
+	if targetType != models.ReportTargetUser {
+		count, err := s.repo.CountOpen(ctx, targetType, targetID)
+		if err != nil {
+			return nil, err
+		}
+		if count >= s.config.AutoHideThreshold {
+			if err := s.repo.SetHidden(ctx, targetType, targetID, true); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return report, nil
+}
+
+func (s *service) Queue(ctx context.Context, status models.ReportStatus, page, limit int) ([]QueueItem, error) {
+	if status == "" {
+		status = models.ReportOpen
+	}
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	items, err := s.repo.ListQueue(ctx, status, int64((page-1)*limit), int64(limit))
+	if err != nil {
+		return nil, err
+	}
+	for i := range items {
+		hidden, err := s.repo.IsHidden(ctx, items[i].TargetType, items[i].TargetID)
+		if err != nil {
+			return nil, err
+		}
+		items[i].Hidden = hidden
+	}
+	return items, nil
+}
+
+func (s *service) Reports(ctx context.Context, targetType string, targetID primitive.ObjectID) ([]models.Report, error) {
+	t, err := parseTarget(targetType)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.FindByTarget(ctx, t, targetID)
+}
+
+// Act áp dụng quyết định kiểm duyệt lên nội dung/user và đóng toàn bộ báo cáo đang mở của nó
+func (s *service) Act(ctx context.Context, moderatorID primitive.ObjectID, targetType string, targetID primitive.ObjectID, req request.ModerationActionRequest) error {
+	t, err := parseTarget(targetType)
+	if err != nil {
+		return err
+	}
+	action := models.ModerationAction(req.Action)
+	if action == models.ModerationRemove && t == models.ReportTargetUser {
+		return ErrInvalidAction
+	}
+
+	count, err := s.repo.CountOpen(ctx, t, targetID)
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrNoOpenReports
+	}
+	reports, err := s.repo.FindByTarget(ctx, t, targetID)
+	if err != nil {
+		return err
+	}
+	ownerID := reports[0].TargetOwnerID
+
+	status := models.ReportResolved
+	var ownerMessage string
+	switch action {
+	case models.ModerationDismiss:
+		// Không vi phạm: hiện lại nội dung nếu đã bị ẩn tự động
+		status = models.ReportDismissed
+		if err := s.repo.SetHidden(ctx, t, targetID, false); err != nil {
+			return err
+		}
+	case models.ModerationRemove:
+		if err := s.repo.SetHidden(ctx, t, targetID, true); err != nil {
+			return err
+		}
+		ownerMessage = fmt.Sprintf("Một %s của bạn đã bị gỡ do vi phạm tiêu chuẩn cộng đồng.", targetLabel(t))
+	case models.ModerationWarn:
+		if err := s.userRepo.AddWarning(ctx, ownerID); err != nil {
+			return err
+		}
+		ownerMessage = fmt.Sprintf("Bạn bị cảnh cáo vì một %s vi phạm tiêu chuẩn cộng đồng.", targetLabel(t))
+	case models.ModerationSuspend:
+		duration := s.config.SuspendDuration
+		if req.SuspendDays > 0 {
+			duration = time.Duration(req.SuspendDays) * 24 * time.Hour
+		}
+		until := time.Now().Add(duration)
+		if err := s.userRepo.Suspend(ctx, ownerID, until); err != nil {
+			return err
+		}
+		// Thu hồi các JWT đã cấp; route ghi nội dung còn kiểm tra suspendedUntil phòng khi Redis lỗi
+		if err := s.sessions.RevokeAll(ctx, ownerID.Hex()); err != nil {
+			log.Printf("⚠️ không thu hồi được phiên của tài khoản bị khoá %s: %v", ownerID.Hex(), err)
+		}
+		ownerMessage = fmt.Sprintf("Tài khoản của bạn bị tạm khoá đến %s do vi phạm tiêu chuẩn cộng đồng.", until.Format("02/01/2006 15:04"))
+	default:
+		return ErrInvalidAction
+	}
+
+	resolved, err := s.repo.Resolve(ctx, t, targetID, status, action, req.Note, moderatorID)
+	if err != nil {
+		return err
+	}
+
+	metadata := map[string]interface{}{"action": string(action), "reports": len(resolved)}
+	if req.Note != "" {
+		metadata["note"] = req.Note
+	}
+	s.audit.Record(ctx, models.AuditEvent{
+		Action:     models.AuditModerationAction,
+		Success:    true,
+		UserID:     &ownerID,
+		ActorID:    &moderatorID,
+		TargetType: string(t),
+		TargetID:   targetID.Hex(),
+		Metadata:   metadata,
+	})
+
+	if ownerMessage != "" {
+		if req.Note != "" {
+			ownerMessage += " Ghi chú: " + req.Note
+		}
+		s.notify(ctx, ownerID, models.NotificationModeration, t, targetID, ownerMessage)
+	}
+
+	reporterMessage := "Cảm ơn bạn đã báo cáo. Chúng tôi đã xem xét và xử lý nội dung vi phạm."
+	if status == models.ReportDismissed {
+		reporterMessage = "Cảm ơn bạn đã báo cáo. Sau khi xem xét, nội dung này không vi phạm tiêu chuẩn cộng đồng."
+	}
+	for _, rep := range resolved {
+		if rep.ReporterID.IsZero() {
+			continue // báo cáo do bộ lọc tự động tạo
+		}
+		s.notify(ctx, rep.ReporterID, models.NotificationReport, t, targetID, reporterMessage)
+	}
+	return nil
+}
+
+func (s *service) notify(ctx context.Context, recipient primitive.ObjectID, kind models.NotificationType, t models.ReportTargetType, targetID primitive.ObjectID, message string) {
+	noti := &models.Notification{
+		Recipient: recipient,
+		Type:      kind,
+		Message:   message,
+		CreatedAt: time.Now(),
+	}
+	switch t {
+	case models.ReportTargetPost:
+		noti.PostID = &targetID
+	case models.ReportTargetVideo:
+		noti.VideoID = &targetID
+	case models.ReportTargetShort:
+		noti.ShortID = &targetID
+	}
+	if err := s.notificationRepo.Create(ctx, noti); err != nil {
+		log.Printf("⚠️ không tạo được thông báo kiểm duyệt cho %s: %v", recipient.Hex(), err)
+	}
+}
+
+func targetLabel(t models.ReportTargetType) string {
+	switch t {
+	case models.ReportTargetPost:
+		return "bài viết"
+	case models.ReportTargetComment:
+		return "bình luận"
+	case models.ReportTargetVideo:
+		return "video"
+	case models.ReportTargetShort:
+		return "short"
+	}
+	return "nội dung"
+}
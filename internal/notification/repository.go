@@ -14,6 +14,11 @@ type NotificationRepository interface {
 	GetByRecipient(ctx context.Context, userID primitive.ObjectID) ([]models.Notification, error)
 	Create(ctx context.Context, notif *models.Notification) error
 	MarkAsRead(ctx context.Context, notifID primitive.ObjectID) error
+	DeleteByVideo(ctx context.Context, videoID primitive.ObjectID) error
+	DeleteByShort(ctx context.Context, shortID primitive.ObjectID) error
+	DeleteByPost(ctx context.Context, postID primitive.ObjectID) error
+	DeleteByComment(ctx context.Context, commentID primitive.ObjectID) error
+	DeleteByUser(ctx context.Context, userID primitive.ObjectID) error
 }
 
 type notificationRepository struct {
@@ -60,4 +65,48 @@ func (r *notificationRepository) MarkAsRead(ctx context.Context, notifID primiti
 	update := bson.M{"$set": bson.M{"isRead": true}}
 	_, err := r.Collection.UpdateOne(ctx, filter, update)
 	return err
-}
\ No newline at end of file
+}
+
+func (r *notificationRepository) DeleteByVideo(ctx context.Context, videoID primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	_, err := r.Collection.DeleteMany(ctx, bson.M{"video": videoID})
+	return err
+}
+
+func (r *notificationRepository) DeleteByShort(ctx context.Context, shortID primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	_, err := r.Collection.DeleteMany(ctx, bson.M{"short": shortID})
+	return err
+}
+
+func (r *notificationRepository) DeleteByPost(ctx context.Context, postID primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	_, err := r.Collection.DeleteMany(ctx, bson.M{"post": postID})
+	return err
+}
+
+func (r *notificationRepository) DeleteByComment(ctx context.Context, commentID primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	_, err := r.Collection.DeleteMany(ctx, bson.M{"comment": commentID})
+	return err
+}
+
+// DeleteByUser xoá thông báo user nhận được hoặc do user tạo ra
+func (r *notificationRepository) DeleteByUser(ctx context.Context, userID primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	_, err := r.Collection.DeleteMany(ctx, bson.M{"$or": bson.A{
+		bson.M{"recipient": userID},
+		bson.M{"sender": userID},
+	}})
+	return err
+}
@@ -0,0 +1,40 @@
+package otp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"math/big"
+	"strings"
+)
+
+// generateCode sinh mã số ngẫu nhiên bằng crypto/rand, phân bố đều trên mọi chữ số
+func generateCode(length int) (string, error) {
+	var b strings.Builder
+	b.Grow(length)
+	ten := big.NewInt(10)
+	for i := 0; i < length; i++ {
+		n, err := rand.Int(rand.Reader, ten)
+		if err != nil {
+			return "", err
+		}
+		b.WriteByte(byte('0' + n.Int64()))
+	}
+	return b.String(), nil
+}
+
+// hashCode gắn mã với purpose và identifier: mã gửi cho mục đích/người này không dùng được cho nơi khác
+func hashCode(secret []byte, purpose, identifier, code string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(purpose))
+	mac.Write([]byte{0})
+	mac.Write([]byte(identifier))
+	mac.Write([]byte{0})
+	mac.Write([]byte(code))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func matchCode(secret []byte, purpose, identifier, code, stored string) bool {
+	return hmac.Equal([]byte(hashCode(secret, purpose, identifier, code)), []byte(stored))
+}
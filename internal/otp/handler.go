@@ -6,6 +6,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"socialnetwork/dto/request"
 	"socialnetwork/models"
+	"socialnetwork/pkg/ratelimit"
 )
 
 type OTPHandler struct {
@@ -32,6 +33,9 @@ func (h *OTPHandler) SendOTP(c *gin.Context) {
 		Channel:    req.Channel,
 	})
 	if err != nil {
+		if ratelimit.WriteError(c, err) {
+			return
+		}
 		// fmt.Printf("[DEBUG][SendOTP] Failed to send OTP: %v\n", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -58,6 +62,9 @@ func (h *OTPHandler) VerifyOTP(c *gin.Context) {
 		Channel:    req.Channel,
 	})
 	if err != nil {
+		if ratelimit.WriteError(c, err) {
+			return
+		}
 		// fmt.Printf("[DEBUG][VerifyOTP] OTP verification failed: %v\n", err)
 		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 		return
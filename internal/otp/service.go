@@ -2,30 +2,71 @@ package otp
 
 import (
 	"context"
+	"crypto/rand"
 	"errors"
 	"fmt"
-	"math/rand"
+	"log"
 	"strings"
 	"time"
 
 	"github.com/go-redis/redis/v8"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"socialnetwork/internal/audit"
 	"socialnetwork/models"
 	"socialnetwork/pkg/email"
+	"socialnetwork/pkg/ratelimit"
 	"socialnetwork/pkg/sms"
 	"socialnetwork/pkg/utils"
 )
 
+// Các purpose do hệ thống dùng nội bộ
+const (
+	PurposeVerify         = "verify"
+	PurposeForgotPassword = "forgot_password"
+	PurposeChangeEmail    = "change_email"
+	PurposeChangePhone    = "change_phone"
+	PurposeTwoFactor      = "two_factor"
+	PurposeLoginLink      = "login_link"
+	PurposeLoginCode      = "login_code"
+)
+
+var (
+	ErrInvalidCode    = errors.New("mã OTP không hợp lệ hoặc đã hết hạn")
+	ErrWrongCode      = errors.New("mã OTP không chính xác")
+	ErrInvalidPurpose = errors.New("mục đích OTP không hợp lệ")
+)
+
 type Service interface {
-	SaveOTP(ctx context.Context, key string, code string, duration time.Duration) error
-	VerifyOTP(ctx context.Context, req *models.VerifyOTPRequest) error
-	DeleteOTP(ctx context.Context, key string) error
 	SendOTP(ctx context.Context, req *models.SendOTPRequest) error
-	SendOTPWithCustomKey(ctx context.Context, req *models.SendOTPRequest) error
-	PeekIdentifierByCustomKey(ctx context.Context, key string) (string, error)
+	VerifyOTP(ctx context.Context, req *models.VerifyOTPRequest) error
 	SendForgotPasswordOTP(ctx context.Context, email string) error
 	SendRawEmail(ctx context.Context, to, subject, body string) error
-	GetRawOTP(ctx context.Context, key string) (string, error)
+	SendRawSMS(ctx context.Context, to, body string) error
+	// SendInternalOTP/VerifyInternalOTP dùng cho purpose nội bộ (vd: two_factor), không có hiệu ứng phụ như VerifyOTP
+	SendInternalOTP(ctx context.Context, req *models.SendOTPRequest) error
+	VerifyInternalOTP(ctx context.Context, req *models.VerifyOTPRequest) error
+	// Issue sinh mã mới cho (purpose, identifier), huỷ mã cũ; payload được trả lại khi Consume thành công
+	Issue(ctx context.Context, purpose, identifier, payload string) (string, Policy, error)
+	// Consume kiểm tra mã và xoá ngay khi đúng để mỗi mã chỉ dùng được một lần
+	Consume(ctx context.Context, purpose, identifier, code string) (string, error)
+}
+
+// Policy - độ dài và thời gian hiệu lực của mã cho từng purpose
+type Policy struct {
+	Length   int
+	TTL      time.Duration
+	Internal bool // chỉ phát hành từ luồng nội bộ, không qua /otp/send
+}
+
+type Config struct {
+	Secret          []byte            // khoá HMAC để băm mã, phải giống nhau giữa các instance
+	DefaultPolicy   Policy            // dùng cho purpose không có trong Policies
+	Policies        map[string]Policy // ghi đè theo purpose
+	ResendCooldown  time.Duration     // khoảng chờ tối thiểu giữa hai lần gửi cho cùng identifier
+	MaxSendsPerHour int               // số lần gửi tối đa mỗi giờ cho cùng identifier
+	MaxAttempts     int               // số lần nhập sai tối đa trước khi khoá
+	Lockout         time.Duration     // thời gian khoá xác thực sau khi nhập sai quá số lần
 }
 
 type service struct {
@@ -33,6 +74,9 @@ type service struct {
 	emailSender email.EmailSender
 	smsSender   sms.SMSSender
 	repo        OTPrepository
+	limiter     *ratelimit.Limiter
+	audit       *audit.Logger
+	config      Config
 }
 
 func normalizePhone(phone string) string {
@@ -42,144 +86,231 @@ func normalizePhone(phone string) string {
 	return phone
 }
 
-func NewService(redisClient *redis.Client, emailSender email.EmailSender, smsSender sms.SMSSender, repo OTPrepository) Service {
+func NewService(redisClient *redis.Client, emailSender email.EmailSender, smsSender sms.SMSSender, repo OTPrepository, auditLog *audit.Logger, config Config) Service {
+	if len(config.Secret) == 0 {
+		// Mã đã gửi sẽ mất hiệu lực khi restart và không dùng chung được giữa nhiều instance
+		log.Println("⚠️ OTP_SECRET chưa cấu hình, dùng khoá ngẫu nhiên tạm thời")
+		config.Secret = make([]byte, 32)
+		if _, err := rand.Read(config.Secret); err != nil {
+			log.Fatalf("❌ Không tạo được khoá OTP: %v", err)
+		}
+	}
+	if config.DefaultPolicy.Length <= 0 {
+		config.DefaultPolicy.Length = 6
+	}
+	if config.DefaultPolicy.TTL <= 0 {
+		config.DefaultPolicy.TTL = 5 * time.Minute
+	}
+	policies := map[string]Policy{
+		PurposeVerify:         {Length: 6, TTL: 10 * time.Minute},
+		PurposeForgotPassword: {Length: 6, TTL: 5 * time.Minute},
+		PurposeChangeEmail:    {Length: 6, TTL: 10 * time.Minute, Internal: true},
+		PurposeChangePhone:    {Length: 6, TTL: 10 * time.Minute, Internal: true},
+		PurposeTwoFactor:      {Length: 6, TTL: 5 * time.Minute, Internal: true},
+		PurposeLoginLink:      {Length: 32, TTL: 15 * time.Minute, Internal: true}, // token dài vì nằm trong URL, không phải gõ tay
+		PurposeLoginCode:      {Length: 6, TTL: 5 * time.Minute, Internal: true},
+	}
+	for purpose, p := range config.Policies {
+		policies[purpose] = p
+	}
+	for purpose, p := range policies {
+		if p.Length <= 0 {
+			p.Length = config.DefaultPolicy.Length
+		}
+		if p.TTL <= 0 {
+			p.TTL = config.DefaultPolicy.TTL
+		}
+		policies[purpose] = p
+	}
+	config.Policies = policies
+	if config.ResendCooldown <= 0 {
+		config.ResendCooldown = 60 * time.Second
+	}
+	if config.MaxSendsPerHour <= 0 {
+		config.MaxSendsPerHour = 5
+	}
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = 5
+	}
+	if config.Lockout <= 0 {
+		config.Lockout = 15 * time.Minute
+	}
 	return &service{
 		redisClient: redisClient,
 		emailSender: emailSender,
 		smsSender:   smsSender,
 		repo:        repo,
+		limiter:     ratelimit.New(redisClient),
+		audit:       auditLog,
+		config:      config,
 	}
 }
 
-func GenerateOTP(length int) string {
-	rand.Seed(time.Now().UnixNano())
-	format := "%06d"
-	max := 1000000
-	if length == 4 {
-		format = "%04d"
-		max = 10000
+func (s *service) policy(purpose string) Policy {
+	if p, ok := s.config.Policies[purpose]; ok {
+		return p
 	}
-	return fmt.Sprintf(format, rand.Intn(max))
+	return s.config.DefaultPolicy
 }
 
 func normalizeIdentifier(channel, identifier string) (string, error) {
 	if channel == "phone" {
-		formatted := utils.FormatPhoneToE164(identifier)
+		formatted := utils.NormalizePhone(identifier)
 		if !utils.IsValidPhoneE164(formatted) {
 			return "", errors.New("invalid phone number format")
 		}
 		return formatted, nil
 	}
-	return identifier, nil
+	return utils.NormalizeEmail(identifier), nil
 }
 
-func (s *service) SendOTP(ctx context.Context, req *models.SendOTPRequest) error {
-	normalizedID := req.Identifier
-	if req.Channel == "phone" {
-		normalizedID = utils.FormatPhoneToE164(req.Identifier)
-		if !utils.IsValidPhoneE164(normalizedID) {
-			return errors.New("invalid phone number format")
-		}
-	}
-
-	otp := GenerateOTP(6)
-	key := fmt.Sprintf("otp:%s:%s", normalizedID, req.Purpose)
-	expire := 5 * time.Minute
+func otpKey(purpose, identifier string) string {
+	return fmt.Sprintf("otp:%s:%s", purpose, identifier)
+}
 
-	if err := s.redisClient.Set(ctx, key, otp, expire).Err(); err != nil {
-		return err
+func (s *service) Issue(ctx context.Context, purpose, identifier, payload string) (string, Policy, error) {
+	p := s.policy(purpose)
+	scope := purpose + ":" + identifier
+	if err := s.throttleSend(ctx, scope); err != nil {
+		return "", p, err
 	}
 
-	message := fmt.Sprintf("Your OTP code is %s. It is valid for 5 minutes.", otp)
+	code, err := generateCode(p.Length)
+	if err != nil {
+		return "", p, err
+	}
 
-	switch req.Channel {
-	case "email":
-		return s.emailSender.Send(req.Identifier, "OTP Verification", message)
-	case "phone":
-		return s.smsSender.Send(normalizedID, message)
-	default:
-		return errors.New("invalid channel")
+	// Chỉ lưu HMAC của mã; ghi đè key nên mã gửi trước đó mất hiệu lực ngay
+	key := otpKey(purpose, identifier)
+	pipe := s.redisClient.TxPipeline()
+	pipe.Del(ctx, key)
+	pipe.HSet(ctx, key, "hash", hashCode(s.config.Secret, purpose, identifier, code), "payload", payload)
+	pipe.Expire(ctx, key, p.TTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return "", p, err
 	}
+	s.record(ctx, models.AuditOTPSent, true, purpose, identifier)
+	return code, p, nil
 }
 
-func (s *service) SendOTPWithCustomKey(ctx context.Context, req *models.SendOTPRequest) error {
-	normalizedID := req.Identifier
-	if req.Channel == "phone" {
-		normalizedID = utils.FormatPhoneToE164(req.Identifier)
-		if !utils.IsValidPhoneE164(normalizedID) {
-			return errors.New("invalid phone number format")
-		}
+func (s *service) Consume(ctx context.Context, purpose, identifier, code string) (string, error) {
+	scope := purpose + ":" + identifier
+	if err := s.checkAttempts(ctx, scope); err != nil {
+		return "", err
 	}
 
-	otp := GenerateOTP(6)
-	key := req.CustomKey
-	if key == "" {
-		key = fmt.Sprintf("otp:%s:%s", normalizedID, req.Purpose)
+	key := otpKey(purpose, identifier)
+	stored, err := s.redisClient.HGetAll(ctx, key).Result()
+	if err != nil {
+		return "", err
 	}
-	expire := 5 * time.Minute
-
-	// Lưu dạng "identifier:otp" để dễ tách ra
-	if err := s.redisClient.Set(ctx, key, normalizedID+":"+otp, expire).Err(); err != nil {
-		return err
+	if stored["hash"] == "" {
+		return "", ErrInvalidCode
 	}
 
-	message := fmt.Sprintf("Your OTP code is %s. It is valid for 5 minutes.", otp)
-
-	switch req.Channel {
-	case "email":
-		return s.emailSender.Send(req.Identifier, "OTP Verification", message)
-	case "phone":
-		return s.smsSender.Send(normalizedID, message)
-	default:
-		return errors.New("invalid channel")
+	if !matchCode(s.config.Secret, purpose, identifier, code, stored["hash"]) {
+		s.record(ctx, models.AuditOTPFailed, false, purpose, identifier)
+		if err := s.recordFailedAttempt(ctx, scope, key); err != nil {
+			var limitErr *ratelimit.Error
+			if errors.As(err, &limitErr) {
+				s.record(ctx, models.AuditOTPLocked, false, purpose, identifier)
+			}
+			return "", err
+		}
+		return "", ErrWrongCode
 	}
-}
 
-func (s *service) PeekIdentifierByCustomKey(ctx context.Context, key string) (string, error) {
-	val, err := s.redisClient.Get(ctx, key).Result()
+	// Del trả về 0 nghĩa là request khác đã dùng mã này trước
+	deleted, err := s.redisClient.Del(ctx, key).Result()
 	if err != nil {
-		return "", errors.New("OTP không tồn tại hoặc đã hết hạn")
+		return "", err
+	}
+	if deleted == 0 {
+		return "", ErrInvalidCode
 	}
+	s.clearAttempts(ctx, scope)
+	s.record(ctx, models.AuditOTPVerified, true, purpose, identifier)
+	return stored["payload"], nil
+}
 
-	parts := strings.Split(val, ":")
-	if len(parts) != 2 {
-		return "", errors.New("dữ liệu OTP không hợp lệ")
+// record ghi audit cho OTP; identifier là ID người dùng (mã nội bộ) thì gắn luôn vào UserID, còn lại chỉ lưu dạng đã che
+func (s *service) record(ctx context.Context, action models.AuditAction, success bool, purpose, identifier string) {
+	event := models.AuditEvent{
+		Action:   action,
+		Success:  success,
+		Metadata: map[string]interface{}{"purpose": purpose},
+	}
+	if id, err := primitive.ObjectIDFromHex(identifier); err == nil {
+		event.UserID = &id
+	} else {
+		event.Metadata["identifier"] = audit.MaskIdentifier(identifier)
 	}
+	s.audit.Record(ctx, event)
+}
 
-	return parts[0], nil
+func (s *service) SendOTP(ctx context.Context, req *models.SendOTPRequest) error {
+	if s.policy(req.Purpose).Internal {
+		return ErrInvalidPurpose
+	}
+	return s.send(ctx, req)
 }
 
-func (s *service) SaveOTP(ctx context.Context, key, code string, duration time.Duration) error {
-	return s.redisClient.Set(ctx, key, code, duration).Err()
+func (s *service) SendInternalOTP(ctx context.Context, req *models.SendOTPRequest) error {
+	return s.send(ctx, req)
 }
 
-func (s *service) DeleteOTP(ctx context.Context, key string) error {
-	return s.redisClient.Del(ctx, key).Err()
+func (s *service) VerifyInternalOTP(ctx context.Context, req *models.VerifyOTPRequest) error {
+	normalizedID, err := normalizeIdentifier(req.Channel, req.Identifier)
+	if err != nil {
+		return err
+	}
+	_, err = s.Consume(ctx, req.Purpose, normalizedID, req.OTP)
+	return err
 }
 
-func (s *service) VerifyOTP(ctx context.Context, req *models.VerifyOTPRequest) error {
+func (s *service) send(ctx context.Context, req *models.SendOTPRequest) error {
 	normalizedID, err := normalizeIdentifier(req.Channel, req.Identifier)
 	if err != nil {
 		return err
 	}
 
-	key := fmt.Sprintf("otp:%s:%s", normalizedID, req.Purpose)
+	otp, p, err := s.Issue(ctx, req.Purpose, normalizedID, "")
+	if err != nil {
+		return err
+	}
+
+	message := fmt.Sprintf("Your OTP code is %s. It is valid for %d minutes.", otp, int(p.TTL.Minutes()))
+
+	switch req.Channel {
+	case "email":
+		return s.emailSender.Send(req.Identifier, "OTP Verification", message)
+	case "phone":
+		return s.smsSender.Send(normalizedID, message)
+	default:
+		return errors.New("invalid channel")
+	}
+}
+
+func (s *service) VerifyOTP(ctx context.Context, req *models.VerifyOTPRequest) error {
+	if s.policy(req.Purpose).Internal {
+		return ErrInvalidPurpose
+	}
 
-	storedOTP, err := s.redisClient.Get(ctx, key).Result()
+	normalizedID, err := normalizeIdentifier(req.Channel, req.Identifier)
 	if err != nil {
-		return errors.New("mã OTP không hợp lệ hoặc đã hết hạn")
+		return err
 	}
 
-	if storedOTP != req.OTP {
-		return errors.New("mã OTP không chính xác")
+	if _, err := s.Consume(ctx, req.Purpose, normalizedID, req.OTP); err != nil {
+		return err
 	}
 
-	if req.Purpose == "verify" {
+	if req.Purpose == PurposeVerify {
 		var user *models.User
 		switch req.Channel {
 		case "email":
 			user, err = s.repo.FindByEmail(ctx, normalizedID)
-			if err != nil {
+			if err != nil || user == nil {
 				return errors.New("không tìm thấy người dùng với email này")
 			}
 			update := bson.M{
@@ -194,7 +325,7 @@ func (s *service) VerifyOTP(ctx context.Context, req *models.VerifyOTPRequest) e
 
 		case "phone":
 			user, err = s.repo.FindByPhone(ctx, normalizedID)
-			if err != nil {
+			if err != nil || user == nil {
 				return errors.New("không tìm thấy người dùng với số điện thoại này")
 			}
 			update := bson.M{
@@ -212,24 +343,73 @@ func (s *service) VerifyOTP(ctx context.Context, req *models.VerifyOTPRequest) e
 		}
 	}
 
-	_ = s.redisClient.Del(ctx, key)
-
 	return nil
 }
 
 func (s *service) SendForgotPasswordOTP(ctx context.Context, email string) error {
 	req := &models.SendOTPRequest{
 		Identifier: email,
-		Purpose:    "forgot_password",
+		Purpose:    PurposeForgotPassword,
 		Channel:    "email",
 	}
-	return s.SendOTP(ctx, req)
+	return s.send(ctx, req)
 }
 
 func (s *service) SendRawEmail(ctx context.Context, to, subject, body string) error {
 	return s.emailSender.Send(to, subject, body)
 }
 
-func (s *service) GetRawOTP(ctx context.Context, key string) (string, error) {
-	return s.redisClient.Get(ctx, key).Result()
+func (s *service) SendRawSMS(ctx context.Context, to, body string) error {
+	return s.smsSender.Send(to, body)
+}
+
+// throttleSend áp dụng cooldown gửi lại và giới hạn số lần gửi mỗi giờ cho một scope (purpose:identifier)
+func (s *service) throttleSend(ctx context.Context, scope string) error {
+	if err := s.limiter.Cooldown(ctx, "otp_send", scope, s.config.ResendCooldown); err != nil {
+		return err
+	}
+	return s.limiter.Allow(ctx, ratelimit.Rule{
+		Name:   "otp_send",
+		Limit:  s.config.MaxSendsPerHour,
+		Window: time.Hour,
+	}, scope)
+}
+
+// checkAttempts trả về lỗi nếu scope đang bị khoá do nhập sai quá nhiều lần
+func (s *service) checkAttempts(ctx context.Context, scope string) error {
+	ttl, err := s.redisClient.PTTL(ctx, "otp:lock:"+scope).Result()
+	if err != nil {
+		return err
+	}
+	if ttl > 0 {
+		return &ratelimit.Error{Message: "Bạn đã nhập sai mã OTP quá nhiều lần", RetryAfter: ttl}
+	}
+	return nil
+}
+
+// recordFailedAttempt đếm lần nhập sai; đủ MaxAttempts thì khoá scope và huỷ luôn mã OTP hiện tại
+func (s *service) recordFailedAttempt(ctx context.Context, scope, otpKey string) error {
+	attemptsKey := "otp:attempts:" + scope
+	count, err := s.redisClient.Incr(ctx, attemptsKey).Result()
+	if err != nil {
+		return err
+	}
+	if count == 1 {
+		s.redisClient.Expire(ctx, attemptsKey, s.config.Lockout)
+	}
+	if count < int64(s.config.MaxAttempts) {
+		return nil
+	}
+
+	pipe := s.redisClient.TxPipeline()
+	pipe.Set(ctx, "otp:lock:"+scope, 1, s.config.Lockout)
+	pipe.Del(ctx, attemptsKey, otpKey)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return err
+	}
+	return &ratelimit.Error{Message: "Bạn đã nhập sai mã OTP quá nhiều lần", RetryAfter: s.config.Lockout}
+}
+
+func (s *service) clearAttempts(ctx context.Context, scope string) {
+	s.redisClient.Del(ctx, "otp:attempts:"+scope)
 }
@@ -0,0 +1,87 @@
+package passwordless
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"socialnetwork/dto/request"
+	"socialnetwork/internal/otp"
+	"socialnetwork/internal/user"
+	"socialnetwork/models"
+	"socialnetwork/pkg/ratelimit"
+)
+
+type Handler struct {
+	service     Service
+	userService user.Service
+}
+
+func NewHandler(service Service, userService user.Service) *Handler {
+	return &Handler{service: service, userService: userService}
+}
+
+// POST /auth/passwordless/start
+func (h *Handler) Start(c *gin.Context) {
+	var req request.PasswordlessStartRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.service.Start(c.Request.Context(), req.Channel, req.Identifier); err != nil {
+		writeError(c, err)
+		return
+	}
+	// Luôn trả cùng một thông báo dù tài khoản có tồn tại hay không
+	c.JSON(http.StatusOK, gin.H{"message": "Nếu tài khoản tồn tại, liên kết hoặc mã đăng nhập đã được gửi"})
+}
+
+// POST /auth/passwordless/complete
+func (h *Handler) Complete(c *gin.Context) {
+	var req request.PasswordlessCompleteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	h.complete(c, req.Channel, req.Identifier, req.Code)
+}
+
+// GET /auth/passwordless/complete?uid=&token= - mở trực tiếp từ link trong email
+func (h *Handler) CompleteLink(c *gin.Context) {
+	uid, token := c.Query("uid"), c.Query("token")
+	if uid == "" || token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidLink.Error()})
+		return
+	}
+	h.complete(c, ChannelEmail, uid, token)
+}
+
+func (h *Handler) complete(c *gin.Context, channel, identifier, code string) {
+	u, err := h.service.Complete(c.Request.Context(), channel, identifier, code)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	result, err := h.userService.LoginVerified(user.WithLoginMethod(c.Request.Context(), models.LoginMethodPasswordless), u)
+	if err != nil {
+		user.WriteLoginError(c, err)
+		return
+	}
+	user.RespondLogin(c, result)
+}
+
+func writeError(c *gin.Context, err error) {
+	if ratelimit.WriteError(c, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, ErrInvalidLink), errors.Is(err, otp.ErrInvalidCode), errors.Is(err, otp.ErrWrongCode):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrInvalidPhone), errors.Is(err, ErrInvalidChannel):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,178 @@
+package passwordless
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"socialnetwork/internal/otp"
+	"socialnetwork/internal/user"
+	"socialnetwork/models"
+	"socialnetwork/pkg/email"
+	"socialnetwork/pkg/ratelimit"
+	"socialnetwork/pkg/utils"
+)
+
+const (
+	ChannelEmail = "email"
+	ChannelPhone = "phone"
+)
+
+var (
+	ErrInvalidLink    = errors.New("liên kết đăng nhập không hợp lệ hoặc đã hết hạn")
+	ErrInvalidPhone   = errors.New("số điện thoại không hợp lệ")
+	ErrInvalidChannel = errors.New("kênh đăng nhập không hợp lệ")
+)
+
+type Config struct {
+	LinkURL string // URL trong email, kèm ?uid=&token=
+}
+
+type Service interface {
+	// Start gửi link/mã đăng nhập; không báo lỗi khi tài khoản không tồn tại để tránh dò tài khoản
+	Start(ctx context.Context, channel, identifier string) error
+	// Complete xác thực link/mã và trả về user để cấp phiên đăng nhập
+	Complete(ctx context.Context, channel, identifier, code string) (*models.User, error)
+}
+
+type service struct {
+	userRepo    user.Repository
+	otpService  otp.Service
+	emailSender email.EmailSender
+	config      Config
+}
+
+func NewService(userRepo user.Repository, otpService otp.Service, emailSender email.EmailSender, config Config) Service {
+	return &service{
+		userRepo:    userRepo,
+		otpService:  otpService,
+		emailSender: emailSender,
+		config:      config,
+	}
+}
+
+func usable(u *models.User) bool {
+	return u != nil && u.DeletedAt == nil
+}
+
+// Chỉ gửi/chấp nhận link, mã qua kênh đã xác minh; kênh chưa xác minh có thể là của người khác
+func emailUsable(u *models.User) bool {
+	return usable(u) && u.EmailVerified
+}
+
+func phoneUsable(u *models.User) bool {
+	return usable(u) && u.PhoneVerified
+}
+
+func normalizePhone(phone string) (string, error) {
+	formatted := utils.NormalizePhone(phone)
+	if !utils.IsValidPhoneE164(formatted) {
+		return "", ErrInvalidPhone
+	}
+	return formatted, nil
+}
+
+func (s *service) Start(ctx context.Context, channel, identifier string) error {
+	switch channel {
+	case ChannelEmail:
+		s.sendLink(ctx, utils.NormalizeEmail(identifier))
+		return nil
+	case ChannelPhone:
+		phone, err := normalizePhone(identifier)
+		if err != nil {
+			return err
+		}
+		u, err := s.userRepo.FindByPhone(ctx, phone)
+		if err != nil {
+			return err
+		}
+		if !phoneUsable(u) {
+			return nil
+		}
+		return s.otpService.SendInternalOTP(ctx, &models.SendOTPRequest{
+			Identifier: phone,
+			Channel:    ChannelPhone,
+			Purpose:    otp.PurposeLoginCode,
+		})
+	default:
+		return ErrInvalidChannel
+	}
+}
+
+// sendLink chỉ log lỗi, không trả về: lỗi chỉ xảy ra khi tài khoản tồn tại nên trả về sẽ lộ tài khoản
+func (s *service) sendLink(ctx context.Context, address string) {
+	u, err := s.userRepo.FindByEmail(ctx, address)
+	if err != nil || !emailUsable(u) {
+		return
+	}
+
+	// Token gắn với user ID (không phải email) để link vẫn đúng người nếu email bị đổi sau đó
+	token, policy, err := s.otpService.Issue(ctx, otp.PurposeLoginLink, u.ID.Hex(), "")
+	if err != nil {
+		log.Printf("⚠️ không tạo được link đăng nhập cho %s: %v", u.ID.Hex(), err)
+		return
+	}
+
+	q := url.Values{}
+	q.Set("uid", u.ID.Hex())
+	q.Set("token", token)
+	link := s.config.LinkURL + "?" + q.Encode()
+
+	body := fmt.Sprintf("Nhấn vào liên kết sau để đăng nhập: %s\nLiên kết chỉ dùng được một lần và hết hạn sau %d phút. Nếu bạn không yêu cầu, hãy bỏ qua email này.",
+		link, int(policy.TTL.Minutes()))
+	if err := s.emailSender.Send(u.Email, "Liên kết đăng nhập", body); err != nil {
+		log.Printf("⚠️ không gửi được link đăng nhập cho %s: %v", u.ID.Hex(), err)
+	}
+}
+
+func (s *service) Complete(ctx context.Context, channel, identifier, code string) (*models.User, error) {
+	switch channel {
+	case ChannelEmail:
+		userID, err := primitive.ObjectIDFromHex(identifier)
+		if err != nil {
+			return nil, ErrInvalidLink
+		}
+		if _, err := s.otpService.Consume(ctx, otp.PurposeLoginLink, userID.Hex(), strings.TrimSpace(code)); err != nil {
+			var limitErr *ratelimit.Error
+			if errors.As(err, &limitErr) {
+				return nil, err
+			}
+			return nil, ErrInvalidLink
+		}
+		u, err := s.userRepo.GetByID(ctx, userID)
+		if err != nil || !emailUsable(u) {
+			return nil, ErrInvalidLink
+		}
+		return u, nil
+
+	case ChannelPhone:
+		phone, err := normalizePhone(identifier)
+		if err != nil {
+			return nil, err
+		}
+		err = s.otpService.VerifyInternalOTP(ctx, &models.VerifyOTPRequest{
+			Identifier: phone,
+			Channel:    ChannelPhone,
+			Purpose:    otp.PurposeLoginCode,
+			OTP:        strings.TrimSpace(code),
+		})
+		if err != nil {
+			return nil, err
+		}
+		u, err := s.userRepo.FindByPhone(ctx, phone)
+		if err != nil {
+			return nil, err
+		}
+		if !phoneUsable(u) {
+			return nil, otp.ErrInvalidCode
+		}
+		return u, nil
+
+	default:
+		return nil, ErrInvalidChannel
+	}
+}
@@ -0,0 +1,8 @@
+package post
+
+import "errors"
+
+var (
+	ErrNoChanges    = errors.New("không có trường nào thay đổi")
+	ErrPostNotFound = errors.New("post không tồn tại")
+)
@@ -1,9 +1,11 @@
 package post
 
 import (
+	"errors"
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"net/http"
+	"socialnetwork/internal/contentfilter"
 	"socialnetwork/models"
 	"strconv"
 )
@@ -32,8 +34,15 @@ func (h *PostHandler) CreatePost(c *gin.Context) {
 		return
 	}
 	req.UserID = userID
+	// Bài trong nhóm phải đăng qua /groups/:id/posts
+	req.GroupID = nil
+	req.GroupPending = false
 
 	post, err := h.postService.CreatePost(c.Request.Context(), &req)
+	if errors.Is(err, contentfilter.ErrRejected) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -55,9 +64,31 @@ func (h *PostHandler) GetPost(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 		return
 	}
+	// Bài trong nhóm xem qua /groups/:id/posts để kiểm tra quyền riêng tư của nhóm
+	if post.GroupID != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "post không tồn tại"})
+		return
+	}
 	c.JSON(http.StatusOK, post)
 }
 
+// GET /posts/:id/revisions
+func (h *PostHandler) GetPostRevisions(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := primitive.ObjectIDFromHex(idStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "id không hợp lệ"})
+		return
+	}
+
+	revisions, err := h.postService.ListRevisions(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, revisions)
+}
+
 // PUT /posts/:id
 func (h *PostHandler) UpdatePost(c *gin.Context) {
 	idStr := c.Param("id")
@@ -123,7 +154,15 @@ func (h *PostHandler) UpdatePost(c *gin.Context) {
 		return
 	}
 
-	err = h.postService.UpdatePost(c.Request.Context(), id, updateData)
+	err = h.postService.UpdatePost(c.Request.Context(), id, userID, updateData)
+	if errors.Is(err, contentfilter.ErrRejected) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, ErrNoChanges) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
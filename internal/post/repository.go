@@ -33,10 +33,18 @@ func (r *PostRepository) Create(ctx context.Context, post *models.Post) error {
     return err
 }
 
+// visible thêm điều kiện loại bỏ post đã xoá mềm, bị ẩn do vi phạm hoặc của tài khoản đã vô hiệu hoá
+func visible(filter bson.M) bson.M {
+    filter["deleted_at"] = nil
+    filter["owner_inactive"] = bson.M{"$ne": true}
+    filter["moderation_hidden"] = bson.M{"$ne": true}
+    return filter
+}
+
 // Lấy post theo ID
 func (r *PostRepository) GetByID(ctx context.Context, id primitive.ObjectID) (*models.Post, error) {
     var post models.Post
-    err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&post)
+    err := r.collection.FindOne(ctx, visible(bson.M{"_id": id})).Decode(&post)
     if err == mongo.ErrNoDocuments {
         return nil, errors.New("post không tồn tại")
     }
@@ -47,7 +55,7 @@ func (r *PostRepository) GetByID(ctx context.Context, id primitive.ObjectID) (*m
 func (r *PostRepository) Update(ctx context.Context, id primitive.ObjectID, updateData bson.M) error {
     updateData["updated_at"] = time.Now()
     update := bson.M{"$set": updateData}
-    res, err := r.collection.UpdateByID(ctx, id, update)
+    res, err := r.collection.UpdateOne(ctx, visible(bson.M{"_id": id}), update)
     if err != nil {
         return err
     }
@@ -57,18 +65,89 @@ func (r *PostRepository) Update(ctx context.Context, id primitive.ObjectID, upda
     return nil
 }
 
-// Xóa post
+// Xóa mềm post (chuyển vào thùng rác)
 func (r *PostRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
-    res, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+    res, err := r.collection.UpdateOne(ctx, visible(bson.M{"_id": id}), bson.M{"$set": bson.M{"deleted_at": time.Now()}})
     if err != nil {
         return err
     }
-    if res.DeletedCount == 0 {
+    if res.MatchedCount == 0 {
         return errors.New("post không tồn tại")
     }
     return nil
 }
 
+// Danh sách post trong thùng rác của user, xoá sau thời điểm since
+func (r *PostRepository) ListDeleted(ctx context.Context, ownerID primitive.ObjectID, since time.Time) ([]models.Post, error) {
+    opts := options.Find().SetSort(bson.M{"deleted_at": -1})
+    cursor, err := r.collection.Find(ctx, bson.M{"user_id": ownerID, "deleted_at": bson.M{"$gte": since}}, opts)
+    if err != nil {
+        return nil, err
+    }
+    var posts []models.Post
+    err = cursor.All(ctx, &posts)
+    return posts, err
+}
+
+// Khôi phục post đã xoá sau thời điểm since
+func (r *PostRepository) Restore(ctx context.Context, id, ownerID primitive.ObjectID, since time.Time) error {
+    res, err := r.collection.UpdateOne(ctx,
+        bson.M{"_id": id, "user_id": ownerID, "deleted_at": bson.M{"$gte": since}},
+        bson.M{"$unset": bson.M{"deleted_at": ""}},
+    )
+    if err != nil {
+        return err
+    }
+    if res.MatchedCount == 0 {
+        return mongo.ErrNoDocuments
+    }
+    return nil
+}
+
+// Exists - post chưa bị xoá (không nằm trong thùng rác và chưa bị xoá vĩnh viễn)
+func (r *PostRepository) Exists(ctx context.Context, id primitive.ObjectID) (bool, error) {
+    count, err := r.collection.CountDocuments(ctx, bson.M{"_id": id, "deleted_at": nil}, options.Count().SetLimit(1))
+    return count > 0, err
+}
+
+// Các post đã xoá trước thời điểm before (hết hạn lưu trong thùng rác)
+func (r *PostRepository) FindPurgeable(ctx context.Context, before time.Time, limit int64) ([]models.Post, error) {
+    opts := options.Find().SetLimit(limit)
+    cursor, err := r.collection.Find(ctx, bson.M{"deleted_at": bson.M{"$lt": before}}, opts)
+    if err != nil {
+        return nil, err
+    }
+    var posts []models.Post
+    err = cursor.All(ctx, &posts)
+    return posts, err
+}
+
+// Tất cả post của user, kể cả đã xoá mềm (dùng khi xoá tài khoản)
+func (r *PostRepository) FindAllByOwner(ctx context.Context, ownerID primitive.ObjectID, limit int64) ([]models.Post, error) {
+    cursor, err := r.collection.Find(ctx, bson.M{"user_id": ownerID}, options.Find().SetLimit(limit))
+    if err != nil {
+        return nil, err
+    }
+    var posts []models.Post
+    err = cursor.All(ctx, &posts)
+    return posts, err
+}
+
+// Gỡ like/dislike của user khỏi mọi post
+func (r *PostRepository) PullUserReactions(ctx context.Context, userID primitive.ObjectID) error {
+    _, err := r.collection.UpdateMany(ctx,
+        bson.M{"$or": bson.A{bson.M{"likes": userID}, bson.M{"dislikes": userID}}},
+        bson.M{"$pull": bson.M{"likes": userID, "dislikes": userID}},
+    )
+    return err
+}
+
+// Xoá vĩnh viễn post
+func (r *PostRepository) Purge(ctx context.Context, id primitive.ObjectID) error {
+    _, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+    return err
+}
+
 // Lấy danh sách post có phân trang, sắp xếp theo created_at giảm dần
 func (r *PostRepository) List(ctx context.Context, page, limit int64) ([]models.Post, error) {
     opts := options.Find()
@@ -76,7 +155,8 @@ func (r *PostRepository) List(ctx context.Context, page, limit int64) ([]models.
     opts.SetSkip((page - 1) * limit)
     opts.SetLimit(limit)
 
-    cursor, err := r.collection.Find(ctx, bson.M{}, opts)
+    // Bài trong nhóm chỉ xem qua /groups/:id/posts
+    cursor, err := r.collection.Find(ctx, visible(bson.M{"group_id": nil}), opts)
     if err != nil {
         return nil, err
     }
@@ -94,10 +174,10 @@ func (r *PostRepository) List(ctx context.Context, page, limit int64) ([]models.
 }
 
 func (r *PostRepository) FindByOwnerAndVisibility(ctx context.Context, ownerID primitive.ObjectID, visibility string) ([]models.Post, error) {
-    filter := bson.M{
+    filter := visible(bson.M{
         "user_id":    ownerID,
         "visibility": visibility,
-    }
+    })
     cursor, err := r.collection.Find(ctx, filter)
     if err != nil {
         return nil, err
@@ -108,3 +188,34 @@ func (r *PostRepository) FindByOwnerAndVisibility(ctx context.Context, ownerID p
     }
     return posts, nil
 }
+
+// Bài trong nhóm, mới nhất trước; phân trang theo _id (before rỗng là trang đầu)
+func (r *PostRepository) ListByGroup(ctx context.Context, groupID primitive.ObjectID, pending bool, before primitive.ObjectID, limit int64) ([]models.Post, error) {
+    filter := bson.M{"group_id": groupID, "group_pending": bson.M{"$ne": true}}
+    if pending {
+        filter["group_pending"] = true
+    }
+    if !before.IsZero() {
+        filter["_id"] = bson.M{"$lt": before}
+    }
+    opts := options.Find().SetSort(bson.M{"_id": -1}).SetLimit(limit)
+    cursor, err := r.collection.Find(ctx, visible(filter), opts)
+    if err != nil {
+        return nil, err
+    }
+    posts := []models.Post{}
+    err = cursor.All(ctx, &posts)
+    return posts, err
+}
+
+// Duyệt bài trong nhóm đang chờ
+func (r *PostRepository) ApproveGroupPost(ctx context.Context, id primitive.ObjectID) error {
+    res, err := r.collection.UpdateOne(ctx, visible(bson.M{"_id": id, "group_pending": true}), bson.M{"$unset": bson.M{"group_pending": ""}})
+    if err != nil {
+        return err
+    }
+    if res.MatchedCount == 0 {
+        return errors.New("post không tồn tại")
+    }
+    return nil
+}
@@ -3,7 +3,10 @@ package post
 
 import (
     "context"
+    "socialnetwork/internal/contentfilter"
+    "socialnetwork/internal/revision"
     "socialnetwork/models"
+    "time"
 
 
     "go.mongodb.org/mongo-driver/bson"
@@ -13,31 +16,84 @@ import (
 type PostService interface {
     CreatePost(ctx context.Context, post *models.Post) (*models.Post, error)
     GetPostByID(ctx context.Context, id primitive.ObjectID) (*models.Post, error)
-    UpdatePost(ctx context.Context, id primitive.ObjectID, updateData bson.M) error
+    UpdatePost(ctx context.Context, id, editorID primitive.ObjectID, updateData bson.M) error
     DeletePost(ctx context.Context, id primitive.ObjectID) error
     ListPosts(ctx context.Context, page, limit int64) ([]models.Post, error)
     GetPublicPostsByOwner(ctx context.Context, ownerID primitive.ObjectID) ([]models.Post, error)
+    ListRevisions(ctx context.Context, id primitive.ObjectID) ([]models.Revision, error)
 }
 
 type postService struct {
-    repo *PostRepository
+    repo         *PostRepository
+    revisionRepo revision.Repository
+    filter       contentfilter.Filter
 }
 
-func NewPostService(repo *PostRepository) PostService {
-    return &postService{repo: repo}
+func NewPostService(repo *PostRepository, revisionRepo revision.Repository, filter contentfilter.Filter) PostService {
+    return &postService{repo: repo, revisionRepo: revisionRepo, filter: filter}
 }
 
+// Nội dung bị bộ lọc giữ lại vẫn được lưu nhưng ẩn cho tới khi kiểm duyệt xong
 func (s *postService) CreatePost(ctx context.Context, post *models.Post) (*models.Post, error) {
-    err := s.repo.Create(ctx, post)
-    return post, err
+    verdict, err := contentfilter.Apply(s.filter, &post.Content)
+    if err != nil {
+        return nil, err
+    }
+    post.ModerationHidden = verdict.Held()
+
+    if err := s.repo.Create(ctx, post); err != nil {
+        return nil, err
+    }
+    if verdict.Held() {
+        if err := s.filter.Hold(ctx, models.ReportTargetPost, post.ID, post.UserID, verdict.Rules); err != nil {
+            return nil, err
+        }
+    }
+    return post, nil
 }
 
 func (s *postService) GetPostByID(ctx context.Context, id primitive.ObjectID) (*models.Post, error) {
     return s.repo.GetByID(ctx, id)
 }
 
-func (s *postService) UpdatePost(ctx context.Context, id primitive.ObjectID, updateData bson.M) error {
-    return s.repo.Update(ctx, id, updateData)
+// Lưu phiên bản cũ vào revisions trước khi ghi đè
+func (s *postService) UpdatePost(ctx context.Context, id, editorID primitive.ObjectID, updateData bson.M) error {
+    post, err := s.repo.GetByID(ctx, id)
+    if err != nil {
+        return err
+    }
+
+    var verdict contentfilter.Verdict
+    if content, ok := updateData["content"].(string); ok {
+        if verdict, err = contentfilter.Apply(s.filter, &content); err != nil {
+            return err
+        }
+        updateData["content"] = content
+    }
+
+    previous := map[string]interface{}{
+        "content":   post.Content,
+        "image_url": post.ImageURL,
+        "media":     post.Media,
+    }
+    changed, rev := revision.Diff(revision.ContentPost, id, editorID, previous, updateData)
+    if len(changed) == 0 {
+        return ErrNoChanges
+    }
+
+    changed["edited"] = true
+    changed["edited_at"] = time.Now()
+    if verdict.Held() {
+        changed["moderation_hidden"] = true
+    }
+    if err := s.repo.Update(ctx, id, changed); err != nil {
+        return err
+    }
+    revision.Save(ctx, s.revisionRepo, rev)
+    if verdict.Held() {
+        return s.filter.Hold(ctx, models.ReportTargetPost, id, post.UserID, verdict.Rules)
+    }
+    return nil
 }
 
 func (s *postService) DeletePost(ctx context.Context, id primitive.ObjectID) error {
@@ -50,3 +106,15 @@ func (s *postService) ListPosts(ctx context.Context, page, limit int64) ([]model
 func (s *postService) GetPublicPostsByOwner(ctx context.Context, ownerID primitive.ObjectID) ([]models.Post, error) {
     return s.repo.FindByOwnerAndVisibility(ctx, ownerID, "public")
 }
+
+// ListRevisions - bài trong nhóm coi như không tồn tại, giống GET /posts/:id
+func (s *postService) ListRevisions(ctx context.Context, id primitive.ObjectID) ([]models.Revision, error) {
+    p, err := s.repo.GetByID(ctx, id)
+    if err != nil {
+        return nil, err
+    }
+    if p.GroupID != nil {
+        return nil, ErrPostNotFound
+    }
+    return s.revisionRepo.ListByContent(ctx, revision.ContentPost, id)
+}
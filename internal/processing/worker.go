@@ -0,0 +1,337 @@
+package processing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"socialnetwork/internal/short"
+	"socialnetwork/internal/video"
+	"socialnetwork/models"
+	"socialnetwork/pkg/media"
+)
+
+// MaxShortDuration - short phải ngắn hơn 60 giây
+const MaxShortDuration = 60.0
+
+type Config struct {
+	OutputDir   string // thư mục lưu HLS + thumbnail
+	BaseURL     string // URL public tương ứng với OutputDir
+	Renditions  []media.RenditionSpec
+	MaxAttempts int
+	JobTimeout  time.Duration
+
+	// URL gốc của kho upload (vd: https://cdn.example.com/uploads/); chỉ nhận file gốc nằm dưới các URL này
+	AllowedSources []string
+}
+
+// Worker lấy job từ hàng đợi, probe/transcode/đóng gói HLS và cập nhật trạng thái
+type Worker struct {
+	queue        media.JobQueue
+	processor    media.Processor
+	videoRepo    video.VideoRepository
+	videoService video.VideoService
+	shortRepo    short.ShortRepository
+	shortService short.ShortService
+	config       Config
+}
+
+func NewWorker(
+	queue media.JobQueue,
+	processor media.Processor,
+	videoRepo video.VideoRepository,
+	videoService video.VideoService,
+	shortRepo short.ShortRepository,
+	shortService short.ShortService,
+	config Config,
+) *Worker {
+	if len(config.Renditions) == 0 {
+		config.Renditions = media.DefaultRenditions
+	}
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = 3
+	}
+	if config.JobTimeout <= 0 {
+		config.JobTimeout = 30 * time.Minute
+	}
+	return &Worker{
+		queue:        queue,
+		processor:    processor,
+		videoRepo:    videoRepo,
+		videoService: videoService,
+		shortRepo:    shortRepo,
+		shortService: shortService,
+		config:       config,
+	}
+}
+
+// permanentError - lỗi không nên thử lại (file sai định dạng, short quá dài...)
+type permanentError struct{ msg string }
+
+func (e *permanentError) Error() string { return e.msg }
+
+var errSourceNotAllowed = &permanentError{msg: "URL file gốc không nằm trong kho upload"}
+
+// allowedSource chỉ cho phép URL http(s) nằm dưới một trong các URL gốc của kho upload,
+// tránh để ffmpeg đọc file cục bộ (file:, concat:...) hoặc gọi vào mạng nội bộ
+func (w *Worker) allowedSource(input string) bool {
+	u, err := url.Parse(input)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.User != nil || u.Host == "" {
+		return false
+	}
+	if strings.Contains(u.Path, "..") || strings.Contains(u.RawQuery, "..") {
+		return false
+	}
+	for _, raw := range w.config.AllowedSources {
+		base, err := url.Parse(raw)
+		if err != nil || base.Host == "" {
+			continue
+		}
+		prefix := strings.TrimRight(base.Path, "/") + "/"
+		if u.Scheme == base.Scheme && strings.EqualFold(u.Host, base.Host) && strings.HasPrefix(u.Path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// result - kết quả xử lý chung cho video và short
+type result struct {
+	duration   int
+	hlsURL     string
+	renditions []models.Rendition
+	thumbnail  string
+}
+
+// Run chạy vòng lặp xử lý cho tới khi ctx bị huỷ
+func (w *Worker) Run(ctx context.Context) {
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+
+		job, err := w.queue.Dequeue(ctx, 5*time.Second)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("⚠️ media queue: %v", err)
+				time.Sleep(time.Second)
+			}
+			continue
+		}
+		if job == nil {
+			continue
+		}
+
+		w.handle(ctx, job)
+	}
+}
+
+func (w *Worker) handle(ctx context.Context, job *media.Job) {
+	id, err := primitive.ObjectIDFromHex(job.ID)
+	if err != nil {
+		log.Printf("⚠️ media job có ID không hợp lệ: %s", job.ID)
+		return
+	}
+
+	jobCtx, cancel := context.WithTimeout(ctx, w.config.JobTimeout)
+	defer cancel()
+
+	switch job.Kind {
+	case media.KindVideo:
+		err = w.processVideo(jobCtx, id)
+	case media.KindShort:
+		err = w.processShort(jobCtx, id)
+	default:
+		log.Printf("⚠️ media job loại không hỗ trợ: %s", job.Kind)
+		return
+	}
+	if err == nil {
+		return
+	}
+
+	var perm *permanentError
+	job.Attempts++
+	if !errors.As(err, &perm) && job.Attempts < w.config.MaxAttempts {
+		log.Printf("⚠️ xử lý %s %s lỗi (lần %d), thử lại: %v", job.Kind, job.ID, job.Attempts, err)
+		if qErr := w.queue.Enqueue(ctx, *job); qErr == nil {
+			return
+		}
+	}
+
+	log.Printf("❌ xử lý %s %s thất bại: %v", job.Kind, job.ID, err)
+	w.markFailed(ctx, job.Kind, id, err)
+}
+
+func (w *Worker) processVideo(ctx context.Context, id primitive.ObjectID) error {
+	v, err := w.videoRepo.GetForProcessing(ctx, id)
+	if err != nil {
+		return &permanentError{msg: "video không tồn tại"}
+	}
+
+	if err := w.videoRepo.UpdateFields(ctx, id, bson.M{"status": models.ProcessingProcessing}); err != nil {
+		return err
+	}
+
+	res, err := w.process(ctx, media.KindVideo, id, v.URL, v.Duration, 0)
+	if err != nil {
+		return err
+	}
+
+	thumbnail := v.Thumbnail
+	if thumbnail == "" {
+		thumbnail = res.thumbnail
+	}
+	now := time.Now()
+	err = w.videoRepo.UpdateFields(ctx, id, bson.M{
+		"status":          models.ProcessingReady,
+		"duration":        res.duration,
+		"hlsUrl":          res.hlsURL,
+		"renditions":      res.renditions,
+		"thumbnail":       thumbnail,
+		"processingError": "",
+		"processedAt":     now,
+	})
+	if err != nil {
+		return err
+	}
+
+	// Chỉ khi đã sẵn sàng mới thông báo cho followers; trạng thái đã là ready nên lỗi chỉ log,
+	// trả lỗi ra sẽ khiến job bị thử lại hoặc đánh dấu failed
+	v.Status = models.ProcessingReady
+	if err := w.videoService.NotifyFollowers(ctx, v); err != nil {
+		log.Printf("⚠️ không thông báo được video %s cho followers: %v", id.Hex(), err)
+	}
+	return nil
+}
+
+func (w *Worker) processShort(ctx context.Context, id primitive.ObjectID) error {
+	sh, err := w.shortRepo.GetForProcessing(ctx, id)
+	if err != nil {
+		return &permanentError{msg: "short không tồn tại"}
+	}
+
+	if err := w.shortRepo.UpdateFields(ctx, id, bson.M{"status": models.ProcessingProcessing}); err != nil {
+		return err
+	}
+
+	res, err := w.process(ctx, media.KindShort, id, sh.URL, sh.Duration, MaxShortDuration)
+	if err != nil {
+		return err
+	}
+
+	thumbnail := sh.Thumbnail
+	if thumbnail == "" {
+		thumbnail = res.thumbnail
+	}
+	now := time.Now()
+	err = w.shortRepo.UpdateFields(ctx, id, bson.M{
+		"status":          models.ProcessingReady,
+		"duration":        res.duration,
+		"hlsUrl":          res.hlsURL,
+		"renditions":      res.renditions,
+		"thumbnail":       thumbnail,
+		"processingError": "",
+		"processedAt":     now,
+	})
+	if err != nil {
+		return err
+	}
+
+	sh.Status = models.ProcessingReady
+	if err := w.shortService.NotifyFollowers(ctx, sh); err != nil {
+		log.Printf("⚠️ không thông báo được short %s cho followers: %v", id.Hex(), err)
+	}
+	return nil
+}
+
+// process probe file, kiểm tra thời lượng (maxDuration = 0 là không giới hạn),
+// transcode HLS và lấy thumbnail
+func (w *Worker) process(ctx context.Context, kind string, id primitive.ObjectID, input string, clientDuration int, maxDuration float64) (*result, error) {
+	if strings.TrimSpace(input) == "" {
+		return nil, &permanentError{msg: "thiếu URL file gốc"}
+	}
+	if !w.allowedSource(input) {
+		return nil, errSourceNotAllowed
+	}
+
+	probe, err := w.processor.Probe(ctx, input)
+	if errors.Is(err, media.ErrNoVideoStream) || errors.Is(err, media.ErrUnknownDuration) {
+		return nil, &permanentError{msg: err.Error()}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Thời lượng lấy từ file, không tin client; chỉ processor không đo thời lượng (mock) mới dùng số client gửi
+	duration := probe.Duration
+	if duration <= 0 {
+		duration = float64(clientDuration)
+	}
+	if maxDuration > 0 && duration >= maxDuration {
+		return nil, &permanentError{msg: fmt.Sprintf("thời lượng %.1fs vượt quá giới hạn %.0fs", duration, maxDuration)}
+	}
+
+	relDir := filepath.Join(kind+"s", id.Hex())
+	outDir := filepath.Join(w.config.OutputDir, relDir)
+	if err := os.MkdirAll(outDir, 0o755); err != nil {
+		return nil, err
+	}
+	baseURL := strings.TrimRight(w.config.BaseURL, "/") + "/" + filepath.ToSlash(relDir)
+
+	specs := media.SelectRenditions(w.config.Renditions, probe.Height)
+	outputs, err := w.processor.TranscodeHLS(ctx, input, outDir, specs)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &result{duration: int(math.Ceil(duration))}
+	for _, o := range outputs {
+		res.renditions = append(res.renditions, models.Rendition{
+			Name:        o.Name,
+			Width:       o.Width,
+			Height:      o.Height,
+			Bitrate:     o.Bitrate,
+			PlaylistURL: baseURL + "/" + o.Playlist,
+		})
+	}
+	if len(outputs) > 0 {
+		res.hlsURL = baseURL + "/master.m3u8"
+	}
+
+	// Lấy thumbnail ở 10% thời lượng; lỗi thumbnail không làm hỏng cả job
+	thumbPath := filepath.Join(outDir, "thumbnail.jpg")
+	if err := w.processor.ExtractThumbnail(ctx, input, thumbPath, duration/10); err != nil {
+		log.Printf("⚠️ không lấy được thumbnail %s %s: %v", kind, id.Hex(), err)
+	} else if _, err := os.Stat(thumbPath); err == nil {
+		res.thumbnail = baseURL + "/thumbnail.jpg"
+	}
+
+	return res, nil
+}
+
+func (w *Worker) markFailed(ctx context.Context, kind string, id primitive.ObjectID, cause error) {
+	fields := bson.M{
+		"status":          models.ProcessingFailed,
+		"processingError": cause.Error(),
+	}
+
+	var err error
+	switch kind {
+	case media.KindVideo:
+		err = w.videoRepo.UpdateFields(ctx, id, fields)
+	case media.KindShort:
+		err = w.shortRepo.UpdateFields(ctx, id, fields)
+	}
+	if err != nil {
+		log.Printf("⚠️ không cập nhật được trạng thái failed cho %s %s: %v", kind, id.Hex(), err)
+	}
+}
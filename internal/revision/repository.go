@@ -0,0 +1,109 @@
+package revision
+
+import (
+	"context"
+	"log"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"socialnetwork/models"
+)
+
+// Loại nội dung có lịch sử chỉnh sửa
+const (
+	ContentVideo   = "video"
+	ContentShort   = "short"
+	ContentPost    = "post"
+	ContentComment = "comment"
+)
+
+type Repository interface {
+	Create(ctx context.Context, rev *models.Revision) error
+	ListByContent(ctx context.Context, contentType string, contentID primitive.ObjectID) ([]models.Revision, error)
+	DeleteByContent(ctx context.Context, contentType string, contentIDs []primitive.ObjectID) error
+}
+
+type repository struct {
+	collection *mongo.Collection
+}
+
+func NewRepository(db *mongo.Database) Repository {
+	return &repository{collection: db.Collection("revisions")}
+}
+
+func (r *repository) Create(ctx context.Context, rev *models.Revision) error {
+	rev.ID = primitive.NewObjectID()
+	rev.CreatedAt = time.Now()
+	_, err := r.collection.InsertOne(ctx, rev)
+	return err
+}
+
+// ListByContent - lịch sử chỉnh sửa, mới nhất trước
+func (r *repository) ListByContent(ctx context.Context, contentType string, contentID primitive.ObjectID) ([]models.Revision, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}})
+	cursor, err := r.collection.Find(ctx, bson.M{"contentType": contentType, "contentId": contentID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	revisions := []models.Revision{}
+	err = cursor.All(ctx, &revisions)
+	return revisions, err
+}
+
+func (r *repository) DeleteByContent(ctx context.Context, contentType string, contentIDs []primitive.ObjectID) error {
+	if len(contentIDs) == 0 {
+		return nil
+	}
+	_, err := r.collection.DeleteMany(ctx, bson.M{"contentType": contentType, "contentId": bson.M{"$in": contentIDs}})
+	return err
+}
+
+// Diff so sánh giá trị cũ với các trường sắp cập nhật, trả về các trường thực sự thay đổi
+// (caller chỉ cập nhật những trường đó) cùng revision tương ứng; nil nếu không có thay đổi.
+// Revision chỉ được lưu (Save) sau khi cập nhật thành công.
+func Diff(contentType string, contentID, editorID primitive.ObjectID, previous, updates map[string]interface{}) (map[string]interface{}, *models.Revision) {
+	changed := map[string]interface{}{}
+	rev := &models.Revision{
+		ContentType: contentType,
+		ContentID:   contentID,
+		EditorID:    editorID,
+		Previous:    map[string]interface{}{},
+	}
+	for field, newVal := range updates {
+		oldVal := previous[field]
+		if equal(oldVal, newVal) {
+			continue
+		}
+		changed[field] = newVal
+		rev.Changed = append(rev.Changed, field)
+		rev.Previous[field] = oldVal
+	}
+	if len(changed) == 0 {
+		return changed, nil
+	}
+	sort.Strings(rev.Changed)
+	return changed, rev
+}
+
+// Save lưu revision sau khi nội dung đã được cập nhật; lỗi chỉ log vì thay đổi đã có hiệu lực
+func Save(ctx context.Context, repo Repository, rev *models.Revision) {
+	if err := repo.Create(ctx, rev); err != nil {
+		log.Printf("⚠️ không lưu được lịch sử chỉnh sửa %s %s: %v", rev.ContentType, rev.ContentID.Hex(), err)
+	}
+}
+
+func equal(a, b interface{}) bool {
+	// So sánh qua bson để xử lý được slice ([]string media...)
+	ab, errA := bson.Marshal(bson.M{"v": a})
+	bb, errB := bson.Marshal(bson.M{"v": b})
+	if errA != nil || errB != nil {
+		return false
+	}
+	return string(ab) == string(bb)
+}
@@ -0,0 +1,10 @@
+package short
+
+import "errors"
+
+var (
+	ErrShortNotFound = errors.New("short not found")
+	ErrNotOwner      = errors.New("bạn không phải chủ sở hữu short này")
+	ErrNoChanges     = errors.New("không có trường nào để cập nhật")
+	ErrEmptyTitle    = errors.New("title không được để trống")
+)
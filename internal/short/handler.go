@@ -1,10 +1,13 @@
 package short
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"socialnetwork/dto/request"
+	"socialnetwork/internal/contentfilter"
 	"socialnetwork/models"
 )
 
@@ -23,9 +26,20 @@ func (h *ShortHandler) CreateShort(c *gin.Context) {
 		return
 	}
 
-	sh.OwnerID = c.MustGet("userID").(primitive.ObjectID)
+	ownerID, err := primitive.ObjectIDFromHex(c.GetString("userID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		return
+	}
+	sh.OwnerID = ownerID
+
+	// Validate visibility (nếu không có thì mặc định public)
+	if sh.Visibility != "private" && sh.Visibility != "public" {
+		sh.Visibility = "public"
+	}
+
 	if err := h.service.CreateShort(c.Request.Context(), &sh); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		writeError(c, err)
 		return
 	}
 
@@ -45,11 +59,27 @@ func (h *ShortHandler) GetShortByID(c *gin.Context) {
 		return
 	}
 
+	userID, _ := primitive.ObjectIDFromHex(c.GetString("userID"))
+	if sh.Visibility == "private" && sh.OwnerID != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Bạn không có quyền xem short này"})
+		return
+	}
+
+	// Short chưa xử lý xong chỉ chủ sở hữu mới thấy
+	if !models.IsProcessed(sh.Status) && sh.OwnerID != userID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "short not found"})
+		return
+	}
+
 	c.JSON(http.StatusOK, sh)
 }
 
 func (h *ShortHandler) GetShortsByOwner(c *gin.Context) {
-	ownerID := c.MustGet("userID").(primitive.ObjectID)
+	ownerID, err := primitive.ObjectIDFromHex(c.GetString("userID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		return
+	}
 	list, err := h.service.GetShortsByOwner(c.Request.Context(), ownerID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch shorts"})
@@ -66,9 +96,13 @@ func (h *ShortHandler) DeleteShort(c *gin.Context) {
 		return
 	}
 
-	ownerID := c.MustGet("userID").(primitive.ObjectID)
+	ownerID, err := primitive.ObjectIDFromHex(c.GetString("userID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		return
+	}
 	if err := h.service.DeleteShort(c.Request.Context(), id, ownerID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete short"})
+		writeError(c, err)
 		return
 	}
 
@@ -91,3 +125,70 @@ func (h *ShortHandler) GetPublicShortsByOwner(c *gin.Context) {
 
 	c.JSON(http.StatusOK, shorts)
 }
+
+// PUT /video_short/shorts/:id
+func (h *ShortHandler) UpdateShort(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid short ID"})
+		return
+	}
+
+	userID, err := primitive.ObjectIDFromHex(c.GetString("userID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	var req request.UpdateShortRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	sh, err := h.service.UpdateShort(c.Request.Context(), id, userID, &req)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, sh)
+}
+
+// GET /video_short/shorts/:id/revisions
+func (h *ShortHandler) GetShortRevisions(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid short ID"})
+		return
+	}
+
+	userID, err := primitive.ObjectIDFromHex(c.GetString("userID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	revisions, err := h.service.ListRevisions(c.Request.Context(), id, userID)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, revisions)
+}
+
+func writeError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrShortNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrNotOwner):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrNoChanges), errors.Is(err, ErrEmptyTitle):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, contentfilter.ErrRejected):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -8,15 +8,27 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type ShortRepository interface {
 	Create(ctx context.Context, short *models.Short) error
 	GetByID(ctx context.Context, id primitive.ObjectID) (*models.Short, error)
+	GetForProcessing(ctx context.Context, id primitive.ObjectID) (*models.Short, error)
+	GetForOwner(ctx context.Context, id, ownerID primitive.ObjectID) (*models.Short, error)
 	GetByOwner(ctx context.Context, ownerID primitive.ObjectID) ([]models.Short, error)
 	IncrementViews(ctx context.Context, id primitive.ObjectID) error
+	IncrementViewStats(ctx context.Context, id primitive.ObjectID, views int, watchTime, completion float64) error
 	Delete(ctx context.Context, id, ownerID primitive.ObjectID) error
 	FindByOwnerAndVisibility(ctx context.Context, ownerID primitive.ObjectID, visibility string) ([]models.Short, error)
+	UpdateFields(ctx context.Context, id primitive.ObjectID, fields bson.M) error
+	GetByIDs(ctx context.Context, ids []primitive.ObjectID) ([]models.Short, error)
+	FindFeedCandidates(ctx context.Context, since, until time.Time, excludeOwners []primitive.ObjectID, limit int64) ([]models.Short, error)
+	ListDeleted(ctx context.Context, ownerID primitive.ObjectID, since time.Time) ([]models.Short, error)
+	Restore(ctx context.Context, id, ownerID primitive.ObjectID, since time.Time) error
+	FindPurgeable(ctx context.Context, before time.Time, limit int64) ([]models.Short, error)
+	Purge(ctx context.Context, id primitive.ObjectID) error
+	FindAllByOwner(ctx context.Context, ownerID primitive.ObjectID, limit int64) ([]models.Short, error)
 }
 
 type shortRepository struct {
@@ -27,6 +39,14 @@ func NewShortRepository(db *mongo.Database) ShortRepository {
 	return &shortRepository{collection: db.Collection("shorts")}
 }
 
+// visible thêm điều kiện loại bỏ short đã xoá mềm, bị ẩn do vi phạm hoặc của tài khoản đã vô hiệu hoá
+func visible(filter bson.M) bson.M {
+	filter["deletedAt"] = nil
+	filter["ownerInactive"] = bson.M{"$ne": true}
+	filter["moderationHidden"] = bson.M{"$ne": true}
+	return filter
+}
+
 func (r *shortRepository) Create(ctx context.Context, short *models.Short) error {
 	short.CreatedAt = time.Now()
 	_, err := r.collection.InsertOne(ctx, short)
@@ -35,12 +55,26 @@ func (r *shortRepository) Create(ctx context.Context, short *models.Short) error
 
 func (r *shortRepository) GetByID(ctx context.Context, id primitive.ObjectID) (*models.Short, error) {
 	var s models.Short
-	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&s)
+	err := r.collection.FindOne(ctx, visible(bson.M{"_id": id})).Decode(&s)
+	return &s, err
+}
+
+// GetForProcessing - kể cả short đang bị ẩn chờ kiểm duyệt (worker vẫn phải transcode)
+func (r *shortRepository) GetForProcessing(ctx context.Context, id primitive.ObjectID) (*models.Short, error) {
+	var s models.Short
+	err := r.collection.FindOne(ctx, bson.M{"_id": id, "deletedAt": nil}).Decode(&s)
+	return &s, err
+}
+
+// GetForOwner - short của chủ sở hữu, kể cả đang bị ẩn chờ kiểm duyệt (chủ vẫn phải sửa được)
+func (r *shortRepository) GetForOwner(ctx context.Context, id, ownerID primitive.ObjectID) (*models.Short, error) {
+	var s models.Short
+	err := r.collection.FindOne(ctx, bson.M{"_id": id, "ownerId": ownerID, "deletedAt": nil}).Decode(&s)
 	return &s, err
 }
 
 func (r *shortRepository) GetByOwner(ctx context.Context, ownerID primitive.ObjectID) ([]models.Short, error) {
-	cursor, err := r.collection.Find(ctx, bson.M{"ownerId": ownerID})
+	cursor, err := r.collection.Find(ctx, visible(bson.M{"ownerId": ownerID}))
 	if err != nil {
 		return nil, err
 	}
@@ -57,15 +91,26 @@ func (r *shortRepository) IncrementViews(ctx context.Context, id primitive.Objec
 }
 
 func (r *shortRepository) Delete(ctx context.Context, id, ownerID primitive.ObjectID) error {
-	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": id, "ownerId": ownerID})
-	return err
+	res, err := r.collection.UpdateOne(ctx,
+		visible(bson.M{"_id": id, "ownerId": ownerID}),
+		bson.M{"$set": bson.M{"deletedAt": time.Now()}},
+	)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
 }
 
 func (r *shortRepository) FindByOwnerAndVisibility(ctx context.Context, ownerID primitive.ObjectID, visibility string) ([]models.Short, error) {
-    filter := bson.M{
-        "owner_id":   ownerID,
+    filter := visible(bson.M{
+        "ownerId":   ownerID,
         "visibility": visibility,
-    }
+        // Chỉ lấy nội dung đã xử lý xong ($in nil để khớp cả dữ liệu cũ chưa có status)
+        "status": bson.M{"$in": bson.A{models.ProcessingReady, nil}},
+    })
     cursor, err := r.collection.Find(ctx, filter)
     if err != nil {
         return nil, err
@@ -76,3 +121,118 @@ func (r *shortRepository) FindByOwnerAndVisibility(ctx context.Context, ownerID
     }
     return shorts, nil
 }
+
+func (r *shortRepository) UpdateFields(ctx context.Context, id primitive.ObjectID, fields bson.M) error {
+	res, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": fields})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// IncrementViewStats cộng dồn lượt xem/thời gian xem (dùng khi flush theo lô)
+func (r *shortRepository) IncrementViewStats(ctx context.Context, id primitive.ObjectID, views int, watchTime, completion float64) error {
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$inc": bson.M{
+		"views":           views,
+		"watchTime":       watchTime,
+		"completionTotal": completion,
+	}})
+	return err
+}
+
+func (r *shortRepository) GetByIDs(ctx context.Context, ids []primitive.ObjectID) ([]models.Short, error) {
+	cursor, err := r.collection.Find(ctx, visible(bson.M{"_id": bson.M{"$in": ids}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var shorts []models.Short
+	err = cursor.All(ctx, &shorts)
+	return shorts, err
+}
+
+// FindFeedCandidates lấy các short public đã xử lý xong trong khoảng [since, until]
+func (r *shortRepository) FindFeedCandidates(ctx context.Context, since, until time.Time, excludeOwners []primitive.ObjectID, limit int64) ([]models.Short, error) {
+	filter := visible(bson.M{
+		"visibility": "public",
+		"status":     bson.M{"$in": bson.A{models.ProcessingReady, nil}},
+		"createdAt":  bson.M{"$gte": since, "$lte": until},
+	})
+	if len(excludeOwners) > 0 {
+		filter["ownerId"] = bson.M{"$nin": excludeOwners}
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}, {Key: "_id", Value: -1}}).SetLimit(limit)
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var shorts []models.Short
+	err = cursor.All(ctx, &shorts)
+	return shorts, err
+}
+
+// ListDeleted - short trong thùng rác của owner, xoá sau thời điểm since
+func (r *shortRepository) ListDeleted(ctx context.Context, ownerID primitive.ObjectID, since time.Time) ([]models.Short, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "deletedAt", Value: -1}})
+	cursor, err := r.collection.Find(ctx, bson.M{"ownerId": ownerID, "deletedAt": bson.M{"$gte": since}}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var items []models.Short
+	err = cursor.All(ctx, &items)
+	return items, err
+}
+
+func (r *shortRepository) Restore(ctx context.Context, id, ownerID primitive.ObjectID, since time.Time) error {
+	res, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": id, "ownerId": ownerID, "deletedAt": bson.M{"$gte": since}},
+		bson.M{"$unset": bson.M{"deletedAt": ""}},
+	)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// FindPurgeable - short đã xoá trước thời điểm before (hết hạn lưu)
+func (r *shortRepository) FindPurgeable(ctx context.Context, before time.Time, limit int64) ([]models.Short, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{"deletedAt": bson.M{"$lt": before}}, options.Find().SetLimit(limit))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var items []models.Short
+	err = cursor.All(ctx, &items)
+	return items, err
+}
+
+func (r *shortRepository) Purge(ctx context.Context, id primitive.ObjectID) error {
+	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
+
+// FindAllByOwner - mọi short của owner, kể cả đã xoá mềm (dùng khi xoá tài khoản)
+func (r *shortRepository) FindAllByOwner(ctx context.Context, ownerID primitive.ObjectID, limit int64) ([]models.Short, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{"ownerId": ownerID}, options.Find().SetLimit(limit))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var items []models.Short
+	err = cursor.All(ctx, &items)
+	return items, err
+}
@@ -2,12 +2,20 @@ package short
 
 import (
 	"context"
+	"errors"
+	"strings"
 	"time"
 
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"socialnetwork/dto/request"
+	"socialnetwork/internal/contentfilter"
 	"socialnetwork/internal/follow"
 	"socialnetwork/internal/notification"
+	"socialnetwork/internal/revision"
 	"socialnetwork/models"
+	"socialnetwork/pkg/media"
 )
 
 type ShortService interface {
@@ -17,43 +25,91 @@ type ShortService interface {
 	IncrementView(ctx context.Context, id primitive.ObjectID) error
 	DeleteShort(ctx context.Context, id, ownerID primitive.ObjectID) error
 	GetPublicShortsByOwner(ctx context.Context, ownerID primitive.ObjectID) ([]models.Short, error)
+	NotifyFollowers(ctx context.Context, sh *models.Short) error
+	UpdateShort(ctx context.Context, id, editorID primitive.ObjectID, req *request.UpdateShortRequest) (*models.Short, error)
+	ListRevisions(ctx context.Context, id, requesterID primitive.ObjectID) ([]models.Revision, error)
 }
 
 type shortService struct {
 	repo             ShortRepository
 	followRepo       follow.FollowRepository
 	notificationRepo notification.NotificationRepository
+	jobQueue         media.JobQueue
+	revisionRepo     revision.Repository
+	filter           contentfilter.Filter
 }
 
 func NewShortService(
 	repo ShortRepository,
 	followRepo follow.FollowRepository,
 	notificationRepo notification.NotificationRepository,
+	jobQueue media.JobQueue,
+	revisionRepo revision.Repository,
+	filter contentfilter.Filter,
 ) ShortService {
-	return &shortService{repo, followRepo, notificationRepo}
+	return &shortService{repo, followRepo, notificationRepo, jobQueue, revisionRepo, filter}
 }
 
 func (s *shortService) CreateShort(ctx context.Context, sh *models.Short) error {
-	err := s.repo.Create(ctx, sh)
+	// Thời lượng thật (< 60s) được worker kiểm tra sau khi probe file
+	sh.ID = primitive.NewObjectID()
+	sh.Status = models.ProcessingPending
+	sh.HLSURL = ""
+	sh.Renditions = nil
+	sh.ProcessingError = ""
+	sh.ProcessedAt = nil
+	sh.PublishedAt = nil
+	sh.Edited = false
+	sh.EditedAt = nil
+
+	// Short bị bộ lọc giữ lại vẫn được xử lý media nhưng ẩn cho tới khi kiểm duyệt xong
+	verdict, err := contentfilter.Apply(s.filter, &sh.Title)
 	if err != nil {
 		return err
 	}
+	sh.ModerationHidden = verdict.Held()
 
-	followers, err := s.followRepo.GetFollowers(ctx, sh.OwnerID)
-	if err == nil {
-		for _, f := range followers {
-			noti := &models.Notification{
-				Recipient: f.Follower,
-				Sender:    sh.OwnerID,
-				Type:      models.NotificationNewShort,
-				ShortID:   &sh.ID,
-				IsRead:    false,
-				CreatedAt: time.Now(),
-			}
-			_ = s.notificationRepo.Create(ctx, noti)
+	err = s.repo.Create(ctx, sh)
+	if err != nil {
+		return err
+	}
+	if verdict.Held() {
+		if err := s.filter.Hold(ctx, models.ReportTargetShort, sh.ID, sh.OwnerID, verdict.Rules); err != nil {
+			return err
 		}
 	}
 
+	return s.jobQueue.Enqueue(ctx, media.Job{Kind: media.KindShort, ID: sh.ID.Hex()})
+}
+
+// NotifyFollowers gửi thông báo short mới đến followers (chỉ với short public,
+// chưa thông báo lần nào, không bị ẩn chờ kiểm duyệt)
+func (s *shortService) NotifyFollowers(ctx context.Context, sh *models.Short) error {
+	if sh.Visibility != "public" || sh.PublishedAt != nil || sh.ModerationHidden {
+		return nil
+	}
+
+	now := time.Now()
+	if err := s.repo.UpdateFields(ctx, sh.ID, bson.M{"publishedAt": now}); err != nil {
+		return err
+	}
+	sh.PublishedAt = &now
+
+	followers, err := s.followRepo.GetFollowers(ctx, sh.OwnerID)
+	if err != nil {
+		return err
+	}
+	for _, f := range followers {
+		noti := &models.Notification{
+			Recipient: f.Follower,
+			Sender:    sh.OwnerID,
+			Type:      models.NotificationNewShort,
+			ShortID:   &sh.ID,
+			IsRead:    false,
+			CreatedAt: time.Now(),
+		}
+		_ = s.notificationRepo.Create(ctx, noti)
+	}
 	return nil
 }
 
@@ -69,10 +125,122 @@ func (s *shortService) IncrementView(ctx context.Context, id primitive.ObjectID)
 	return s.repo.IncrementViews(ctx, id)
 }
 
+// DeleteShort chuyển short vào thùng rác, có thể khôi phục trong thời hạn lưu
 func (s *shortService) DeleteShort(ctx context.Context, id, ownerID primitive.ObjectID) error {
-	return s.repo.Delete(ctx, id, ownerID)
+	if err := s.repo.Delete(ctx, id, ownerID); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return ErrShortNotFound
+		}
+		return err
+	}
+	return nil
 }
 
 func (s *shortService) GetPublicShortsByOwner(ctx context.Context, ownerID primitive.ObjectID) ([]models.Short, error) {
     return s.repo.FindByOwnerAndVisibility(ctx, ownerID, "public")
 }
+
+
+func (s *shortService) UpdateShort(ctx context.Context, id, editorID primitive.ObjectID, req *request.UpdateShortRequest) (*models.Short, error) {
+	sh, err := s.repo.GetForOwner(ctx, id, editorID)
+	if err != nil {
+		return nil, ErrShortNotFound
+	}
+
+	updates := map[string]interface{}{}
+	if req.Title != nil {
+		title := strings.TrimSpace(*req.Title)
+		if title == "" {
+			return nil, ErrEmptyTitle
+		}
+		updates["title"] = title
+	}
+	if req.Thumbnail != nil {
+		updates["thumbnail"] = *req.Thumbnail
+	}
+	if req.Visibility != nil {
+		updates["visibility"] = *req.Visibility
+	}
+
+	var verdict contentfilter.Verdict
+	if title, ok := updates["title"].(string); ok {
+		if verdict, err = contentfilter.Apply(s.filter, &title); err != nil {
+			return nil, err
+		}
+		updates["title"] = title
+	}
+
+	previous := map[string]interface{}{
+		"title":      sh.Title,
+		"thumbnail":  sh.Thumbnail,
+		"visibility": sh.Visibility,
+	}
+	changed, rev := revision.Diff(revision.ContentShort, id, editorID, previous, updates)
+	if len(changed) == 0 {
+		return nil, ErrNoChanges
+	}
+
+	now := time.Now()
+	changed["edited"] = true
+	changed["editedAt"] = now
+	if verdict.Held() {
+		changed["moderationHidden"] = true
+	}
+	if err := s.repo.UpdateFields(ctx, id, changed); err != nil {
+		return nil, err
+	}
+	revision.Save(ctx, s.revisionRepo, rev)
+	if verdict.Held() {
+		sh.ModerationHidden = true
+		if err := s.filter.Hold(ctx, models.ReportTargetShort, id, sh.OwnerID, verdict.Rules); err != nil {
+			return nil, err
+		}
+	}
+
+	oldVisibility := sh.Visibility
+	if v, ok := changed["title"].(string); ok {
+		sh.Title = v
+	}
+	if v, ok := changed["thumbnail"].(string); ok {
+		sh.Thumbnail = v
+	}
+	if v, ok := changed["visibility"].(string); ok {
+		sh.Visibility = v
+	}
+	sh.Edited = true
+	sh.EditedAt = &now
+
+	if err := s.applyVisibilityTransition(ctx, sh, oldVisibility); err != nil {
+		return nil, err
+	}
+	return sh, nil
+}
+
+// applyVisibilityTransition: chuyển sang private thì thu hồi thông báo đã gửi,
+// chuyển sang public (và đã xử lý xong) thì thông báo cho followers nếu chưa từng thông báo.
+// publishedAt giữ nguyên khi ẩn đi để đổi qua lại không gửi thông báo lặp lại.
+func (s *shortService) applyVisibilityTransition(ctx context.Context, sh *models.Short, oldVisibility string) error {
+	if sh.Visibility == oldVisibility {
+		return nil
+	}
+
+	if sh.Visibility == "public" {
+		if !models.IsProcessed(sh.Status) {
+			return nil // worker sẽ thông báo khi xử lý xong
+		}
+		return s.NotifyFollowers(ctx, sh)
+	}
+
+	return s.notificationRepo.DeleteByShort(ctx, sh.ID)
+}
+
+func (s *shortService) ListRevisions(ctx context.Context, id, requesterID primitive.ObjectID) ([]models.Revision, error) {
+	sh, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, ErrShortNotFound
+	}
+	if sh.OwnerID != requesterID {
+		return nil, ErrNotOwner
+	}
+	return s.revisionRepo.ListByContent(ctx, revision.ContentShort, id)
+}
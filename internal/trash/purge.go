@@ -0,0 +1,233 @@
+package trash
+
+import (
+	"context"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"socialnetwork/internal/analytics"
+	"socialnetwork/internal/revision"
+	"socialnetwork/internal/views"
+	"socialnetwork/models"
+	"socialnetwork/pkg/media"
+)
+
+// RunPurger định kỳ xoá vĩnh viễn nội dung đã hết hạn lưu trong thùng rác
+func (s *service) RunPurger(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := s.Purge(ctx)
+			if err != nil {
+				log.Printf("⚠️ dọn thùng rác thất bại: %v", err)
+			} else if n > 0 {
+				log.Printf("🗑️ đã xoá vĩnh viễn %d nội dung trong thùng rác", n)
+			}
+		}
+	}
+}
+
+// Purge xoá vĩnh viễn (kèm dữ liệu phụ thuộc) các nội dung xoá trước hạn lưu.
+// Mỗi loại xử lý tối đa PurgeBatchSize mục mỗi lần chạy.
+func (s *service) Purge(ctx context.Context) (int, error) {
+	before := s.cutoff()
+	total := 0
+
+	for _, purge := range []func(context.Context, time.Time) (int, error){
+		s.purgePosts,
+		s.purgeComments,
+		s.purgeVideos,
+		s.purgeShorts,
+	} {
+		n, err := purge(ctx, before)
+		total += n
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+func (s *service) purgePosts(ctx context.Context, before time.Time) (int, error) {
+	posts, err := s.postRepo.FindPurgeable(ctx, before, s.config.PurgeBatchSize)
+	if err != nil {
+		return 0, err
+	}
+	return s.purgePostList(ctx, posts)
+}
+
+// purgePostList xoá post cùng comment, thông báo và lịch sử chỉnh sửa liên quan
+func (s *service) purgePostList(ctx context.Context, posts []models.Post) (int, error) {
+	for i, p := range posts {
+		commentIDs, err := s.commentRepo.PurgeByPost(ctx, p.ID)
+		if err != nil {
+			return i, err
+		}
+		for _, id := range commentIDs {
+			if err := s.notificationRepo.DeleteByComment(ctx, id); err != nil {
+				return i, err
+			}
+		}
+		if err := s.revisionRepo.DeleteByContent(ctx, revision.ContentComment, commentIDs); err != nil {
+			return i, err
+		}
+		if err := s.analyticsRepo.DeleteReactions(ctx, analytics.ReactionComment, commentIDs); err != nil {
+			return i, err
+		}
+		if err := s.notificationRepo.DeleteByPost(ctx, p.ID); err != nil {
+			return i, err
+		}
+		if err := s.revisionRepo.DeleteByContent(ctx, revision.ContentPost, []primitive.ObjectID{p.ID}); err != nil {
+			return i, err
+		}
+		if err := s.postRepo.Purge(ctx, p.ID); err != nil {
+			return i, err
+		}
+	}
+	return len(posts), nil
+}
+
+func (s *service) purgeComments(ctx context.Context, before time.Time) (int, error) {
+	comments, err := s.commentRepo.FindPurgeable(ctx, before, s.config.PurgeBatchSize)
+	if err != nil {
+		return 0, err
+	}
+	return s.purgeCommentList(ctx, comments)
+}
+
+// purgeCommentList xoá comment cùng thông báo, lượt thích đã ghi và lịch sử chỉnh sửa liên quan
+func (s *service) purgeCommentList(ctx context.Context, comments []*models.Comment) (int, error) {
+	for i, c := range comments {
+		if err := s.notificationRepo.DeleteByComment(ctx, c.ID); err != nil {
+			return i, err
+		}
+		if err := s.revisionRepo.DeleteByContent(ctx, revision.ContentComment, []primitive.ObjectID{c.ID}); err != nil {
+			return i, err
+		}
+		if err := s.analyticsRepo.DeleteReactions(ctx, analytics.ReactionComment, []primitive.ObjectID{c.ID}); err != nil {
+			return i, err
+		}
+		if err := s.commentRepo.Purge(ctx, c.ID); err != nil {
+			return i, err
+		}
+	}
+	return len(comments), nil
+}
+
+func (s *service) purgeVideos(ctx context.Context, before time.Time) (int, error) {
+	videos, err := s.videoRepo.FindPurgeable(ctx, before, s.config.PurgeBatchSize)
+	if err != nil {
+		return 0, err
+	}
+	return s.purgeVideoList(ctx, videos)
+}
+
+// purgeVideoList xoá video cùng thông báo, lịch sử chỉnh sửa, view event và file HLS
+func (s *service) purgeVideoList(ctx context.Context, videos []models.Video) (int, error) {
+	for i, v := range videos {
+		if err := s.notificationRepo.DeleteByVideo(ctx, v.ID); err != nil {
+			return i, err
+		}
+		if err := s.purgeMedia(ctx, media.KindVideo, v.ID); err != nil {
+			return i, err
+		}
+		if err := s.videoRepo.Purge(ctx, v.ID); err != nil {
+			return i, err
+		}
+	}
+	return len(videos), nil
+}
+
+func (s *service) purgeShorts(ctx context.Context, before time.Time) (int, error) {
+	shorts, err := s.shortRepo.FindPurgeable(ctx, before, s.config.PurgeBatchSize)
+	if err != nil {
+		return 0, err
+	}
+	return s.purgeShortList(ctx, shorts)
+}
+
+func (s *service) purgeShortList(ctx context.Context, shorts []models.Short) (int, error) {
+	for i, sh := range shorts {
+		if err := s.notificationRepo.DeleteByShort(ctx, sh.ID); err != nil {
+			return i, err
+		}
+		if err := s.purgeMedia(ctx, media.KindShort, sh.ID); err != nil {
+			return i, err
+		}
+		if err := s.shortRepo.Purge(ctx, sh.ID); err != nil {
+			return i, err
+		}
+	}
+	return len(shorts), nil
+}
+
+// PurgeOwner xoá vĩnh viễn toàn bộ nội dung của một user (kể cả chưa vào thùng rác),
+// dùng khi tài khoản bị xoá
+func (s *service) PurgeOwner(ctx context.Context, ownerID primitive.ObjectID) (int, error) {
+	limit := s.config.PurgeBatchSize
+	total := 0
+	for {
+		posts, err := s.postRepo.FindAllByOwner(ctx, ownerID, limit)
+		if err != nil {
+			return total, err
+		}
+		comments, err := s.commentRepo.FindAllByUser(ctx, ownerID, limit)
+		if err != nil {
+			return total, err
+		}
+		videos, err := s.videoRepo.FindAllByOwner(ctx, ownerID, limit)
+		if err != nil {
+			return total, err
+		}
+		shorts, err := s.shortRepo.FindAllByOwner(ctx, ownerID, limit)
+		if err != nil {
+			return total, err
+		}
+		if len(posts)+len(comments)+len(videos)+len(shorts) == 0 {
+			return total, nil
+		}
+
+		// Comment của user trên post của chính họ đã bị xoá cùng post, Purge bỏ qua khi không còn
+		steps := []func() (int, error){
+			func() (int, error) { return s.purgePostList(ctx, posts) },
+			func() (int, error) { return s.purgeCommentList(ctx, comments) },
+			func() (int, error) { return s.purgeVideoList(ctx, videos) },
+			func() (int, error) { return s.purgeShortList(ctx, shorts) },
+		}
+		for _, step := range steps {
+			n, err := step()
+			total += n
+			if err != nil {
+				return total, err
+			}
+		}
+	}
+}
+
+// purgeMedia xoá dữ liệu phụ của video/short: revisions, view_events và thư mục
+// HLS + thumbnail (cùng cấu trúc <OutputDir>/<kind>s/<id> của worker xử lý media)
+func (s *service) purgeMedia(ctx context.Context, kind string, id primitive.ObjectID) error {
+	contentType, revisionType := views.ContentVideo, revision.ContentVideo
+	if kind == media.KindShort {
+		contentType, revisionType = views.ContentShort, revision.ContentShort
+	}
+
+	if err := s.revisionRepo.DeleteByContent(ctx, revisionType, []primitive.ObjectID{id}); err != nil {
+		return err
+	}
+	if err := s.viewRepo.DeleteByContent(ctx, contentType, id); err != nil {
+		return err
+	}
+	if s.config.MediaDir == "" {
+		return nil
+	}
+	return os.RemoveAll(filepath.Join(s.config.MediaDir, kind+"s", id.Hex()))
+}
@@ -0,0 +1,203 @@
+package trash
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"socialnetwork/internal/analytics"
+	"socialnetwork/internal/comment"
+	"socialnetwork/internal/notification"
+	"socialnetwork/internal/post"
+	"socialnetwork/internal/revision"
+	"socialnetwork/internal/short"
+	"socialnetwork/internal/video"
+	"socialnetwork/internal/views"
+)
+
+// Loại nội dung có thể nằm trong thùng rác
+const (
+	TypePost    = "post"
+	TypeComment = "comment"
+	TypeVideo   = "video"
+	TypeShort   = "short"
+)
+
+var (
+	ErrInvalidType   = errors.New("loại nội dung không hợp lệ")
+	ErrNotFound      = errors.New("không tìm thấy nội dung trong thùng rác hoặc đã hết hạn khôi phục")
+	ErrParentDeleted = errors.New("bài viết chứa bình luận này đã bị xoá; hãy khôi phục bài viết trước")
+)
+
+type Config struct {
+	Retention      time.Duration // thời gian giữ trong thùng rác trước khi xoá vĩnh viễn
+	PurgeBatchSize int64
+	MediaDir       string // thư mục HLS/thumbnail do worker xử lý media tạo ra
+}
+
+// Item - một nội dung trong thùng rác
+type Item struct {
+	Type      string             `json:"type"`
+	ID        primitive.ObjectID `json:"id"`
+	Title     string             `json:"title"`
+	Thumbnail string             `json:"thumbnail,omitempty"`
+	DeletedAt time.Time          `json:"deletedAt"`
+	PurgeAt   time.Time          `json:"purgeAt"` // sau thời điểm này không thể khôi phục
+}
+
+type Service interface {
+	List(ctx context.Context, userID primitive.ObjectID) ([]Item, error)
+	Restore(ctx context.Context, userID primitive.ObjectID, contentType string, id primitive.ObjectID) error
+	Purge(ctx context.Context) (int, error)
+	PurgeOwner(ctx context.Context, ownerID primitive.ObjectID) (int, error)
+	RunPurger(ctx context.Context, interval time.Duration)
+}
+
+type service struct {
+	postRepo         *post.PostRepository
+	commentRepo      comment.Repository
+	videoRepo        video.VideoRepository
+	shortRepo        short.ShortRepository
+	notificationRepo notification.NotificationRepository
+	revisionRepo     revision.Repository
+	viewRepo         views.Repository
+	analyticsRepo    analytics.Repository
+	config           Config
+}
+
+func NewService(
+	postRepo *post.PostRepository,
+	commentRepo comment.Repository,
+	videoRepo video.VideoRepository,
+	shortRepo short.ShortRepository,
+	notificationRepo notification.NotificationRepository,
+	revisionRepo revision.Repository,
+	viewRepo views.Repository,
+	analyticsRepo analytics.Repository,
+	config Config,
+) Service {
+	if config.Retention <= 0 {
+		config.Retention = 30 * 24 * time.Hour
+	}
+	if config.PurgeBatchSize <= 0 {
+		config.PurgeBatchSize = 100
+	}
+	return &service{
+		postRepo:         postRepo,
+		commentRepo:      commentRepo,
+		videoRepo:        videoRepo,
+		shortRepo:        shortRepo,
+		notificationRepo: notificationRepo,
+		revisionRepo:     revisionRepo,
+		viewRepo:         viewRepo,
+		analyticsRepo:    analyticsRepo,
+		config:           config,
+	}
+}
+
+// cutoff - nội dung xoá trước thời điểm này đã hết hạn khôi phục
+func (s *service) cutoff() time.Time {
+	return time.Now().Add(-s.config.Retention)
+}
+
+func (s *service) List(ctx context.Context, userID primitive.ObjectID) ([]Item, error) {
+	since := s.cutoff()
+	items := []Item{}
+
+	posts, err := s.postRepo.ListDeleted(ctx, userID, since)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range posts {
+		items = append(items, s.item(TypePost, p.ID, preview(p.Content), p.ImageURL, p.DeletedAt))
+	}
+
+	comments, err := s.commentRepo.ListDeleted(ctx, userID, since)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range comments {
+		items = append(items, s.item(TypeComment, c.ID, preview(c.Content), "", c.DeletedAt))
+	}
+
+	videos, err := s.videoRepo.ListDeleted(ctx, userID, since)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range videos {
+		items = append(items, s.item(TypeVideo, v.ID, v.Title, v.Thumbnail, v.DeletedAt))
+	}
+
+	shorts, err := s.shortRepo.ListDeleted(ctx, userID, since)
+	if err != nil {
+		return nil, err
+	}
+	for _, sh := range shorts {
+		items = append(items, s.item(TypeShort, sh.ID, sh.Title, sh.Thumbnail, sh.DeletedAt))
+	}
+
+	// Mới xoá gần nhất lên đầu
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].DeletedAt.After(items[j].DeletedAt)
+	})
+	return items, nil
+}
+
+func (s *service) item(contentType string, id primitive.ObjectID, title, thumbnail string, deletedAt *time.Time) Item {
+	it := Item{Type: contentType, ID: id, Title: title, Thumbnail: thumbnail}
+	if deletedAt != nil {
+		it.DeletedAt = *deletedAt
+		it.PurgeAt = deletedAt.Add(s.config.Retention)
+	}
+	return it
+}
+
+func preview(content string) string {
+	content = strings.TrimSpace(content)
+	if r := []rune(content); len(r) > 80 {
+		return string(r[:80]) + "…"
+	}
+	return content
+}
+
+func (s *service) Restore(ctx context.Context, userID primitive.ObjectID, contentType string, id primitive.ObjectID) error {
+	since := s.cutoff()
+
+	var err error
+	switch contentType {
+	case TypePost:
+		err = s.postRepo.Restore(ctx, id, userID, since)
+	case TypeComment:
+		err = s.restoreComment(ctx, id, userID, since)
+	case TypeVideo:
+		err = s.videoRepo.Restore(ctx, id, userID, since)
+	case TypeShort:
+		err = s.shortRepo.Restore(ctx, id, userID, since)
+	default:
+		return ErrInvalidType
+	}
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// restoreComment - không khôi phục bình luận khi bài viết gốc đang trong thùng rác hoặc đã bị xoá vĩnh viễn
+func (s *service) restoreComment(ctx context.Context, id, userID primitive.ObjectID, since time.Time) error {
+	c, err := s.commentRepo.FindDeleted(ctx, id, userID, since)
+	if err != nil {
+		return err
+	}
+	exists, err := s.postRepo.Exists(ctx, c.PostID)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrParentDeleted
+	}
+	return s.commentRepo.Restore(ctx, id, userID, since)
+}
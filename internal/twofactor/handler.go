@@ -0,0 +1,183 @@
+package twofactor
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"socialnetwork/dto/request"
+	"socialnetwork/internal/otp"
+	"socialnetwork/internal/user"
+	"socialnetwork/models"
+	"socialnetwork/pkg/ratelimit"
+)
+
+type Handler struct {
+	service     Service
+	userService user.Service
+}
+
+func NewHandler(service Service, userService user.Service) *Handler {
+	return &Handler{service: service, userService: userService}
+}
+
+// GET /me/2fa
+func (h *Handler) GetStatus(c *gin.Context) {
+	userID, err := primitive.ObjectIDFromHex(c.GetString("userID"))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	status, err := h.service.Status(c.Request.Context(), userID)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// POST /me/2fa/setup
+func (h *Handler) Setup(c *gin.Context) {
+	userID, err := primitive.ObjectIDFromHex(c.GetString("userID"))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	result, err := h.service.Setup(c.Request.Context(), userID)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// POST /me/2fa/confirm
+func (h *Handler) Confirm(c *gin.Context) {
+	userID, err := primitive.ObjectIDFromHex(c.GetString("userID"))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req request.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	codes, err := h.service.Confirm(c.Request.Context(), userID, req.Code)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":       "Đã bật xác thực hai lớp. Hãy lưu các mã khôi phục ở nơi an toàn, chúng chỉ hiển thị một lần.",
+		"recoveryCodes": codes,
+	})
+}
+
+// POST /me/2fa/recovery-codes
+func (h *Handler) RegenerateRecoveryCodes(c *gin.Context) {
+	userID, err := primitive.ObjectIDFromHex(c.GetString("userID"))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req request.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	codes, err := h.service.RegenerateRecoveryCodes(c.Request.Context(), userID, req.Code)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"recoveryCodes": codes})
+}
+
+// DELETE /me/2fa
+func (h *Handler) Disable(c *gin.Context) {
+	userID, err := primitive.ObjectIDFromHex(c.GetString("userID"))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req request.TwoFactorDisableRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.service.Disable(c.Request.Context(), userID, req.Password, req.Code); err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Đã tắt xác thực hai lớp"})
+}
+
+// POST /auth/2fa/send
+func (h *Handler) SendCode(c *gin.Context) {
+	var req request.TwoFactorSendRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.service.SendChallengeOTP(c.Request.Context(), req.ChallengeToken, req.Channel); err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Mã xác thực đã được gửi"})
+}
+
+// POST /auth/2fa/verify
+func (h *Handler) Verify(c *gin.Context) {
+	var req request.TwoFactorVerifyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	u, err := h.service.Verify(c.Request.Context(), req.ChallengeToken, req.Method, req.Code)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	token, err := h.userService.CompleteLogin(user.WithLoginMethod(c.Request.Context(), models.LoginMethodTwoFactor), u)
+	if err != nil {
+		user.WriteLoginError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Đăng nhập thành công",
+		"token":   token,
+	})
+}
+
+func writeError(c *gin.Context, err error) {
+	if ratelimit.WriteError(c, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, ErrInvalidChallenge), errors.Is(err, ErrTooManyAttempts),
+		errors.Is(err, ErrInvalidCode), errors.Is(err, ErrWrongPassword),
+		errors.Is(err, otp.ErrInvalidCode), errors.Is(err, otp.ErrWrongCode):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrAlreadyEnabled), errors.Is(err, ErrNotEnabled), errors.Is(err, ErrNoPendingSetup):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrChannelUnavailable):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,95 @@
+package twofactor
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Repository - đọc/ghi trường twoFactor trong collection users
+type Repository interface {
+	SetPending(ctx context.Context, userID primitive.ObjectID, secret string) error
+	Enable(ctx context.Context, userID primitive.ObjectID, secret string, step int64, recoveryHashes []string) error
+	Disable(ctx context.Context, userID primitive.ObjectID) error
+	// UseStep ghi nhận step TOTP đã dùng; false nếu step này (hoặc mới hơn) đã được dùng trước đó
+	UseStep(ctx context.Context, userID primitive.ObjectID, step int64) (bool, error)
+	// UseRecoveryCode xoá mã khôi phục; false nếu mã không tồn tại hoặc đã dùng
+	UseRecoveryCode(ctx context.Context, userID primitive.ObjectID, hash string) (bool, error)
+	ReplaceRecoveryCodes(ctx context.Context, userID primitive.ObjectID, hashes []string) error
+}
+
+type repository struct {
+	collection *mongo.Collection
+}
+
+func NewRepository(db *mongo.Database) Repository {
+	return &repository{collection: db.Collection("users")}
+}
+
+func (r *repository) SetPending(ctx context.Context, userID primitive.ObjectID, secret string) error {
+	_, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": userID},
+		bson.M{"$set": bson.M{"twoFactor.pendingSecret": secret, "updatedAt": time.Now()}},
+	)
+	return err
+}
+
+func (r *repository) Enable(ctx context.Context, userID primitive.ObjectID, secret string, step int64, recoveryHashes []string) error {
+	now := time.Now()
+	_, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": userID},
+		bson.M{
+			"$set": bson.M{
+				"twoFactor.enabled":       true,
+				"twoFactor.secret":        secret,
+				"twoFactor.lastUsedStep":  step,
+				"twoFactor.recoveryCodes": recoveryHashes,
+				"twoFactor.enabledAt":     now,
+				"updatedAt":               now,
+			},
+			"$unset": bson.M{"twoFactor.pendingSecret": ""},
+		},
+	)
+	return err
+}
+
+func (r *repository) Disable(ctx context.Context, userID primitive.ObjectID) error {
+	_, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": userID},
+		bson.M{"$unset": bson.M{"twoFactor": ""}, "$set": bson.M{"updatedAt": time.Now()}},
+	)
+	return err
+}
+
+func (r *repository) UseStep(ctx context.Context, userID primitive.ObjectID, step int64) (bool, error) {
+	res, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": userID, "twoFactor.lastUsedStep": bson.M{"$lt": step}},
+		bson.M{"$set": bson.M{"twoFactor.lastUsedStep": step}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount == 1, nil
+}
+
+func (r *repository) UseRecoveryCode(ctx context.Context, userID primitive.ObjectID, hash string) (bool, error) {
+	res, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": userID, "twoFactor.recoveryCodes": hash},
+		bson.M{"$pull": bson.M{"twoFactor.recoveryCodes": hash}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount == 1, nil
+}
+
+func (r *repository) ReplaceRecoveryCodes(ctx context.Context, userID primitive.ObjectID, hashes []string) error {
+	_, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": userID},
+		bson.M{"$set": bson.M{"twoFactor.recoveryCodes": hashes, "updatedAt": time.Now()}},
+	)
+	return err
+}
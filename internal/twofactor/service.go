@@ -0,0 +1,449 @@
+package twofactor
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"socialnetwork/internal/audit"
+	"socialnetwork/internal/otp"
+	"socialnetwork/internal/user"
+	"socialnetwork/models"
+	"socialnetwork/pkg/auth"
+	"socialnetwork/pkg/totp"
+)
+
+// Các cách hoàn tất challenge đăng nhập
+const (
+	MethodTOTP     = "totp"
+	MethodRecovery = "recovery"
+	MethodEmail    = "email"
+	MethodPhone    = "phone"
+)
+
+var (
+	ErrAlreadyEnabled     = errors.New("xác thực hai lớp đã được bật")
+	ErrNotEnabled         = errors.New("xác thực hai lớp chưa được bật")
+	ErrNoPendingSetup     = errors.New("chưa bắt đầu thiết lập xác thực hai lớp")
+	ErrInvalidCode        = errors.New("mã xác thực không đúng hoặc đã được sử dụng")
+	ErrInvalidChallenge   = errors.New("phiên đăng nhập đã hết hạn, vui lòng đăng nhập lại")
+	ErrTooManyAttempts    = errors.New("nhập sai quá nhiều lần, vui lòng đăng nhập lại")
+	ErrChannelUnavailable = errors.New("tài khoản chưa có kênh nhận mã này")
+	ErrWrongPassword      = errors.New("mật khẩu không đúng")
+	ErrUserNotFound       = errors.New("người dùng không tồn tại")
+)
+
+type Config struct {
+	Issuer            string        // tên hiển thị trong ứng dụng authenticator
+	ChallengeTTL      time.Duration // thời gian để hoàn tất bước thứ hai sau khi nhập mật khẩu
+	MaxAttempts       int64         // số lần nhập sai tối đa cho một challenge
+	RecoveryCodeCount int
+}
+
+// SetupResult - secret và URI để client hiển thị QR code
+type SetupResult struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+type Status struct {
+	Enabled           bool       `json:"enabled"`
+	EnabledAt         *time.Time `json:"enabledAt,omitempty"`
+	RecoveryCodesLeft int        `json:"recoveryCodesLeft"`
+}
+
+type Service interface {
+	Status(ctx context.Context, userID primitive.ObjectID) (*Status, error)
+	Setup(ctx context.Context, userID primitive.ObjectID) (*SetupResult, error)
+	Confirm(ctx context.Context, userID primitive.ObjectID, code string) ([]string, error)
+	RegenerateRecoveryCodes(ctx context.Context, userID primitive.ObjectID, code string) ([]string, error)
+	Disable(ctx context.Context, userID primitive.ObjectID, password, code string) error
+	// VerifyCode xác thực lại user đang đăng nhập bằng mã TOTP hoặc mã khôi phục (trước thao tác nhạy cảm)
+	VerifyCode(ctx context.Context, u *models.User, code string) error
+
+	Challenge(ctx context.Context, u *models.User) (string, []string, error)
+	SendChallengeOTP(ctx context.Context, token, channel string) error
+	Verify(ctx context.Context, token, method, code string) (*models.User, error)
+}
+
+type service struct {
+	repo        Repository
+	userRepo    user.Repository
+	otpService  otp.Service
+	redisClient *redis.Client
+	audit       *audit.Logger
+	config      Config
+}
+
+func NewService(repo Repository, userRepo user.Repository, otpService otp.Service, redisClient *redis.Client, auditLog *audit.Logger, config Config) Service {
+	if config.Issuer == "" {
+		config.Issuer = "SocialNetwork"
+	}
+	if config.ChallengeTTL <= 0 {
+		config.ChallengeTTL = 5 * time.Minute
+	}
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = 5
+	}
+	if config.RecoveryCodeCount <= 0 {
+		config.RecoveryCodeCount = 10
+	}
+	return &service{
+		repo:        repo,
+		userRepo:    userRepo,
+		otpService:  otpService,
+		redisClient: redisClient,
+		audit:       auditLog,
+		config:      config,
+	}
+}
+
+func (s *service) getUser(ctx context.Context, userID primitive.ObjectID) (*models.User, error) {
+	u, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil || u == nil || u.DeletedAt != nil {
+		return nil, ErrUserNotFound
+	}
+	return u, nil
+}
+
+func enabled(u *models.User) bool {
+	return u.TwoFactor != nil && u.TwoFactor.Enabled
+}
+
+func (s *service) Status(ctx context.Context, userID primitive.ObjectID) (*Status, error) {
+	u, err := s.getUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !enabled(u) {
+		return &Status{}, nil
+	}
+	return &Status{
+		Enabled:           true,
+		EnabledAt:         u.TwoFactor.EnabledAt,
+		RecoveryCodesLeft: len(u.TwoFactor.RecoveryCodes),
+	}, nil
+}
+
+// Setup tạo secret mới ở trạng thái chờ; chỉ có hiệu lực sau khi Confirm bằng một mã đúng
+func (s *service) Setup(ctx context.Context, userID primitive.ObjectID) (*SetupResult, error) {
+	u, err := s.getUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if enabled(u) {
+		return nil, ErrAlreadyEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.SetPending(ctx, userID, secret); err != nil {
+		return nil, err
+	}
+
+	account := u.Email
+	if account == "" {
+		account = u.Username
+	}
+	return &SetupResult{Secret: secret, URI: totp.ProvisioningURI(s.config.Issuer, account, secret)}, nil
+}
+
+func (s *service) Confirm(ctx context.Context, userID primitive.ObjectID, code string) ([]string, error) {
+	u, err := s.getUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if enabled(u) {
+		return nil, ErrAlreadyEnabled
+	}
+	if u.TwoFactor == nil || u.TwoFactor.PendingSecret == "" {
+		return nil, ErrNoPendingSetup
+	}
+
+	step, ok := totp.Validate(u.TwoFactor.PendingSecret, strings.TrimSpace(code), time.Now(), 1)
+	if !ok {
+		return nil, ErrInvalidCode
+	}
+
+	codes, hashes, err := s.newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.Enable(ctx, userID, u.TwoFactor.PendingSecret, step, hashes); err != nil {
+		return nil, err
+	}
+	s.record(ctx, models.AuditTwoFactorEnabled, true, userID, nil)
+	return codes, nil
+}
+
+// RegenerateRecoveryCodes thay toàn bộ mã khôi phục cũ, cần một mã TOTP hợp lệ
+func (s *service) RegenerateRecoveryCodes(ctx context.Context, userID primitive.ObjectID, code string) ([]string, error) {
+	u, err := s.getUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !enabled(u) {
+		return nil, ErrNotEnabled
+	}
+	if err := s.checkTOTP(ctx, u, code); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := s.newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.ReplaceRecoveryCodes(ctx, userID, hashes); err != nil {
+		return nil, err
+	}
+	s.record(ctx, models.AuditRecoveryCodesRenewed, true, userID, nil)
+	return codes, nil
+}
+
+func (s *service) Disable(ctx context.Context, userID primitive.ObjectID, password, code string) error {
+	u, err := s.getUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !enabled(u) {
+		return ErrNotEnabled
+	}
+	if !auth.CheckPasswordHash(password, u.Password) {
+		return ErrWrongPassword
+	}
+
+	if err := s.checkTOTP(ctx, u, code); err != nil {
+		if err := s.checkRecoveryCode(ctx, u, code); err != nil {
+			return err
+		}
+	}
+	if err := s.repo.Disable(ctx, userID); err != nil {
+		return err
+	}
+	s.record(ctx, models.AuditTwoFactorDisabled, true, userID, nil)
+	return nil
+}
+
+func (s *service) VerifyCode(ctx context.Context, u *models.User, code string) error {
+	if !enabled(u) {
+		return ErrNotEnabled
+	}
+	if err := s.checkTOTP(ctx, u, code); err != nil {
+		if err := s.checkRecoveryCode(ctx, u, code); err != nil {
+			s.record(ctx, models.AuditTwoFactorFailed, false, u.ID, map[string]interface{}{"reason": "reauth"})
+			return err
+		}
+	}
+	return nil
+}
+
+func challengeKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return "2fa:challenge:" + hex.EncodeToString(sum[:])
+}
+
+// Challenge tạo token ngắn hạn thay cho JWT khi user đã qua bước mật khẩu; Redis chỉ lưu hash của token
+func (s *service) Challenge(ctx context.Context, u *models.User) (string, []string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", nil, err
+	}
+	token := base64.RawURLEncoding.EncodeToString(buf)
+
+	key := challengeKey(token)
+	pipe := s.redisClient.TxPipeline()
+	pipe.HSet(ctx, key, "userID", u.ID.Hex(), "attempts", 0)
+	pipe.Expire(ctx, key, s.config.ChallengeTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return "", nil, err
+	}
+
+	methods := []string{MethodTOTP}
+	if len(u.TwoFactor.RecoveryCodes) > 0 {
+		methods = append(methods, MethodRecovery)
+	}
+	if u.Email != "" {
+		methods = append(methods, MethodEmail)
+	}
+	if u.Phone != "" && u.PhoneVerified {
+		methods = append(methods, MethodPhone)
+	}
+	return token, methods, nil
+}
+
+func (s *service) challengeUser(ctx context.Context, token string) (*models.User, error) {
+	userIDHex, err := s.redisClient.HGet(ctx, challengeKey(token), "userID").Result()
+	if err == redis.Nil {
+		return nil, ErrInvalidChallenge
+	}
+	if err != nil {
+		return nil, err
+	}
+	userID, err := primitive.ObjectIDFromHex(userIDHex)
+	if err != nil {
+		return nil, ErrInvalidChallenge
+	}
+	u, err := s.getUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !enabled(u) {
+		return nil, ErrInvalidChallenge
+	}
+	return u, nil
+}
+
+func otpDestination(u *models.User, channel string) (string, error) {
+	switch channel {
+	case MethodEmail:
+		if u.Email != "" {
+			return u.Email, nil
+		}
+	case MethodPhone:
+		if u.Phone != "" && u.PhoneVerified {
+			return u.Phone, nil
+		}
+	}
+	return "", ErrChannelUnavailable
+}
+
+// SendChallengeOTP gửi mã qua email/SMS làm phương án thay thế khi không dùng được ứng dụng authenticator
+func (s *service) SendChallengeOTP(ctx context.Context, token, channel string) error {
+	u, err := s.challengeUser(ctx, token)
+	if err != nil {
+		return err
+	}
+	destination, err := otpDestination(u, channel)
+	if err != nil {
+		return err
+	}
+	return s.otpService.SendInternalOTP(ctx, &models.SendOTPRequest{
+		Identifier: destination,
+		Channel:    channel,
+		Purpose:    otp.PurposeTwoFactor,
+	})
+}
+
+// Verify hoàn tất challenge; trả về user để tầng gọi cấp JWT
+func (s *service) Verify(ctx context.Context, token, method, code string) (*models.User, error) {
+	u, err := s.challengeUser(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	key := challengeKey(token)
+	attempts, err := s.redisClient.HIncrBy(ctx, key, "attempts", 1).Result()
+	if err != nil {
+		return nil, err
+	}
+	if attempts > s.config.MaxAttempts {
+		s.redisClient.Del(ctx, key)
+		s.record(ctx, models.AuditTwoFactorFailed, false, u.ID, map[string]interface{}{"method": method, "reason": "too_many_attempts"})
+		return nil, ErrTooManyAttempts
+	}
+
+	switch method {
+	case MethodTOTP:
+		err = s.checkTOTP(ctx, u, code)
+	case MethodRecovery:
+		err = s.checkRecoveryCode(ctx, u, code)
+	case MethodEmail, MethodPhone:
+		destination, derr := otpDestination(u, method)
+		if derr != nil {
+			return nil, derr
+		}
+		err = s.otpService.VerifyInternalOTP(ctx, &models.VerifyOTPRequest{
+			Identifier: destination,
+			Channel:    method,
+			Purpose:    otp.PurposeTwoFactor,
+			OTP:        strings.TrimSpace(code),
+		})
+	default:
+		return nil, ErrInvalidCode
+	}
+	if err != nil {
+		s.record(ctx, models.AuditTwoFactorFailed, false, u.ID, map[string]interface{}{"method": method})
+		return nil, err
+	}
+
+	// Challenge chỉ dùng một lần; Del trả về 0 nghĩa là request khác đã hoàn tất trước
+	deleted, err := s.redisClient.Del(ctx, key).Result()
+	if err != nil {
+		return nil, err
+	}
+	if deleted == 0 {
+		return nil, ErrInvalidChallenge
+	}
+	return u, nil
+}
+
+func (s *service) checkTOTP(ctx context.Context, u *models.User, code string) error {
+	step, ok := totp.Validate(u.TwoFactor.Secret, strings.TrimSpace(code), time.Now(), 1)
+	if !ok {
+		return ErrInvalidCode
+	}
+	fresh, err := s.repo.UseStep(ctx, u.ID, step)
+	if err != nil {
+		return err
+	}
+	if !fresh {
+		return ErrInvalidCode
+	}
+	return nil
+}
+
+func (s *service) checkRecoveryCode(ctx context.Context, u *models.User, code string) error {
+	used, err := s.repo.UseRecoveryCode(ctx, u.ID, hashRecoveryCode(code))
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrInvalidCode
+	}
+	s.record(ctx, models.AuditRecoveryCodeUsed, true, u.ID, nil)
+	return nil
+}
+
+func (s *service) record(ctx context.Context, action models.AuditAction, success bool, userID primitive.ObjectID, metadata map[string]interface{}) {
+	s.audit.Record(ctx, models.AuditEvent{
+		Action:   action,
+		Success:  success,
+		UserID:   &userID,
+		Metadata: metadata,
+	})
+}
+
+var recoveryEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// newRecoveryCodes sinh mã dạng XXXXX-XXXXX; chỉ trả bản rõ một lần, DB giữ SHA-256
+func (s *service) newRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, s.config.RecoveryCodeCount)
+	hashes := make([]string, 0, s.config.RecoveryCodeCount)
+	for i := 0; i < s.config.RecoveryCodeCount; i++ {
+		buf := make([]byte, 7)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, nil, err
+		}
+		raw := recoveryEncoding.EncodeToString(buf)[:10]
+		code := raw[:5] + "-" + raw[5:]
+		codes = append(codes, code)
+		hashes = append(hashes, hashRecoveryCode(code))
+	}
+	return codes, hashes, nil
+}
+
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(code))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
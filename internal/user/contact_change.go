@@ -0,0 +1,270 @@
+package user
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"go.mongodb.org/mongo-driver/bson"
+	"socialnetwork/dto/request"
+	"socialnetwork/internal/audit"
+	"socialnetwork/internal/otp"
+	"socialnetwork/models"
+	"socialnetwork/pkg/auth"
+	"socialnetwork/pkg/ratelimit"
+	"socialnetwork/pkg/utils"
+)
+
+// Đổi email/số điện thoại:
+//  1. xác thực lại bằng mật khẩu hoặc mã 2FA
+//  2. gửi OTP tới địa chỉ mới, gửi link huỷ tới email/số điện thoại hiện tại
+//  3. địa chỉ cũ vẫn dùng được cho tới khi địa chỉ mới được xác nhận bằng OTP
+const (
+	contactEmail = "email"
+	contactPhone = "phone"
+)
+
+var (
+	ErrReauthRequired         = errors.New("cần nhập mật khẩu hoặc mã xác thực hai lớp để tiếp tục")
+	ErrReauthFailed           = errors.New("mật khẩu hoặc mã xác thực không đúng")
+	ErrContactChangeCancelled = errors.New("yêu cầu thay đổi đã bị huỷ hoặc đã hết hạn")
+	ErrInvalidCancelLink      = errors.New("liên kết huỷ không hợp lệ hoặc đã hết hạn")
+	ErrUsePhoneChange         = errors.New("số điện thoại phải được đổi qua /users/change-phone để xác minh số mới")
+)
+
+func contactPurpose(kind string) string {
+	if kind == contactPhone {
+		return otp.PurposeChangePhone
+	}
+	return otp.PurposeChangeEmail
+}
+
+func pendingChangeKey(kind, userID string) string {
+	return "contact_change:" + kind + ":" + userID
+}
+
+func cancelChangeKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return "contact_change_cancel:" + hex.EncodeToString(sum[:])
+}
+
+// Tài khoản không có mật khẩu (chỉ đăng nhập OIDC/passwordless) xác thực lại bằng cách đăng nhập lại:
+// phiên phải được cấp trong khoảng này
+const freshLoginWindow = 5 * time.Minute
+
+// Reauthenticate - xác thực lại cho thao tác nhạy cảm ngoài package user (vô hiệu hoá, xoá tài khoản).
+// Nhận mật khẩu hoặc mã 2FA như reauthenticate; tài khoản không có mật khẩu còn được chấp nhận phiên vừa đăng nhập.
+func (s *service) Reauthenticate(ctx context.Context, user *models.User, password, code string, issuedAt time.Time) error {
+	if password == "" && code == "" && user.Password == "" && !issuedAt.IsZero() && time.Since(issuedAt) < freshLoginWindow {
+		return nil
+	}
+	return s.reauthenticate(ctx, user, password, code)
+}
+
+// reauthenticate yêu cầu mật khẩu hoặc mã 2FA trước thao tác nhạy cảm; sai nhiều lần bị chờ/khoá như đăng nhập
+func (s *service) reauthenticate(ctx context.Context, user *models.User, password, code string) error {
+	scope := "reauth:" + user.ID.Hex()
+	if err := s.throttle.check(ctx, scope); err != nil {
+		return err
+	}
+
+	switch {
+	case password != "" && user.Password != "":
+		if auth.CheckPasswordHash(password, user.Password) {
+			s.throttle.reset(ctx, scope)
+			return nil
+		}
+	case code != "" && user.TwoFactor != nil && user.TwoFactor.Enabled && s.twoFactor != nil:
+		if err := s.twoFactor.VerifyCode(ctx, user, strings.TrimSpace(code)); err == nil {
+			s.throttle.reset(ctx, scope)
+			return nil
+		}
+	default:
+		return ErrReauthRequired
+	}
+	s.throttle.fail(ctx, scope)
+	return ErrReauthFailed
+}
+
+// startContactChange phát hành OTP cho địa chỉ mới và gửi link huỷ tới các địa chỉ hiện tại.
+// Trả về mã để caller gửi qua kênh tương ứng.
+func (s *service) startContactChange(ctx context.Context, user *models.User, kind, value string) (string, otp.Policy, error) {
+	userID := user.ID.Hex()
+	code, policy, err := s.otpService.Issue(ctx, contactPurpose(kind), userID, value)
+	if err != nil {
+		return "", policy, err
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", policy, err
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+
+	// Yêu cầu mới thay yêu cầu cũ; link huỷ nào của user cũng huỷ yêu cầu đang chờ
+	pipe := s.redisClient.TxPipeline()
+	pipe.Del(ctx, pendingChangeKey(kind, userID))
+	pipe.HSet(ctx, pendingChangeKey(kind, userID), "value", value)
+	pipe.Expire(ctx, pendingChangeKey(kind, userID), policy.TTL)
+	pipe.Set(ctx, cancelChangeKey(token), kind+":"+userID, policy.TTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return "", policy, err
+	}
+
+	label, masked := "email", audit.MaskIdentifier(value)
+	if kind == contactPhone {
+		label = "số điện thoại"
+	}
+	link := s.config.ContactChangeCancelURL + "?" + url.Values{"token": {token}}.Encode()
+	message := fmt.Sprintf("Có yêu cầu đổi %s tài khoản của bạn sang %s. Nếu không phải bạn, hãy huỷ trong %d phút: %s",
+		label, masked, int(policy.TTL.Minutes()), link)
+	s.notifyContacts(ctx, user, "Yêu cầu thay đổi "+label, message)
+	return code, policy, nil
+}
+
+// finishContactChange kiểm tra OTP và yêu cầu vẫn còn (chưa bị huỷ), trả về địa chỉ mới
+func (s *service) finishContactChange(ctx context.Context, kind, userID, code string) (string, error) {
+	value, err := s.otpService.Consume(ctx, contactPurpose(kind), userID, code)
+	if err != nil {
+		return "", err
+	}
+
+	pipe := s.redisClient.TxPipeline()
+	pending := pipe.HGet(ctx, pendingChangeKey(kind, userID), "value")
+	pipe.Del(ctx, pendingChangeKey(kind, userID))
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return "", err
+	}
+	if pending.Val() != value {
+		return "", ErrContactChangeCancelled
+	}
+	return value, nil
+}
+
+// notifyContacts gửi thông báo tới email và số điện thoại hiện tại của user; lỗi chỉ được log
+func (s *service) notifyContacts(ctx context.Context, user *models.User, subject, message string) {
+	if user.Email != "" {
+		if err := s.emailSender.Send(user.Email, subject, message); err != nil {
+			log.Printf("⚠️ Không gửi được email thông báo cho %s: %v", user.ID.Hex(), err)
+		}
+	}
+	if user.Phone != "" {
+		if err := s.otpService.SendRawSMS(ctx, user.Phone, message); err != nil {
+			log.Printf("⚠️ Không gửi được SMS thông báo cho %s: %v", user.ID.Hex(), err)
+		}
+	}
+}
+
+func (s *service) ChangePhoneRequest(ctx context.Context, userID string, req *request.ChangePhoneRequest) error {
+	user, err := s.repo.FindByID(ctx, userID)
+	if err != nil {
+		return errors.New("người dùng không tồn tại")
+	}
+
+	phone := utils.NormalizePhone(req.NewPhone)
+	if err := validateUser(&models.User{Phone: phone}, "Phone"); err != nil {
+		return err
+	}
+	if phone == user.Phone {
+		return errors.New("số điện thoại mới trùng số hiện tại")
+	}
+	// Xác thực lại trước khi kiểm tra trùng để không dò được số điện thoại của người khác
+	if err := s.reauthenticate(ctx, user, req.Password, req.Code); err != nil {
+		return err
+	}
+	existing, err := s.repo.FindByPhone(ctx, phone)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return ErrPhoneTaken
+	}
+
+	code, policy, err := s.startContactChange(ctx, user, contactPhone, phone)
+	if err != nil {
+		var limitErr *ratelimit.Error
+		if errors.As(err, &limitErr) {
+			return err
+		}
+		return errors.New("không thể lưu mã OTP")
+	}
+
+	message := fmt.Sprintf("Mã xác thực thay đổi số điện thoại của bạn là %s. Có hiệu lực trong %d phút.", code, int(policy.TTL.Minutes()))
+	if err := s.otpService.SendRawSMS(ctx, phone, message); err != nil {
+		return errors.New("không thể gửi SMS xác thực")
+	}
+
+	s.auditUser(ctx, models.AuditPhoneChangeRequested, user.ID, true, map[string]interface{}{"newPhone": audit.MaskIdentifier(phone)})
+	return nil
+}
+
+func (s *service) VerifyPhoneRequest(ctx context.Context, userID string, req *request.VerifyPhoneRequest) error {
+	newPhone, err := s.finishContactChange(ctx, contactPhone, userID, req.OTP)
+	if err != nil {
+		return err
+	}
+
+	user, err := s.repo.FindByID(ctx, userID)
+	if err != nil {
+		return errors.New("người dùng không tồn tại")
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			"phone":         newPhone,
+			"phoneVerified": true, // mã OTP đã gửi tới số mới nên số này đã được xác minh
+			"updatedAt":     time.Now(),
+		},
+	}
+	if err := s.repo.UpdateByID(ctx, user.ID.Hex(), update); err != nil {
+		return duplicateKeyError(err)
+	}
+
+	s.notifyContacts(ctx, user, "Số điện thoại đã được thay đổi",
+		fmt.Sprintf("Số điện thoại tài khoản của bạn đã được đổi thành %s. Nếu không phải bạn, hãy đặt lại mật khẩu ngay.", audit.MaskIdentifier(newPhone)))
+	s.auditUser(ctx, models.AuditPhoneChanged, user.ID, true, map[string]interface{}{
+		"oldPhone": audit.MaskIdentifier(user.Phone),
+		"newPhone": audit.MaskIdentifier(newPhone),
+	})
+	return nil
+}
+
+// CancelContactChange xử lý link huỷ gửi tới địa chỉ hiện tại; mã OTP đã gửi tới địa chỉ mới không còn dùng được
+func (s *service) CancelContactChange(ctx context.Context, token string) error {
+	if token == "" {
+		return ErrInvalidCancelLink
+	}
+	key := cancelChangeKey(token)
+	pipe := s.redisClient.TxPipeline()
+	get := pipe.Get(ctx, key)
+	pipe.Del(ctx, key)
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return err
+	}
+
+	kind, userID, ok := strings.Cut(get.Val(), ":")
+	if !ok {
+		return ErrInvalidCancelLink
+	}
+	deleted, err := s.redisClient.Del(ctx, pendingChangeKey(kind, userID)).Result()
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return ErrInvalidCancelLink // đã xác nhận xong hoặc hết hạn
+	}
+
+	if user, err := s.repo.FindByID(ctx, userID); err == nil {
+		s.auditUser(ctx, models.AuditContactChangeCancelled, user.ID, true, map[string]interface{}{"kind": kind})
+	}
+	return nil
+}
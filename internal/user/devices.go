@@ -0,0 +1,265 @@
+package user
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"socialnetwork/models"
+	"socialnetwork/pkg/requestinfo"
+)
+
+var (
+	ErrInvalidAlertLink      = errors.New("liên kết cảnh báo không hợp lệ hoặc đã hết hạn")
+	ErrPasswordResetRequired = errors.New("vì lý do bảo mật, bạn cần đặt lại mật khẩu trước khi đăng nhập")
+	ErrDeviceNotFound        = errors.New("không tìm thấy thiết bị")
+)
+
+// deviceFingerprint nhận diện thiết bị theo User-Agent (IP đổi liên tục trên mạng di động nên không dùng)
+func deviceFingerprint(userAgent string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(strings.TrimSpace(userAgent))))
+	return hex.EncodeToString(sum[:16])
+}
+
+// ipNetwork trả về dải mạng của IP (/24 với IPv4, /48 với IPv6) để so "vị trí" mà không cần GeoIP
+func ipNetwork(ip string) string {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return ""
+	}
+	if v4 := parsed.To4(); v4 != nil {
+		return (&net.IPNet{IP: v4.Mask(net.CIDRMask(24, 32)), Mask: net.CIDRMask(24, 32)}).String()
+	}
+	return (&net.IPNet{IP: parsed.Mask(net.CIDRMask(48, 128)), Mask: net.CIDRMask(48, 128)}).String()
+}
+
+// describeDevice tạo tên dễ đọc từ User-Agent, vd: "Chrome trên Windows"
+func describeDevice(userAgent string) string {
+	ua := strings.ToLower(userAgent)
+
+	browser := "Trình duyệt không xác định"
+	for _, b := range []struct{ token, name string }{
+		{"edg/", "Edge"}, {"opr/", "Opera"}, {"firefox/", "Firefox"}, {"chrome/", "Chrome"}, {"safari/", "Safari"},
+		{"okhttp", "Ứng dụng Android"}, {"cfnetwork", "Ứng dụng iOS"}, {"curl/", "curl"},
+	} {
+		if strings.Contains(ua, b.token) {
+			browser = b.name
+			break
+		}
+	}
+
+	platform := ""
+	for _, o := range []struct{ token, name string }{
+		{"windows", "Windows"}, {"iphone", "iPhone"}, {"ipad", "iPad"}, {"android", "Android"},
+		{"mac os", "macOS"}, {"cros", "ChromeOS"}, {"linux", "Linux"},
+	} {
+		if strings.Contains(ua, o.token) {
+			platform = o.name
+			break
+		}
+	}
+	if platform == "" {
+		return browser
+	}
+	return browser + " trên " + platform
+}
+
+// checkDevice ghi nhận thiết bị/vị trí của lần đăng nhập và gửi cảnh báo khi lạ.
+// Lần đăng nhập đầu tiên (chưa có thiết bị nào) chỉ ghi nhận, không cảnh báo.
+// Lỗi ở đây chỉ được log, không làm hỏng đăng nhập.
+func (s *service) checkDevice(ctx context.Context, user *models.User) {
+	info := requestinfo.From(ctx)
+	if info.UserAgent == "" && info.IP == "" {
+		return
+	}
+	fingerprint, network := deviceFingerprint(info.UserAgent), ipNetwork(info.IP)
+
+	device, err := s.repo.FindDevice(ctx, user.ID, fingerprint)
+	if err != nil {
+		log.Println("⚠️ Không kiểm tra được thiết bị đăng nhập:", err)
+		return
+	}
+
+	if device != nil {
+		newLocation := network != "" && !containsString(device.Networks, network)
+		if err := s.repo.TouchDevice(ctx, device.ID, info.IP, network); err != nil {
+			log.Println("⚠️ Không cập nhật được thiết bị đăng nhập:", err)
+		}
+		if newLocation {
+			device.LastIP, device.LastSeenAt = info.IP, time.Now()
+			s.sendLoginAlert(ctx, user, device, "vị trí mới")
+		}
+		return
+	}
+
+	known, err := s.repo.CountDevices(ctx, user.ID)
+	if err != nil {
+		log.Println("⚠️ Không kiểm tra được thiết bị đăng nhập:", err)
+		return
+	}
+
+	now := time.Now()
+	device = &models.KnownDevice{
+		UserID:      user.ID,
+		Fingerprint: fingerprint,
+		Name:        describeDevice(info.UserAgent),
+		UserAgent:   info.UserAgent,
+		LastIP:      info.IP,
+		FirstSeenAt: now,
+		LastSeenAt:  now,
+	}
+	if network != "" {
+		device.Networks = []string{network}
+	}
+	if err := s.repo.CreateDevice(ctx, device); err != nil {
+		// Hai lần đăng nhập đồng thời từ cùng thiết bị: lần kia đã ghi nhận rồi
+		if !mongo.IsDuplicateKeyError(err) {
+			log.Println("⚠️ Không lưu được thiết bị đăng nhập:", err)
+		}
+		return
+	}
+	if known > 0 {
+		s.sendLoginAlert(ctx, user, device, "thiết bị mới")
+	}
+}
+
+func containsString(list []string, v string) bool {
+	for _, item := range list {
+		if item == v {
+			return true
+		}
+	}
+	return false
+}
+
+func loginAlertKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return "login_alert:" + hex.EncodeToString(sum[:])
+}
+
+// sendLoginAlert gửi email + thông báo trong ứng dụng kèm link "Không phải tôi"
+func (s *service) sendLoginAlert(ctx context.Context, user *models.User, device *models.KnownDevice, reason string) {
+	s.auditUser(ctx, models.AuditNewDevice, user.ID, true, map[string]interface{}{
+		"deviceId": device.ID.Hex(),
+		"device":   device.Name,
+		"reason":   reason,
+	})
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		log.Println("⚠️ Không tạo được link cảnh báo đăng nhập:", err)
+		return
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+	// Chỉ lưu SHA-256 của token, giống mã OTP
+	key := loginAlertKey(token)
+	pipe := s.redisClient.TxPipeline()
+	pipe.HSet(ctx, key, "userId", user.ID.Hex(), "deviceId", device.ID.Hex())
+	pipe.Expire(ctx, key, s.config.LoginAlertTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Println("⚠️ Không lưu được link cảnh báo đăng nhập:", err)
+		return
+	}
+	link := s.config.LoginAlertURL + "?" + url.Values{"token": {token}}.Encode()
+
+	when := device.LastSeenAt.Format("15:04 02/01/2006")
+	message := fmt.Sprintf("Tài khoản của bạn vừa đăng nhập từ %s (%s, IP %s) lúc %s.", reason, device.Name, device.LastIP, when)
+
+	noti := &models.Notification{
+		Recipient: user.ID,
+		Type:      models.NotificationSystem,
+		Message:   message + " Nếu không phải bạn, hãy chọn \"Không phải tôi\".",
+		Link:      link,
+		CreatedAt: time.Now(),
+	}
+	if s.notifications != nil {
+		if err := s.notifications.Create(ctx, noti); err != nil {
+			log.Println("⚠️ Không tạo được thông báo đăng nhập:", err)
+		}
+	}
+
+	if user.Email == "" {
+		return
+	}
+	body := fmt.Sprintf("%s\nNếu đó là bạn, bạn có thể bỏ qua email này.\nNếu không phải bạn, hãy mở liên kết sau để đăng xuất mọi phiên và đặt lại mật khẩu: %s\nLiên kết hết hạn sau %d ngày.",
+		message, link, int(s.config.LoginAlertTTL.Hours()/24))
+	if err := s.emailSender.Send(user.Email, "Cảnh báo đăng nhập mới", body); err != nil {
+		log.Printf("⚠️ Không gửi được email cảnh báo đăng nhập cho %s: %v", user.ID.Hex(), err)
+	}
+}
+
+// ReportUnrecognizedLogin xử lý link "Không phải tôi": thu hồi mọi phiên, quên thiết bị lạ
+// và buộc đặt lại mật khẩu qua email. Luồng đặt lại chỉ có qua email nên tài khoản chưa có
+// email đã xác minh không bị buộc (nếu không sẽ bị khoá vĩnh viễn); trả về có buộc đặt lại hay không
+func (s *service) ReportUnrecognizedLogin(ctx context.Context, token string) (bool, error) {
+	if token == "" {
+		return false, ErrInvalidAlertLink
+	}
+	// Link chỉ dùng một lần
+	key := loginAlertKey(token)
+	pipe := s.redisClient.TxPipeline()
+	get := pipe.HGetAll(ctx, key)
+	pipe.Del(ctx, key)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return false, err
+	}
+	stored := get.Val()
+	userID, err := primitive.ObjectIDFromHex(stored["userId"])
+	if err != nil {
+		return false, ErrInvalidAlertLink
+	}
+
+	user, err := s.repo.GetByID(ctx, userID)
+	if err != nil || user.DeletedAt != nil {
+		return false, ErrInvalidAlertLink
+	}
+
+	if err := s.sessions.RevokeAll(ctx, userID.Hex()); err != nil {
+		return false, err
+	}
+	resetRequired := user.Email != "" && user.EmailVerified
+	if resetRequired {
+		if err := s.repo.UpdateByID(ctx, userID.Hex(), bson.M{"$set": bson.M{"passwordResetRequired": true, "updatedAt": time.Now()}}); err != nil {
+			return false, err
+		}
+	}
+	// Thiết bị lạ bị quên để lần đăng nhập sau từ đó lại được cảnh báo
+	if deviceID, err := primitive.ObjectIDFromHex(stored["deviceId"]); err == nil {
+		if err := s.repo.DeleteDevice(ctx, userID, deviceID); err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+			log.Println("⚠️ Không xoá được thiết bị lạ:", err)
+		}
+	}
+	s.auditUser(ctx, models.AuditLoginReported, userID, true, map[string]interface{}{"deviceId": stored["deviceId"], "resetRequired": resetRequired})
+
+	if resetRequired {
+		if err := s.otpService.SendForgotPasswordOTP(ctx, user.Email); err != nil {
+			log.Printf("⚠️ Không gửi được mã đặt lại mật khẩu cho %s: %v", userID.Hex(), err)
+		}
+	}
+	return resetRequired, nil
+}
+
+func (s *service) Devices(ctx context.Context, userID primitive.ObjectID) ([]models.KnownDevice, error) {
+	return s.repo.FindDevices(ctx, userID)
+}
+
+// RemoveDevice quên một thiết bị; lần đăng nhập sau từ thiết bị đó sẽ lại được cảnh báo
+func (s *service) RemoveDevice(ctx context.Context, userID, deviceID primitive.ObjectID) error {
+	err := s.repo.DeleteDevice(ctx, userID, deviceID)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return ErrDeviceNotFound
+	}
+	return err
+}
@@ -2,13 +2,17 @@ package user
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"html/template"
 	"net/http"
 	"socialnetwork/dto/request"
 	"socialnetwork/dto/response"
 	"socialnetwork/models"
+	"socialnetwork/pkg/auth"
+	"socialnetwork/pkg/ratelimit"
 	"strconv"
 	"strings"
 )
@@ -21,13 +25,6 @@ func NewHandler(service Service) *Handler {
 	return &Handler{service: service}
 }
 
-func normalizePhone(phone string) string {
-	if strings.HasPrefix(phone, "0") {
-		return "+84" + phone[1:]
-	}
-	return phone
-}
-
 // Register - Đăng ký người dùng mới
 func (h *Handler) Register(c *gin.Context) {
 	var req request.RegisterRequest
@@ -36,22 +33,27 @@ func (h *Handler) Register(c *gin.Context) {
 		return
 	}
 
-	// Khởi tạo user cơ bản
+	// Khởi tạo user cơ bản; client cũ chưa gửi username thì dùng name làm username
+	username := req.Username
+	if username == "" {
+		username = req.Name
+	}
 	user := &models.User{
-		Username: req.Name,
-		Password: req.Password, // sẽ hash ở service
+		Username:    username,
+		DisplayName: req.Name,
+		Password:    req.Password, // sẽ hash ở service
 	}
 
-	// Xác định xem identifier là email hay số điện thoại
+	// Xác định xem identifier là email hay số điện thoại (service sẽ chuẩn hoá)
 	if strings.Contains(req.Identifier, "@") {
 		user.Email = req.Identifier
 	} else {
-		user.Phone = normalizePhone(req.Identifier)
+		user.Phone = req.Identifier
 	}
 
 	// Gọi service
-	if err := h.service.Register(context.TODO(), user); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := h.service.Register(c.Request.Context(), user); err != nil {
+		writeAccountError(c, err)
 		return
 	}
 
@@ -68,15 +70,42 @@ func (h *Handler) Login(c *gin.Context) {
 		return
 	}
 
-	token, err := h.service.Login(context.TODO(), normalizePhone(req.Identifier), req.Password)
+	result, err := h.service.Login(c.Request.Context(), req.Identifier, req.Password)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		WriteLoginError(c, err)
+		return
+	}
+
+	RespondLogin(c, result)
+}
+
+// WriteLoginError trả lỗi đăng nhập dùng chung cho mọi phương thức (mật khẩu, OAuth, passwordless)
+func WriteLoginError(c *gin.Context, err error) {
+	if ratelimit.WriteError(c, err) {
+		return
+	}
+	if errors.Is(err, ErrPasswordResetRequired) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error(), "code": "password_reset_required"})
+		return
+	}
+	c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+}
+
+// RespondLogin trả JWT, hoặc challenge token nếu còn bước xác thực hai lớp
+func RespondLogin(c *gin.Context, result *LoginResult) {
+	if result.ChallengeToken != "" {
+		c.JSON(http.StatusOK, gin.H{
+			"message":           "Cần xác thực hai lớp",
+			"twoFactorRequired": true,
+			"challengeToken":    result.ChallengeToken,
+			"methods":           result.Methods,
+		})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Đăng nhập thành công",
-		"token":   token,
+		"token":   result.Token,
 	})
 }
 
@@ -98,6 +127,122 @@ func (h *Handler) GetMe(c *gin.Context) {
 	c.JSON(http.StatusOK, resp)
 }
 
+// GET /users/me/login-history?limit=
+func (h *Handler) GetLoginHistory(c *gin.Context) {
+	userID, err := primitive.ObjectIDFromHex(c.GetString("userID"))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+	limit, _ := strconv.ParseInt(c.Query("limit"), 10, 64)
+
+	events, err := h.service.LoginHistory(c.Request.Context(), userID, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"events": events})
+}
+
+// GET /users/me/devices
+func (h *Handler) GetDevices(c *gin.Context) {
+	userID, err := primitive.ObjectIDFromHex(c.GetString("userID"))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	devices, err := h.service.Devices(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"devices": devices})
+}
+
+// DELETE /users/me/devices/:id
+func (h *Handler) RemoveDevice(c *gin.Context) {
+	userID, err := primitive.ObjectIDFromHex(c.GetString("userID"))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+	deviceID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID không hợp lệ"})
+		return
+	}
+
+	if err := h.service.RemoveDevice(c.Request.Context(), userID, deviceID); err != nil {
+		if errors.Is(err, ErrDeviceNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Đã xoá thiết bị"})
+}
+
+// POST /auth/login-alerts/not-me - nhận JSON hoặc form từ trang xác nhận bên dưới
+func (h *Handler) ReportLogin(c *gin.Context) {
+	var req request.LoginAlertRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	h.reportLogin(c, req.Token)
+}
+
+var reportLoginPage = template.Must(template.New("not-me").Parse(`<!DOCTYPE html>
+<html lang="vi">
+<head><meta charset="utf-8"><meta name="viewport" content="width=device-width, initial-scale=1"><title>Không phải tôi</title></head>
+<body>
+<p>Bạn xác nhận lần đăng nhập này không phải của bạn? Mọi thiết bị sẽ bị đăng xuất và bạn nên đổi mật khẩu ngay.</p>
+<form method="post" action="/auth/login-alerts/not-me">
+<input type="hidden" name="token" value="{{.}}">
+<button type="submit">Xác nhận, không phải tôi</button>
+</form>
+</body>
+</html>`))
+
+// GET /auth/login-alerts/not-me?token= - mở từ link trong email/thông báo; chỉ hiện trang xác nhận
+// (trình quét link của hộp thư có thể tự mở GET nên không được đổi trạng thái ở đây)
+func (h *Handler) ReportLoginLink(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidAlertLink.Error()})
+		return
+	}
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	c.Header("Referrer-Policy", "no-referrer")
+	c.Status(http.StatusOK)
+	reportLoginPage.Execute(c.Writer, token)
+}
+
+func (h *Handler) reportLogin(c *gin.Context, token string) {
+	resetRequired, err := h.service.ReportUnrecognizedLogin(c.Request.Context(), token)
+	if err != nil {
+		if errors.Is(err, ErrInvalidAlertLink) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !resetRequired {
+		c.JSON(http.StatusOK, gin.H{
+			"message":       "Đã đăng xuất khỏi mọi thiết bị. Hãy đăng nhập lại và đổi mật khẩu ngay.",
+			"resetRequired": false,
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":       "Đã đăng xuất khỏi mọi thiết bị. Vui lòng đặt lại mật khẩu bằng mã đã gửi tới email của bạn trước khi đăng nhập lại.",
+		"resetRequired": true,
+	})
+}
+
 // GetUsers - Lấy danh sách người dùng
 func (h *Handler) GetUsers(c *gin.Context) {
 	page := 1
@@ -167,6 +312,11 @@ func (h *Handler) UpdateMe(c *gin.Context) {
 	}
 
 	if err := h.service.UpdateProfile(c.Request.Context(), userID.(string), &req); err != nil {
+		var verr *ValidationError
+		if errors.As(err, &verr) || errors.Is(err, ErrPhoneTaken) || errors.Is(err, ErrUsePhoneChange) {
+			writeAccountError(c, err)
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Không thể cập nhật thông tin"})
 		return
 	}
@@ -206,6 +356,9 @@ func (h *Handler) ForgotPassword(c *gin.Context) {
 
 	err := h.service.SendForgotPasswordOTP(c.Request.Context(), req.Email)
 	if err != nil {
+		if ratelimit.WriteError(c, err) {
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -224,6 +377,9 @@ func (h *Handler) ResetPassword(c *gin.Context) {
 
 	err := h.service.ResetPassword(c.Request.Context(), &req)
 	if err != nil {
+		if ratelimit.WriteError(c, err) {
+			return
+		}
 		// fmt.Printf("ResetPassword error: %v\n", err)
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -252,7 +408,7 @@ func (h *Handler) ChangeEmailRequest(c *gin.Context) {
 
 	err := h.service.ChangeEmailRequest(c.Request.Context(), userID, &req)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		writeContactChangeError(c, err)
 		return
 	}
 
@@ -280,13 +436,98 @@ func (h *Handler) VerifyEmailRequest(c *gin.Context) {
 
 	err := h.service.VerifyEmailRequest(c.Request.Context(), userID, &req)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		writeContactChangeError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Thay đổi email thành công"})
 }
 
+// POST /users/change-phone
+func (h *Handler) ChangePhoneRequest(c *gin.Context) {
+	var req request.ChangePhoneRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.service.ChangePhoneRequest(c.Request.Context(), c.GetString("userID"), &req); err != nil {
+		writeContactChangeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Mã xác thực đã được gửi tới số điện thoại mới"})
+}
+
+// POST /users/verify-phone
+func (h *Handler) VerifyPhoneRequest(c *gin.Context) {
+	var req request.VerifyPhoneRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.service.VerifyPhoneRequest(c.Request.Context(), c.GetString("userID"), &req); err != nil {
+		writeContactChangeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Thay đổi số điện thoại thành công"})
+}
+
+// POST /users/contact-change/cancel - nhận JSON hoặc form từ trang xác nhận bên dưới
+func (h *Handler) CancelContactChange(c *gin.Context) {
+	var req request.CancelContactChangeRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.service.CancelContactChange(c.Request.Context(), req.Token); err != nil {
+		writeContactChangeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Đã huỷ yêu cầu thay đổi. Nếu bạn không tạo yêu cầu này, hãy đổi mật khẩu ngay."})
+}
+
+var cancelContactChangePage = template.Must(template.New("cancel-contact-change").Parse(`<!DOCTYPE html>
+<html lang="vi">
+<head><meta charset="utf-8"><meta name="viewport" content="width=device-width, initial-scale=1"><title>Huỷ thay đổi liên hệ</title></head>
+<body>
+<p>Bạn muốn huỷ yêu cầu thay đổi email/số điện thoại của tài khoản? Mã xác nhận đã gửi tới địa chỉ mới sẽ không dùng được nữa.</p>
+<form method="post" action="/users/contact-change/cancel">
+<input type="hidden" name="token" value="{{.}}">
+<button type="submit">Huỷ yêu cầu thay đổi</button>
+</form>
+</body>
+</html>`))
+
+// GET /users/contact-change/cancel?token= - mở từ link trong email/SMS; chỉ hiện trang xác nhận
+// (trình quét link có thể tự mở GET nên không được huỷ ở đây)
+func (h *Handler) CancelContactChangeLink(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ErrInvalidCancelLink.Error()})
+		return
+	}
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	c.Header("Referrer-Policy", "no-referrer")
+	c.Status(http.StatusOK)
+	cancelContactChangePage.Execute(c.Writer, token)
+}
+
+func writeContactChangeError(c *gin.Context, err error) {
+	if ratelimit.WriteError(c, err) {
+		return
+	}
+	var verr *ValidationError
+	switch {
+	case errors.Is(err, ErrReauthRequired), errors.Is(err, ErrReauthFailed):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error(), "code": "reauth_required"})
+	case errors.Is(err, ErrEmailTaken), errors.Is(err, ErrPhoneTaken), errors.As(err, &verr):
+		writeAccountError(c, err)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+}
+
 // POST /users/:id/friends/request
 func (h *Handler) SendFriendRequest(c *gin.Context) {
 	userIDStr := c.GetString("userID")
@@ -423,3 +664,21 @@ func (h *Handler) ToggleHideProfile(c *gin.Context) {
 		"hide":    body.Hide, // thêm trạng thái trả về nếu cần
 	})
 }
+
+// writeAccountError - lỗi dữ liệu đăng ký/cập nhật hồ sơ
+func writeAccountError(c *gin.Context, err error) {
+	var verr *ValidationError
+	var perr *auth.PasswordError
+	switch {
+	case errors.As(err, &verr):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "fields": verr.Fields})
+	case errors.As(err, &perr):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "reasons": perr.Reasons})
+	case errors.Is(err, ErrReservedUsername), errors.Is(err, auth.ErrBreachedPassword), errors.Is(err, ErrUsePhoneChange):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrUsernameTaken), errors.Is(err, ErrEmailTaken), errors.Is(err, ErrPhoneTaken):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,99 @@
+package user
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"socialnetwork/pkg/ratelimit"
+)
+
+// loginThrottle theo dõi số lần đăng nhập sai theo scope: user ID với tài khoản có thật, identifier với
+// tài khoản không tồn tại (vẫn bị chờ/khoá để phản hồi không tiết lộ tài khoản có thật hay không):
+//   - từ lần sai thứ DelayAfter: phải chờ 1s, 2s, 4s... (tối đa MaxDelay) mới được thử tiếp
+//   - đủ MaxFailedLogins lần trong FailureWindow: khoá LockoutDuration
+type loginThrottle struct {
+	client *redis.Client
+	config Config
+}
+
+func throttleKey(kind, scope string) string {
+	sum := sha256.Sum256([]byte(scope))
+	return "login:" + kind + ":" + hex.EncodeToString(sum[:16])
+}
+
+func lockoutError(d time.Duration) error {
+	return &ratelimit.Error{
+		Message:    "Đăng nhập sai quá nhiều lần, vui lòng thử lại sau",
+		RetryAfter: d,
+	}
+}
+
+// check trả về *ratelimit.Error nếu scope đang bị khoá hoặc đang trong thời gian chờ.
+// Redis lỗi thì cho qua (giống middleware.RateLimit) để không chặn đăng nhập của mọi người.
+func (t *loginThrottle) check(ctx context.Context, scope string) error {
+	if t == nil || t.client == nil {
+		return nil
+	}
+	pipe := t.client.Pipeline()
+	lock := pipe.PTTL(ctx, throttleKey("lock", scope))
+	delay := pipe.PTTL(ctx, throttleKey("delay", scope))
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		log.Println("⚠️ login throttle:", err)
+		return nil
+	}
+	if d := lock.Val(); d > 0 {
+		return lockoutError(d)
+	}
+	if d := delay.Val(); d > 0 {
+		return lockoutError(d)
+	}
+	return nil
+}
+
+// fail ghi nhận một lần đăng nhập sai và đặt thời gian chờ/khoá tương ứng; trả về true khi vừa khoá
+func (t *loginThrottle) fail(ctx context.Context, scope string) bool {
+	if t == nil || t.client == nil {
+		return false
+	}
+	key := throttleKey("fail", scope)
+	pipe := t.client.TxPipeline()
+	incr := pipe.Incr(ctx, key)
+	ttl := pipe.PTTL(ctx, key)
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Println("⚠️ login throttle:", err)
+		return false
+	}
+	if ttl.Val() < 0 {
+		t.client.PExpire(ctx, key, t.config.FailureWindow)
+	}
+
+	failures := int(incr.Val())
+	if failures >= t.config.MaxFailedLogins {
+		t.client.Set(ctx, throttleKey("lock", scope), 1, t.config.LockoutDuration)
+		t.client.Del(ctx, key)
+		return true
+	}
+	if failures >= t.config.DelayAfter {
+		d := t.config.MaxDelay
+		if shift := failures - t.config.DelayAfter; shift < 16 {
+			d = time.Second << uint(shift)
+		}
+		if d > t.config.MaxDelay {
+			d = t.config.MaxDelay
+		}
+		t.client.Set(ctx, throttleKey("delay", scope), 1, d)
+	}
+	return false
+}
+
+// reset xoá bộ đếm sau khi đăng nhập đúng mật khẩu
+func (t *loginThrottle) reset(ctx context.Context, scope string) {
+	if t == nil || t.client == nil {
+		return
+	}
+	t.client.Del(ctx, throttleKey("fail", scope), throttleKey("delay", scope))
+}
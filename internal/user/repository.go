@@ -6,12 +6,14 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 	"socialnetwork/models"
 	"time"
 	"errors"
 )
 
 type Repository interface {
+	EnsureIndexes(ctx context.Context) error
 	Create(ctx context.Context, user *models.User) error
 	FindByEmail(ctx context.Context, email string) (*models.User, error)
 	 FindByIdentifier(ctx context.Context, identifier string) (*models.User, error)
@@ -22,6 +24,7 @@ type Repository interface {
 	SendFriendRequest(ctx context.Context, fromID, toID primitive.ObjectID) error
 	AcceptFriendRequest(ctx context.Context, userID, requesterID primitive.ObjectID) error
 	BlockUser(ctx context.Context, userID, targetID primitive.ObjectID) error
+	FindBlockerIDs(ctx context.Context, userID primitive.ObjectID) ([]primitive.ObjectID, error)
 	ToggleHideProfile(ctx context.Context, userID primitive.ObjectID, hide bool) error
 	FriendRequestExists(ctx context.Context, fromID, toID primitive.ObjectID) (bool, error)
 	CancelFriendRequest(ctx context.Context, fromID, toID primitive.ObjectID) error
@@ -31,6 +34,69 @@ type Repository interface {
 	IncrementFollowingCount(ctx context.Context, userID primitive.ObjectID) error
 	DecrementFollowingCount(ctx context.Context, userID primitive.ObjectID) error
 	FindByPhone(ctx context.Context, phone string) (*models.User, error)
+	Deactivate(ctx context.Context, userID primitive.ObjectID, deletionScheduledAt *time.Time) error
+	Reactivate(ctx context.Context, userID primitive.ObjectID) error
+	FindDueDeletions(ctx context.Context, now time.Time, limit int64) ([]models.User, error)
+	RemoveFromSocialGraph(ctx context.Context, userID primitive.ObjectID) error
+	Anonymize(ctx context.Context, userID primitive.ObjectID) error
+	AddWarning(ctx context.Context, userID primitive.ObjectID) error
+	Suspend(ctx context.Context, userID primitive.ObjectID, until time.Time) error
+	RecordLogin(ctx context.Context, event *models.LoginEvent) error
+	ReplacePasswordHash(ctx context.Context, userID primitive.ObjectID, oldHash, newHash string) error
+	FindLoginHistory(ctx context.Context, userID primitive.ObjectID, limit int64) ([]models.LoginEvent, error)
+	FindDevice(ctx context.Context, userID primitive.ObjectID, fingerprint string) (*models.KnownDevice, error)
+	FindDevices(ctx context.Context, userID primitive.ObjectID) ([]models.KnownDevice, error)
+	CountDevices(ctx context.Context, userID primitive.ObjectID) (int64, error)
+	CreateDevice(ctx context.Context, device *models.KnownDevice) error
+	TouchDevice(ctx context.Context, deviceID primitive.ObjectID, ip, network string) error
+	DeleteDevice(ctx context.Context, userID, deviceID primitive.ObjectID) error
+}
+
+const (
+	usernameIndex = "username_unique"
+	emailIndex    = "email_unique"
+	phoneIndex    = "phone_unique"
+)
+
+// EnsureIndexes - username không phân biệt hoa thường; email/phone chỉ unique khi có giá trị
+// (tài khoản đăng ký bằng số điện thoại không có email và ngược lại)
+func (r *repository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "username", Value: 1}},
+			Options: options.Index().SetName(usernameIndex).SetUnique(true).
+				SetCollation(&options.Collation{Locale: "en", Strength: 2}),
+		},
+		{
+			Keys: bson.D{{Key: "email", Value: 1}},
+			Options: options.Index().SetName(emailIndex).SetUnique(true).
+				SetPartialFilterExpression(bson.M{"email": bson.M{"$gt": ""}}),
+		},
+		{
+			Keys: bson.D{{Key: "phone", Value: 1}},
+			Options: options.Index().SetName(phoneIndex).SetUnique(true).
+				SetPartialFilterExpression(bson.M{"phone": bson.M{"$gt": ""}}),
+		},
+		{Keys: bson.D{{Key: "blockedUsers", Value: 1}}},
+	})
+	if err != nil {
+		return err
+	}
+
+	// Lịch sử đăng nhập chỉ giữ 180 ngày
+	_, err = r.db.Collection("login_history").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "createdAt", Value: -1}}},
+		{Keys: bson.D{{Key: "createdAt", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(180 * 24 * 3600)},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.Collection("known_devices").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "userId", Value: 1}, {Key: "fingerprint", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	return err
 }
 
 func (r *repository) FindByID(ctx context.Context, id string) (*models.User, error) {
@@ -106,8 +172,13 @@ func (r *repository) FindByIdentifier(ctx context.Context, identifier string) (*
 }
 
 
+// activeUsers - bỏ qua tài khoản đã vô hiệu hoá hoặc đã xoá
+func activeUsers() bson.M {
+	return bson.M{"deactivatedAt": nil, "deletedAt": nil}
+}
+
 func (r *repository) GetAllUsers(ctx context.Context) ([]*models.User, error) {
-	cursor, err := r.collection.Find(ctx, bson.M{})
+	cursor, err := r.collection.Find(ctx, activeUsers())
 	if err != nil {
 		return nil, err
 	}
@@ -126,7 +197,7 @@ func (r *repository) GetAllUsers(ctx context.Context) ([]*models.User, error) {
 }
 
 func (r *repository) FindAll(ctx context.Context) ([]*models.User, error) {
-	cursor, err := r.collection.Find(ctx, bson.M{})
+	cursor, err := r.collection.Find(ctx, activeUsers())
 	if err != nil {
 		return nil, err
 	}
@@ -208,6 +279,21 @@ func (r *repository) BlockUser(ctx context.Context, userID, targetID primitive.O
 	return err
 }
 
+// FindBlockerIDs - ID những người đã chặn userID
+func (r *repository) FindBlockerIDs(ctx context.Context, userID primitive.ObjectID) ([]primitive.ObjectID, error) {
+	values, err := r.collection.Distinct(ctx, "_id", bson.M{"blockedUsers": userID})
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]primitive.ObjectID, 0, len(values))
+	for _, v := range values {
+		if id, ok := v.(primitive.ObjectID); ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
 func (r *repository) ToggleHideProfile(ctx context.Context, userID primitive.ObjectID, hide bool) error {
 	_, err := r.collection.UpdateOne(ctx,
 		bson.M{"_id": userID},
@@ -268,4 +354,258 @@ func (r *repository) GetByID(ctx context.Context, id primitive.ObjectID) (*model
 		return nil, err
 	}
 	return &user, nil
-}
\ No newline at end of file
+}
+
+// setContentHidden ẩn/hiện toàn bộ nội dung của user (post, comment, video, short)
+func (r *repository) setContentHidden(ctx context.Context, userID primitive.ObjectID, hidden bool) error {
+	targets := []struct {
+		collection string
+		ownerField string
+		flagField  string
+	}{
+		{"posts", "user_id", "owner_inactive"},
+		{"comments", "user_id", "owner_inactive"},
+		{"videos", "ownerId", "ownerInactive"},
+		{"shorts", "ownerId", "ownerInactive"},
+	}
+	for _, t := range targets {
+		update := bson.M{"$unset": bson.M{t.flagField: ""}}
+		if hidden {
+			update = bson.M{"$set": bson.M{t.flagField: true}}
+		}
+		if _, err := r.db.Collection(t.collection).UpdateMany(ctx, bson.M{t.ownerField: userID}, update); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Deactivate vô hiệu hoá tài khoản và ẩn nội dung; deletionScheduledAt != nil nghĩa là
+// tài khoản sẽ bị xoá vĩnh viễn tại thời điểm đó
+func (r *repository) Deactivate(ctx context.Context, userID primitive.ObjectID, deletionScheduledAt *time.Time) error {
+	set := bson.M{"deactivatedAt": time.Now(), "updatedAt": time.Now()}
+	if deletionScheduledAt != nil {
+		set["deletionScheduledAt"] = *deletionScheduledAt
+	}
+	res, err := r.collection.UpdateOne(ctx, bson.M{"_id": userID, "deletedAt": nil}, bson.M{"$set": set})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return r.setContentHidden(ctx, userID, true)
+}
+
+// Reactivate khôi phục tài khoản (huỷ lịch xoá nếu có) và hiện lại nội dung
+func (r *repository) Reactivate(ctx context.Context, userID primitive.ObjectID) error {
+	_, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": userID, "deletedAt": nil},
+		bson.M{
+			"$unset": bson.M{"deactivatedAt": "", "deletionScheduledAt": ""},
+			"$set":   bson.M{"updatedAt": time.Now()},
+		},
+	)
+	if err != nil {
+		return err
+	}
+	return r.setContentHidden(ctx, userID, false)
+}
+
+// FindDueDeletions - tài khoản đã hết thời gian chờ xoá
+func (r *repository) FindDueDeletions(ctx context.Context, now time.Time, limit int64) ([]models.User, error) {
+	filter := bson.M{"deletionScheduledAt": bson.M{"$lte": now}, "deletedAt": nil}
+	cursor, err := r.collection.Find(ctx, filter, options.Find().SetLimit(limit))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var users []models.User
+	err = cursor.All(ctx, &users)
+	return users, err
+}
+
+// RemoveFromSocialGraph gỡ user khỏi danh sách bạn bè, lời mời, chặn, follow của mọi người
+func (r *repository) RemoveFromSocialGraph(ctx context.Context, userID primitive.ObjectID) error {
+	_, err := r.collection.UpdateMany(ctx,
+		bson.M{"$or": bson.A{
+			bson.M{"friends": userID},
+			bson.M{"friendRequests": userID},
+			bson.M{"blockedUsers": userID},
+			bson.M{"followers": userID},
+			bson.M{"following": userID},
+		}},
+		bson.M{"$pull": bson.M{
+			"friends":        userID,
+			"friendRequests": userID,
+			"blockedUsers":   userID,
+			"followers":      userID,
+			"following":      userID,
+		}},
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.Collection("friend_requests").DeleteMany(ctx, bson.M{
+		"$or": bson.A{bson.M{"from": userID}, bson.M{"to": userID}},
+	})
+	return err
+}
+
+// Anonymize xoá dữ liệu cá nhân, giữ lại bản ghi rỗng để các tham chiếu cũ không bị lỗi
+func (r *repository) Anonymize(ctx context.Context, userID primitive.ObjectID) error {
+	now := time.Now()
+	_, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": userID},
+		bson.M{
+			"$set": bson.M{
+				"username":       "deleted_" + userID.Hex(),
+				"displayName":    "Người dùng đã xoá",
+				"followerCount":  0,
+				"followingCount": 0,
+				"isActive":       false,
+				"hideProfile":    true,
+				"deletedAt":      now,
+				"updatedAt":      now,
+			},
+			"$unset": bson.M{
+				"email": "", "phone": "", "password": "",
+				"avatarUrl": "", "coverUrl": "", "bio": "", "gender": "", "birthDate": "",
+				"location": "", "website": "",
+				"followers": "", "following": "", "friends": "", "friendRequests": "", "blockedUsers": "",
+				"roles": "", "searchTerms": "", "lastLogin": "", "deletionScheduledAt": "", "twoFactor": "",
+			},
+		},
+	)
+	if err != nil {
+		return err
+	}
+	// Gỡ mọi liên kết đăng nhập mạng xã hội để tài khoản provider có thể đăng ký lại từ đầu
+	if _, err = r.db.Collection("user_identities").DeleteMany(ctx, bson.M{"userId": userID}); err != nil {
+		return err
+	}
+	if _, err = r.db.Collection("login_history").DeleteMany(ctx, bson.M{"userId": userID}); err != nil {
+		return err
+	}
+	_, err = r.db.Collection("known_devices").DeleteMany(ctx, bson.M{"userId": userID})
+	return err
+}
+
+// AddWarning tăng số lần bị cảnh cáo do vi phạm
+func (r *repository) AddWarning(ctx context.Context, userID primitive.ObjectID) error {
+	_, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": userID},
+		bson.M{"$inc": bson.M{"warningCount": 1}, "$set": bson.M{"updatedAt": time.Now()}},
+	)
+	return err
+}
+
+// Suspend khoá tài khoản đến thời điểm until (không đăng nhập được cho tới khi hết hạn)
+func (r *repository) Suspend(ctx context.Context, userID primitive.ObjectID, until time.Time) error {
+	_, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": userID},
+		bson.M{"$set": bson.M{"suspendedUntil": until, "updatedAt": time.Now()}},
+	)
+	return err
+}
+
+// RecordLogin ghi lịch sử đăng nhập; đăng nhập thành công thì cập nhật luôn lastLogin
+func (r *repository) RecordLogin(ctx context.Context, event *models.LoginEvent) error {
+	event.ID = primitive.NewObjectID()
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now()
+	}
+	if _, err := r.db.Collection("login_history").InsertOne(ctx, event); err != nil {
+		return err
+	}
+	if !event.Success {
+		return nil
+	}
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": event.UserID}, bson.M{"$set": bson.M{"lastLogin": event.CreatedAt}})
+	return err
+}
+
+func (r *repository) FindLoginHistory(ctx context.Context, userID primitive.ObjectID, limit int64) ([]models.LoginEvent, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}}).SetLimit(limit)
+	cursor, err := r.db.Collection("login_history").Find(ctx, bson.M{"userId": userID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	events := []models.LoginEvent{}
+	if err := cursor.All(ctx, &events); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// ReplacePasswordHash đổi hash mật khẩu (cùng mật khẩu) nếu hash hiện tại vẫn là oldHash
+func (r *repository) ReplacePasswordHash(ctx context.Context, userID primitive.ObjectID, oldHash, newHash string) error {
+	_, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": userID, "password": oldHash},
+		bson.M{"$set": bson.M{"password": newHash}},
+	)
+	return err
+}
+
+// FindDevice trả về nil nếu user chưa từng đăng nhập từ thiết bị này
+func (r *repository) FindDevice(ctx context.Context, userID primitive.ObjectID, fingerprint string) (*models.KnownDevice, error) {
+	var device models.KnownDevice
+	err := r.db.Collection("known_devices").FindOne(ctx, bson.M{"userId": userID, "fingerprint": fingerprint}).Decode(&device)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &device, nil
+}
+
+func (r *repository) FindDevices(ctx context.Context, userID primitive.ObjectID) ([]models.KnownDevice, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "lastSeenAt", Value: -1}})
+	cursor, err := r.db.Collection("known_devices").Find(ctx, bson.M{"userId": userID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	devices := []models.KnownDevice{}
+	if err := cursor.All(ctx, &devices); err != nil {
+		return nil, err
+	}
+	return devices, nil
+}
+
+func (r *repository) CountDevices(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	return r.db.Collection("known_devices").CountDocuments(ctx, bson.M{"userId": userID})
+}
+
+func (r *repository) CreateDevice(ctx context.Context, device *models.KnownDevice) error {
+	device.ID = primitive.NewObjectID()
+	_, err := r.db.Collection("known_devices").InsertOne(ctx, device)
+	return err
+}
+
+// TouchDevice cập nhật lần dùng gần nhất và ghi nhận thêm dải mạng mới (nếu có)
+func (r *repository) TouchDevice(ctx context.Context, deviceID primitive.ObjectID, ip, network string) error {
+	update := bson.M{"$set": bson.M{"lastSeenAt": time.Now(), "lastIp": ip}}
+	if network != "" {
+		update["$addToSet"] = bson.M{"networks": network}
+	}
+	_, err := r.db.Collection("known_devices").UpdateOne(ctx, bson.M{"_id": deviceID}, update)
+	return err
+}
+
+func (r *repository) DeleteDevice(ctx context.Context, userID, deviceID primitive.ObjectID) error {
+	res, err := r.db.Collection("known_devices").DeleteOne(ctx, bson.M{"_id": deviceID, "userId": userID})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
@@ -4,21 +4,33 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"socialnetwork/dto/request"
+	"socialnetwork/internal/audit"
+	"socialnetwork/internal/notification"
 	"socialnetwork/internal/otp"
 	"socialnetwork/models"
 	"socialnetwork/pkg/auth"
 	"socialnetwork/pkg/email"
+	"socialnetwork/pkg/ratelimit"
+	"socialnetwork/pkg/requestinfo"
+	"socialnetwork/pkg/utils"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/go-redis/redis/v8"
+	"go.mongodb.org/mongo-driver/mongo"
+
 	"go.mongodb.org/mongo-driver/bson"
 )
 
 type Service interface {
 	Register(ctx context.Context, user *models.User) error
-	Login(ctx context.Context, identifier, password string) (string, error)
+	Login(ctx context.Context, identifier, password string) (*LoginResult, error)
+	LoginVerified(ctx context.Context, user *models.User) (*LoginResult, error)
+	CompleteLogin(ctx context.Context, user *models.User) (string, error)
 	GetByID(ctx context.Context, id string) (*models.User, error)
 	GetAllUsers(ctx context.Context) ([]*models.User, error)
 	UpdateProfile(ctx context.Context, id string, req *request.UpdateProfileRequest) error
@@ -27,53 +39,351 @@ type Service interface {
 	ResetPassword(ctx context.Context, req *request.ResetPasswordRequest) error
 	ChangeEmailRequest(ctx context.Context, userID string, req *request.ChangeEmailRequest) error
 	VerifyEmailRequest(ctx context.Context, userID string, req *request.VerifyEmailRequest) error
+	ChangePhoneRequest(ctx context.Context, userID string, req *request.ChangePhoneRequest) error
+	VerifyPhoneRequest(ctx context.Context, userID string, req *request.VerifyPhoneRequest) error
+	CancelContactChange(ctx context.Context, token string) error
 	SendFriendRequest(ctx context.Context, fromID, toID primitive.ObjectID) error
 	AcceptFriendRequest(ctx context.Context, userID, requesterID primitive.ObjectID) error
 	BlockUser(ctx context.Context, userID, targetID primitive.ObjectID) error
 	ToggleHideProfile(ctx context.Context, userID primitive.ObjectID, hide bool) error
 	CancelFriendRequest(ctx context.Context, fromID, toID primitive.ObjectID) error
 	FriendRequestExists(ctx context.Context, fromID, toID primitive.ObjectID) (bool, error)
+	LoginHistory(ctx context.Context, userID primitive.ObjectID, limit int64) ([]models.LoginEvent, error)
+	Devices(ctx context.Context, userID primitive.ObjectID) ([]models.KnownDevice, error)
+	RemoveDevice(ctx context.Context, userID, deviceID primitive.ObjectID) error
+	ReportUnrecognizedLogin(ctx context.Context, token string) (bool, error)
+	Reauthenticate(ctx context.Context, user *models.User, password, code string, issuedAt time.Time) error
+}
+
+// ErrInvalidCredentials - dùng chung cho sai mật khẩu và tài khoản không tồn tại để không lộ thông tin tài khoản
+var ErrInvalidCredentials = errors.New("thông tin đăng nhập không đúng")
+
+// TwoFactorGate tạo challenge khi tài khoản đã bật xác thực hai lớp (twofactor.Service)
+type TwoFactorGate interface {
+	Challenge(ctx context.Context, user *models.User) (token string, methods []string, err error)
+	VerifyCode(ctx context.Context, user *models.User, code string) error
+}
+
+// LoginResult - có Token khi đăng nhập xong, hoặc ChallengeToken khi còn phải xác thực hai lớp
+type LoginResult struct {
+	Token          string
+	ChallengeToken string
+	Methods        []string
+}
+
+type Config struct {
+	PasswordPolicy auth.PasswordPolicy
+	// Thư mục bộ dữ liệu mật khẩu bị lộ dạng range của HIBP (<PREFIX>.txt); trống thì chỉ dùng danh sách phổ biến có sẵn
+	BreachListDir string
+
+	// Đăng nhập sai: chờ tăng dần từ lần thứ DelayAfter, khoá LockoutDuration khi đủ MaxFailedLogins lần trong FailureWindow
+	DelayAfter      int
+	MaxDelay        time.Duration
+	MaxFailedLogins int
+	FailureWindow   time.Duration
+	LockoutDuration time.Duration
+
+	// Cảnh báo đăng nhập từ thiết bị/vị trí mới: link "Không phải tôi" (kèm ?token=) và thời hạn của link
+	LoginAlertURL string
+	LoginAlertTTL time.Duration
+
+	// Link huỷ yêu cầu đổi email/số điện thoại gửi tới địa chỉ hiện tại (kèm ?token=)
+	ContactChangeCancelURL string
 }
 
 type service struct {
-	repo        Repository
-	otpService  otp.Service // interface quản lý OTP
-	emailSender email.EmailSender
+	repo          Repository
+	otpService    otp.Service // interface quản lý OTP
+	emailSender   email.EmailSender
+	twoFactor     TwoFactorGate
+	notifications notification.NotificationRepository
+	redisClient   *redis.Client
+	throttle      *loginThrottle
+	sessions      *auth.SessionStore
+	breaches      auth.BreachChecker
+	audit         *audit.Logger
+	config        Config
 }
 
-func NewService(repo Repository, otpService otp.Service, emailSender email.EmailSender) Service {
+func NewService(repo Repository, otpService otp.Service, emailSender email.EmailSender, twoFactor TwoFactorGate, notifications notification.NotificationRepository, redisClient *redis.Client, auditLog *audit.Logger, config Config) Service {
+	if config.DelayAfter <= 0 {
+		config.DelayAfter = 3
+	}
+	if config.MaxDelay <= 0 {
+		config.MaxDelay = 30 * time.Second
+	}
+	if config.MaxFailedLogins <= 0 {
+		config.MaxFailedLogins = 10
+	}
+	if config.FailureWindow <= 0 {
+		config.FailureWindow = 15 * time.Minute
+	}
+	if config.LockoutDuration <= 0 {
+		config.LockoutDuration = 15 * time.Minute
+	}
+	if config.LoginAlertTTL <= 0 {
+		config.LoginAlertTTL = 7 * 24 * time.Hour
+	}
 	return &service{
-		repo:        repo,
-		otpService:  otpService,
-		emailSender: emailSender,
+		repo:          repo,
+		otpService:    otpService,
+		emailSender:   emailSender,
+		twoFactor:     twoFactor,
+		notifications: notifications,
+		redisClient:   redisClient,
+		throttle:      &loginThrottle{client: redisClient, config: config},
+		sessions:      auth.NewSessionStore(redisClient),
+		breaches:      auth.NewLocalBreachList(config.BreachListDir),
+		audit:         auditLog,
+		config:        config,
 	}
 }
 
+type loginMethodKey struct{}
+
+// WithLoginMethod ghi phương thức đăng nhập (models.LoginMethod*) vào context để lưu vào lịch sử đăng nhập
+func WithLoginMethod(ctx context.Context, method string) context.Context {
+	return context.WithValue(ctx, loginMethodKey{}, method)
+}
+
+func loginMethod(ctx context.Context) string {
+	if method, ok := ctx.Value(loginMethodKey{}).(string); ok {
+		return method
+	}
+	return models.LoginMethodPassword
+}
+
+func (s *service) recordLogin(ctx context.Context, userID primitive.ObjectID, success bool, reason string) {
+	info := requestinfo.From(ctx)
+	event := &models.LoginEvent{
+		UserID:    userID,
+		Success:   success,
+		Method:    loginMethod(ctx),
+		Reason:    reason,
+		IP:        info.IP,
+		UserAgent: info.UserAgent,
+	}
+	if err := s.repo.RecordLogin(ctx, event); err != nil {
+		log.Println("⚠️ Không ghi được lịch sử đăng nhập:", err)
+	}
+
+	action := models.AuditLogin
+	if !success {
+		action = models.AuditLoginFailed
+	}
+	metadata := map[string]interface{}{"method": event.Method}
+	if reason != "" {
+		metadata["reason"] = reason
+	}
+	s.auditUser(ctx, action, userID, success, metadata)
+}
+
+// auditUser ghi sự kiện bảo mật do chính user thực hiện trên tài khoản của mình
+func (s *service) auditUser(ctx context.Context, action models.AuditAction, userID primitive.ObjectID, success bool, metadata map[string]interface{}) {
+	s.audit.Record(ctx, models.AuditEvent{
+		Action:   action,
+		Success:  success,
+		UserID:   &userID,
+		Metadata: metadata,
+	})
+}
+
+// loginScope - khoá theo user ID khi tài khoản tồn tại, để đổi qua lại email/username/số điện thoại
+// không nhân số lần thử; identifier chỉ dùng cho tài khoản không tồn tại
+func loginScope(identifier string, user *models.User) string {
+	if user != nil {
+		return "user:" + user.ID.Hex()
+	}
+	return identifier
+}
+
+// loginFailed ghi nhận lần đăng nhập sai; scope bị khoá thì lưu thêm sự kiện khoá
+func (s *service) loginFailed(ctx context.Context, scope, identifier string, user *models.User) {
+	locked := s.throttle.fail(ctx, scope)
+	if user != nil {
+		s.recordLogin(ctx, user.ID, false, "wrong_password")
+	} else {
+		// Không có tài khoản: chỉ lưu identifier đã che để vẫn phát hiện được dò mật khẩu hàng loạt
+		s.audit.Record(ctx, models.AuditEvent{
+			Action:   models.AuditLoginFailed,
+			Metadata: map[string]interface{}{"identifier": audit.MaskIdentifier(identifier), "reason": "unknown_account"},
+		})
+	}
+	if locked {
+		event := models.AuditEvent{
+			Action:   models.AuditLoginLocked,
+			Metadata: map[string]interface{}{"identifier": audit.MaskIdentifier(identifier), "duration": s.config.LockoutDuration.String()},
+		}
+		if user != nil {
+			event.UserID = &user.ID
+		}
+		s.audit.Record(ctx, event)
+	}
+}
+
+// checkNewPassword áp dụng chính sách mật khẩu và danh sách mật khẩu bị lộ
+func (s *service) checkNewPassword(ctx context.Context, password string, user *models.User) error {
+	if err := s.config.PasswordPolicy.Validate(password, user.Username, user.Email, user.Phone); err != nil {
+		return err
+	}
+	breached, err := s.breaches.IsBreached(ctx, password)
+	if err != nil {
+		// Không đọc được danh sách thì vẫn cho đổi mật khẩu, chỉ ghi log
+		log.Println("⚠️ Không kiểm tra được danh sách mật khẩu bị lộ:", err)
+		return nil
+	}
+	if breached {
+		return auth.ErrBreachedPassword
+	}
+	return nil
+}
+
+// rehashIfNeeded băm lại mật khẩu khi hash đang lưu dùng thuật toán/tham số cũ (chỉ làm được lúc có mật khẩu gốc)
+func (s *service) rehashIfNeeded(ctx context.Context, user *models.User, password string) {
+	if !auth.NeedsRehash(user.Password) {
+		return
+	}
+	hashed, err := auth.HashPassword(password)
+	if err != nil {
+		log.Println("⚠️ Không băm lại được mật khẩu:", err)
+		return
+	}
+	// Chỉ ghi đè khi hash chưa bị đổi bởi request khác (vd: đổi mật khẩu cùng lúc)
+	if err := s.repo.ReplacePasswordHash(ctx, user.ID, user.Password, hashed); err != nil {
+		log.Println("⚠️ Không lưu được hash mật khẩu mới:", err)
+		return
+	}
+	user.Password = hashed
+}
+
+var (
+	dummyHashOnce sync.Once
+	dummyHash     string
+)
+
+// compareDummyHash tốn thời gian tương đương so mật khẩu thật khi tài khoản không tồn tại,
+// tránh đoán tài khoản qua thời gian phản hồi
+func compareDummyHash(password string) {
+	dummyHashOnce.Do(func() {
+		dummyHash, _ = auth.HashPassword("dummy-password-for-timing")
+	})
+	auth.CheckPasswordHash(password, dummyHash)
+}
+
 func (s *service) Register(ctx context.Context, user *models.User) error {
+	user.Username = strings.TrimSpace(user.Username)
+	user.Email = utils.NormalizeEmail(user.Email)
+	user.Phone = utils.NormalizePhone(user.Phone)
+
+	if err := validateUser(user); err != nil {
+		return err
+	}
+	if IsReservedUsername(user.Username) {
+		return ErrReservedUsername
+	}
+	if err := s.checkNewPassword(ctx, user.Password, user); err != nil {
+		return err
+	}
+
+	// Kiểm tra trước để báo lỗi rõ ràng; unique index vẫn chặn trường hợp đăng ký đồng thời
+	if user.Email != "" {
+		if existing, err := s.repo.FindByIdentifier(ctx, user.Email); err != nil {
+			return err
+		} else if existing != nil {
+			return ErrEmailTaken
+		}
+	}
+	if user.Phone != "" {
+		if existing, err := s.repo.FindByIdentifier(ctx, user.Phone); err != nil {
+			return err
+		} else if existing != nil {
+			return ErrPhoneTaken
+		}
+	}
+
 	// Hash mật khẩu
 	hashedPassword, err := auth.HashPassword(user.Password)
 	if err != nil {
 		return err
 	}
 	user.Password = hashedPassword
+	user.UpdatedAt = time.Now()
 
-	// TODO: kiểm tra trùng email/phone nếu cần
-	return s.repo.Create(ctx, user)
+	return duplicateKeyError(s.repo.Create(ctx, user))
 }
 
-func (s *service) Login(ctx context.Context, identifier, password string) (string, error) {
+func (s *service) Login(ctx context.Context, identifier, password string) (*LoginResult, error) {
+	identifier = utils.NormalizeIdentifier(identifier)
 	user, err := s.repo.FindByIdentifier(ctx, identifier)
 
 	if err != nil {
-		return "", errors.New("lỗi truy vấn tài khoản")
+		return nil, errors.New("lỗi truy vấn tài khoản")
+	}
+
+	scope := loginScope(identifier, user)
+	if err := s.throttle.check(ctx, scope); err != nil {
+		return nil, err
 	}
 
-	if user == nil {
-		return "", errors.New("tài khoản không tồn tại")
+	// Tài khoản không tồn tại hoặc chỉ đăng nhập bằng mạng xã hội: phản hồi giống hệt sai mật khẩu
+	if user == nil || user.DeletedAt != nil || user.Password == "" {
+		compareDummyHash(password)
+		s.loginFailed(ctx, scope, identifier, nil)
+		return nil, ErrInvalidCredentials
 	}
 
+	ctx = WithLoginMethod(ctx, models.LoginMethodPassword)
 	if !auth.CheckPasswordHash(password, user.Password) {
-		return "", errors.New("mật khẩu không đúng")
+		s.loginFailed(ctx, scope, identifier, user)
+		return nil, ErrInvalidCredentials
+	}
+	s.throttle.reset(ctx, scope)
+	s.rehashIfNeeded(ctx, user, password)
+
+	return s.LoginVerified(ctx, user)
+}
+
+// LoginVerified tiếp tục đăng nhập khi user đã chứng minh được danh tính (mật khẩu, magic link...)
+func (s *service) LoginVerified(ctx context.Context, user *models.User) (*LoginResult, error) {
+	// Đã báo "không phải tôi": mọi phương thức đăng nhập đều bị chặn đến khi đặt lại mật khẩu
+	if user.PasswordResetRequired {
+		s.recordLogin(ctx, user.ID, false, "password_reset_required")
+		return nil, ErrPasswordResetRequired
+	}
+	if user.SuspendedUntil != nil && time.Now().Before(*user.SuspendedUntil) {
+		return nil, fmt.Errorf("tài khoản bị tạm khoá đến %s", user.SuspendedUntil.Format("02/01/2006 15:04"))
+	}
+
+	// Đã bật 2FA: chưa cấp JWT, client phải hoàn tất qua /auth/2fa/verify
+	if user.TwoFactor != nil && user.TwoFactor.Enabled && s.twoFactor != nil {
+		challenge, methods, err := s.twoFactor.Challenge(ctx, user)
+		if err != nil {
+			return nil, err
+		}
+		return &LoginResult{ChallengeToken: challenge, Methods: methods}, nil
+	}
+
+	token, err := s.CompleteLogin(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	return &LoginResult{Token: token}, nil
+}
+
+// CompleteLogin cấp JWT sau khi user đã vượt qua mọi bước xác thực
+func (s *service) CompleteLogin(ctx context.Context, user *models.User) (string, error) {
+	if user.PasswordResetRequired {
+		return "", ErrPasswordResetRequired
+	}
+	if user.SuspendedUntil != nil && time.Now().Before(*user.SuspendedUntil) {
+		return "", fmt.Errorf("tài khoản bị tạm khoá đến %s", user.SuspendedUntil.Format("02/01/2006 15:04"))
+	}
+
+	// Đăng nhập lại sẽ kích hoạt tài khoản đã vô hiệu hoá và huỷ lịch xoá (nếu có)
+	if user.DeactivatedAt != nil {
+		if err := s.repo.Reactivate(ctx, user.ID); err != nil {
+			return "", err
+		}
 	}
 
 	token, err := auth.GenerateJWT(user.ID.Hex())
@@ -81,11 +391,20 @@ func (s *service) Login(ctx context.Context, identifier, password string) (strin
 		return "", err
 	}
 
+	s.recordLogin(ctx, user.ID, true, "")
+	s.checkDevice(ctx, user)
 	return token, nil
 }
 
 
 
+func (s *service) LoginHistory(ctx context.Context, userID primitive.ObjectID, limit int64) ([]models.LoginEvent, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	return s.repo.FindLoginHistory(ctx, userID, limit)
+}
+
 func (s *service) GetByID(ctx context.Context, id string) (*models.User, error) {
 	return s.repo.FindByID(ctx, id)
 }
@@ -96,14 +415,26 @@ func (s *service) GetAllUsers(ctx context.Context) ([]*models.User, error) {
 
 func (s *service) UpdateProfile(ctx context.Context, id string, req *request.UpdateProfileRequest) error {
 	update := bson.M{}
+	// Gán giá trị mới vào model để kiểm tra bằng tag `validate`, chỉ với các field thay đổi
+	var candidate models.User
+	var fields []string
 
 	if req.DisplayName != "" {
 		update["displayName"] = req.DisplayName
+		candidate.DisplayName = req.DisplayName
+		fields = append(fields, "DisplayName")
 	}
 	if req.Bio != "" {
 		update["bio"] = req.Bio
+		candidate.Bio = req.Bio
+		fields = append(fields, "Bio")
 	}
 	if req.Gender != "" {
+		switch models.Gender(req.Gender) {
+		case models.GenderMale, models.GenderFemale, models.GenderOther, models.GenderPrivate:
+		default:
+			return &ValidationError{Fields: map[string]string{"Gender": "oneof"}}
+		}
 		update["gender"] = req.Gender
 	}
 	if req.BirthDate != nil {
@@ -111,25 +442,47 @@ func (s *service) UpdateProfile(ctx context.Context, id string, req *request.Upd
 	}
 	if req.AvatarURL != "" {
 		update["avatarUrl"] = req.AvatarURL
+		candidate.AvatarURL = req.AvatarURL
+		fields = append(fields, "AvatarURL")
 	}
 	if req.CoverURL != "" {
 		update["coverUrl"] = req.CoverURL
+		candidate.CoverURL = req.CoverURL
+		fields = append(fields, "CoverURL")
 	}
 	if req.Location != "" {
 		update["location"] = req.Location
 	}
 	if req.Website != "" {
 		update["website"] = req.Website
+		candidate.Website = req.Website
+		fields = append(fields, "Website")
+	}
+	if req.MessagePrivacy != "" {
+		switch req.MessagePrivacy {
+		case models.MessagePrivacyEveryone, models.MessagePrivacyFriends:
+		default:
+			return &ValidationError{Fields: map[string]string{"MessagePrivacy": "oneof"}}
+		}
+		update["messagePrivacy"] = req.MessagePrivacy
 	}
 	if req.Phone != "" {
-		update["phone"] = req.Phone
+		// Số điện thoại dùng để đăng nhập nên phải đổi qua luồng xác minh số mới
+		return ErrUsePhoneChange
 	}
 
 	if len(update) == 0 {
 		return nil // không có gì để cập nhật
 	}
+	if len(fields) > 0 {
+		if err := validateUser(&candidate, fields...); err != nil {
+			return err
+		}
+	}
+
+	update["updatedAt"] = time.Now()
 
-	return s.repo.UpdateByID(ctx, id, bson.M{"$set": update})
+	return duplicateKeyError(s.repo.UpdateByID(ctx, id, bson.M{"$set": update}))
 }
 
 func (s *service) ChangePassword(ctx context.Context, userID string, req *request.ChangePasswordRequest) error {
@@ -141,6 +494,9 @@ func (s *service) ChangePassword(ctx context.Context, userID string, req *reques
 	if !auth.CheckPasswordHash(req.OldPassword, user.Password) {
 		return errors.New("mật khẩu cũ không đúng")
 	}
+	if err := s.checkNewPassword(ctx, req.NewPassword, user); err != nil {
+		return err
+	}
 
 	hashedPassword, err := auth.HashPassword(req.NewPassword)
 	if err != nil {
@@ -151,34 +507,59 @@ func (s *service) ChangePassword(ctx context.Context, userID string, req *reques
 		"$set": bson.M{
 			"password": hashedPassword,
 		},
+		"$unset": bson.M{"passwordResetRequired": ""},
 	}
 
-	return s.repo.UpdateByID(ctx, userID, update)
+	if err := s.repo.UpdateByID(ctx, userID, update); err != nil {
+		return err
+	}
+	s.auditUser(ctx, models.AuditPasswordChanged, user.ID, true, nil)
+	return nil
 }
 
 // Gửi mã OTP quên mật khẩu
 func (s *service) SendForgotPasswordOTP(ctx context.Context, email string) error {
-	_, err := s.repo.FindByEmail(ctx, email)
+	email = utils.NormalizeEmail(email)
+	u, err := s.repo.FindByEmail(ctx, email)
+	if errors.Is(err, mongo.ErrNoDocuments) || (err == nil && u.DeletedAt != nil) {
+		// Không báo email không tồn tại; phản hồi giống như đã gửi mã
+		return nil
+	}
 	if err != nil {
-		return errors.New("email không tồn tại")
+		return err
 	}
 
-	return s.otpService.SendForgotPasswordOTP(ctx, email)
+	if err := s.otpService.SendForgotPasswordOTP(ctx, email); err != nil {
+		return err
+	}
+	s.auditUser(ctx, models.AuditPasswordResetRequested, u.ID, true, nil)
+	return nil
 }
 
 // Reset mật khẩu bằng OTP
 func (s *service) ResetPassword(ctx context.Context, req *request.ResetPasswordRequest) error {
+	req.Email = utils.NormalizeEmail(req.Email)
+
+	// Kiểm tra mật khẩu mới trước khi dùng OTP để mật khẩu yếu không làm mất mã
+	if err := s.checkNewPassword(ctx, req.NewPassword, &models.User{Email: req.Email}); err != nil {
+		return err
+	}
+
 	// Tạo VerifyOTPRequest từ req
 	verifyReq := &models.VerifyOTPRequest{
 		Identifier: req.Email,
-		Purpose:    "forgot_password",
+		Purpose:    otp.PurposeForgotPassword,
 		OTP:        req.OTP,
 		Channel:    "email",
 	}
 
 	// Kiểm tra OTP hợp lệ
 	if err := s.otpService.VerifyOTP(ctx, verifyReq); err != nil {
-		return errors.New("mã OTP không hợp lệ hoặc đã hết hạn")
+		var limitErr *ratelimit.Error
+		if errors.As(err, &limitErr) {
+			return err
+		}
+		return otp.ErrInvalidCode
 	}
 
 	user, err := s.repo.FindByEmail(ctx, req.Email)
@@ -195,15 +576,13 @@ func (s *service) ResetPassword(ctx context.Context, req *request.ResetPasswordR
 		"$set": bson.M{
 			"password": hashedPassword,
 		},
+		"$unset": bson.M{"passwordResetRequired": ""},
 	}
 
 	if err := s.repo.UpdateByID(ctx, user.ID.Hex(), update); err != nil {
 		return err
 	}
-
-	// Xoá OTP sau khi dùng
-	s.otpService.DeleteOTP(ctx, "forgot_password:"+req.Email)
-
+	s.auditUser(ctx, models.AuditPasswordReset, user.ID, true, nil)
 	return nil
 }
 
@@ -213,55 +592,49 @@ func (s *service) ChangeEmailRequest(ctx context.Context, userID string, req *re
 		return errors.New("người dùng không tồn tại")
 	}
 
-	if user.Email != req.OldEmail {
+	req.OldEmail, req.NewEmail = utils.NormalizeEmail(req.OldEmail), utils.NormalizeEmail(req.NewEmail)
+	if req.OldEmail != "" && user.Email != req.OldEmail {
 		return errors.New("email hiện tại không đúng")
 	}
+	if req.NewEmail == user.Email {
+		return errors.New("email mới trùng email hiện tại")
+	}
+	// Xác thực lại trước khi kiểm tra trùng để không dò được email của người khác
+	if err := s.reauthenticate(ctx, user, req.Password, req.Code); err != nil {
+		return err
+	}
 
 	existingUser, err := s.repo.FindByEmail(ctx, req.NewEmail)
 	if err == nil && existingUser != nil {
-		return errors.New("email mới đã tồn tại")
+		return ErrEmailTaken
 	}
 
-	// 🔐 Tạo OTP
-	otp := otp.GenerateOTP(6)
-
-	// 🔑 Lưu vào Redis: key = change_email:<userID>, value = <newEmail>:<otp>
-	key := fmt.Sprintf("change_email:%s", user.ID.Hex())
-	value := fmt.Sprintf("%s:%s", req.NewEmail, otp)
-
-	err = s.otpService.SaveOTP(ctx, key, value, 5*time.Minute)
+	// 🔐 Tạo OTP gắn với user, email mới đi kèm làm payload; email cũ nhận link huỷ
+	code, policy, err := s.startContactChange(ctx, user, contactEmail, req.NewEmail)
 	if err != nil {
+		var limitErr *ratelimit.Error
+		if errors.As(err, &limitErr) {
+			return err
+		}
 		return errors.New("không thể lưu mã OTP")
 	}
 
 	// ✉️ Gửi OTP qua email mới
-	message := fmt.Sprintf("Mã xác thực thay đổi email của bạn là %s. Có hiệu lực trong 5 phút.", otp)
+	message := fmt.Sprintf("Mã xác thực thay đổi email của bạn là %s. Có hiệu lực trong %d phút.", code, int(policy.TTL.Minutes()))
 	err = s.otpService.SendRawEmail(ctx, req.NewEmail, "Xác thực thay đổi email", message)
 	if err != nil {
 		return errors.New("không thể gửi email xác thực")
 	}
 
+	s.auditUser(ctx, models.AuditEmailChangeRequested, user.ID, true, map[string]interface{}{"newEmail": audit.MaskIdentifier(req.NewEmail)})
 	return nil
 }
 
 func (s *service) VerifyEmailRequest(ctx context.Context, userID string, req *request.VerifyEmailRequest) error {
-	key := fmt.Sprintf("change_email:%s", userID)
-
-	val, err := s.otpService.GetRawOTP(ctx, key)
+	// Email cũ vẫn dùng được cho tới bước này
+	newEmail, err := s.finishContactChange(ctx, contactEmail, userID, req.OTP)
 	if err != nil {
-		return errors.New("mã OTP không hợp lệ hoặc đã hết hạn")
-	}
-
-	parts := strings.Split(val, ":")
-	if len(parts) != 2 {
-		return errors.New("dữ liệu OTP không hợp lệ")
-	}
-
-	newEmail := parts[0]
-	storedOTP := parts[1]
-
-	if req.OTP != storedOTP {
-		return errors.New("mã OTP không chính xác")
+		return err
 	}
 
 	user, err := s.repo.FindByID(ctx, userID)
@@ -271,19 +644,23 @@ func (s *service) VerifyEmailRequest(ctx context.Context, userID string, req *re
 
 	update := bson.M{
 		"$set": bson.M{
-			"email":      newEmail,
-			"isVerified": true,
-			"updatedAt":  time.Now(),
+			"email":         newEmail,
+			"emailVerified": true, // mã OTP đã gửi tới email mới nên email này đã được xác minh
+			"isVerified":    true,
+			"updatedAt":     time.Now(),
 		},
 	}
 
 	if err := s.repo.UpdateByID(ctx, user.ID.Hex(), update); err != nil {
-		return err
+		return duplicateKeyError(err)
 	}
 
-	// Xóa OTP sau khi dùng
-	s.otpService.DeleteOTP(ctx, key)
-
+	s.notifyContacts(ctx, user, "Email đã được thay đổi",
+		fmt.Sprintf("Email tài khoản của bạn đã được đổi thành %s. Nếu không phải bạn, hãy đặt lại mật khẩu ngay.", audit.MaskIdentifier(newEmail)))
+	s.auditUser(ctx, models.AuditEmailChanged, user.ID, true, map[string]interface{}{
+		"oldEmail": audit.MaskIdentifier(user.Email),
+		"newEmail": audit.MaskIdentifier(newEmail),
+	})
 	return nil
 }
 
@@ -337,7 +714,17 @@ func (s *service) BlockUser(ctx context.Context, userID, targetID primitive.Obje
 	if userID == targetID {
 		return errors.New("không thể chặn chính mình")
 	}
-	return s.repo.BlockUser(ctx, userID, targetID)
+	if err := s.repo.BlockUser(ctx, userID, targetID); err != nil {
+		return err
+	}
+	s.audit.Record(ctx, models.AuditEvent{
+		Action:     models.AuditUserBlocked,
+		Success:    true,
+		UserID:     &userID,
+		TargetType: "user",
+		TargetID:   targetID.Hex(),
+	})
+	return nil
 }
 
 func (s *service) ToggleHideProfile(ctx context.Context, userID primitive.ObjectID, hide bool) error {
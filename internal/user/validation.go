@@ -0,0 +1,84 @@
+package user
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var (
+	ErrUsernameTaken    = errors.New("tên người dùng đã tồn tại")
+	ErrEmailTaken       = errors.New("email đã được sử dụng")
+	ErrPhoneTaken       = errors.New("số điện thoại đã được sử dụng")
+	ErrReservedUsername = errors.New("tên người dùng này không được phép sử dụng")
+)
+
+// ValidationError - dữ liệu không thoả ràng buộc `validate` trên models.User
+type ValidationError struct {
+	Fields map[string]string
+}
+
+func (e *ValidationError) Error() string {
+	parts := make([]string, 0, len(e.Fields))
+	for field, rule := range e.Fields {
+		parts = append(parts, fmt.Sprintf("%s: %s", field, rule))
+	}
+	return "dữ liệu không hợp lệ (" + strings.Join(parts, ", ") + ")"
+}
+
+// Dùng tag `validate` (khác tag `binding` của Gin) để kiểm tra model trước khi ghi DB
+var validate = validator.New()
+
+// validateUser kiểm tra toàn bộ model, hoặc chỉ các field được chỉ định (khi cập nhật một phần)
+func validateUser(v interface{}, fields ...string) error {
+	var err error
+	if len(fields) > 0 {
+		err = validate.StructPartial(v, fields...)
+	} else {
+		err = validate.Struct(v)
+	}
+
+	var verrs validator.ValidationErrors
+	if !errors.As(err, &verrs) {
+		return err
+	}
+	out := &ValidationError{Fields: map[string]string{}}
+	for _, fe := range verrs {
+		out.Fields[fe.Field()] = fe.Tag()
+	}
+	return out
+}
+
+// Tên trùng với route, vai trò hoặc dễ bị dùng để mạo danh hệ thống
+var reservedUsernames = map[string]bool{
+	"admin": true, "administrator": true, "root": true, "system": true, "support": true,
+	"moderator": true, "mod": true, "staff": true, "official": true, "security": true,
+	"help": true, "api": true, "auth": true, "login": true, "logout": true, "register": true,
+	"me": true, "settings": true, "users": true, "posts": true, "comments": true,
+	"videos": true, "shorts": true, "feed": true, "media": true, "null": true, "undefined": true,
+}
+
+func IsReservedUsername(username string) bool {
+	name := strings.ToLower(username)
+	return reservedUsernames[name] || strings.HasPrefix(name, "deleted_")
+}
+
+// duplicateKeyError chuyển lỗi trùng unique index thành lỗi nghiệp vụ theo tên index
+func duplicateKeyError(err error) error {
+	if !mongo.IsDuplicateKeyError(err) {
+		return err
+	}
+	msg := err.Error()
+	switch {
+	case strings.Contains(msg, usernameIndex):
+		return ErrUsernameTaken
+	case strings.Contains(msg, emailIndex):
+		return ErrEmailTaken
+	case strings.Contains(msg, phoneIndex):
+		return ErrPhoneTaken
+	}
+	return err
+}
@@ -0,0 +1,10 @@
+package video
+
+import "errors"
+
+var (
+	ErrVideoNotFound = errors.New("video not found")
+	ErrNotOwner      = errors.New("bạn không phải chủ sở hữu video này")
+	ErrNoChanges     = errors.New("không có trường nào để cập nhật")
+	ErrEmptyTitle    = errors.New("title không được để trống")
+)
@@ -1,10 +1,13 @@
 package video
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"socialnetwork/dto/request"
+	"socialnetwork/internal/contentfilter"
 	"socialnetwork/models"
 )
 
@@ -37,7 +40,7 @@ func (h *VideoHandler) CreateVideo(c *gin.Context) {
 	}
 
 	if err := h.videoService.CreateVideo(c.Request.Context(), &video); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		writeError(c, err)
 		return
 	}
 
@@ -72,6 +75,12 @@ func (h *VideoHandler) GetVideoByID(c *gin.Context) {
 		return
 	}
 
+	// Video chưa xử lý xong chỉ chủ sở hữu mới thấy (để theo dõi trạng thái)
+	if !models.IsProcessed(video.Status) && video.OwnerID != userID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "video not found"})
+		return
+	}
+
 	c.JSON(http.StatusOK, video)
 }
 
@@ -114,11 +123,82 @@ func (h *VideoHandler) DeleteVideo(c *gin.Context) {
 		return
 	}
 
-	ownerID := c.MustGet("userID").(primitive.ObjectID)
+	ownerID, err := primitive.ObjectIDFromHex(c.GetString("userID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		return
+	}
 	if err := h.videoService.DeleteVideo(c.Request.Context(), id, ownerID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete video"})
+		writeError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "video deleted"})
 }
+
+// PUT /video_short/videos/:id
+func (h *VideoHandler) UpdateVideo(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid video ID"})
+		return
+	}
+
+	userID, err := primitive.ObjectIDFromHex(c.GetString("userID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	var req request.UpdateVideoRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	video, err := h.videoService.UpdateVideo(c.Request.Context(), id, userID, &req)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, video)
+}
+
+// GET /video_short/videos/:id/revisions
+func (h *VideoHandler) GetVideoRevisions(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid video ID"})
+		return
+	}
+
+	userID, err := primitive.ObjectIDFromHex(c.GetString("userID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	revisions, err := h.videoService.ListRevisions(c.Request.Context(), id, userID)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, revisions)
+}
+
+func writeError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrVideoNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrNotOwner):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrNoChanges), errors.Is(err, ErrEmptyTitle):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, contentfilter.ErrRejected):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
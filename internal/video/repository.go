@@ -8,15 +8,25 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type VideoRepository interface {
 	Create(ctx context.Context, video *models.Video) error
 	GetByID(ctx context.Context, id primitive.ObjectID) (*models.Video, error)
+	GetForProcessing(ctx context.Context, id primitive.ObjectID) (*models.Video, error)
+	GetForOwner(ctx context.Context, id, ownerID primitive.ObjectID) (*models.Video, error)
 	GetByOwner(ctx context.Context, ownerID primitive.ObjectID) ([]models.Video, error)
 	IncrementViews(ctx context.Context, id primitive.ObjectID) error
+	IncrementViewStats(ctx context.Context, id primitive.ObjectID, views int, watchTime, completion float64) error
 	Delete(ctx context.Context, id primitive.ObjectID, ownerID primitive.ObjectID) error
 	FindByOwnerAndVisibility(ctx context.Context, ownerID primitive.ObjectID, visibility string) ([]models.Video, error)
+	UpdateFields(ctx context.Context, id primitive.ObjectID, fields bson.M) error
+	ListDeleted(ctx context.Context, ownerID primitive.ObjectID, since time.Time) ([]models.Video, error)
+	Restore(ctx context.Context, id, ownerID primitive.ObjectID, since time.Time) error
+	FindPurgeable(ctx context.Context, before time.Time, limit int64) ([]models.Video, error)
+	Purge(ctx context.Context, id primitive.ObjectID) error
+	FindAllByOwner(ctx context.Context, ownerID primitive.ObjectID, limit int64) ([]models.Video, error)
 }
 
 type videoRepository struct {
@@ -27,6 +37,14 @@ func NewVideoRepository(db *mongo.Database) VideoRepository {
 	return &videoRepository{collection: db.Collection("videos")}
 }
 
+// visible thêm điều kiện loại bỏ video đã xoá mềm, bị ẩn do vi phạm hoặc của tài khoản đã vô hiệu hoá
+func visible(filter bson.M) bson.M {
+	filter["deletedAt"] = nil
+	filter["ownerInactive"] = bson.M{"$ne": true}
+	filter["moderationHidden"] = bson.M{"$ne": true}
+	return filter
+}
+
 func (r *videoRepository) Create(ctx context.Context, video *models.Video) error {
 	video.CreatedAt = time.Now()
 	video.UpdatedAt = video.CreatedAt
@@ -36,12 +54,26 @@ func (r *videoRepository) Create(ctx context.Context, video *models.Video) error
 
 func (r *videoRepository) GetByID(ctx context.Context, id primitive.ObjectID) (*models.Video, error) {
 	var video models.Video
-	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&video)
+	err := r.collection.FindOne(ctx, visible(bson.M{"_id": id})).Decode(&video)
+	return &video, err
+}
+
+// GetForProcessing - kể cả video đang bị ẩn chờ kiểm duyệt (worker vẫn phải transcode)
+func (r *videoRepository) GetForProcessing(ctx context.Context, id primitive.ObjectID) (*models.Video, error) {
+	var video models.Video
+	err := r.collection.FindOne(ctx, bson.M{"_id": id, "deletedAt": nil}).Decode(&video)
+	return &video, err
+}
+
+// GetForOwner - video của chủ sở hữu, kể cả đang bị ẩn chờ kiểm duyệt (chủ vẫn phải sửa được)
+func (r *videoRepository) GetForOwner(ctx context.Context, id, ownerID primitive.ObjectID) (*models.Video, error) {
+	var video models.Video
+	err := r.collection.FindOne(ctx, bson.M{"_id": id, "ownerId": ownerID, "deletedAt": nil}).Decode(&video)
 	return &video, err
 }
 
 func (r *videoRepository) GetByOwner(ctx context.Context, ownerID primitive.ObjectID) ([]models.Video, error) {
-	cursor, err := r.collection.Find(ctx, bson.M{"ownerId": ownerID})
+	cursor, err := r.collection.Find(ctx, visible(bson.M{"ownerId": ownerID}))
 	if err != nil {
 		return nil, err
 	}
@@ -58,15 +90,26 @@ func (r *videoRepository) IncrementViews(ctx context.Context, id primitive.Objec
 }
 
 func (r *videoRepository) Delete(ctx context.Context, id, ownerID primitive.ObjectID) error {
-	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": id, "ownerId": ownerID})
-	return err
+	res, err := r.collection.UpdateOne(ctx,
+		visible(bson.M{"_id": id, "ownerId": ownerID}),
+		bson.M{"$set": bson.M{"deletedAt": time.Now()}},
+	)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
 }
 
 func (r *videoRepository) FindByOwnerAndVisibility(ctx context.Context, ownerID primitive.ObjectID, visibility string) ([]models.Video, error) {
-    filter := bson.M{
-        "owner_id":  ownerID,
+    filter := visible(bson.M{
+        "ownerId":  ownerID,
         "visibility": visibility,
-    }
+        // Chỉ lấy nội dung đã xử lý xong ($in nil để khớp cả dữ liệu cũ chưa có status)
+        "status": bson.M{"$in": bson.A{models.ProcessingReady, nil}},
+    })
     cursor, err := r.collection.Find(ctx, filter)
     if err != nil {
         return nil, err
@@ -77,3 +120,84 @@ func (r *videoRepository) FindByOwnerAndVisibility(ctx context.Context, ownerID
     }
     return videos, nil
 }
+
+func (r *videoRepository) UpdateFields(ctx context.Context, id primitive.ObjectID, fields bson.M) error {
+	fields["updatedAt"] = time.Now()
+	res, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": fields})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// IncrementViewStats cộng dồn lượt xem/thời gian xem (dùng khi flush theo lô)
+func (r *videoRepository) IncrementViewStats(ctx context.Context, id primitive.ObjectID, views int, watchTime, completion float64) error {
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$inc": bson.M{
+		"views":           views,
+		"watchTime":       watchTime,
+		"completionTotal": completion,
+	}})
+	return err
+}
+
+// ListDeleted - video trong thùng rác của owner, xoá sau thời điểm since
+func (r *videoRepository) ListDeleted(ctx context.Context, ownerID primitive.ObjectID, since time.Time) ([]models.Video, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "deletedAt", Value: -1}})
+	cursor, err := r.collection.Find(ctx, bson.M{"ownerId": ownerID, "deletedAt": bson.M{"$gte": since}}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var items []models.Video
+	err = cursor.All(ctx, &items)
+	return items, err
+}
+
+func (r *videoRepository) Restore(ctx context.Context, id, ownerID primitive.ObjectID, since time.Time) error {
+	res, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": id, "ownerId": ownerID, "deletedAt": bson.M{"$gte": since}},
+		bson.M{"$unset": bson.M{"deletedAt": ""}},
+	)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// FindPurgeable - video đã xoá trước thời điểm before (hết hạn lưu)
+func (r *videoRepository) FindPurgeable(ctx context.Context, before time.Time, limit int64) ([]models.Video, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{"deletedAt": bson.M{"$lt": before}}, options.Find().SetLimit(limit))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var items []models.Video
+	err = cursor.All(ctx, &items)
+	return items, err
+}
+
+func (r *videoRepository) Purge(ctx context.Context, id primitive.ObjectID) error {
+	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
+
+// FindAllByOwner - mọi video của owner, kể cả đã xoá mềm (dùng khi xoá tài khoản)
+func (r *videoRepository) FindAllByOwner(ctx context.Context, ownerID primitive.ObjectID, limit int64) ([]models.Video, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{"ownerId": ownerID}, options.Find().SetLimit(limit))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var items []models.Video
+	err = cursor.All(ctx, &items)
+	return items, err
+}
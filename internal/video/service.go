@@ -2,12 +2,20 @@ package video
 
 import (
 	"context"
+	"errors"
+	"strings"
 	"time"
 
+	"socialnetwork/dto/request"
+	"socialnetwork/internal/contentfilter"
 	"socialnetwork/internal/notification"
 	"socialnetwork/internal/follow"
+	"socialnetwork/internal/revision"
 	"socialnetwork/models"
+	"socialnetwork/pkg/media"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 type VideoService interface {
@@ -17,44 +25,92 @@ type VideoService interface {
 	IncrementView(ctx context.Context, id primitive.ObjectID) error
 	DeleteVideo(ctx context.Context, id, ownerID primitive.ObjectID) error
 	GetPublicVideosByOwner(ctx context.Context, ownerID primitive.ObjectID) ([]models.Video, error)
+	NotifyFollowers(ctx context.Context, video *models.Video) error
+	UpdateVideo(ctx context.Context, id, editorID primitive.ObjectID, req *request.UpdateVideoRequest) (*models.Video, error)
+	ListRevisions(ctx context.Context, id, requesterID primitive.ObjectID) ([]models.Revision, error)
 }
 
 type videoService struct {
 	videoRepo        VideoRepository
 	followRepo       follow.FollowRepository
 	notificationRepo notification.NotificationRepository
+	jobQueue         media.JobQueue
+	revisionRepo     revision.Repository
+	filter           contentfilter.Filter
 }
 
 func NewVideoService(
 	videoRepo VideoRepository,
 	followRepo follow.FollowRepository,
 	notificationRepo notification.NotificationRepository,
+	jobQueue media.JobQueue,
+	revisionRepo revision.Repository,
+	filter contentfilter.Filter,
 ) VideoService {
-	return &videoService{videoRepo, followRepo, notificationRepo}
+	return &videoService{videoRepo, followRepo, notificationRepo, jobQueue, revisionRepo, filter}
 }
 
 func (s *videoService) CreateVideo(ctx context.Context, video *models.Video) error {
-	err := s.videoRepo.Create(ctx, video)
+	// Thời lượng, rendition... do worker xử lý media điền, không tin dữ liệu client gửi lên
+	video.ID = primitive.NewObjectID()
+	video.Status = models.ProcessingPending
+	video.HLSURL = ""
+	video.Renditions = nil
+	video.ProcessingError = ""
+	video.ProcessedAt = nil
+	video.PublishedAt = nil
+	video.Edited = false
+	video.EditedAt = nil
+
+	// Video bị bộ lọc giữ lại vẫn được xử lý media nhưng ẩn cho tới khi kiểm duyệt xong
+	verdict, err := contentfilter.Apply(s.filter, &video.Title, &video.Description)
 	if err != nil {
 		return err
 	}
+	video.ModerationHidden = verdict.Held()
 
-	// Gửi thông báo đến followers
-	followers, err := s.followRepo.GetFollowers(ctx, video.OwnerID)
-	if err == nil {
-		for _, f := range followers {
-			noti := &models.Notification{
-				Recipient: f.Follower,
-				Sender:    video.OwnerID,
-				Type:      models.NotificationNewVideo,
-				VideoID:   &video.ID,
-				IsRead:    false,
-				CreatedAt: time.Now(),
-			}
-			_ = s.notificationRepo.Create(ctx, noti)
+	err = s.videoRepo.Create(ctx, video)
+	if err != nil {
+		return err
+	}
+	if verdict.Held() {
+		if err := s.filter.Hold(ctx, models.ReportTargetVideo, video.ID, video.OwnerID, verdict.Rules); err != nil {
+			return err
 		}
 	}
 
+	// Thông báo cho followers sẽ được gửi khi video xử lý xong (NotifyFollowers)
+	return s.jobQueue.Enqueue(ctx, media.Job{Kind: media.KindVideo, ID: video.ID.Hex()})
+}
+
+// NotifyFollowers gửi thông báo video mới đến followers (chỉ với video public,
+// chưa thông báo lần nào, không bị ẩn chờ kiểm duyệt)
+func (s *videoService) NotifyFollowers(ctx context.Context, video *models.Video) error {
+	if video.Visibility != "public" || video.PublishedAt != nil || video.ModerationHidden {
+		return nil
+	}
+
+	now := time.Now()
+	if err := s.videoRepo.UpdateFields(ctx, video.ID, bson.M{"publishedAt": now}); err != nil {
+		return err
+	}
+	video.PublishedAt = &now
+
+	followers, err := s.followRepo.GetFollowers(ctx, video.OwnerID)
+	if err != nil {
+		return err
+	}
+	for _, f := range followers {
+		noti := &models.Notification{
+			Recipient: f.Follower,
+			Sender:    video.OwnerID,
+			Type:      models.NotificationNewVideo,
+			VideoID:   &video.ID,
+			IsRead:    false,
+			CreatedAt: time.Now(),
+		}
+		_ = s.notificationRepo.Create(ctx, noti)
+	}
 	return nil
 }
 
@@ -70,11 +126,136 @@ func (s *videoService) IncrementView(ctx context.Context, id primitive.ObjectID)
 	return s.videoRepo.IncrementViews(ctx, id)
 }
 
+// DeleteVideo chuyển video vào thùng rác, có thể khôi phục trong thời hạn lưu
 func (s *videoService) DeleteVideo(ctx context.Context, id, ownerID primitive.ObjectID) error {
-	return s.videoRepo.Delete(ctx, id, ownerID)
+	if err := s.videoRepo.Delete(ctx, id, ownerID); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return ErrVideoNotFound
+		}
+		return err
+	}
+	return nil
 }
 
 func (s *videoService) GetPublicVideosByOwner(ctx context.Context, ownerID primitive.ObjectID) ([]models.Video, error) {
     // Gọi repo để lấy video chỉ với visibility = "public"
     return s.videoRepo.FindByOwnerAndVisibility(ctx, ownerID, "public")
 }
+
+func (s *videoService) UpdateVideo(ctx context.Context, id, editorID primitive.ObjectID, req *request.UpdateVideoRequest) (*models.Video, error) {
+	video, err := s.videoRepo.GetForOwner(ctx, id, editorID)
+	if err != nil {
+		return nil, ErrVideoNotFound
+	}
+
+	updates := map[string]interface{}{}
+	if req.Title != nil {
+		title := strings.TrimSpace(*req.Title)
+		if title == "" {
+			return nil, ErrEmptyTitle
+		}
+		updates["title"] = title
+	}
+	if req.Description != nil {
+		updates["description"] = strings.TrimSpace(*req.Description)
+	}
+	if req.Thumbnail != nil {
+		updates["thumbnail"] = *req.Thumbnail
+	}
+	if req.Visibility != nil {
+		updates["visibility"] = *req.Visibility
+	}
+
+	var verdict contentfilter.Verdict
+	title, hasTitle := updates["title"].(string)
+	description, hasDescription := updates["description"].(string)
+	if hasTitle || hasDescription {
+		if verdict, err = contentfilter.Apply(s.filter, &title, &description); err != nil {
+			return nil, err
+		}
+		if hasTitle {
+			updates["title"] = title
+		}
+		if hasDescription {
+			updates["description"] = description
+		}
+	}
+
+	previous := map[string]interface{}{
+		"title":       video.Title,
+		"description": video.Description,
+		"thumbnail":   video.Thumbnail,
+		"visibility":  video.Visibility,
+	}
+	changed, rev := revision.Diff(revision.ContentVideo, id, editorID, previous, updates)
+	if len(changed) == 0 {
+		return nil, ErrNoChanges
+	}
+
+	now := time.Now()
+	changed["edited"] = true
+	changed["editedAt"] = now
+	if verdict.Held() {
+		changed["moderationHidden"] = true
+	}
+	if err := s.videoRepo.UpdateFields(ctx, id, changed); err != nil {
+		return nil, err
+	}
+	revision.Save(ctx, s.revisionRepo, rev)
+	if verdict.Held() {
+		video.ModerationHidden = true
+		if err := s.filter.Hold(ctx, models.ReportTargetVideo, id, video.OwnerID, verdict.Rules); err != nil {
+			return nil, err
+		}
+	}
+
+	oldVisibility := video.Visibility
+	if v, ok := changed["title"].(string); ok {
+		video.Title = v
+	}
+	if v, ok := changed["description"].(string); ok {
+		video.Description = v
+	}
+	if v, ok := changed["thumbnail"].(string); ok {
+		video.Thumbnail = v
+	}
+	if v, ok := changed["visibility"].(string); ok {
+		video.Visibility = v
+	}
+	video.Edited = true
+	video.EditedAt = &now
+
+	if err := s.applyVisibilityTransition(ctx, video, oldVisibility); err != nil {
+		return nil, err
+	}
+	return video, nil
+}
+
+// applyVisibilityTransition: chuyển sang private thì thu hồi thông báo đã gửi,
+// chuyển sang public (và đã xử lý xong) thì thông báo cho followers nếu chưa từng thông báo.
+// publishedAt giữ nguyên khi ẩn đi để đổi qua lại không gửi thông báo lặp lại.
+func (s *videoService) applyVisibilityTransition(ctx context.Context, video *models.Video, oldVisibility string) error {
+	if video.Visibility == oldVisibility {
+		return nil
+	}
+
+	if video.Visibility == "public" {
+		if !models.IsProcessed(video.Status) {
+			return nil // worker sẽ thông báo khi xử lý xong
+		}
+		return s.NotifyFollowers(ctx, video)
+	}
+
+	return s.notificationRepo.DeleteByVideo(ctx, video.ID)
+}
+
+func (s *videoService) ListRevisions(ctx context.Context, id, requesterID primitive.ObjectID) ([]models.Revision, error) {
+	video, err := s.videoRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, ErrVideoNotFound
+	}
+	if video.OwnerID != requesterID {
+		return nil, ErrNotOwner
+	}
+	return s.revisionRepo.ListByContent(ctx, revision.ContentVideo, id)
+}
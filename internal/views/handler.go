@@ -0,0 +1,114 @@
+package views
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type Handler struct {
+	service Service
+}
+
+func NewHandler(service Service) *Handler {
+	return &Handler{service: service}
+}
+
+type trackViewRequest struct {
+	WatchTime float64 `json:"watchTime" binding:"gte=0"` // số giây đã xem
+	DeviceID  string  `json:"deviceId"`
+}
+
+// POST /video_short/videos/:id/view
+func (h *Handler) TrackVideoView(c *gin.Context) {
+	h.trackView(c, ContentVideo)
+}
+
+// POST /video_short/shorts/:id/view
+func (h *Handler) TrackShortView(c *gin.Context) {
+	h.trackView(c, ContentShort)
+}
+
+// GET /video_short/videos/:id/analytics
+func (h *Handler) GetVideoAnalytics(c *gin.Context) {
+	h.getAnalytics(c, ContentVideo)
+}
+
+// GET /video_short/shorts/:id/analytics
+func (h *Handler) GetShortAnalytics(c *gin.Context) {
+	h.getAnalytics(c, ContentShort)
+}
+
+func (h *Handler) trackView(c *gin.Context, contentType string) {
+	contentID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + contentType + " ID"})
+		return
+	}
+
+	var req trackViewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.DeviceID == "" {
+		req.DeviceID = c.GetHeader("X-Device-ID")
+	}
+
+	in := &ViewInput{
+		ContentType: contentType,
+		ContentID:   contentID,
+		DeviceID:    req.DeviceID,
+		IP:          c.ClientIP(),
+		WatchTime:   req.WatchTime,
+	}
+	if viewerID, err := primitive.ObjectIDFromHex(c.GetString("userID")); err == nil {
+		in.ViewerID = &viewerID
+	}
+
+	counted, err := h.service.RecordView(c.Request.Context(), in)
+	if err != nil {
+		if errors.Is(err, ErrContentNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to record view"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"counted": counted})
+}
+
+func (h *Handler) getAnalytics(c *gin.Context, contentType string) {
+	contentID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + contentType + " ID"})
+		return
+	}
+
+	userID, err := primitive.ObjectIDFromHex(c.GetString("userID"))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	days, _ := strconv.Atoi(c.DefaultQuery("days", "30"))
+
+	analytics, err := h.service.GetAnalytics(c.Request.Context(), contentType, contentID, userID, days)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrContentNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, ErrForbidden):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get analytics"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, analytics)
+}
@@ -0,0 +1,114 @@
+package views
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"socialnetwork/models"
+)
+
+// EventStats - thống kê tính từ các view event đã lưu
+type EventStats struct {
+	Events         int     `bson:"events"`
+	CountedViews   int     `bson:"countedViews"`
+	CompletedViews int     `bson:"completedViews"`
+	AvgCompletion  float64 `bson:"avgCompletion"`
+}
+
+type DailyViews struct {
+	Date      string  `bson:"_id" json:"date"` // YYYY-MM-DD
+	Views     int     `bson:"views" json:"views"`
+	WatchTime float64 `bson:"watchTime" json:"watchTime"`
+}
+
+type Repository interface {
+	InsertMany(ctx context.Context, events []models.ViewEvent) error
+	Stats(ctx context.Context, contentType string, contentID primitive.ObjectID) (*EventStats, error)
+	Daily(ctx context.Context, contentType string, contentID primitive.ObjectID, since time.Time) ([]DailyViews, error)
+	DeleteByContent(ctx context.Context, contentType string, contentID primitive.ObjectID) error
+}
+
+type repository struct {
+	collection *mongo.Collection
+}
+
+func NewRepository(db *mongo.Database) Repository {
+	return &repository{collection: db.Collection("view_events")}
+}
+
+func (r *repository) InsertMany(ctx context.Context, events []models.ViewEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	docs := make([]interface{}, len(events))
+	for i := range events {
+		docs[i] = events[i]
+	}
+	_, err := r.collection.InsertMany(ctx, docs)
+	return err
+}
+
+func (r *repository) Stats(ctx context.Context, contentType string, contentID primitive.ObjectID) (*EventStats, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"contentType": contentType, "contentId": contentID}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":          nil,
+			"events":       bson.M{"$sum": 1},
+			"countedViews": bson.M{"$sum": bson.M{"$cond": bson.A{"$counted", 1, 0}}},
+			"completedViews": bson.M{"$sum": bson.M{"$cond": bson.A{
+				bson.M{"$and": bson.A{"$counted", bson.M{"$gte": bson.A{"$completion", CompletedThreshold}}}}, 1, 0,
+			}}},
+			"avgCompletion": bson.M{"$avg": bson.M{"$cond": bson.A{"$counted", "$completion", nil}}},
+		}}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var stats EventStats
+	if cursor.Next(ctx) {
+		if err := cursor.Decode(&stats); err != nil {
+			return nil, err
+		}
+	}
+	return &stats, cursor.Err()
+}
+
+func (r *repository) Daily(ctx context.Context, contentType string, contentID primitive.ObjectID, since time.Time) ([]DailyViews, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"contentType": contentType,
+			"contentId":   contentID,
+			"createdAt":   bson.M{"$gte": since},
+		}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":       bson.M{"$dateToString": bson.M{"format": "%Y-%m-%d", "date": "$createdAt"}},
+			"views":     bson.M{"$sum": bson.M{"$cond": bson.A{"$counted", 1, 0}}},
+			"watchTime": bson.M{"$sum": bson.M{"$cond": bson.A{"$counted", "$watchTime", 0}}},
+		}}},
+		{{Key: "$sort", Value: bson.M{"_id": 1}}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var daily []DailyViews
+	if err := cursor.All(ctx, &daily); err != nil {
+		return nil, err
+	}
+	return daily, nil
+}
+
+func (r *repository) DeleteByContent(ctx context.Context, contentType string, contentID primitive.ObjectID) error {
+	_, err := r.collection.DeleteMany(ctx, bson.M{"contentType": contentType, "contentId": contentID})
+	return err
+}
@@ -0,0 +1,452 @@
+package views
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"socialnetwork/internal/short"
+	"socialnetwork/internal/video"
+	"socialnetwork/models"
+)
+
+// Loại nội dung được theo dõi lượt xem
+const (
+	ContentVideo = "video"
+	ContentShort = "short"
+)
+
+// CompletedThreshold - xem >= 90% được coi là xem hết
+const CompletedThreshold = 0.9
+
+const eventsKey = "views:events"
+
+var (
+	ErrContentNotFound = errors.New("nội dung không tồn tại")
+	ErrForbidden       = errors.New("bạn không có quyền xem thống kê nội dung này")
+	ErrInvalidType     = errors.New("loại nội dung không hợp lệ")
+)
+
+type Config struct {
+	DedupWindow     time.Duration // trong khoảng này 1 user/thiết bị chỉ được tính 1 lượt xem
+	MinWatchSeconds float64       // xem tối thiểu bao nhiêu giây mới tính lượt xem
+	SeenTTL         time.Duration // thời gian giữ danh sách nội dung đã xem (dùng cho feed)
+	FlushBatchSize  int64
+}
+
+// ViewInput - dữ liệu một lần báo cáo lượt xem
+type ViewInput struct {
+	ContentType string
+	ContentID   primitive.ObjectID
+	ViewerID    *primitive.ObjectID
+	DeviceID    string
+	IP          string
+	WatchTime   float64
+}
+
+// ContentAnalytics - thống kê trả về cho chủ sở hữu
+type ContentAnalytics struct {
+	ContentType    string       `json:"contentType"`
+	ContentID      string       `json:"contentId"`
+	Views          int          `json:"views"`
+	UniqueViewers  int64        `json:"uniqueViewers"`
+	TotalWatchTime float64      `json:"totalWatchTime"`
+	AvgWatchTime   float64      `json:"avgWatchTime"`
+	AvgCompletion  float64      `json:"avgCompletion"`
+	CompletionRate float64      `json:"completionRate"` // tỉ lệ lượt xem xem hết
+	Daily          []DailyViews `json:"daily"`
+}
+
+type Service interface {
+	RecordView(ctx context.Context, in *ViewInput) (bool, error)
+	GetAnalytics(ctx context.Context, contentType string, contentID, requesterID primitive.ObjectID, days int) (*ContentAnalytics, error)
+	HasSeen(ctx context.Context, contentType string, userID primitive.ObjectID) (map[string]bool, error)
+	Flush(ctx context.Context) error
+	RunFlusher(ctx context.Context, interval time.Duration)
+}
+
+type service struct {
+	repo        Repository
+	videoRepo   video.VideoRepository
+	shortRepo   short.ShortRepository
+	redisClient *redis.Client
+	config      Config
+}
+
+func NewService(repo Repository, videoRepo video.VideoRepository, shortRepo short.ShortRepository, redisClient *redis.Client, config Config) Service {
+	if config.DedupWindow <= 0 {
+		config.DedupWindow = 30 * time.Minute
+	}
+	if config.MinWatchSeconds <= 0 {
+		config.MinWatchSeconds = 3
+	}
+	if config.SeenTTL <= 0 {
+		config.SeenTTL = 30 * 24 * time.Hour
+	}
+	if config.FlushBatchSize <= 0 {
+		config.FlushBatchSize = 500
+	}
+	return &service{
+		repo:        repo,
+		videoRepo:   videoRepo,
+		shortRepo:   shortRepo,
+		redisClient: redisClient,
+		config:      config,
+	}
+}
+
+// contentInfo - các trường cần dùng chung của video và short
+type contentInfo struct {
+	OwnerID         primitive.ObjectID
+	Duration        int
+	Visibility      string
+	Status          models.ProcessingStatus
+	Views           int
+	WatchTime       float64
+	CompletionTotal float64
+}
+
+func (s *service) loadContent(ctx context.Context, contentType string, id primitive.ObjectID) (*contentInfo, error) {
+	switch contentType {
+	case ContentVideo:
+		v, err := s.videoRepo.GetByID(ctx, id)
+		if err != nil {
+			return nil, ErrContentNotFound
+		}
+		return &contentInfo{v.OwnerID, v.Duration, v.Visibility, v.Status, v.Views, v.WatchTime, v.CompletionTotal}, nil
+	case ContentShort:
+		sh, err := s.shortRepo.GetByID(ctx, id)
+		if err != nil {
+			return nil, ErrContentNotFound
+		}
+		return &contentInfo{sh.OwnerID, sh.Duration, sh.Visibility, sh.Status, sh.Views, sh.WatchTime, sh.CompletionTotal}, nil
+	default:
+		return nil, ErrInvalidType
+	}
+}
+
+func pendingKey(contentType string) string {
+	return "views:pending:" + contentType
+}
+
+func seenKey(contentType string, userID primitive.ObjectID) string {
+	return fmt.Sprintf("views:seen:%s:%s", contentType, userID.Hex())
+}
+
+func uniqueKey(contentType string, id primitive.ObjectID) string {
+	return fmt.Sprintf("views:uniq:%s:%s", contentType, id.Hex())
+}
+
+// RecordView ghi nhận một lần xem, trả về true nếu được tính là lượt xem mới
+func (s *service) RecordView(ctx context.Context, in *ViewInput) (bool, error) {
+	info, err := s.loadContent(ctx, in.ContentType, in.ContentID)
+	if err != nil {
+		return false, err
+	}
+
+	isOwner := in.ViewerID != nil && *in.ViewerID == info.OwnerID
+	if !models.IsProcessed(info.Status) || (info.Visibility == "private" && !isOwner) {
+		return false, ErrContentNotFound
+	}
+
+	// Định danh người xem: ưu tiên user, sau đó thiết bị, cuối cùng là IP
+	var viewerKey string
+	switch {
+	case in.ViewerID != nil:
+		viewerKey = "u:" + in.ViewerID.Hex()
+	case in.DeviceID != "":
+		viewerKey = "d:" + in.DeviceID
+	default:
+		viewerKey = "ip:" + in.IP
+	}
+
+	// Không tin watchTime client gửi vượt quá thời lượng
+	watchTime := math.Max(in.WatchTime, 0)
+	completion := 0.0
+	if info.Duration > 0 {
+		watchTime = math.Min(watchTime, float64(info.Duration))
+		completion = watchTime / float64(info.Duration)
+	}
+
+	// Chủ sở hữu tự xem không tính; phải xem đủ lâu mới tính
+	minWatch := s.config.MinWatchSeconds
+	if info.Duration > 0 && float64(info.Duration) < minWatch {
+		minWatch = float64(info.Duration)
+	}
+	counted := false
+	if !isOwner && watchTime >= minWatch {
+		dedupKey := fmt.Sprintf("views:dedup:%s:%s:%s", in.ContentType, in.ContentID.Hex(), viewerKey)
+		counted, err = s.redisClient.SetNX(ctx, dedupKey, 1, s.config.DedupWindow).Result()
+		if err != nil {
+			return false, err
+		}
+	}
+
+	// Thời gian xem và view event chỉ ghi cùng lượt xem được tính (đã chống trùng, không tính chủ sở hữu),
+	// nếu không gửi lặp lại request sẽ thổi phồng tổng thời gian xem và làm phình view_events
+	id := in.ContentID.Hex()
+	pipe := s.redisClient.TxPipeline()
+	if counted {
+		event := models.ViewEvent{
+			ContentType: in.ContentType,
+			ContentID:   in.ContentID,
+			OwnerID:     info.OwnerID,
+			ViewerID:    in.ViewerID,
+			DeviceID:    in.DeviceID,
+			WatchTime:   watchTime,
+			Completion:  completion,
+			Counted:     true,
+			CreatedAt:   time.Now(),
+		}
+		data, err := json.Marshal(event)
+		if err != nil {
+			return false, err
+		}
+		pipe.HIncrByFloat(ctx, pendingKey(in.ContentType), id+":watch", watchTime)
+		pipe.HIncrBy(ctx, pendingKey(in.ContentType), id+":views", 1)
+		pipe.HIncrByFloat(ctx, pendingKey(in.ContentType), id+":completion", completion)
+		pipe.PFAdd(ctx, uniqueKey(in.ContentType, in.ContentID), viewerKey)
+		pipe.RPush(ctx, eventsKey, data)
+	}
+	if in.ViewerID != nil {
+		pipe.SAdd(ctx, seenKey(in.ContentType, *in.ViewerID), id)
+		pipe.Expire(ctx, seenKey(in.ContentType, *in.ViewerID), s.config.SeenTTL)
+	}
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return false, err
+	}
+	return counted, nil
+}
+
+// HasSeen trả về tập ID nội dung user đã xem (trong SeenTTL)
+func (s *service) HasSeen(ctx context.Context, contentType string, userID primitive.ObjectID) (map[string]bool, error) {
+	members, err := s.redisClient.SMembers(ctx, seenKey(contentType, userID)).Result()
+	if err != nil && err != redis.Nil {
+		return nil, err
+	}
+	seen := make(map[string]bool, len(members))
+	for _, m := range members {
+		seen[m] = true
+	}
+	return seen, nil
+}
+
+type pendingStats struct {
+	views      int
+	watchTime  float64
+	completion float64
+}
+
+func parsePending(values map[string]string) map[string]*pendingStats {
+	stats := make(map[string]*pendingStats)
+	for field, raw := range values {
+		idx := strings.LastIndex(field, ":")
+		if idx < 0 {
+			continue
+		}
+		id, metric := field[:idx], field[idx+1:]
+		st, ok := stats[id]
+		if !ok {
+			st = &pendingStats{}
+			stats[id] = st
+		}
+		val, _ := strconv.ParseFloat(raw, 64)
+		switch metric {
+		case "views":
+			st.views = int(val)
+		case "watch":
+			st.watchTime = val
+		case "completion":
+			st.completion = val
+		}
+	}
+	return stats
+}
+
+// Flush đẩy bộ đếm đang chờ trong Redis xuống MongoDB theo lô
+func (s *service) Flush(ctx context.Context) error {
+	for _, contentType := range []string{ContentVideo, ContentShort} {
+		if err := s.flushCounters(ctx, contentType); err != nil {
+			return err
+		}
+	}
+	return s.flushEvents(ctx)
+}
+
+func (s *service) flushCounters(ctx context.Context, contentType string) error {
+	flushingKey := pendingKey(contentType) + ":flushing"
+
+	// Lần flush trước bị lỗi giữa chừng thì xử lý nốt trước, không thì đổi tên
+	// hash đang ghi để các lượt xem mới không bị lẫn vào lô này
+	exists, err := s.redisClient.Exists(ctx, flushingKey).Result()
+	if err != nil {
+		return err
+	}
+	if exists == 0 {
+		err := s.redisClient.Rename(ctx, pendingKey(contentType), flushingKey).Err()
+		if err != nil {
+			if strings.Contains(err.Error(), "no such key") {
+				return nil
+			}
+			return err
+		}
+	}
+
+	values, err := s.redisClient.HGetAll(ctx, flushingKey).Result()
+	if err != nil {
+		return err
+	}
+
+	for idHex, st := range parsePending(values) {
+		id, err := primitive.ObjectIDFromHex(idHex)
+		if err == nil {
+			switch contentType {
+			case ContentVideo:
+				err = s.videoRepo.IncrementViewStats(ctx, id, st.views, st.watchTime, st.completion)
+			case ContentShort:
+				err = s.shortRepo.IncrementViewStats(ctx, id, st.views, st.watchTime, st.completion)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		// Xoá từng nội dung sau khi ghi xong để lỗi giữa chừng không bị cộng 2 lần
+		s.redisClient.HDel(ctx, flushingKey, idHex+":views", idHex+":watch", idHex+":completion")
+	}
+
+	return s.redisClient.Del(ctx, flushingKey).Err()
+}
+
+func (s *service) flushEvents(ctx context.Context) error {
+	for {
+		raw, err := s.redisClient.LRange(ctx, eventsKey, 0, s.config.FlushBatchSize-1).Result()
+		if err != nil {
+			return err
+		}
+		if len(raw) == 0 {
+			return nil
+		}
+
+		events := make([]models.ViewEvent, 0, len(raw))
+		for _, item := range raw {
+			var ev models.ViewEvent
+			if err := json.Unmarshal([]byte(item), &ev); err != nil {
+				continue
+			}
+			events = append(events, ev)
+		}
+		if err := s.repo.InsertMany(ctx, events); err != nil {
+			return err
+		}
+
+		// Sự kiện mới được RPUSH vào cuối nên cắt phần đầu đã ghi là an toàn
+		if err := s.redisClient.LTrim(ctx, eventsKey, int64(len(raw)), -1).Err(); err != nil {
+			return err
+		}
+		if int64(len(raw)) < s.config.FlushBatchSize {
+			return nil
+		}
+	}
+}
+
+// RunFlusher flush định kỳ cho tới khi ctx bị huỷ
+func (s *service) RunFlusher(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Flush(ctx); err != nil {
+				log.Printf("⚠️ flush lượt xem thất bại: %v", err)
+			}
+		}
+	}
+}
+
+func (s *service) GetAnalytics(ctx context.Context, contentType string, contentID, requesterID primitive.ObjectID, days int) (*ContentAnalytics, error) {
+	info, err := s.loadContent(ctx, contentType, contentID)
+	if err != nil {
+		return nil, err
+	}
+	if info.OwnerID != requesterID {
+		return nil, ErrForbidden
+	}
+
+	// Cộng thêm phần chưa flush để số liệu luôn mới
+	views, watchTime, completion := info.Views, info.WatchTime, info.CompletionTotal
+	id := contentID.Hex()
+	for _, key := range []string{pendingKey(contentType), pendingKey(contentType) + ":flushing"} {
+		vals, err := s.redisClient.HMGet(ctx, key, id+":views", id+":watch", id+":completion").Result()
+		if err != nil {
+			continue
+		}
+		st := parsePending(map[string]string{
+			id + ":views":      toString(vals[0]),
+			id + ":watch":      toString(vals[1]),
+			id + ":completion": toString(vals[2]),
+		})[id]
+		if st != nil {
+			views += st.views
+			watchTime += st.watchTime
+			completion += st.completion
+		}
+	}
+
+	unique, err := s.redisClient.PFCount(ctx, uniqueKey(contentType, contentID)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	stats, err := s.repo.Stats(ctx, contentType, contentID)
+	if err != nil {
+		return nil, err
+	}
+
+	if days <= 0 || days > 365 {
+		days = 30
+	}
+	since := time.Now().UTC().AddDate(0, 0, -days+1).Truncate(24 * time.Hour)
+	daily, err := s.repo.Daily(ctx, contentType, contentID, since)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &ContentAnalytics{
+		ContentType:    contentType,
+		ContentID:      id,
+		Views:          views,
+		UniqueViewers:  unique,
+		TotalWatchTime: watchTime,
+		Daily:          daily,
+	}
+	if views > 0 {
+		res.AvgWatchTime = watchTime / float64(views)
+		res.AvgCompletion = completion / float64(views)
+	}
+	if stats.CountedViews > 0 {
+		res.CompletionRate = float64(stats.CompletedViews) / float64(stats.CountedViews)
+	}
+	return res, nil
+}
+
+func toString(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	if str, ok := v.(string); ok {
+		return str
+	}
+	return ""
+}
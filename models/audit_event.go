@@ -0,0 +1,62 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type AuditAction string
+
+const (
+	AuditLogin         AuditAction = "auth.login"
+	AuditLoginFailed   AuditAction = "auth.login_failed"
+	AuditLoginLocked   AuditAction = "auth.login_locked" // khoá tạm thời do sai mật khẩu nhiều lần
+	AuditNewDevice     AuditAction = "auth.new_device"
+	AuditLoginReported AuditAction = "auth.login_reported" // user báo "không phải tôi": thu hồi phiên, buộc đặt lại mật khẩu
+
+	AuditPasswordChanged        AuditAction = "account.password_changed"
+	AuditPasswordResetRequested AuditAction = "account.password_reset_requested"
+	AuditPasswordReset          AuditAction = "account.password_reset"
+	AuditEmailChangeRequested   AuditAction = "account.email_change_requested"
+	AuditEmailChanged           AuditAction = "account.email_changed"
+	AuditPhoneChangeRequested   AuditAction = "account.phone_change_requested"
+	AuditPhoneChanged           AuditAction = "account.phone_changed"
+	AuditContactChangeCancelled AuditAction = "account.contact_change_cancelled"
+
+	AuditTwoFactorEnabled     AuditAction = "2fa.enabled"
+	AuditTwoFactorDisabled    AuditAction = "2fa.disabled"
+	AuditRecoveryCodesRenewed AuditAction = "2fa.recovery_codes_regenerated"
+	AuditTwoFactorFailed      AuditAction = "2fa.verify_failed"
+	AuditRecoveryCodeUsed     AuditAction = "2fa.recovery_code_used"
+
+	AuditOTPSent     AuditAction = "otp.sent"
+	AuditOTPVerified AuditAction = "otp.verified"
+	AuditOTPFailed   AuditAction = "otp.verify_failed"
+	AuditOTPLocked   AuditAction = "otp.locked"
+
+	AuditUserBlocked AuditAction = "social.user_blocked"
+
+	AuditModerationAction  AuditAction = "admin.moderation_action"
+	AuditFilterRuleCreated AuditAction = "admin.filter_rule_created"
+	AuditFilterRuleUpdated AuditAction = "admin.filter_rule_updated"
+	AuditFilterRuleDeleted AuditAction = "admin.filter_rule_deleted"
+	AuditFilterRulesReload AuditAction = "admin.filter_rules_reloaded"
+)
+
+// AuditEvent - bản ghi chỉ thêm (append-only) trong audit_events
+type AuditEvent struct {
+	ID      primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	Action  AuditAction         `bson:"action" json:"action"`
+	Success bool                `bson:"success" json:"success"`
+	UserID  *primitive.ObjectID `bson:"userId,omitempty" json:"userId,omitempty"`   // tài khoản bị tác động
+	ActorID *primitive.ObjectID `bson:"actorId,omitempty" json:"actorId,omitempty"` // người thực hiện khi khác UserID (vd: admin)
+
+	TargetType string                 `bson:"targetType,omitempty" json:"targetType,omitempty"`
+	TargetID   string                 `bson:"targetId,omitempty" json:"targetId,omitempty"`
+	Metadata   map[string]interface{} `bson:"metadata,omitempty" json:"metadata,omitempty"`
+
+	IP        string    `bson:"ip,omitempty" json:"ip,omitempty"`
+	UserAgent string    `bson:"userAgent,omitempty" json:"userAgent,omitempty"`
+	CreatedAt time.Time `bson:"createdAt" json:"createdAt"`
+}
@@ -12,6 +12,10 @@ type Comment struct {
     UserID    primitive.ObjectID   `bson:"user_id" json:"user_id"`
     Content   string               `bson:"content" json:"content"`
     Likes     []primitive.ObjectID `bson:"likes,omitempty" json:"likes,omitempty"`
+    Edited    bool                 `bson:"edited" json:"edited"`
+    EditedAt  *time.Time           `bson:"edited_at,omitempty" json:"edited_at,omitempty"`
+    DeletedAt *time.Time           `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"` // xoá mềm (thùng rác)
+    ModerationHidden bool          `bson:"moderation_hidden,omitempty" json:"moderation_hidden,omitempty"` // bị ẩn do báo cáo vi phạm
     CreatedAt time.Time            `bson:"created_at" json:"created_at"`
     UpdatedAt time.Time            `bson:"updated_at" json:"updated_at"`
 }
@@ -0,0 +1,80 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type ConversationType string
+
+const (
+	ConversationDirect ConversationType = "direct" // hai người
+	ConversationGroup  ConversationType = "group"
+)
+
+// Vai trò trong cuộc trò chuyện nhóm
+const (
+	ConversationRoleAdmin  = "admin"
+	ConversationRoleMember = "member"
+)
+
+// Thư mục của hội thoại trong hộp thư từng thành viên
+const (
+	FolderInbox    = "inbox"
+	FolderRequests = "requests" // tin nhắn chờ: người gửi không phải bạn bè/người mình theo dõi
+	FolderSpam     = "spam"     // yêu cầu đã bị từ chối
+)
+
+// ConversationMember - trạng thái của từng thành viên: số tin chưa đọc và tin đã đọc gần nhất (read receipt)
+type ConversationMember struct {
+	UserID            primitive.ObjectID  `bson:"userId" json:"userId"`
+	Role              string              `bson:"role,omitempty" json:"role,omitempty"`
+	Folder            string              `bson:"folder,omitempty" json:"-"` // trống coi như inbox; chỉ trả cho chính thành viên qua ConversationView
+	InvitedBy         *primitive.ObjectID `bson:"invitedBy,omitempty" json:"invitedBy,omitempty"`
+	UnreadCount       int64               `bson:"unreadCount" json:"-"` // chỉ trả cho chính thành viên qua ConversationView
+	LastReadMessageID *primitive.ObjectID `bson:"lastReadMessageId,omitempty" json:"lastReadMessageId,omitempty"`
+	LastReadAt        *time.Time          `bson:"lastReadAt,omitempty" json:"lastReadAt,omitempty"`
+	JoinedAt          time.Time           `bson:"joinedAt" json:"joinedAt"`
+}
+
+// InFolder trả về thư mục của hội thoại với thành viên này
+func (m *ConversationMember) InFolder() string {
+	if m.Folder == "" {
+		return FolderInbox
+	}
+	return m.Folder
+}
+
+// MessagePreview - tin nhắn cuối để hiển thị danh sách hội thoại
+type MessagePreview struct {
+	ID        primitive.ObjectID `bson:"id" json:"id"`
+	SenderID  primitive.ObjectID `bson:"senderId" json:"senderId"`
+	Text      string             `bson:"text,omitempty" json:"text,omitempty"`
+	Deleted   bool               `bson:"deleted,omitempty" json:"deleted,omitempty"`
+	CreatedAt time.Time          `bson:"createdAt" json:"createdAt"`
+}
+
+type Conversation struct {
+	ID        primitive.ObjectID   `bson:"_id,omitempty" json:"id"`
+	Type      ConversationType     `bson:"type" json:"type"`
+	Title     string               `bson:"title,omitempty" json:"title,omitempty"` // chỉ với nhóm
+	DirectKey string               `bson:"directKey,omitempty" json:"-"`           // "<id nhỏ>:<id lớn>", unique để mỗi cặp chỉ có một hội thoại
+	Members   []ConversationMember `bson:"members" json:"members"`
+	CreatedBy primitive.ObjectID   `bson:"createdBy" json:"createdBy"`
+
+	LastMessage *MessagePreview `bson:"lastMessage,omitempty" json:"lastMessage,omitempty"`
+
+	CreatedAt time.Time `bson:"createdAt" json:"createdAt"`
+	UpdatedAt time.Time `bson:"updatedAt" json:"updatedAt"` // thời điểm có tin nhắn mới nhất, dùng để sắp xếp hộp thư
+}
+
+// Member trả về thành viên theo user ID, nil nếu không thuộc hội thoại
+func (c *Conversation) Member(userID primitive.ObjectID) *ConversationMember {
+	for i := range c.Members {
+		if c.Members[i].UserID == userID {
+			return &c.Members[i]
+		}
+	}
+	return nil
+}
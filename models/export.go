@@ -0,0 +1,29 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+type ExportStatus string
+
+const (
+	ExportPending    ExportStatus = "pending"
+	ExportProcessing ExportStatus = "processing"
+	ExportReady      ExportStatus = "ready"
+	ExportFailed     ExportStatus = "failed"
+	ExportExpired    ExportStatus = "expired" // file đã bị xoá sau khi link hết hạn
+)
+
+// ExportJob - yêu cầu tải xuống toàn bộ dữ liệu cá nhân
+type ExportJob struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID      primitive.ObjectID `bson:"userId" json:"userId"`
+	Status      ExportStatus       `bson:"status" json:"status"`
+	FilePath    string             `bson:"filePath,omitempty" json:"-"`
+	Size        int64              `bson:"size,omitempty" json:"size,omitempty"` // bytes
+	Error       string             `bson:"error,omitempty" json:"error,omitempty"`
+	CreatedAt   time.Time          `bson:"createdAt" json:"createdAt"`
+	CompletedAt *time.Time         `bson:"completedAt,omitempty" json:"completedAt,omitempty"`
+	ExpiresAt   *time.Time         `bson:"expiresAt,omitempty" json:"expiresAt,omitempty"` // hạn của link tải
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+type FilterRuleType string
+
+const (
+	FilterRuleWords FilterRuleType = "words" // danh sách từ/cụm từ cấm
+	FilterRuleLinks FilterRuleType = "links" // link: tên miền cấm hoặc quá nhiều link
+	FilterRuleSpam  FilterRuleType = "spam"  // lặp từ quá nhiều lần
+)
+
+type FilterAction string
+
+const (
+	FilterAllow  FilterAction = "allow"
+	FilterMask   FilterAction = "mask"   // che phần vi phạm bằng ***
+	FilterHold   FilterAction = "hold"   // vẫn lưu nhưng ẩn, chờ kiểm duyệt
+	FilterReject FilterAction = "reject" // từ chối lưu
+)
+
+// FilterRule - luật lọc nội dung tự động, nạp lại lúc chạy từ collection filter_rules
+type FilterRule struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	Name      string             `bson:"name" json:"name"`
+	Type      FilterRuleType     `bson:"type" json:"type"`
+	Language  string             `bson:"language,omitempty" json:"language,omitempty"` // vi, en... (chỉ để quản lý)
+	Words     []string           `bson:"words,omitempty" json:"words,omitempty"`       // với type=words
+	Domains   []string           `bson:"domains,omitempty" json:"domains,omitempty"`   // với type=links
+	MaxLinks  int                `bson:"maxLinks,omitempty" json:"maxLinks,omitempty"` // với type=links, 0 = chỉ xét tên miền
+	MaxRepeat int                `bson:"maxRepeat,omitempty" json:"maxRepeat,omitempty"`
+	Action    FilterAction       `bson:"action" json:"action"`
+	Enabled   bool               `bson:"enabled" json:"enabled"`
+	CreatedAt time.Time          `bson:"createdAt" json:"createdAt"`
+	UpdatedAt time.Time          `bson:"updatedAt" json:"updatedAt"`
+}
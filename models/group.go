@@ -0,0 +1,106 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type GroupPrivacy string
+
+const (
+	GroupPublic  GroupPrivacy = "public"  // ai cũng thấy nhóm và bài viết
+	GroupPrivate GroupPrivacy = "private" // ai cũng tìm thấy nhóm, chỉ thành viên thấy bài viết
+	GroupSecret  GroupPrivacy = "secret"  // chỉ thành viên và người được mời thấy nhóm
+)
+
+type GroupRole string
+
+const (
+	GroupRoleOwner     GroupRole = "owner"
+	GroupRoleAdmin     GroupRole = "admin"
+	GroupRoleModerator GroupRole = "moderator"
+	GroupRoleMember    GroupRole = "member"
+)
+
+type GroupMemberStatus string
+
+const (
+	GroupMemberActive  GroupMemberStatus = "active"
+	GroupMemberPending GroupMemberStatus = "pending" // đã gửi yêu cầu tham gia, chờ duyệt
+	GroupMemberInvited GroupMemberStatus = "invited" // được mời, chưa chấp nhận
+	GroupMemberBanned  GroupMemberStatus = "banned"  // bị cấm, không xin vào lại được
+)
+
+// Mức thông báo bài viết mới của từng thành viên
+const (
+	GroupNotifyAll = "all"
+	GroupNotifyOff = "off"
+)
+
+type GroupRule struct {
+	Title       string `bson:"title" json:"title"`
+	Description string `bson:"description,omitempty" json:"description,omitempty"`
+}
+
+type Group struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name         string             `bson:"name" json:"name"`
+	Description  string             `bson:"description,omitempty" json:"description,omitempty"`
+	Privacy      GroupPrivacy       `bson:"privacy" json:"privacy"`
+	OwnerID      primitive.ObjectID `bson:"ownerId" json:"ownerId"`
+	Rules        []GroupRule        `bson:"rules,omitempty" json:"rules,omitempty"`
+	PostApproval bool               `bson:"postApproval" json:"postApproval"` // bài của thành viên phải được duyệt mới hiện
+	MemberCount  int64              `bson:"memberCount" json:"memberCount"`
+
+	CreatedAt time.Time  `bson:"createdAt" json:"createdAt"`
+	UpdatedAt time.Time  `bson:"updatedAt" json:"updatedAt"`
+	DeletedAt *time.Time `bson:"deletedAt,omitempty" json:"-"`
+}
+
+// GroupMember - quan hệ user với nhóm, lưu riêng collection để phân trang danh sách thành viên
+type GroupMember struct {
+	ID            primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	GroupID       primitive.ObjectID  `bson:"groupId" json:"groupId"`
+	UserID        primitive.ObjectID  `bson:"userId" json:"userId"`
+	Role          GroupRole           `bson:"role" json:"role"`
+	Status        GroupMemberStatus   `bson:"status" json:"status"`
+	InvitedBy     *primitive.ObjectID `bson:"invitedBy,omitempty" json:"invitedBy,omitempty"`
+	Notifications string              `bson:"notifications,omitempty" json:"notifications,omitempty"`
+	MutedUntil    *time.Time          `bson:"mutedUntil,omitempty" json:"mutedUntil,omitempty"` // bị tạm cấm đăng bài
+	JoinedAt      *time.Time          `bson:"joinedAt,omitempty" json:"joinedAt,omitempty"`
+	CreatedAt     time.Time           `bson:"createdAt" json:"createdAt"`
+	UpdatedAt     time.Time           `bson:"updatedAt" json:"updatedAt"`
+}
+
+type GroupModerationAction string
+
+const (
+	GroupActionRequestApproved GroupModerationAction = "request_approved"
+	GroupActionRequestDeclined GroupModerationAction = "request_declined"
+	GroupActionPostApproved    GroupModerationAction = "post_approved"
+	GroupActionPostDeclined    GroupModerationAction = "post_declined"
+	GroupActionPostRemoved     GroupModerationAction = "post_removed"
+	GroupActionMemberRemoved   GroupModerationAction = "member_removed"
+	GroupActionMemberBanned    GroupModerationAction = "member_banned"
+	GroupActionMemberUnbanned  GroupModerationAction = "member_unbanned"
+	GroupActionMemberMuted     GroupModerationAction = "member_muted"
+	GroupActionRoleChanged     GroupModerationAction = "role_changed"
+	GroupActionSettingsChanged GroupModerationAction = "settings_changed"
+)
+
+// GroupModerationEntry - nhật ký kiểm duyệt của nhóm, chỉ quản trị viên/kiểm duyệt viên của nhóm xem được
+type GroupModerationEntry struct {
+	ID           primitive.ObjectID    `bson:"_id,omitempty" json:"id"`
+	GroupID      primitive.ObjectID    `bson:"groupId" json:"groupId"`
+	ActorID      primitive.ObjectID    `bson:"actorId" json:"actorId"`
+	Action       GroupModerationAction `bson:"action" json:"action"`
+	TargetUserID *primitive.ObjectID   `bson:"targetUserId,omitempty" json:"targetUserId,omitempty"`
+	TargetPostID *primitive.ObjectID   `bson:"targetPostId,omitempty" json:"targetPostId,omitempty"`
+	Reason       string                `bson:"reason,omitempty" json:"reason,omitempty"`
+	Metadata     map[string]string     `bson:"metadata,omitempty" json:"metadata,omitempty"`
+	CreatedAt    time.Time             `bson:"createdAt" json:"createdAt"`
+}
+
+// Visibility của bài đăng trong nhóm: quyền xem theo quyền riêng tư của nhóm, không hiện ở trang cá nhân
+const PostVisibilityGroup = "group"
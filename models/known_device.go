@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// KnownDevice - thiết bị user đã từng đăng nhập, lưu trong known_devices.
+// Thiết bị nhận diện theo dấu vân tay User-Agent, vị trí theo dải mạng của IP (/24 với IPv4, /48 với IPv6).
+type KnownDevice struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID      primitive.ObjectID `bson:"userId" json:"-"`
+	Fingerprint string             `bson:"fingerprint" json:"-"`
+	Name        string             `bson:"name" json:"name"` // vd: "Chrome trên Windows"
+	UserAgent   string             `bson:"userAgent,omitempty" json:"userAgent,omitempty"`
+	Networks    []string           `bson:"networks,omitempty" json:"-"`
+	LastIP      string             `bson:"lastIp,omitempty" json:"lastIp,omitempty"`
+	FirstSeenAt time.Time          `bson:"firstSeenAt" json:"firstSeenAt"`
+	LastSeenAt  time.Time          `bson:"lastSeenAt" json:"lastSeenAt"`
+}
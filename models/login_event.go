@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Phương thức đăng nhập ghi trong LoginEvent.Method
+const (
+	LoginMethodPassword     = "password"
+	LoginMethodPasswordless = "passwordless"
+	LoginMethodOAuth        = "oauth"
+	LoginMethodTwoFactor    = "two_factor"
+)
+
+// LoginEvent - một lần đăng nhập (thành công hoặc sai mật khẩu) của user, lưu trong login_history
+type LoginEvent struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID    primitive.ObjectID `bson:"userId" json:"-"`
+	Success   bool               `bson:"success" json:"success"`
+	Method    string             `bson:"method" json:"method"`
+	Reason    string             `bson:"reason,omitempty" json:"reason,omitempty"` // lý do thất bại
+	IP        string             `bson:"ip,omitempty" json:"ip,omitempty"`
+	UserAgent string             `bson:"userAgent,omitempty" json:"userAgent,omitempty"`
+	CreatedAt time.Time          `bson:"createdAt" json:"createdAt"`
+}
@@ -0,0 +1,25 @@
+package models
+
+// Trạng thái xử lý media của video/short
+type ProcessingStatus string
+
+const (
+	ProcessingPending    ProcessingStatus = "pending"    // Vừa upload, chờ xử lý
+	ProcessingProcessing ProcessingStatus = "processing" // Đang transcode
+	ProcessingReady      ProcessingStatus = "ready"      // Đã sẵn sàng, hiển thị công khai
+	ProcessingFailed     ProcessingStatus = "failed"     // Xử lý lỗi
+)
+
+// Rendition - một độ phân giải đã transcode (HLS variant)
+type Rendition struct {
+	Name        string `bson:"name" json:"name"` // vd: 720p
+	Width       int    `bson:"width" json:"width"`
+	Height      int    `bson:"height" json:"height"`
+	Bitrate     int    `bson:"bitrate" json:"bitrate"` // kbps
+	PlaylistURL string `bson:"playlistUrl" json:"playlistUrl"`
+}
+
+// IsProcessed - dữ liệu cũ (chưa có status) được coi là đã sẵn sàng
+func IsProcessed(status ProcessingStatus) bool {
+	return status == "" || status == ProcessingReady
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Loại tệp đính kèm trong tin nhắn
+const (
+	AttachmentImage = "image"
+	AttachmentVideo = "video"
+	AttachmentAudio = "audio"
+	AttachmentFile  = "file"
+)
+
+type MessageAttachment struct {
+	Type     string `bson:"type" json:"type"`
+	URL      string `bson:"url" json:"url"`
+	Name     string `bson:"name,omitempty" json:"name,omitempty"`
+	MimeType string `bson:"mimeType,omitempty" json:"mimeType,omitempty"`
+	Size     int64  `bson:"size,omitempty" json:"size,omitempty"`
+}
+
+type Message struct {
+	ID             primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	ConversationID primitive.ObjectID  `bson:"conversationId" json:"conversationId"`
+	SenderID       primitive.ObjectID  `bson:"senderId" json:"senderId"`
+	Text           string              `bson:"text,omitempty" json:"text,omitempty"`
+	Attachments    []MessageAttachment `bson:"attachments,omitempty" json:"attachments,omitempty"`
+
+	EditedAt  *time.Time `bson:"editedAt,omitempty" json:"editedAt,omitempty"`
+	DeletedAt *time.Time `bson:"deletedAt,omitempty" json:"deletedAt,omitempty"` // thu hồi: xoá nội dung, giữ lại vị trí trong hội thoại
+	CreatedAt time.Time  `bson:"createdAt" json:"createdAt"`
+}
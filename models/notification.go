@@ -16,6 +16,16 @@ const (
 	NotificationNewPost  NotificationType = "new_post"    // Người bạn theo dõi tạo post
 	NotificationNewVideo NotificationType = "new_video"   // Người bạn theo dõi tạo video
 	NotificationNewShort NotificationType = "new_short"   // Người bạn theo dõi tạo short
+	NotificationReport   NotificationType = "report"      // Báo cáo của bạn đã được xử lý
+	NotificationModeration NotificationType = "moderation" // Nội dung/tài khoản của bạn bị xử lý vi phạm
+
+	NotificationGroupInvite      NotificationType = "group_invite"       // Bạn được mời vào nhóm
+	NotificationGroupJoinRequest NotificationType = "group_join_request" // Có người xin vào nhóm bạn quản lý
+	NotificationGroupJoined      NotificationType = "group_joined"       // Yêu cầu tham gia nhóm được duyệt
+	NotificationGroupPost        NotificationType = "group_post"         // Bài viết mới trong nhóm
+	NotificationGroupPostPending NotificationType = "group_post_pending" // Có bài viết chờ duyệt
+	NotificationGroupPostApproved NotificationType = "group_post_approved"
+	NotificationGroupModeration  NotificationType = "group_moderation"   // Bạn/bài viết của bạn bị quản trị viên nhóm xử lý
 )
 
 type Notification struct {
@@ -26,7 +36,10 @@ type Notification struct {
 	PostID     *primitive.ObjectID `bson:"post,omitempty" json:"post,omitempty"`     // Bài viết liên quan
 	VideoID    *primitive.ObjectID `bson:"video,omitempty" json:"video,omitempty"`   // Video liên quan
 	ShortID    *primitive.ObjectID `bson:"short,omitempty" json:"short,omitempty"`   // Short liên quan
+	GroupID    *primitive.ObjectID `bson:"group,omitempty" json:"group,omitempty"`   // Nhóm liên quan
+	CommentID  *primitive.ObjectID `bson:"comment,omitempty" json:"comment,omitempty"` // Bình luận liên quan
 	Message    string              `bson:"message,omitempty" json:"message,omitempty"` // Thông báo tuỳ chỉnh
+	Link       string              `bson:"link,omitempty" json:"link,omitempty"`       // Liên kết hành động (vd: "Không phải tôi")
 	IsRead     bool                `bson:"isRead" json:"isRead"`
 	CreatedAt  time.Time           `bson:"createdAt" json:"createdAt"`
 }
@@ -4,13 +4,12 @@ type SendOTPRequest struct {
 	Identifier string `json:"identifier" binding:"required"` // email hoặc phone
 	Channel    string `json:"channel" binding:"required,oneof=email phone"`
 	Purpose    string `json:"purpose" binding:"required"`
-	CustomKey  string `json:"custom_key,omitempty"` // key tuỳ chỉnh cho từng trường hợp
 }
 
 type VerifyOTPRequest struct {
 	Identifier string `json:"identifier" binding:"required"`
 	Purpose    string `json:"purpose" binding:"required"`
-	OTP        string `json:"otp" binding:"required,len=6"`
+	OTP        string `json:"otp" binding:"required,numeric,min=4,max=10"`
 	Channel    string `json:"channel" binding:"required"`
 }
 
@@ -15,6 +15,12 @@ type Post struct {
     Likes     []primitive.ObjectID `bson:"likes,omitempty" json:"likes,omitempty"` // danh sách user đã like
     Dislikes  []primitive.ObjectID `bson:"dislikes,omitempty" json:"dislikes,omitempty"` // danh sách user đã dislike
     Visibility string               `bson:"visibility" json:"visibility"`           // "public", "private", "friends"...
+    Edited    bool                 `bson:"edited" json:"edited"`                    // đã từng chỉnh sửa
+    EditedAt  *time.Time           `bson:"edited_at,omitempty" json:"edited_at,omitempty"`
+    DeletedAt *time.Time           `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"` // xoá mềm (thùng rác)
+    ModerationHidden bool          `bson:"moderation_hidden,omitempty" json:"moderation_hidden,omitempty"` // bị ẩn do báo cáo vi phạm
+    GroupID   *primitive.ObjectID  `bson:"group_id,omitempty" json:"group_id,omitempty"`           // bài đăng trong nhóm
+    GroupPending bool              `bson:"group_pending,omitempty" json:"group_pending,omitempty"` // chờ quản trị viên nhóm duyệt
     CreatedAt time.Time            `bson:"created_at" json:"created_at"`
     UpdatedAt time.Time            `bson:"updated_at" json:"updated_at"`
 }
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+type ReportTargetType string
+
+const (
+	ReportTargetPost    ReportTargetType = "post"
+	ReportTargetComment ReportTargetType = "comment"
+	ReportTargetVideo   ReportTargetType = "video"
+	ReportTargetShort   ReportTargetType = "short"
+	ReportTargetUser    ReportTargetType = "user"
+)
+
+type ReportReason string
+
+const (
+	ReportReasonSpam           ReportReason = "spam"
+	ReportReasonHarassment     ReportReason = "harassment"     // quấy rối, bắt nạt
+	ReportReasonHateSpeech     ReportReason = "hate_speech"    // thù ghét, phân biệt đối xử
+	ReportReasonViolence       ReportReason = "violence"       // bạo lực, đe doạ
+	ReportReasonNudity         ReportReason = "nudity"         // khoả thân, nội dung người lớn
+	ReportReasonMisinformation ReportReason = "misinformation" // tin giả
+	ReportReasonImpersonation  ReportReason = "impersonation"  // mạo danh
+	ReportReasonOther          ReportReason = "other"
+	ReportReasonAutoFilter     ReportReason = "auto_filter" // bộ lọc tự động giữ lại chờ duyệt (không có người báo cáo)
+)
+
+type ReportStatus string
+
+const (
+	ReportOpen      ReportStatus = "open"
+	ReportResolved  ReportStatus = "resolved"  // đã xử lý (gỡ nội dung, cảnh cáo, khoá tài khoản)
+	ReportDismissed ReportStatus = "dismissed" // không vi phạm
+)
+
+type ModerationAction string
+
+const (
+	ModerationDismiss ModerationAction = "dismiss"
+	ModerationRemove  ModerationAction = "remove"
+	ModerationWarn    ModerationAction = "warn"
+	ModerationSuspend ModerationAction = "suspend"
+)
+
+// Report - một lượt báo cáo nội dung/người dùng vi phạm
+type Report struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	ReporterID    primitive.ObjectID `bson:"reporterId" json:"reporterId"`
+	TargetType    ReportTargetType   `bson:"targetType" json:"targetType"`
+	TargetID      primitive.ObjectID `bson:"targetId" json:"targetId"`
+	TargetOwnerID primitive.ObjectID `bson:"targetOwnerId" json:"targetOwnerId"` // chủ nội dung (hoặc chính user bị báo cáo)
+	Reason        ReportReason       `bson:"reason" json:"reason"`
+	Details       string             `bson:"details,omitempty" json:"details,omitempty"`
+	Status        ReportStatus       `bson:"status" json:"status"`
+
+	// Kết quả xử lý
+	Action     ModerationAction    `bson:"action,omitempty" json:"action,omitempty"`
+	Note       string              `bson:"note,omitempty" json:"note,omitempty"`
+	ResolvedBy *primitive.ObjectID `bson:"resolvedBy,omitempty" json:"resolvedBy,omitempty"`
+	ResolvedAt *time.Time          `bson:"resolvedAt,omitempty" json:"resolvedAt,omitempty"`
+
+	CreatedAt time.Time `bson:"createdAt" json:"createdAt"`
+}
@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Revision - bản lưu nội dung trước mỗi lần chỉnh sửa (video, short, post, comment)
+type Revision struct {
+	ID          primitive.ObjectID     `bson:"_id,omitempty" json:"id"`
+	ContentType string                 `bson:"contentType" json:"contentType"`
+	ContentID   primitive.ObjectID     `bson:"contentId" json:"contentId"`
+	EditorID    primitive.ObjectID     `bson:"editorId" json:"editorId"`
+	Changed     []string               `bson:"changed" json:"changed"`   // các trường bị thay đổi
+	Previous    map[string]interface{} `bson:"previous" json:"previous"` // giá trị cũ của các trường đó
+	CreatedAt   time.Time              `bson:"createdAt" json:"createdAt"`
+}
@@ -15,5 +15,27 @@ type Short struct {
 	Dislikes  int                `bson:"dislikes" json:"dislikes"`
 	Visibility string               `bson:"visibility" json:"visibility"`
 	Views     int                `bson:"views" json:"views"`
+	WatchTime       float64 `bson:"watchTime" json:"-"`       // tổng thời gian xem (giây)
+	CompletionTotal float64 `bson:"completionTotal" json:"-"` // tổng tỉ lệ xem hết của các lượt xem
+	Thumbnail string             `bson:"thumbnail,omitempty" json:"thumbnail,omitempty"`
+
+	// Xử lý media (transcode + HLS)
+	Status          ProcessingStatus `bson:"status,omitempty" json:"status,omitempty"`
+	HLSURL          string           `bson:"hlsUrl,omitempty" json:"hlsUrl,omitempty"`
+	Renditions      []Rendition      `bson:"renditions,omitempty" json:"renditions,omitempty"`
+	ProcessingError string           `bson:"processingError,omitempty" json:"processingError,omitempty"`
+	ProcessedAt     *time.Time       `bson:"processedAt,omitempty" json:"processedAt,omitempty"`
+	PublishedAt     *time.Time       `bson:"publishedAt,omitempty" json:"publishedAt,omitempty"` // lần đầu thông báo cho followers
+
+	// Chỉnh sửa
+	Edited   bool       `bson:"edited" json:"edited"`
+	EditedAt *time.Time `bson:"editedAt,omitempty" json:"editedAt,omitempty"`
+
+	// Xoá mềm: nằm trong thùng rác, bị xoá vĩnh viễn sau thời hạn lưu
+	DeletedAt *time.Time `bson:"deletedAt,omitempty" json:"deletedAt,omitempty"`
+
+	// Bị ẩn do báo cáo vi phạm (tự động khi đủ ngưỡng hoặc do kiểm duyệt viên gỡ)
+	ModerationHidden bool `bson:"moderationHidden,omitempty" json:"moderationHidden,omitempty"`
+
 	CreatedAt time.Time          `bson:"createdAt" json:"createdAt"`
 }
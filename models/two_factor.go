@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// TwoFactor - cấu hình xác thực hai lớp bằng ứng dụng authenticator (TOTP)
+type TwoFactor struct {
+	Enabled       bool       `bson:"enabled" json:"enabled"`
+	Secret        string     `bson:"secret,omitempty" json:"-"`
+	PendingSecret string     `bson:"pendingSecret,omitempty" json:"-"` // secret đang chờ xác nhận khi đăng ký
+	RecoveryCodes []string   `bson:"recoveryCodes,omitempty" json:"-"` // chỉ lưu SHA-256, mỗi mã dùng một lần
+	LastUsedStep  int64      `bson:"lastUsedStep,omitempty" json:"-"`  // chặn dùng lại mã TOTP đã dùng
+	EnabledAt     *time.Time `bson:"enabledAt,omitempty" json:"enabledAt,omitempty"`
+}
@@ -14,11 +14,24 @@ const (
 	GenderPrivate Gender = "private"
 )
 
+// Ai được nhắn tin trực tiếp cho user (MessagePrivacy)
+const (
+	MessagePrivacyEveryone = "everyone"
+	MessagePrivacyFriends  = "friends" // chỉ bạn bè
+)
+
+// Vai trò trong Roles
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
 type User struct {
 	// ID chính và thông tin đăng nhập
 	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
 	Username      string             `bson:"username" json:"username" validate:"required,alphanum,min=3,max=30"`
-	Email         string             `bson:"email" json:"email" validate:"required,email"`
+	Email         string             `bson:"email" json:"email" validate:"required_without=Phone,omitempty,email"` // đăng ký bằng số điện thoại thì không có email
 	Phone         string             `bson:"phone,omitempty" json:"phone,omitempty" validate:"omitempty,e164"`
 	Password      string             `bson:"password,omitempty" json:"password,omitempty"`
 	PhoneVerified bool               `bson:"phoneVerified" json:"phoneVerified"`
@@ -50,12 +63,29 @@ type User struct {
 	// Ẩn/hiện trang cá nhân
 	HideProfile bool `bson:"hideProfile" json:"hideProfile"`
 
+	// Quyền nhắn tin trực tiếp; trống coi như "everyone"
+	MessagePrivacy string `bson:"messagePrivacy,omitempty" json:"messagePrivacy,omitempty"`
+
 	// Bảo mật & quyền hạn
 	Roles      []string   `bson:"roles,omitempty" json:"roles,omitempty"` // vd: ["user","admin"]
 	IsVerified bool       `bson:"isVerified" json:"isVerified"`
 	IsActive   bool       `bson:"isActive" json:"isActive"`
 	LastLogin  *time.Time `bson:"lastLogin,omitempty" json:"lastLogin,omitempty"`
 
+	// Bật khi user báo "không phải tôi": không đăng nhập bằng mật khẩu được cho tới khi đặt lại mật khẩu
+	PasswordResetRequired bool `bson:"passwordResetRequired,omitempty" json:"passwordResetRequired,omitempty"`
+
+	// Xác thực hai lớp
+	TwoFactor *TwoFactor `bson:"twoFactor,omitempty" json:"twoFactor,omitempty"`
+
+	// Vô hiệu hoá / xoá tài khoản (đăng nhập lại trước DeletionScheduledAt sẽ khôi phục)
+	DeactivatedAt       *time.Time `bson:"deactivatedAt,omitempty" json:"deactivatedAt,omitempty"`
+	DeletionScheduledAt *time.Time `bson:"deletionScheduledAt,omitempty" json:"deletionScheduledAt,omitempty"`
+
+	// Kiểm duyệt: cảnh cáo và khoá tài khoản tạm thời
+	WarningCount   int        `bson:"warningCount,omitempty" json:"warningCount,omitempty"`
+	SuspendedUntil *time.Time `bson:"suspendedUntil,omitempty" json:"suspendedUntil,omitempty"`
+
 	// Timestamps
 	CreatedAt time.Time  `bson:"createdAt" json:"createdAt"`
 	UpdatedAt time.Time  `bson:"updatedAt" json:"updatedAt"`
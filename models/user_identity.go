@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// UserIdentity - tài khoản mạng xã hội (Google/Facebook/GitHub...) đã liên kết với User
+type UserIdentity struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID      primitive.ObjectID `bson:"userId" json:"userId"`
+	Provider    string             `bson:"provider" json:"provider"`
+	Subject     string             `bson:"subject" json:"-"` // định danh ổn định do provider cấp (claim sub)
+	Email       string             `bson:"email,omitempty" json:"email,omitempty"`
+	Name        string             `bson:"name,omitempty" json:"name,omitempty"`
+	CreatedAt   time.Time          `bson:"createdAt" json:"createdAt"`
+	LastLoginAt *time.Time         `bson:"lastLoginAt,omitempty" json:"lastLoginAt,omitempty"`
+}
@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ViewEvent - một lần xem video/short, dùng cho thống kê
+type ViewEvent struct {
+	ID          primitive.ObjectID  `bson:"_id,omitempty" json:"id,omitempty"`
+	ContentType string              `bson:"contentType" json:"contentType"` // "video" | "short"
+	ContentID   primitive.ObjectID  `bson:"contentId" json:"contentId"`
+	OwnerID     primitive.ObjectID  `bson:"ownerId" json:"ownerId"`
+	ViewerID    *primitive.ObjectID `bson:"viewerId,omitempty" json:"viewerId,omitempty"`
+	DeviceID    string              `bson:"deviceId,omitempty" json:"deviceId,omitempty"`
+	WatchTime   float64             `bson:"watchTime" json:"watchTime"`   // giây
+	Completion  float64             `bson:"completion" json:"completion"` // 0..1
+	Counted     bool                `bson:"counted" json:"counted"`       // có được tính là 1 lượt xem không (sau khi chống trùng)
+	CreatedAt   time.Time           `bson:"createdAt" json:"createdAt"`
+}
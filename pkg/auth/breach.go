@@ -0,0 +1,90 @@
+package auth
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var ErrBreachedPassword = errors.New("mật khẩu này đã xuất hiện trong các vụ lộ dữ liệu, vui lòng chọn mật khẩu khác")
+
+// BreachChecker kiểm tra mật khẩu có nằm trong danh sách mật khẩu đã bị lộ
+type BreachChecker interface {
+	IsBreached(ctx context.Context, password string) (bool, error)
+}
+
+// LocalBreachList tra cứu theo mô hình k-anonymity của Have I Been Pwned: SHA-1 của mật khẩu được
+// chia thành 5 ký tự đầu (prefix) và phần còn lại (suffix); mỗi prefix là một file <Dir>/<PREFIX>.txt
+// gồm các dòng "SUFFIX:COUNT" (đúng định dạng của API range / công cụ tải bộ dữ liệu HIBP).
+// Chỉ đọc file của đúng prefix nên không cần nạp toàn bộ danh sách vào bộ nhớ.
+// Không có Dir thì chỉ kiểm tra danh sách mật khẩu phổ biến có sẵn.
+type LocalBreachList struct {
+	Dir string
+}
+
+func NewLocalBreachList(dir string) *LocalBreachList {
+	return &LocalBreachList{Dir: dir}
+}
+
+// Mật khẩu phổ biến nhất, luôn bị từ chối kể cả khi chưa cấu hình bộ dữ liệu đầy đủ
+var commonPasswords = []string{
+	"123456", "123456789", "12345678", "password", "qwerty123", "qwerty", "111111", "1234567890",
+	"1234567", "password1", "password123", "12345", "123123", "000000", "iloveyou", "abc123",
+	"123321", "654321", "qwertyuiop", "1q2w3e4r", "1qaz2wsx", "admin", "admin123", "welcome",
+	"welcome1", "letmein", "monkey", "dragon", "football", "baseball", "sunshine", "princess",
+	"Password1", "Password123", "P@ssw0rd", "P@ssword1", "Abc@1234", "Abcd@1234", "Aa123456",
+	"Aa@123456", "Admin@123", "Qwerty@123", "Matkhau123", "matkhau", "Anhyeuem123", "anhyeuem",
+	"Vietnam@123", "Zxcvbnm123", "Iloveyou1", "Welcome@123",
+}
+
+var commonHashes = func() map[string]struct{} {
+	m := make(map[string]struct{}, len(commonPasswords))
+	for _, p := range commonPasswords {
+		m[sha1Hex(p)] = struct{}{}
+	}
+	return m
+}()
+
+func sha1Hex(s string) string {
+	sum := sha1.Sum([]byte(s))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+func (l *LocalBreachList) IsBreached(ctx context.Context, password string) (bool, error) {
+	hash := sha1Hex(password)
+	if _, ok := commonHashes[hash]; ok {
+		return true, nil
+	}
+	if l == nil || l.Dir == "" {
+		return false, nil
+	}
+
+	prefix, suffix := hash[:5], hash[5:]
+	f, err := os.Open(filepath.Join(l.Dir, prefix+".txt"))
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if err := ctx.Err(); err != nil {
+			return false, err
+		}
+		line := strings.TrimSpace(scanner.Text())
+		candidate, count, _ := strings.Cut(line, ":")
+		// Bộ dữ liệu có "padding" với COUNT = 0 để che số lượng thật, bỏ qua các dòng này
+		if strings.EqualFold(candidate, suffix) && count != "0" {
+			return true, nil
+		}
+	}
+	return false, scanner.Err()
+}
@@ -1,19 +1,236 @@
 package auth
 
 import (
-    "fmt"
-    "golang.org/x/crypto/bcrypt"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Thuật toán băm mật khẩu. Hash lưu theo định dạng PHC có tên thuật toán + tham số,
+// nên có thể đổi thuật toán/tham số mà hash cũ vẫn kiểm tra được:
+//
+//	$argon2id$v=19$m=19456,t=2,p=1$<salt>$<hash>
+//	$2a$14$...  (bcrypt)
+const (
+	AlgorithmArgon2id = "argon2id"
+	AlgorithmBcrypt   = "bcrypt"
 )
 
+type Argon2Params struct {
+	Memory      uint32 // KiB
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// HashConfig - thuật toán và tham số cho hash mới; hash cũ khác cấu hình sẽ được băm lại khi đăng nhập
+type HashConfig struct {
+	Algorithm  string
+	Argon2     Argon2Params
+	BcryptCost int
+}
+
+var ErrInvalidHash = errors.New("định dạng hash mật khẩu không hợp lệ")
+
+// Mặc định theo khuyến nghị OWASP cho argon2id (19 MiB, 2 vòng, 1 luồng)
+var DefaultHashConfig = HashConfig{
+	Algorithm: AlgorithmArgon2id,
+	Argon2: Argon2Params{
+		Memory:      19 * 1024,
+		Iterations:  2,
+		Parallelism: 1,
+		SaltLength:  16,
+		KeyLength:   32,
+	},
+	BcryptCost: 12,
+}
+
+var (
+	hashMu     sync.RWMutex
+	hashConfig = DefaultHashConfig
+)
+
+// SetHashConfig đặt cấu hình băm mật khẩu (gọi một lần khi khởi động); giá trị 0 lấy theo mặc định
+func SetHashConfig(cfg HashConfig) {
+	if cfg.Algorithm == "" {
+		cfg.Algorithm = DefaultHashConfig.Algorithm
+	}
+	if cfg.Argon2.Memory == 0 {
+		cfg.Argon2.Memory = DefaultHashConfig.Argon2.Memory
+	}
+	if cfg.Argon2.Iterations == 0 {
+		cfg.Argon2.Iterations = DefaultHashConfig.Argon2.Iterations
+	}
+	if cfg.Argon2.Parallelism == 0 {
+		cfg.Argon2.Parallelism = DefaultHashConfig.Argon2.Parallelism
+	}
+	if cfg.Argon2.SaltLength == 0 {
+		cfg.Argon2.SaltLength = DefaultHashConfig.Argon2.SaltLength
+	}
+	if cfg.Argon2.KeyLength == 0 {
+		cfg.Argon2.KeyLength = DefaultHashConfig.Argon2.KeyLength
+	}
+	if cfg.BcryptCost == 0 {
+		cfg.BcryptCost = DefaultHashConfig.BcryptCost
+	}
+
+	hashMu.Lock()
+	hashConfig = cfg
+	hashMu.Unlock()
+}
+
+func currentHashConfig() HashConfig {
+	hashMu.RLock()
+	defer hashMu.RUnlock()
+	return hashConfig
+}
+
+// HashConfigFromEnv đọc PASSWORD_HASH_ALGORITHM, ARGON2_MEMORY_KB, ARGON2_ITERATIONS,
+// ARGON2_PARALLELISM, BCRYPT_COST; biến không đặt thì dùng mặc định
+func HashConfigFromEnv() (HashConfig, error) {
+	cfg := HashConfig{Algorithm: strings.ToLower(os.Getenv("PASSWORD_HASH_ALGORITHM"))}
+	if cfg.Algorithm != "" && cfg.Algorithm != AlgorithmArgon2id && cfg.Algorithm != AlgorithmBcrypt {
+		return cfg, fmt.Errorf("PASSWORD_HASH_ALGORITHM không hỗ trợ: %s", cfg.Algorithm)
+	}
+
+	readUint := func(name string, bits int) (uint64, error) {
+		v := os.Getenv(name)
+		if v == "" {
+			return 0, nil
+		}
+		n, err := strconv.ParseUint(v, 10, bits)
+		if err != nil {
+			return 0, fmt.Errorf("%s không hợp lệ: %w", name, err)
+		}
+		return n, nil
+	}
+
+	memory, err := readUint("ARGON2_MEMORY_KB", 32)
+	if err != nil {
+		return cfg, err
+	}
+	iterations, err := readUint("ARGON2_ITERATIONS", 32)
+	if err != nil {
+		return cfg, err
+	}
+	parallelism, err := readUint("ARGON2_PARALLELISM", 8)
+	if err != nil {
+		return cfg, err
+	}
+	cost, err := readUint("BCRYPT_COST", 8)
+	if err != nil {
+		return cfg, err
+	}
+	if cost != 0 && (int(cost) < bcrypt.MinCost || int(cost) > bcrypt.MaxCost) {
+		return cfg, fmt.Errorf("BCRYPT_COST phải trong khoảng %d-%d", bcrypt.MinCost, bcrypt.MaxCost)
+	}
+
+	cfg.Argon2.Memory = uint32(memory)
+	cfg.Argon2.Iterations = uint32(iterations)
+	cfg.Argon2.Parallelism = uint8(parallelism)
+	cfg.BcryptCost = int(cost)
+	return cfg, nil
+}
+
+// HashPassword băm mật khẩu theo cấu hình hiện tại
 func HashPassword(password string) (string, error) {
-    bytes, err := bcrypt.GenerateFromPassword([]byte(password), 14)
-    return string(bytes), err
+	cfg := currentHashConfig()
+	if cfg.Algorithm == AlgorithmBcrypt {
+		bytes, err := bcrypt.GenerateFromPassword([]byte(password), cfg.BcryptCost)
+		return string(bytes), err
+	}
+
+	p := cfg.Argon2
+	salt := make([]byte, p.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, p.Iterations, p.Memory, p.Parallelism, p.KeyLength)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, p.Memory, p.Iterations, p.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
 }
 
+// CheckPasswordHash nhận cả hash argon2id và bcrypt
 func CheckPasswordHash(password, hash string) bool {
-    err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
-    if err != nil {
-        fmt.Println("So sánh mật khẩu thất bại:", err)
-    }
-    return err == nil
-}
\ No newline at end of file
+	if strings.HasPrefix(hash, "$argon2id$") {
+		p, salt, key, err := decodeArgon2(hash)
+		if err != nil {
+			fmt.Println("So sánh mật khẩu thất bại:", err)
+			return false
+		}
+		other := argon2.IDKey([]byte(password), salt, p.Iterations, p.Memory, p.Parallelism, uint32(len(key)))
+		return subtle.ConstantTimeCompare(key, other) == 1
+	}
+
+	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	if err != nil && !errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		fmt.Println("So sánh mật khẩu thất bại:", err)
+	}
+	return err == nil
+}
+
+// NeedsRehash cho biết hash được tạo bằng thuật toán hoặc tham số khác cấu hình hiện tại
+func NeedsRehash(hash string) bool {
+	cfg := currentHashConfig()
+
+	if strings.HasPrefix(hash, "$argon2id$") {
+		if cfg.Algorithm != AlgorithmArgon2id {
+			return true
+		}
+		p, salt, key, err := decodeArgon2(hash)
+		if err != nil {
+			return true
+		}
+		want := cfg.Argon2
+		return p.Memory != want.Memory || p.Iterations != want.Iterations || p.Parallelism != want.Parallelism ||
+			uint32(len(salt)) != want.SaltLength || uint32(len(key)) != want.KeyLength
+	}
+
+	if cfg.Algorithm != AlgorithmBcrypt {
+		return true
+	}
+	cost, err := bcrypt.Cost([]byte(hash))
+	return err != nil || cost != cfg.BcryptCost
+}
+
+func decodeArgon2(hash string) (Argon2Params, []byte, []byte, error) {
+	var p Argon2Params
+	// "", "argon2id", "v=19", "m=..,t=..,p=..", salt, key
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return p, nil, nil, ErrInvalidHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return p, nil, nil, ErrInvalidHash
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.Memory, &p.Iterations, &p.Parallelism); err != nil ||
+		p.Iterations == 0 || p.Parallelism == 0 {
+		return p, nil, nil, ErrInvalidHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return p, nil, nil, ErrInvalidHash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return p, nil, nil, ErrInvalidHash
+	}
+	p.SaltLength, p.KeyLength = uint32(len(salt)), uint32(len(key))
+	return p, salt, key, nil
+}
@@ -8,6 +8,9 @@ import (
     "github.com/golang-jwt/jwt/v5"
 )
 
+// TokenTTL - thời hạn của JWT đăng nhập
+const TokenTTL = 24 * time.Hour
+
 // Tạo JWT từ user ID
 func GenerateJWT(userID string) (string, error) {
     secret := os.Getenv("JWT_SECRET")
@@ -17,7 +20,7 @@ func GenerateJWT(userID string) (string, error) {
 
     claims := jwt.MapClaims{
         "user_id": userID,
-        "exp":     time.Now().Add(time.Hour * 24).Unix(),
+        "exp":     time.Now().Add(TokenTTL).Unix(),
         "iat":     time.Now().Unix(),
     }
 
@@ -0,0 +1,104 @@
+package auth
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// PasswordPolicy - quy tắc độ mạnh mật khẩu khi đăng ký / đổi / đặt lại mật khẩu
+type PasswordPolicy struct {
+	MinLength  int // mặc định 8
+	MaxLength  int // mặc định 72 (giới hạn của bcrypt)
+	MinClasses int // số nhóm ký tự tối thiểu trong: thường, hoa, số, ký tự đặc biệt; mặc định 3
+}
+
+// PasswordError liệt kê các quy tắc mật khẩu chưa thoả
+type PasswordError struct {
+	Reasons []string
+}
+
+func (e *PasswordError) Error() string {
+	return "mật khẩu chưa đủ mạnh: " + strings.Join(e.Reasons, "; ")
+}
+
+func (p PasswordPolicy) withDefaults() PasswordPolicy {
+	if p.MinLength <= 0 {
+		p.MinLength = 8
+	}
+	if p.MaxLength <= 0 {
+		p.MaxLength = 72
+	}
+	if p.MinClasses <= 0 {
+		p.MinClasses = 3
+	}
+	return p
+}
+
+// Validate kiểm tra mật khẩu; personal là các thông tin của chính user (username, email, số điện thoại...)
+// mà mật khẩu không được trùng hoặc chứa
+func (p PasswordPolicy) Validate(password string, personal ...string) error {
+	p = p.withDefaults()
+	var reasons []string
+
+	length := len([]rune(password))
+	if length < p.MinLength {
+		reasons = append(reasons, "tối thiểu "+strconv.Itoa(p.MinLength)+" ký tự")
+	}
+	if len(password) > p.MaxLength {
+		reasons = append(reasons, "tối đa "+strconv.Itoa(p.MaxLength)+" byte")
+	}
+
+	var lower, upper, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+	classes := 0
+	for _, ok := range []bool{lower, upper, digit, symbol} {
+		if ok {
+			classes++
+		}
+	}
+	if classes < p.MinClasses {
+		reasons = append(reasons, "cần ít nhất "+strconv.Itoa(p.MinClasses)+" nhóm: chữ thường, chữ hoa, chữ số, ký tự đặc biệt")
+	}
+
+	if containsPersonal(password, personal) {
+		reasons = append(reasons, "không được chứa tên người dùng, email hoặc số điện thoại")
+	}
+
+	if len(reasons) > 0 {
+		return &PasswordError{Reasons: reasons}
+	}
+	return nil
+}
+
+func containsPersonal(password string, personal []string) bool {
+	lowered := strings.ToLower(password)
+	for _, info := range personal {
+		info = strings.ToLower(strings.TrimSpace(info))
+		if info == "" {
+			continue
+		}
+		// Với email thì so cả phần trước @
+		candidates := []string{info}
+		if at := strings.Index(info, "@"); at > 0 {
+			candidates = append(candidates, info[:at])
+		}
+		for _, c := range candidates {
+			if lowered == c || (len(c) >= 4 && strings.Contains(lowered, c)) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,44 @@
+package auth
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// SessionStore lưu thời điểm thu hồi phiên theo user; JWT cấp trước (hoặc cùng giây với) thời điểm
+// đó bị từ chối. Key chỉ cần sống bằng TokenTTL vì sau đó mọi token cũ đã tự hết hạn.
+type SessionStore struct {
+	client *redis.Client
+}
+
+func NewSessionStore(client *redis.Client) *SessionStore {
+	return &SessionStore{client: client}
+}
+
+func revokedKey(userID string) string {
+	return "session:revoked:" + userID
+}
+
+// RevokeAll đăng xuất mọi phiên hiện có của user
+func (s *SessionStore) RevokeAll(ctx context.Context, userID string) error {
+	return s.client.Set(ctx, revokedKey(userID), time.Now().Unix(), TokenTTL).Err()
+}
+
+// Revoked cho biết token cấp lúc issuedAt đã bị thu hồi hay chưa
+func (s *SessionStore) Revoked(ctx context.Context, userID string, issuedAt time.Time) (bool, error) {
+	v, err := s.client.Get(ctx, revokedKey(userID)).Result()
+	if err == redis.Nil {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	revokedAt, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return false, err
+	}
+	return issuedAt.Unix() <= revokedAt, nil
+}
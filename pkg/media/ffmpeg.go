@@ -0,0 +1,182 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+type FFmpegProcessor struct {
+	ffmpegPath  string
+	ffprobePath string
+}
+
+func NewFFmpegProcessor(ffmpegPath, ffprobePath string) Processor {
+	return &FFmpegProcessor{
+		ffmpegPath:  ffmpegPath,
+		ffprobePath: ffprobePath,
+	}
+}
+
+// File gốc luôn lấy qua HTTP(S) từ kho upload; chặn các giao thức khác (file, concat, pipe...)
+const protocolWhitelist = "http,https,tcp,tls"
+
+type ffprobeOutput struct {
+	Streams []struct {
+		CodecType string `json:"codec_type"`
+		Width     int    `json:"width"`
+		Height    int    `json:"height"`
+	} `json:"streams"`
+	Format struct {
+		Duration string `json:"duration"`
+	} `json:"format"`
+}
+
+func (p *FFmpegProcessor) Probe(ctx context.Context, input string) (*ProbeResult, error) {
+	out, err := p.run(ctx, p.ffprobePath,
+		"-protocol_whitelist", protocolWhitelist,
+		"-v", "error",
+		"-print_format", "json",
+		"-show_format", "-show_streams",
+		input,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var parsed ffprobeOutput
+	if err := json.Unmarshal(out, &parsed); err != nil {
+		return nil, fmt.Errorf("không đọc được kết quả ffprobe: %v", err)
+	}
+
+	result := &ProbeResult{}
+	hasVideo := false
+	for _, st := range parsed.Streams {
+		switch st.CodecType {
+		case "video":
+			if !hasVideo {
+				result.Width, result.Height = st.Width, st.Height
+				hasVideo = true
+			}
+		case "audio":
+			result.HasAudio = true
+		}
+	}
+	if !hasVideo {
+		return nil, ErrNoVideoStream
+	}
+
+	result.Duration, err = strconv.ParseFloat(parsed.Format.Duration, 64)
+	if err != nil || result.Duration <= 0 {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownDuration, parsed.Format.Duration)
+	}
+	return result, nil
+}
+
+func (p *FFmpegProcessor) TranscodeHLS(ctx context.Context, input, outDir string, specs []RenditionSpec) ([]RenditionOutput, error) {
+	probe, err := p.Probe(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	var outputs []RenditionOutput
+	for _, spec := range specs {
+		dir := filepath.Join(outDir, spec.Name)
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, err
+		}
+
+		args := []string{
+			"-y", "-protocol_whitelist", protocolWhitelist, "-i", input,
+			"-vf", fmt.Sprintf("scale=-2:%d", spec.Height),
+			"-c:v", "libx264", "-preset", "veryfast", "-profile:v", "main",
+			"-b:v", fmt.Sprintf("%dk", spec.Bitrate),
+			"-maxrate", fmt.Sprintf("%dk", spec.Bitrate*107/100),
+			"-bufsize", fmt.Sprintf("%dk", spec.Bitrate*3/2),
+			"-g", "48", "-keyint_min", "48", "-sc_threshold", "0",
+		}
+		if probe.HasAudio {
+			args = append(args, "-c:a", "aac", "-b:a", "128k", "-ac", "2")
+		} else {
+			args = append(args, "-an")
+		}
+		args = append(args,
+			"-hls_time", "6",
+			"-hls_playlist_type", "vod",
+			"-hls_segment_filename", filepath.Join(dir, "seg_%03d.ts"),
+			filepath.Join(dir, "index.m3u8"),
+		)
+
+		if _, err := p.run(ctx, p.ffmpegPath, args...); err != nil {
+			return nil, fmt.Errorf("transcode %s thất bại: %v", spec.Name, err)
+		}
+
+		outputs = append(outputs, RenditionOutput{
+			RenditionSpec: spec,
+			Width:         scaledWidth(probe.Width, probe.Height, spec.Height),
+			Playlist:      spec.Name + "/index.m3u8",
+		})
+	}
+
+	if err := WriteMasterPlaylist(filepath.Join(outDir, "master.m3u8"), outputs); err != nil {
+		return nil, err
+	}
+	return outputs, nil
+}
+
+func (p *FFmpegProcessor) ExtractThumbnail(ctx context.Context, input, outPath string, at float64) error {
+	if err := os.MkdirAll(filepath.Dir(outPath), 0o755); err != nil {
+		return err
+	}
+	_, err := p.run(ctx, p.ffmpegPath,
+		"-y",
+		"-ss", strconv.FormatFloat(at, 'f', 2, 64),
+		"-protocol_whitelist", protocolWhitelist,
+		"-i", input,
+		"-frames:v", "1",
+		"-vf", "scale=-2:720",
+		outPath,
+	)
+	return err
+}
+
+func (p *FFmpegProcessor) run(ctx context.Context, bin string, args ...string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, bin, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if len(msg) > 500 {
+			msg = msg[len(msg)-500:]
+		}
+		return nil, fmt.Errorf("%s: %v: %s", filepath.Base(bin), err, msg)
+	}
+	return stdout.Bytes(), nil
+}
+
+// WriteMasterPlaylist ghi master playlist HLS trỏ tới các rendition
+func WriteMasterPlaylist(path string, outputs []RenditionOutput) error {
+	var b strings.Builder
+	b.WriteString("#EXTM3U\n#EXT-X-VERSION:3\n")
+	for _, o := range outputs {
+		fmt.Fprintf(&b, "#EXT-X-STREAM-INF:BANDWIDTH=%d,RESOLUTION=%dx%d,NAME=\"%s\"\n%s\n",
+			(o.Bitrate+128)*1000, o.Width, o.Height, o.Name, o.Playlist)
+	}
+	return os.WriteFile(path, []byte(b.String()), 0o644)
+}
+
+// scaledWidth tính chiều rộng giữ tỉ lệ, làm tròn về số chẵn (yêu cầu của libx264)
+func scaledWidth(srcWidth, srcHeight, height int) int {
+	if srcHeight == 0 {
+		return 0
+	}
+	w := srcWidth * height / srcHeight
+	return w - w%2
+}
@@ -0,0 +1,28 @@
+package media
+
+import (
+	"context"
+	"fmt"
+)
+
+// MockProcessor - dùng khi máy không có ffmpeg, không transcode thật
+type MockProcessor struct{}
+
+func (m *MockProcessor) Probe(ctx context.Context, input string) (*ProbeResult, error) {
+	fmt.Printf("🎞️ Probe %s (mock)\n", input)
+	return &ProbeResult{}, nil
+}
+
+func (m *MockProcessor) TranscodeHLS(ctx context.Context, input, outDir string, specs []RenditionSpec) ([]RenditionOutput, error) {
+	fmt.Printf("🎞️ Transcode %s -> %s (mock, bỏ qua)\n", input, outDir)
+	return nil, nil
+}
+
+func (m *MockProcessor) ExtractThumbnail(ctx context.Context, input, outPath string, at float64) error {
+	fmt.Printf("🎞️ Thumbnail %s -> %s (mock, bỏ qua)\n", input, outPath)
+	return nil
+}
+
+func NewMockProcessor() Processor {
+	return &MockProcessor{}
+}
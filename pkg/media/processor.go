@@ -0,0 +1,66 @@
+package media
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	ErrNoVideoStream   = errors.New("file không chứa luồng video")
+	ErrUnknownDuration = errors.New("không đọc được thời lượng file")
+)
+
+// ProbeResult - thông tin đọc được từ file gốc
+type ProbeResult struct {
+	Duration float64 // giây; 0 khi processor không đo thời lượng (mock), processor thật trả ErrUnknownDuration
+	Width    int
+	Height   int
+	HasAudio bool
+}
+
+// RenditionSpec - cấu hình một độ phân giải cần transcode
+type RenditionSpec struct {
+	Name    string
+	Height  int
+	Bitrate int // kbps
+}
+
+// RenditionOutput - kết quả transcode một rendition
+type RenditionOutput struct {
+	RenditionSpec
+	Width    int
+	Playlist string // đường dẫn tương đối tới playlist trong thư mục output
+}
+
+// DefaultRenditions - các độ phân giải mặc định (chỉ dùng những cái <= chiều cao gốc)
+var DefaultRenditions = []RenditionSpec{
+	{Name: "1080p", Height: 1080, Bitrate: 5000},
+	{Name: "720p", Height: 720, Bitrate: 2800},
+	{Name: "480p", Height: 480, Bitrate: 1400},
+	{Name: "360p", Height: 360, Bitrate: 800},
+}
+
+type Processor interface {
+	// Probe đọc metadata (thời lượng, kích thước) của file
+	Probe(ctx context.Context, input string) (*ProbeResult, error)
+	// TranscodeHLS transcode sang các rendition và đóng gói HLS vào outDir,
+	// trả về các rendition đã tạo (master playlist nằm ở outDir/master.m3u8)
+	TranscodeHLS(ctx context.Context, input, outDir string, specs []RenditionSpec) ([]RenditionOutput, error)
+	// ExtractThumbnail lấy một khung hình tại giây `at` và lưu ra outPath
+	ExtractThumbnail(ctx context.Context, input, outPath string, at float64) error
+}
+
+// SelectRenditions chọn các rendition không vượt quá độ phân giải gốc,
+// luôn giữ ít nhất rendition nhỏ nhất
+func SelectRenditions(specs []RenditionSpec, sourceHeight int) []RenditionSpec {
+	var selected []RenditionSpec
+	for _, spec := range specs {
+		if spec.Height <= sourceHeight {
+			selected = append(selected, spec)
+		}
+	}
+	if len(selected) == 0 && len(specs) > 0 {
+		selected = append(selected, specs[len(specs)-1])
+	}
+	return selected
+}
@@ -0,0 +1,63 @@
+package media
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const jobQueueKey = "media:jobs"
+
+// Loại nội dung cần xử lý
+const (
+	KindVideo = "video"
+	KindShort = "short"
+)
+
+type Job struct {
+	Kind     string `json:"kind"`
+	ID       string `json:"id"`
+	Attempts int    `json:"attempts"`
+}
+
+// JobQueue - hàng đợi job xử lý media trên Redis list
+type JobQueue interface {
+	Enqueue(ctx context.Context, job Job) error
+	// Dequeue chờ tối đa timeout, trả về nil nếu không có job
+	Dequeue(ctx context.Context, timeout time.Duration) (*Job, error)
+}
+
+type redisJobQueue struct {
+	client *redis.Client
+}
+
+func NewRedisJobQueue(client *redis.Client) JobQueue {
+	return &redisJobQueue{client: client}
+}
+
+func (q *redisJobQueue) Enqueue(ctx context.Context, job Job) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	return q.client.LPush(ctx, jobQueueKey, data).Err()
+}
+
+func (q *redisJobQueue) Dequeue(ctx context.Context, timeout time.Duration) (*Job, error) {
+	res, err := q.client.BRPop(ctx, timeout, jobQueueKey).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// res[0] là key, res[1] là giá trị
+	var job Job
+	if err := json.Unmarshal([]byte(res[1]), &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
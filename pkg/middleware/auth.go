@@ -2,21 +2,36 @@ package middleware
 
 import (
 	"errors"
-	// "log"
+	"log"
 	"net/http"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
+	"socialnetwork/pkg/auth"
 )
 
+var sessions *auth.SessionStore
+
+// UseSessionStore bật kiểm tra phiên bị thu hồi cho JWTAuthMiddleware (gọi một lần khi khởi động)
+func UseSessionStore(store *auth.SessionStore) {
+	sessions = store
+}
+
 
 
 func VerifyToken(tokenStr string) (string, error) {
+	userID, _, err := parseToken(tokenStr)
+	return userID, err
+}
+
+// parseToken trả về user ID và thời điểm cấp token (iat)
+func parseToken(tokenStr string) (string, time.Time, error) {
 	secret := os.Getenv("JWT_SECRET")
 	if secret == "" {
-		return "", errors.New("JWT_SECRET is not set")
+		return "", time.Time{}, errors.New("JWT_SECRET is not set")
 	}
 
 	token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
@@ -26,20 +41,24 @@ func VerifyToken(tokenStr string) (string, error) {
 		return []byte(secret), nil
 	})
 	if err != nil || !token.Valid {
-		return "", errors.New("invalid token")
+		return "", time.Time{}, errors.New("invalid token")
 	}
 
 	claims, ok := token.Claims.(jwt.MapClaims)
 	if !ok {
-		return "", errors.New("invalid token claims")
+		return "", time.Time{}, errors.New("invalid token claims")
 	}
 
 	userID, ok := claims["user_id"].(string)
 	if !ok {
-		return "", errors.New("user_id not found in token")
+		return "", time.Time{}, errors.New("user_id not found in token")
 	}
 
-	return userID, nil
+	var issuedAt time.Time
+	if iat, err := claims.GetIssuedAt(); err == nil && iat != nil {
+		issuedAt = iat.Time
+	}
+	return userID, issuedAt, nil
 }
 
 func JWTAuthMiddleware() gin.HandlerFunc {
@@ -58,15 +77,65 @@ func JWTAuthMiddleware() gin.HandlerFunc {
 		tokenStr = strings.TrimPrefix(tokenStr, "Bearer ")
 		// log.Println("Token after trimming Bearer:", tokenStr)
 
-		userID, err := VerifyToken(tokenStr)
-		if err != nil {
-			// log.Println("Token verification error:", err)
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		if authenticate(c, tokenStr) {
+			c.Next()
+		}
+	}
+}
+
+// OptionalAuthMiddleware - route công khai nhưng trả thêm nội dung khi đã đăng nhập;
+// không có token thì cho qua, token sai vẫn trả 401
+func OptionalAuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tokenStr := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if tokenStr == "" || authenticate(c, tokenStr) {
+			c.Next()
+		}
+	}
+}
+
+// WebSocketAuthMiddleware - trình duyệt không gửi được header Authorization khi mở WebSocket
+// nên nhận thêm token qua query ?token=
+func WebSocketAuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tokenStr := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if tokenStr == "" {
+			tokenStr = c.Query("token")
+		}
+		if tokenStr == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Missing token"})
 			c.Abort()
 			return
 		}
+		if authenticate(c, tokenStr) {
+			c.Next()
+		}
+	}
+}
 
-		c.Set("userID", userID)
-		c.Next()
+// authenticate kiểm tra token và phiên chưa bị thu hồi rồi gán userID vào context; lỗi thì trả 401 và Abort
+func authenticate(c *gin.Context, tokenStr string) bool {
+	userID, issuedAt, err := parseToken(tokenStr)
+	if err != nil {
+		// log.Println("Token verification error:", err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		c.Abort()
+		return false
 	}
+
+	// Redis lỗi thì cho qua giống middleware.RateLimit
+	if sessions != nil {
+		revoked, err := sessions.Revoked(c.Request.Context(), userID, issuedAt)
+		if err != nil {
+			log.Println("⚠️ session check:", err)
+		} else if revoked {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Phiên đăng nhập đã bị thu hồi", "code": "session_revoked"})
+			c.Abort()
+			return false
+		}
+	}
+
+	c.Set("userID", userID)
+	c.Set("tokenIssuedAt", issuedAt)
+	return true
 }
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"log"
+
+	"github.com/gin-gonic/gin"
+	"socialnetwork/pkg/ratelimit"
+)
+
+// ByIP - key giới hạn theo địa chỉ IP của client
+func ByIP(c *gin.Context) string {
+	return "ip:" + c.ClientIP()
+}
+
+// ByUser - key giới hạn theo user đã đăng nhập, chưa đăng nhập thì theo IP
+func ByUser(c *gin.Context) string {
+	if userID := c.GetString("userID"); userID != "" {
+		return "user:" + userID
+	}
+	return ByIP(c)
+}
+
+// RateLimit giới hạn số request theo rule cho từng key; Redis lỗi thì cho qua để không chặn người dùng
+func RateLimit(limiter *ratelimit.Limiter, rule ratelimit.Rule, key func(*gin.Context) string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if limiter == nil {
+			c.Next()
+			return
+		}
+
+		err := limiter.Allow(c.Request.Context(), rule, key(c))
+		if ratelimit.WriteError(c, err) {
+			c.Abort()
+			return
+		}
+		if err != nil {
+			log.Printf("⚠️ rate limit %s lỗi: %v", rule.Name, err)
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,18 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"socialnetwork/pkg/requestinfo"
+)
+
+// RequestInfo gắn IP/User-Agent vào context của request để service đọc qua requestinfo.From
+func RequestInfo() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := requestinfo.With(c.Request.Context(), requestinfo.Info{
+			IP:        c.ClientIP(),
+			UserAgent: c.Request.UserAgent(),
+		})
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// RequireRole chỉ cho phép user có ít nhất một trong các vai trò đã cho.
+// Phải đặt sau JWTAuthMiddleware; vai trò được đọc từ DB mỗi request để
+// việc thu hồi quyền có hiệu lực ngay mà không cần cấp lại token.
+func RequireRole(db *mongo.Database, roles ...string) gin.HandlerFunc {
+	users := db.Collection("users")
+	return func(c *gin.Context) {
+		userID, err := primitive.ObjectIDFromHex(c.GetString("userID"))
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			c.Abort()
+			return
+		}
+
+		var u struct {
+			Roles []string `bson:"roles"`
+		}
+		opts := options.FindOne().SetProjection(bson.M{"roles": 1})
+		err = users.FindOne(c.Request.Context(), bson.M{"_id": userID, "deletedAt": nil}, opts).Decode(&u)
+		if err != nil && err != mongo.ErrNoDocuments {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			c.Abort()
+			return
+		}
+
+		for _, have := range u.Roles {
+			for _, want := range roles {
+				if have == want {
+					c.Set("roles", u.Roles)
+					c.Next()
+					return
+				}
+			}
+		}
+		c.JSON(http.StatusForbidden, gin.H{"error": "Không có quyền truy cập"})
+		c.Abort()
+	}
+}
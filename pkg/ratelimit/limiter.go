@@ -0,0 +1,98 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+)
+
+// Rule - tối đa Limit lượt trong mỗi cửa sổ Window
+type Rule struct {
+	Name   string
+	Limit  int
+	Window time.Duration
+}
+
+// Error - vượt giới hạn, RetryAfter là thời gian phải chờ
+type Error struct {
+	Message    string
+	RetryAfter time.Duration
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s, vui lòng thử lại sau %d giây", e.Message, e.Seconds())
+}
+
+func (e *Error) Seconds() int {
+	return int(math.Ceil(e.RetryAfter.Seconds()))
+}
+
+// Limiter - bộ đếm fixed-window trên Redis, dùng chung giữa các instance
+type Limiter struct {
+	client *redis.Client
+}
+
+func New(client *redis.Client) *Limiter {
+	return &Limiter{client: client}
+}
+
+// Allow tính một lượt cho key; trả về *Error khi đã vượt Limit trong cửa sổ hiện tại
+func (l *Limiter) Allow(ctx context.Context, rule Rule, key string) error {
+	redisKey := fmt.Sprintf("ratelimit:%s:%s", rule.Name, key)
+
+	pipe := l.client.TxPipeline()
+	incr := pipe.Incr(ctx, redisKey)
+	ttl := pipe.PTTL(ctx, redisKey)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return err
+	}
+
+	// Key mới (hoặc mất TTL vì lý do nào đó) thì bắt đầu cửa sổ mới
+	retry := ttl.Val()
+	if retry < 0 {
+		if err := l.client.PExpire(ctx, redisKey, rule.Window).Err(); err != nil {
+			return err
+		}
+		retry = rule.Window
+	}
+
+	if incr.Val() > int64(rule.Limit) {
+		return &Error{Message: "Bạn thao tác quá nhiều lần", RetryAfter: retry}
+	}
+	return nil
+}
+
+// Cooldown chỉ cho phép một lượt cho key trong khoảng d (vd: gửi lại OTP)
+func (l *Limiter) Cooldown(ctx context.Context, name, key string, d time.Duration) error {
+	redisKey := fmt.Sprintf("cooldown:%s:%s", name, key)
+	ok, err := l.client.SetNX(ctx, redisKey, 1, d).Result()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		retry, _ := l.client.PTTL(ctx, redisKey).Result()
+		if retry <= 0 {
+			retry = d
+		}
+		return &Error{Message: "Vui lòng chờ trước khi gửi lại", RetryAfter: retry}
+	}
+	return nil
+}
+
+// WriteError trả về 429 kèm header Retry-After nếu err là lỗi vượt giới hạn
+func WriteError(c *gin.Context, err error) bool {
+	var limitErr *Error
+	if !errors.As(err, &limitErr) {
+		return false
+	}
+	c.Header("Retry-After", strconv.Itoa(limitErr.Seconds()))
+	c.JSON(http.StatusTooManyRequests, gin.H{"error": limitErr.Error(), "retryAfter": limitErr.Seconds()})
+	return true
+}
@@ -0,0 +1,22 @@
+// Package requestinfo mang IP và User-Agent của request xuống tầng service qua context
+// (dùng cho lịch sử đăng nhập, nhật ký bảo mật, nhận diện thiết bị).
+package requestinfo
+
+import "context"
+
+type Info struct {
+	IP        string
+	UserAgent string
+}
+
+type contextKey struct{}
+
+func With(ctx context.Context, info Info) context.Context {
+	return context.WithValue(ctx, contextKey{}, info)
+}
+
+// From trả về Info rỗng nếu context không đi qua middleware.RequestInfo
+func From(ctx context.Context) Info {
+	info, _ := ctx.Value(contextKey{}).(Info)
+	return info
+}
@@ -0,0 +1,28 @@
+package utils
+
+import "strings"
+
+// NormalizeEmail chuẩn hoá email trước khi lưu/tra cứu (bỏ khoảng trắng, chữ thường)
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// NormalizePhone bỏ ký tự phân cách thường gặp (khoảng trắng, -, ., ngoặc) rồi chuyển sang E.164
+func NormalizePhone(phone string) string {
+	cleaned := strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '-', '.', '(', ')':
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(phone))
+	return FormatPhoneToE164(cleaned)
+}
+
+// NormalizeIdentifier - identifier đăng nhập có thể là email hoặc số điện thoại
+func NormalizeIdentifier(identifier string) string {
+	if strings.Contains(identifier, "@") {
+		return NormalizeEmail(identifier)
+	}
+	return NormalizePhone(identifier)
+}
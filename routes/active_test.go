@@ -0,0 +1,129 @@
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"socialnetwork/pkg/middleware"
+)
+
+const testJWTSecret = "test-secret"
+
+// newTestDB cần MongoDB thật (MONGO_URI, mặc định mongodb://localhost:27017); không có thì bỏ qua.
+// Mỗi test dùng một database riêng, xoá khi xong
+func newTestDB(t *testing.T) *mongo.Database {
+	t.Helper()
+	uri := os.Getenv("MONGO_URI")
+	if uri == "" {
+		uri = "mongodb://localhost:27017"
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri).SetServerSelectionTimeout(2*time.Second))
+	if err == nil {
+		err = client.Ping(ctx, nil)
+	}
+	if err != nil {
+		t.Skipf("không kết nối được MongoDB %s: %v", uri, err)
+	}
+	db := client.Database("routes_test_" + primitive.NewObjectID().Hex())
+	t.Cleanup(func() {
+		db.Drop(context.Background())
+		client.Disconnect(context.Background())
+	})
+	return db
+}
+
+func insertUser(t *testing.T, db *mongo.Database, suspendedUntil *time.Time) primitive.ObjectID {
+	t.Helper()
+	id := primitive.NewObjectID()
+	doc := bson.M{"_id": id, "username": "u" + id.Hex(), "email": id.Hex() + "@example.com", "emailVerified": true, "createdAt": time.Now()}
+	if suspendedUntil != nil {
+		doc["suspendedUntil"] = *suspendedUntil
+	}
+	if _, err := db.Collection("users").InsertOne(context.Background(), doc); err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+func bearer(t *testing.T, userID primitive.ObjectID) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"user_id": userID.Hex(),
+		"iat":     time.Now().Unix(),
+		"exp":     time.Now().Add(time.Hour).Unix(),
+	})
+	signed, err := token.SignedString([]byte(testJWTSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return "Bearer " + signed
+}
+
+func TestSuspendedUserCannotWriteOutsideMessaging(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("JWT_SECRET", testJWTSecret)
+	db := newTestDB(t)
+
+	r := gin.New()
+	active := middleware.RequireActiveAccount(db)
+	FollowRoutes(r.Group("/api"), db, nil, active)
+	UserRoutes(r, db, nil, nil, active)
+
+	until := time.Now().Add(24 * time.Hour)
+	suspended := insertUser(t, db, &until)
+	target := insertUser(t, db, nil)
+
+	for _, path := range []string{
+		"/api/follows/" + target.Hex(),
+		"/users/" + target.Hex() + "/friends/request",
+		"/users/" + target.Hex() + "/block",
+	} {
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		req.Header.Set("Authorization", bearer(t, suspended))
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		var body struct {
+			Code string `json:"code"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &body)
+		if w.Code != http.StatusForbidden || body.Code != "account_suspended" {
+			t.Fatalf("POST %s: status %d, code %q; muốn 403 account_suspended", path, w.Code, body.Code)
+		}
+	}
+}
+
+func TestActiveUserPassesGuard(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("JWT_SECRET", testJWTSecret)
+	db := newTestDB(t)
+
+	r := gin.New()
+	FollowRoutes(r.Group("/api"), db, nil, middleware.RequireActiveAccount(db))
+
+	// hết hạn tạm khoá thì ghi lại được
+	expired := time.Now().Add(-time.Hour)
+	follower := insertUser(t, db, &expired)
+	target := insertUser(t, db, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/follows/"+target.Hex(), nil)
+	req.Header.Set("Authorization", bearer(t, follower))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code == http.StatusForbidden || w.Code == http.StatusUnauthorized {
+		t.Fatalf("status %d, body %s; tài khoản hết tạm khoá không được bị chặn", w.Code, w.Body.String())
+	}
+}
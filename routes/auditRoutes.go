@@ -0,0 +1,19 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+	"socialnetwork/internal/audit"
+	"socialnetwork/models"
+	"socialnetwork/pkg/middleware"
+)
+
+func AuditRoutes(r *gin.Engine, db *mongo.Database, handler *audit.Handler) {
+	r.GET("/me/security-events", middleware.JWTAuthMiddleware(), handler.MyEvents)
+
+	// Tra cứu nhật ký bảo mật toàn hệ thống: chỉ admin
+	admin := r.Group("/admin/audit-events", middleware.JWTAuthMiddleware(), middleware.RequireRole(db, models.RoleAdmin))
+	{
+		admin.GET("", handler.Search)
+	}
+}
@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"socialnetwork/internal/user"
+	"socialnetwork/pkg/middleware"
+	"socialnetwork/pkg/ratelimit"
+)
+
+func AuthRoutes(r *gin.Engine, handler *user.Handler, limiter *ratelimit.Limiter) {
+	r.POST("/register", middleware.RateLimit(limiter, registerLimit, middleware.ByIP), handler.Register)
+	r.POST("/login", middleware.RateLimit(limiter, loginLimit, middleware.ByIP), handler.Login)
+
+	// Link "Không phải tôi" trong cảnh báo đăng nhập
+	r.POST("/auth/login-alerts/not-me", middleware.RateLimit(limiter, loginLimit, middleware.ByIP), handler.ReportLogin)
+	r.GET("/auth/login-alerts/not-me", middleware.RateLimit(limiter, loginLimit, middleware.ByIP), handler.ReportLoginLink)
+}
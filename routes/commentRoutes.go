@@ -3,18 +3,20 @@ package routes
 import (
     "socialnetwork/internal/comment"
     "socialnetwork/pkg/middleware"
+    "socialnetwork/pkg/ratelimit"
 
     "github.com/gin-gonic/gin"
     "go.mongodb.org/mongo-driver/mongo"
 )
 
-func CommentRoutes(r *gin.Engine, db *mongo.Database, handler *comment.CommentHandler) {
+func CommentRoutes(r *gin.Engine, db *mongo.Database, handler *comment.CommentHandler, limiter *ratelimit.Limiter, verified, active gin.HandlerFunc) {
     commentGroup := r.Group("/comments")
 
 
-    commentGroup.POST("/:postID",middleware.JWTAuthMiddleware(), handler.CreateComment)
-    commentGroup.GET("/:postID", handler.GetCommentsByPost)
-    commentGroup.PUT("/:id", middleware.JWTAuthMiddleware(), handler.UpdateComment)
+    commentGroup.POST("/:postID",middleware.JWTAuthMiddleware(), verified, middleware.RateLimit(limiter, contentLimit, middleware.ByUser), handler.CreateComment)
+    commentGroup.GET("/:postID", middleware.OptionalAuthMiddleware(), handler.GetCommentsByPost)
+    commentGroup.PUT("/:id", middleware.JWTAuthMiddleware(), verified, handler.UpdateComment)
     commentGroup.DELETE("/:id", middleware.JWTAuthMiddleware(), handler.DeleteComment)
-	commentGroup.PUT("/:id/like", middleware.JWTAuthMiddleware(), handler.ToggleLike)
+	commentGroup.PUT("/:id/like", middleware.JWTAuthMiddleware(), active, handler.ToggleLike)
+    commentGroup.GET("/by-id/:id/revisions", handler.GetCommentRevisions)
 }
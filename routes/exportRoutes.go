@@ -0,0 +1,11 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"socialnetwork/internal/export"
+)
+
+// ExportRoutes - link tải bản xuất dữ liệu (xác thực bằng chữ ký trong link, không cần JWT)
+func ExportRoutes(r *gin.Engine, handler *export.Handler) {
+	r.GET("/exports/:id/download", handler.Download)
+}
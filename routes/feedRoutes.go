@@ -0,0 +1,14 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"socialnetwork/internal/feed"
+	"socialnetwork/pkg/middleware"
+)
+
+func FeedRoutes(r *gin.Engine, handler *feed.Handler) {
+	feedRoutes := r.Group("/video_short", middleware.JWTAuthMiddleware())
+	{
+		feedRoutes.GET("/shorts/feed", handler.GetShortsFeed)
+	}
+}
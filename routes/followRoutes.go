@@ -9,7 +9,7 @@ import (
 	"socialnetwork/pkg/middleware"
 )
 
-func FollowRoutes(rg *gin.RouterGroup, db *mongo.Database, followHandler *follow.FollowHandler) {
+func FollowRoutes(rg *gin.RouterGroup, db *mongo.Database, followHandler *follow.FollowHandler, active gin.HandlerFunc) {
 	followRepo := follow.NewFollowRepository(db)
 	userRepo := user.NewRepository(db)
 	notifRepo := notification.NewNotificationRepository(db)
@@ -19,8 +19,8 @@ func FollowRoutes(rg *gin.RouterGroup, db *mongo.Database, followHandler *follow
 
 	followRoutes := rg.Group("/follows", middleware.JWTAuthMiddleware())
 	{
-		followRoutes.POST("/:id", handler.FollowUser)              // follow user with id
-		followRoutes.DELETE("/:id", handler.UnfollowUser)          // unfollow user with id
+		followRoutes.POST("/:id", active, handler.FollowUser)     // follow user with id
+		followRoutes.DELETE("/:id", active, handler.UnfollowUser) // unfollow user with id
 		followRoutes.GET("/:id/followers", handler.ListFollowers)
 		followRoutes.GET("/:id/following", handler.ListFollowing)
 	}
@@ -0,0 +1,48 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"socialnetwork/internal/groups"
+	"socialnetwork/pkg/middleware"
+	"socialnetwork/pkg/ratelimit"
+)
+
+func GroupRoutes(r *gin.Engine, handler *groups.Handler, limiter *ratelimit.Limiter, verified, active gin.HandlerFunc) {
+	g := r.Group("/groups", middleware.JWTAuthMiddleware())
+	{
+		g.POST("", verified, middleware.RateLimit(limiter, contentLimit, middleware.ByUser), handler.Create)
+		g.GET("", handler.Discover)
+		g.GET("/mine", handler.Mine)
+		g.GET("/:id", handler.Get)
+		g.PUT("/:id", active, handler.Update)
+		g.DELETE("/:id", handler.Delete)
+		g.PUT("/:id/rules", active, handler.UpdateRules)
+
+		// Tham gia, lời mời và yêu cầu tham gia
+		g.POST("/:id/join", active, handler.Join)
+		g.POST("/:id/leave", handler.Leave)
+		g.POST("/:id/invitations", active, handler.Invite)
+		g.POST("/:id/invitation/decline", handler.DeclineInvite)
+		g.GET("/:id/requests", handler.Requests)
+		g.POST("/:id/requests/:userId/approve", active, handler.ApproveRequest)
+		g.POST("/:id/requests/:userId/decline", active, handler.DeclineRequest)
+
+		// Thành viên và kiểm duyệt trong nhóm
+		g.GET("/:id/members", handler.Members)
+		g.PUT("/:id/members/:userId/role", active, handler.SetRole)
+		g.POST("/:id/members/:userId/remove", active, handler.RemoveMember)
+		g.POST("/:id/members/:userId/ban", active, handler.Ban)
+		g.POST("/:id/members/:userId/unban", active, handler.Unban)
+		g.POST("/:id/members/:userId/mute", active, handler.Mute)
+		g.PUT("/:id/notifications", handler.SetNotifications)
+		g.GET("/:id/moderation-log", handler.ModerationLog)
+
+		// Bài viết trong nhóm
+		g.GET("/:id/posts", handler.Posts)
+		g.POST("/:id/posts", verified, middleware.RateLimit(limiter, contentLimit, middleware.ByUser), handler.CreatePost)
+		g.GET("/:id/posts/pending", handler.PendingPosts)
+		g.POST("/:id/posts/:postId/approve", active, handler.ApprovePost)
+		g.POST("/:id/posts/:postId/decline", active, handler.RemovePost)
+		g.DELETE("/:id/posts/:postId", active, handler.RemovePost)
+	}
+}
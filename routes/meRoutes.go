@@ -0,0 +1,24 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"socialnetwork/internal/account"
+	"socialnetwork/internal/analytics"
+	"socialnetwork/internal/export"
+	"socialnetwork/internal/trash"
+	"socialnetwork/pkg/middleware"
+)
+
+// MeRoutes - các API cá nhân của người dùng đang đăng nhập (/me/...)
+func MeRoutes(r *gin.Engine, analyticsHandler *analytics.Handler, trashHandler *trash.Handler, accountHandler *account.Handler, exportHandler *export.Handler, active gin.HandlerFunc) {
+	me := r.Group("/me", middleware.JWTAuthMiddleware())
+	{
+		me.GET("/analytics", analyticsHandler.GetMyAnalytics)
+		me.GET("/trash", trashHandler.GetTrash)
+		me.POST("/trash/:type/:id/restore", active, trashHandler.Restore)
+		me.POST("/deactivate", accountHandler.Deactivate)
+		me.DELETE("", accountHandler.Delete)
+		me.POST("/export", exportHandler.RequestExport)
+		me.GET("/export", exportHandler.ListExports)
+	}
+}
@@ -0,0 +1,38 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"socialnetwork/internal/messaging"
+	"socialnetwork/pkg/middleware"
+	"socialnetwork/pkg/ratelimit"
+)
+
+func MessagingRoutes(r *gin.Engine, handler *messaging.Handler, limiter *ratelimit.Limiter, active gin.HandlerFunc) {
+	conversations := r.Group("/conversations", middleware.JWTAuthMiddleware())
+	{
+		conversations.GET("", handler.List)
+		conversations.GET("/unread", handler.Unread)
+		conversations.POST("/direct", active, handler.StartDirect)
+		conversations.POST("/groups", active, middleware.RateLimit(limiter, contentLimit, middleware.ByUser), handler.CreateGroup)
+		conversations.GET("/:id", handler.Get)
+		conversations.POST("/:id/members", active, handler.AddMembers)
+		conversations.POST("/:id/leave", handler.Leave)
+		conversations.GET("/:id/messages", handler.Messages)
+		conversations.POST("/:id/messages", active, middleware.RateLimit(limiter, messageLimit, middleware.ByUser), handler.Send)
+		conversations.POST("/:id/read", handler.MarkRead)
+
+		// Tin nhắn chờ từ người lạ
+		conversations.POST("/:id/accept", handler.AcceptRequest)
+		conversations.POST("/:id/decline", handler.DeclineRequest)
+		conversations.POST("/:id/block", handler.BlockRequest)
+	}
+
+	messages := r.Group("/messages", middleware.JWTAuthMiddleware())
+	{
+		messages.PUT("/:id", active, handler.Edit)
+		messages.DELETE("/:id", handler.Delete)
+	}
+
+	// Sự kiện realtime: tin nhắn mới/sửa/thu hồi, read receipt, đang soạn tin
+	r.GET("/ws/messages", middleware.WebSocketAuthMiddleware(), handler.Connect)
+}
@@ -0,0 +1,32 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+	"socialnetwork/internal/contentfilter"
+	"socialnetwork/internal/moderation"
+	"socialnetwork/models"
+	"socialnetwork/pkg/middleware"
+)
+
+func ModerationRoutes(r *gin.Engine, db *mongo.Database, handler *moderation.Handler, filterHandler *contentfilter.Handler, active gin.HandlerFunc) {
+	r.POST("/reports", middleware.JWTAuthMiddleware(), active, handler.CreateReport)
+
+	// Hàng đợi kiểm duyệt: chỉ moderator/admin
+	mod := r.Group("/moderation", middleware.JWTAuthMiddleware(), middleware.RequireRole(db, models.RoleModerator, models.RoleAdmin))
+	{
+		mod.GET("/reports", handler.GetQueue)
+		mod.GET("/reports/:type/:id", handler.GetReports)
+		mod.POST("/reports/:type/:id/action", handler.TakeAction)
+	}
+
+	// Luật lọc nội dung tự động: chỉ admin
+	rules := r.Group("/moderation/filter-rules", middleware.JWTAuthMiddleware(), middleware.RequireRole(db, models.RoleAdmin))
+	{
+		rules.GET("", filterHandler.ListRules)
+		rules.POST("", filterHandler.CreateRule)
+		rules.PUT("/:id", filterHandler.UpdateRule)
+		rules.DELETE("/:id", filterHandler.DeleteRule)
+		rules.POST("/reload", filterHandler.Reload)
+	}
+}
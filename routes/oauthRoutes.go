@@ -0,0 +1,25 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"socialnetwork/internal/auth"
+	"socialnetwork/pkg/middleware"
+	"socialnetwork/pkg/ratelimit"
+)
+
+func OAuthRoutes(r *gin.Engine, handler *auth.Handler, limiter *ratelimit.Limiter) {
+	oauth := r.Group("/auth/oauth")
+	{
+		oauth.GET("/providers", handler.ListProviders)
+		oauth.GET("/:provider", middleware.RateLimit(limiter, loginLimit, middleware.ByIP), handler.Start)
+		oauth.GET("/:provider/callback", middleware.RateLimit(limiter, loginLimit, middleware.ByIP), handler.Callback)
+	}
+
+	identities := r.Group("/me/identities")
+	identities.Use(middleware.JWTAuthMiddleware())
+	{
+		identities.GET("", handler.ListIdentities)
+		identities.POST("/:provider", handler.Link)
+		identities.DELETE("/:id", handler.Unlink)
+	}
+}
@@ -3,10 +3,12 @@ package routes
 import (
 	"github.com/gin-gonic/gin"
 	"socialnetwork/internal/otp"
+	"socialnetwork/pkg/middleware"
+	"socialnetwork/pkg/ratelimit"
 )
 
-func OTProutes(r *gin.Engine, otpHandler *otp.OTPHandler) {
-	otpGroup := r.Group("/otp")
+func OTProutes(r *gin.Engine, otpHandler *otp.OTPHandler, limiter *ratelimit.Limiter) {
+	otpGroup := r.Group("/otp", middleware.RateLimit(limiter, otpLimit, middleware.ByIP))
 	{
 		otpGroup.POST("/send", otpHandler.SendOTP)
 		otpGroup.POST("/verify", otpHandler.VerifyOTP)
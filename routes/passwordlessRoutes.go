@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"socialnetwork/internal/passwordless"
+	"socialnetwork/pkg/middleware"
+	"socialnetwork/pkg/ratelimit"
+)
+
+func PasswordlessRoutes(r *gin.Engine, handler *passwordless.Handler, limiter *ratelimit.Limiter) {
+	group := r.Group("/auth/passwordless")
+	{
+		group.POST("/start", middleware.RateLimit(limiter, otpLimit, middleware.ByIP), handler.Start)
+		group.POST("/complete", middleware.RateLimit(limiter, loginLimit, middleware.ByIP), handler.Complete)
+		group.GET("/complete", middleware.RateLimit(limiter, loginLimit, middleware.ByIP), handler.CompleteLink)
+	}
+}
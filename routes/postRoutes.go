@@ -4,15 +4,17 @@ import (
     "socialnetwork/internal/post"
     "github.com/gin-gonic/gin"
     "socialnetwork/pkg/middleware"
+    "socialnetwork/pkg/ratelimit"
 )
 
-func PostRoutes(r *gin.Engine, postHandler *post.PostHandler) {
+func PostRoutes(r *gin.Engine, postHandler *post.PostHandler, limiter *ratelimit.Limiter, verified gin.HandlerFunc) {
     posts := r.Group("/posts")
     {
-        posts.POST("create", middleware.JWTAuthMiddleware(), postHandler.CreatePost)
+        posts.POST("create", middleware.JWTAuthMiddleware(), verified, middleware.RateLimit(limiter, contentLimit, middleware.ByUser), postHandler.CreatePost)
         posts.GET("/public/:ownerID", postHandler.GetPublicPostsByOwner)
         posts.GET("/:id", postHandler.GetPost)
-        posts.PUT("/:id", middleware.JWTAuthMiddleware(), postHandler.UpdatePost)
+        posts.GET("/:id/revisions", postHandler.GetPostRevisions)
+        posts.PUT("/:id", middleware.JWTAuthMiddleware(), verified, postHandler.UpdatePost)
         posts.DELETE("/:id", middleware.JWTAuthMiddleware(), postHandler.DeletePost)
         posts.GET("", postHandler.ListPosts)
     }
@@ -0,0 +1,19 @@
+package routes
+
+import (
+	"time"
+
+	"socialnetwork/pkg/ratelimit"
+)
+
+// Giới hạn request theo nhóm route; giới hạn theo từng identifier nằm trong otp.Service
+var (
+	loginLimit    = ratelimit.Rule{Name: "login", Limit: 10, Window: time.Minute}
+	registerLimit = ratelimit.Rule{Name: "register", Limit: 5, Window: time.Hour}
+	otpLimit      = ratelimit.Rule{Name: "otp", Limit: 10, Window: 10 * time.Minute}
+	passwordLimit = ratelimit.Rule{Name: "password", Limit: 10, Window: 10 * time.Minute}
+	contentLimit  = ratelimit.Rule{Name: "content", Limit: 30, Window: time.Minute}
+	uploadLimit   = ratelimit.Rule{Name: "upload", Limit: 10, Window: time.Hour}
+	messageLimit  = ratelimit.Rule{Name: "message", Limit: 60, Window: time.Minute}
+	viewLimit     = ratelimit.Rule{Name: "view", Limit: 120, Window: time.Minute}
+)
@@ -0,0 +1,26 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"socialnetwork/internal/twofactor"
+	"socialnetwork/pkg/middleware"
+	"socialnetwork/pkg/ratelimit"
+)
+
+func TwoFactorRoutes(r *gin.Engine, handler *twofactor.Handler, limiter *ratelimit.Limiter) {
+	// Bước thứ hai của đăng nhập: chưa có JWT, xác định bằng challengeToken
+	challenge := r.Group("/auth/2fa", middleware.RateLimit(limiter, loginLimit, middleware.ByIP))
+	{
+		challenge.POST("/send", handler.SendCode)
+		challenge.POST("/verify", handler.Verify)
+	}
+
+	me := r.Group("/me/2fa", middleware.JWTAuthMiddleware())
+	{
+		me.GET("", handler.GetStatus)
+		me.POST("/setup", handler.Setup)
+		me.POST("/confirm", handler.Confirm)
+		me.POST("/recovery-codes", handler.RegenerateRecoveryCodes)
+		me.DELETE("", handler.Disable)
+	}
+}
@@ -5,22 +5,31 @@ import (
 	"go.mongodb.org/mongo-driver/mongo"
 	"socialnetwork/internal/user"
 	"socialnetwork/pkg/middleware"
+	"socialnetwork/pkg/ratelimit"
 )
 
-func UserRoutes(r *gin.Engine, db *mongo.Database, handler *user.Handler) {
+func UserRoutes(r *gin.Engine, db *mongo.Database, handler *user.Handler, limiter *ratelimit.Limiter, active gin.HandlerFunc) {
 	userRoutes := r.Group("/users")
+	passwordGuard := middleware.RateLimit(limiter, passwordLimit, middleware.ByIP)
 	{
 		userRoutes.GET("/", handler.GetUsers)
 		userRoutes.GET("/me", middleware.JWTAuthMiddleware(), handler.GetMe)
-		userRoutes.PUT("/me", middleware.JWTAuthMiddleware(), handler.UpdateMe)
+		userRoutes.PUT("/me", middleware.JWTAuthMiddleware(), active, handler.UpdateMe)
+		userRoutes.GET("/me/login-history", middleware.JWTAuthMiddleware(), handler.GetLoginHistory)
+		userRoutes.GET("/me/devices", middleware.JWTAuthMiddleware(), handler.GetDevices)
+		userRoutes.DELETE("/me/devices/:id", middleware.JWTAuthMiddleware(), handler.RemoveDevice)
 		userRoutes.POST("/change-password", middleware.JWTAuthMiddleware(), handler.ChangePassword)
-		userRoutes.POST("/forgot-password", handler.ForgotPassword)
-		userRoutes.POST("/reset-password", handler.ResetPassword)
-		userRoutes.POST("/change-email", middleware.JWTAuthMiddleware(), handler.ChangeEmailRequest)
-		userRoutes.POST("/verify-email", middleware.JWTAuthMiddleware(), handler.VerifyEmailRequest)
-		userRoutes.POST("/:id/friends/request", middleware.JWTAuthMiddleware(), handler.SendFriendRequest)
-		userRoutes.POST("/:id/friends/accept", middleware.JWTAuthMiddleware(),handler.AcceptFriendRequest)
-		userRoutes.POST("/:id/block", middleware.JWTAuthMiddleware(), handler.BlockUser)
-		userRoutes.PUT("/me/hide-profile", middleware.JWTAuthMiddleware(), handler.ToggleHideProfile)
+		userRoutes.POST("/forgot-password", passwordGuard, handler.ForgotPassword)
+		userRoutes.POST("/reset-password", passwordGuard, handler.ResetPassword)
+		userRoutes.POST("/change-email", middleware.JWTAuthMiddleware(), passwordGuard, handler.ChangeEmailRequest)
+		userRoutes.POST("/verify-email", middleware.JWTAuthMiddleware(), passwordGuard, handler.VerifyEmailRequest)
+		userRoutes.POST("/change-phone", middleware.JWTAuthMiddleware(), passwordGuard, handler.ChangePhoneRequest)
+		userRoutes.POST("/verify-phone", middleware.JWTAuthMiddleware(), passwordGuard, handler.VerifyPhoneRequest)
+		userRoutes.POST("/contact-change/cancel", passwordGuard, handler.CancelContactChange)
+		userRoutes.GET("/contact-change/cancel", passwordGuard, handler.CancelContactChangeLink)
+		userRoutes.POST("/:id/friends/request", middleware.JWTAuthMiddleware(), active, handler.SendFriendRequest)
+		userRoutes.POST("/:id/friends/accept", middleware.JWTAuthMiddleware(), active, handler.AcceptFriendRequest)
+		userRoutes.POST("/:id/block", middleware.JWTAuthMiddleware(), active, handler.BlockUser)
+		userRoutes.PUT("/me/hide-profile", middleware.JWTAuthMiddleware(), active, handler.ToggleHideProfile)
 	}
 }
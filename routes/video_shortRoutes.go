@@ -3,27 +3,33 @@ package routes
 import (
 	"github.com/gin-gonic/gin"
 	"socialnetwork/pkg/middleware"
+	"socialnetwork/pkg/ratelimit"
 	"socialnetwork/internal/short"
 	"socialnetwork/internal/video"
 )
 
-func Video_ShortRoutes(r *gin.Engine, videoService video.VideoService, shortService short.ShortService) {
+func Video_ShortRoutes(r *gin.Engine, videoService video.VideoService, shortService short.ShortService, limiter *ratelimit.Limiter, verified gin.HandlerFunc) {
 	videoHandler := video.NewVideoHandler(videoService)
 	shortHandler := short.NewShortHandler(shortService)
 
 	authRoutes := r.Group("/video_short", middleware.JWTAuthMiddleware())
+	uploadGuard := middleware.RateLimit(limiter, uploadLimit, middleware.ByUser)
 	{
 		// Video routes
-		authRoutes.POST("/videos", videoHandler.CreateVideo)
+		authRoutes.POST("/videos", verified, uploadGuard, videoHandler.CreateVideo)
 		authRoutes.GET("/videos/:id", videoHandler.GetVideoByID)
 		authRoutes.GET("/videos", videoHandler.GetVideosByOwner)
+		authRoutes.PUT("/videos/:id", verified, videoHandler.UpdateVideo)
+		authRoutes.GET("/videos/:id/revisions", videoHandler.GetVideoRevisions)
 		authRoutes.DELETE("/videos/:id", videoHandler.DeleteVideo)
 
 		// Short routes
-		authRoutes.POST("/shorts", shortHandler.CreateShort)
+		authRoutes.POST("/shorts", verified, uploadGuard, shortHandler.CreateShort)
 		authRoutes.GET("/shorts/public/:ownerID", shortHandler.GetPublicShortsByOwner)
 		authRoutes.GET("/shorts/:id", shortHandler.GetShortByID)
 		authRoutes.GET("/shorts", shortHandler.GetShortsByOwner)
+		authRoutes.PUT("/shorts/:id", verified, shortHandler.UpdateShort)
+		authRoutes.GET("/shorts/:id/revisions", shortHandler.GetShortRevisions)
 		authRoutes.DELETE("/shorts/:id", shortHandler.DeleteShort)
 	}
 }
@@ -0,0 +1,19 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"socialnetwork/internal/views"
+	"socialnetwork/pkg/middleware"
+	"socialnetwork/pkg/ratelimit"
+)
+
+func ViewRoutes(r *gin.Engine, handler *views.Handler, limiter *ratelimit.Limiter) {
+	viewRoutes := r.Group("/video_short", middleware.JWTAuthMiddleware())
+	viewGuard := middleware.RateLimit(limiter, viewLimit, middleware.ByUser)
+	{
+		viewRoutes.POST("/videos/:id/view", viewGuard, handler.TrackVideoView)
+		viewRoutes.GET("/videos/:id/analytics", handler.GetVideoAnalytics)
+		viewRoutes.POST("/shorts/:id/view", viewGuard, handler.TrackShortView)
+		viewRoutes.GET("/shorts/:id/analytics", handler.GetShortAnalytics)
+	}
+}